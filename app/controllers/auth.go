@@ -2,17 +2,19 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"strconv"
 	"time"
 
 	"gin-fast/app/global/app"
 	"gin-fast/app/models"
+	"gin-fast/app/service"
 
 	"gin-fast/app/utils/common"
-	"gin-fast/app/utils/passwordhelper"
 
-	"github.com/dchest/captcha"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
@@ -20,12 +22,26 @@ import (
 
 type AuthController struct {
 	Common
+	AuthService *service.AuthService
+	MfaService  *service.MfaService
+	OidcService *service.OidcService
+
+	PasswordResetService  *service.PasswordResetService
+	PasswordPolicyService *service.PasswordPolicyService
+	TenantHostService     *service.TenantHostService
 }
 
 // NewAuthController 创建认证控制器
 func NewAuthController() *AuthController {
 	return &AuthController{
-		Common: Common{},
+		Common:      Common{},
+		AuthService: service.NewAuthService(),
+		MfaService:  service.NewMfaService(),
+		OidcService: service.NewOidcService(),
+
+		PasswordResetService:  service.NewPasswordResetService(),
+		PasswordPolicyService: service.NewPasswordPolicyService(),
+		TenantHostService:     service.NewTenantHostService(),
 	}
 }
 
@@ -45,8 +61,9 @@ func (ac *AuthController) Login(c *gin.Context) {
 	if err := req.Validate(c); err != nil {
 		ac.FailAndAbort(c, err.Error(), err)
 	}
+	req.TenantCode = ac.hostTenantCode(c, req.TenantCode)
 
-	// 根据用户名查找用户
+	// 根据用户名查找用户(目录服务登录的用户首次登录时本地可能不存在)
 	user := models.NewUser()
 	err := user.Find(c, func(d *gorm.DB) *gorm.DB {
 		return d.Where("username = ?", req.Username).Preload("Tenant")
@@ -54,75 +71,21 @@ func (ac *AuthController) Login(c *gin.Context) {
 	if err != nil {
 		ac.FailAndAbort(c, "用户查询错误", err)
 	}
-
-	if user.IsEmpty() {
-		ac.FailAndAbort(c, "用户不存在", nil)
-	}
-	if user.Status != 1 {
+	if !user.IsEmpty() && user.Status != 1 {
 		ac.FailAndAbort(c, "用户未启用", nil)
 	}
 
-	var tenantID uint
-	var tenantCode string
-
-	// 检查租户是否存在
-	if req.TenantCode != "" {
-		if user.TenantID > 0 {
-			// 查询用户关联的所有租户
-			userTenantList := models.NewSysUserTenantList()
-			err = userTenantList.Find(c, func(db *gorm.DB) *gorm.DB {
-				return db.Where("user_id = ?", user.ID).Preload("Tenant")
-			})
-			if err != nil {
-				ac.FailAndAbort(c, "查询用户租户关联信息错误", err)
-			}
-
-			// 构建用户关联的租户映射
-			userTenants := make(map[string]*models.Tenant)
-			for _, ut := range userTenantList {
-				if ut.Tenant != nil && ut.Tenant.Code != "" {
-					userTenants[ut.Tenant.Code] = ut.Tenant
-				}
-			}
-			// 检查请求的租户编码是否在用户关联的租户集合中
-			tenant, exists := userTenants[req.TenantCode]
-			if !exists {
-				ac.FailAndAbort(c, "租户编码不在用户关联的租户列表中", nil)
-			}
-
-			if tenant.Status != 1 {
-				ac.FailAndAbort(c, "租户未启用", nil)
-			}
-
-			tenantID = tenant.ID
-			tenantCode = tenant.Code
-		} else {
-			// 全局租户无需检查关联租户
-			tenant := models.NewTenant()
-			err = tenant.Find(c, func(d *gorm.DB) *gorm.DB {
-				return d.Where("code = ?", req.TenantCode)
-			})
-			if err != nil {
-				ac.FailAndAbort(c, "查询租户错误", err)
-			}
-			if tenant.IsEmpty() {
-				ac.FailAndAbort(c, "租户不存在", nil)
-			}
-			if tenant.Status != 1 {
-				ac.FailAndAbort(c, "租户未启用", nil)
-			}
-			tenantID = tenant.ID
-			tenantCode = tenant.Code
-		}
-
-	} else {
-		// 不输入租户编码则使用用户默认租户
-		// 非全局租户需检查启用状态
-		if user.Tenant.ID > 0 && user.Tenant.Status != 1 {
-			ac.FailAndAbort(c, "租户未启用", nil)
-		}
-		tenantID = user.Tenant.ID
-		tenantCode = user.Tenant.Code
+	// 按用户及目标租户选择认证方式(本地密码、目录服务)
+	tenant, err := ac.AuthService.LoginTenant(c, user, req.TenantCode)
+	if err != nil {
+		ac.FailAndAbort(c, "查询租户错误", err)
+	}
+	authenticator, err := ac.AuthService.Authenticator(c, user, tenant)
+	if err != nil {
+		ac.FailAndAbort(c, "查询认证方式错误", err)
+	}
+	if authenticator == nil {
+		ac.FailAndAbort(c, "用户不存在", nil)
 	}
 
 	// 获取安全配置
@@ -140,7 +103,8 @@ func (ac *AuthController) Login(c *gin.Context) {
 		}
 
 		// 验证密码
-		if err = passwordhelper.ComparePassword(user.Password, req.Password); err != nil {
+		authUser, err := authenticator.Authenticate(c, user, tenant, req.Username, req.Password)
+		if errors.Is(err, service.ErrInvalidCredentials) {
 			// 密码错误，增加失败次数
 			failCountKey := "login_fail_count:" + req.Username
 
@@ -169,51 +133,403 @@ func (ac *AuthController) Login(c *gin.Context) {
 			ac.FailAndAbort(c, "密码错误，剩余尝试次数: "+strconv.Itoa(remainingAttempts), nil)
 			return
 		}
+		if err != nil {
+			ac.FailAndAbort(c, err.Error(), err)
+		}
 
 		// 密码正确，清除失败次数
 		failCountKey := "login_fail_count:" + req.Username
 		app.Cache.Del(context.Background(), failCountKey)
+		user = authUser
 	} else {
 		// 未启用登录锁定功能，使用原有逻辑
 		// 验证密码
-		if err = passwordhelper.ComparePassword(user.Password, req.Password); err != nil {
+		authUser, err := authenticator.Authenticate(c, user, tenant, req.Username, req.Password)
+		if errors.Is(err, service.ErrInvalidCredentials) {
 			ac.FailAndAbort(c, "密码错误", err)
 		}
+		if err != nil {
+			ac.FailAndAbort(c, err.Error(), err)
+		}
+		user = authUser
 	}
 
-	// 生成token
-	user.Password = ""
-	token, err := app.TokenService.GenerateTokenWithCache(&app.ClaimsUser{
+	// 目录服务认证的用户可能为首次登录时创建或已绑定的用户，重新加载
+	if user.Tenant.ID != user.TenantID {
+		err = user.Find(c, func(d *gorm.DB) *gorm.DB {
+			return d.Where("id = ?", user.ID).Preload("Tenant")
+		})
+		if err != nil {
+			ac.FailAndAbort(c, "用户查询错误", err)
+		}
+	}
+	if user.Status != 1 {
+		ac.FailAndAbort(c, "用户未启用", nil)
+	}
+
+	tenantID, tenantCode := ac.resolveTenant(c, user, req.TenantCode)
+
+	// 本地密码超过最长使用天数，需先修改密码再完成登录
+	if authenticator.Name() == service.PasswordAuthenticatorName {
+		expired, err := ac.PasswordPolicyService.IsExpired(c, user)
+		if err != nil {
+			ac.FailAndAbort(c, "查询密码策略失败", err)
+		}
+		if expired {
+			passwordToken, passwordExpires, err := ac.PasswordPolicyService.CreateChallenge(&service.PasswordChangeChallenge{
+				UserID:     user.ID,
+				TenantID:   tenantID,
+				TenantCode: tenantCode,
+				DeviceName: req.DeviceName,
+			})
+			if err != nil {
+				ac.FailAndAbort(c, "生成修改密码令牌失败", err)
+			}
+			ac.Success(c, gin.H{
+				"passwordExpired":      true,
+				"passwordToken":        passwordToken,
+				"passwordTokenExpires": passwordExpires.Unix(),
+			})
+			return
+		}
+	}
+
+	ac.Success(c, ac.completeLogin(c, user, tenantID, tenantCode, req.DeviceName))
+}
+
+// LoginPassword 密码过期时修改密码并继续登录
+// @Summary 密码过期时修改密码并继续登录
+// @Description 使用登录返回的修改密码令牌设置新密码，成功后继续登录流程(需要二次验证时返回二次验证令牌)
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param req body models.PasswordChangeLoginRequest true "修改密码令牌及新密码"
+// @Success 200 {object} map[string]interface{} "成功返回访问令牌"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /login/password [post]
+func (ac *AuthController) LoginPassword(c *gin.Context) {
+	var req models.PasswordChangeLoginRequest
+	if err := req.Validate(c); err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+
+	challenge, err := ac.PasswordPolicyService.GetChallenge(req.PasswordToken)
+	if err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+	user := models.NewUser()
+	if err = user.GetUserByID(c, challenge.UserID); err != nil {
+		ac.FailAndAbort(c, "用户查询错误", err)
+	}
+	if user.IsEmpty() || user.Status != 1 {
+		ac.FailAndAbort(c, "用户不存在或未启用", nil)
+	}
+	if err = ac.PasswordPolicyService.SetPassword(c, user, req.Password); err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+	ac.PasswordPolicyService.DeleteChallenge(req.PasswordToken)
+
+	ac.Success(c, ac.completeLogin(c, user, challenge.TenantID, challenge.TenantCode, challenge.DeviceName))
+}
+
+// completeLogin 身份认证通过后完成登录(密码登录及第三方登录共用)
+// 已绑定或被强制要求二次验证的用户先返回挑战令牌，验证通过后再签发token
+func (ac *AuthController) completeLogin(c *gin.Context, user *models.User, tenantID uint, tenantCode string, deviceName string) gin.H {
+	mfaRequired, mfaEnrolled, err := ac.MfaService.CheckLogin(c, user.ID, tenantID)
+	if err != nil {
+		ac.FailAndAbort(c, "查询二次验证信息失败", err)
+	}
+	if mfaRequired {
+		mfaToken, mfaExpires, err := ac.MfaService.CreateChallenge(&service.MfaChallenge{
+			UserID:     user.ID,
+			Username:   user.Username,
+			TenantID:   tenantID,
+			TenantCode: tenantCode,
+			DeviceName: deviceName,
+			Enroll:     !mfaEnrolled,
+		})
+		if err != nil {
+			ac.FailAndAbort(c, "生成二次验证令牌失败", err)
+		}
+		return gin.H{
+			"mfaRequired":     true,
+			"mfaEnrolled":     mfaEnrolled,
+			"mfaToken":        mfaToken,
+			"mfaTokenExpires": mfaExpires.Unix(),
+		}
+	}
+
+	return ac.issueLoginToken(c, &app.ClaimsUser{
 		UserID:     user.ID,
 		Username:   user.Username,
 		TenantID:   tenantID,
 		TenantCode: tenantCode,
-	})
+	}, deviceName)
+}
 
-	if err != nil {
-		ac.FailAndAbort(c, "生成token失败", err)
+// resolveTenant 校验并确定用户登录的租户(登录、刷新token及切换租户共用同一规则)
+// 指定租户编码时，非全局用户需在 sys_user_tenant 中关联该租户；未指定时使用用户默认租户
+// user 需预加载 Tenant
+func (ac *AuthController) resolveTenant(c *gin.Context, user *models.User, tenantCode string) (uint, string) {
+	// 检查租户是否存在
+	if tenantCode != "" {
+		if user.TenantID > 0 {
+			// 查询用户关联的所有租户
+			userTenantList := models.NewSysUserTenantList()
+			err := userTenantList.Find(c, func(db *gorm.DB) *gorm.DB {
+				return db.Where("user_id = ?", user.ID).Preload("Tenant")
+			})
+			if err != nil {
+				ac.FailAndAbort(c, "查询用户租户关联信息错误", err)
+			}
+
+			// 构建用户关联的租户映射
+			userTenants := make(map[string]*models.Tenant)
+			for _, ut := range userTenantList {
+				if ut.Tenant != nil && ut.Tenant.Code != "" {
+					userTenants[ut.Tenant.Code] = ut.Tenant
+				}
+			}
+			// 检查请求的租户编码是否在用户关联的租户集合中
+			tenant, exists := userTenants[tenantCode]
+			if !exists {
+				ac.FailAndAbort(c, "租户编码不在用户关联的租户列表中", nil)
+			}
+
+			if tenant.Status != 1 {
+				ac.FailAndAbort(c, "租户未启用", nil)
+			}
+
+			return tenant.ID, tenant.Code
+		} else {
+			// 全局租户无需检查关联租户
+			tenant := models.NewTenant()
+			err := tenant.Find(c, func(d *gorm.DB) *gorm.DB {
+				return d.Where("code = ?", tenantCode)
+			})
+			if err != nil {
+				ac.FailAndAbort(c, "查询租户错误", err)
+			}
+			if tenant.IsEmpty() {
+				ac.FailAndAbort(c, "租户不存在", nil)
+			}
+			if tenant.Status != 1 {
+				ac.FailAndAbort(c, "租户未启用", nil)
+			}
+			return tenant.ID, tenant.Code
+		}
+
+	} else {
+		// 不输入租户编码则使用用户默认租户
+		// 非全局租户需检查启用状态
+		if user.Tenant.ID > 0 && user.Tenant.Status != 1 {
+			ac.FailAndAbort(c, "租户未启用", nil)
+		}
+		return user.Tenant.ID, user.Tenant.Code
 	}
+}
 
-	// 生成refresh token
-	refreshToken, err := app.TokenService.GenerateRefreshToken(user.ID)
-	if err != nil {
-		ac.FailAndAbort(c, "生成refresh token失败", err)
+// hostTenantCode 通过租户域名访问时使用域名对应的租户编码，指定其他租户编码时中止请求
+func (ac *AuthController) hostTenantCode(c *gin.Context, tenantCode string) string {
+	tenant := ac.TenantHostService.HostTenant(c)
+	if tenant == nil {
+		return tenantCode
 	}
-	claims, err := app.TokenService.ParseToken(token)
+	if tenantCode != "" && tenantCode != tenant.Code {
+		ac.FailAndAbort(c, "租户编码与访问域名不匹配", nil)
+	}
+	return tenant.Code
+}
+
+// issueLoginToken 创建登录会话并签发访问令牌及刷新令牌
+func (ac *AuthController) issueLoginToken(c *gin.Context, claimsUser *app.ClaimsUser, deviceName string) gin.H {
+	// 生成refresh token，每次登录创建一个独立的设备会话
+	refreshToken, err := app.TokenService.GenerateRefreshTokenWithSession(claimsUser.UserID, &app.SessionInfo{
+		TenantID:   claimsUser.TenantID,
+		TenantCode: claimsUser.TenantCode,
+		DeviceName: deviceName,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	})
 	if err != nil {
-		ac.FailAndAbort(c, "解析token失败", err)
+		ac.FailAndAbort(c, "生成refresh token失败", err)
 	}
 	claims1, err := app.TokenService.ParseRefreshToken(refreshToken)
 	if err != nil {
 		ac.FailAndAbort(c, "解析refreshToken失败", err)
 	}
 
-	ac.Success(c, gin.H{
+	// 生成token，携带会话ID
+	claimsUser.SessionID = claims1.SessionID
+	token, err := app.TokenService.GenerateTokenWithCache(claimsUser)
+	if err != nil {
+		ac.FailAndAbort(c, "生成token失败", err)
+	}
+	claims, err := app.TokenService.ParseToken(token)
+	if err != nil {
+		ac.FailAndAbort(c, "解析token失败", err)
+	}
+
+	return gin.H{
 		"accessToken":         token,
 		"accessTokenExpires":  claims.ExpiresAt.Unix(),
 		"refreshToken":        refreshToken,
 		"refreshTokenExpires": claims1.ExpiresAt.Unix(),
+	}
+}
+
+// LoginMfa 登录二次验证
+// @Summary 登录二次验证
+// @Description 使用登录返回的二次验证令牌及验证器App验证码(或恢复码)完成登录
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param mfaReq body models.MfaLoginRequest true "二次验证请求参数"
+// @Success 200 {object} map[string]interface{} "成功返回访问令牌"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /login/mfa [post]
+func (ac *AuthController) LoginMfa(c *gin.Context) {
+	var req models.MfaLoginRequest
+	if err := req.Validate(c); err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+
+	challenge, err := ac.MfaService.GetChallenge(req.MfaToken)
+	if err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+
+	if challenge.Enroll {
+		// 强制绑定场景：校验验证码并启用二次验证
+		err = ac.MfaService.ConfirmEnroll(c, challenge.UserID, req.Code)
+	} else {
+		err = ac.MfaService.Verify(c, challenge.UserID, req.Code)
+	}
+	if err != nil {
+		remainingAttempts := ac.MfaService.RecordChallengeFailure(req.MfaToken)
+		if remainingAttempts == 0 {
+			ac.FailAndAbort(c, "验证失败次数过多，请重新登录", err)
+		}
+		ac.FailAndAbort(c, err.Error()+"，剩余尝试次数: "+strconv.Itoa(remainingAttempts), err)
+	}
+	ac.MfaService.DeleteChallenge(req.MfaToken)
+
+	ac.Success(c, ac.issueLoginToken(c, &app.ClaimsUser{
+		UserID:     challenge.UserID,
+		Username:   challenge.Username,
+		TenantID:   challenge.TenantID,
+		TenantCode: challenge.TenantCode,
+	}, challenge.DeviceName))
+}
+
+// LoginMfaEnroll 登录时绑定二次验证
+// @Summary 登录时绑定二次验证
+// @Description 强制开启二次验证但尚未绑定的用户，使用二次验证令牌获取密钥、二维码地址及恢复码，随后调用 /login/mfa 完成绑定及登录
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param enrollReq body models.MfaLoginEnrollRequest true "绑定请求参数"
+// @Success 200 {object} map[string]interface{} "成功返回绑定信息"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /login/mfa/enroll [post]
+func (ac *AuthController) LoginMfaEnroll(c *gin.Context) {
+	var req models.MfaLoginEnrollRequest
+	if err := req.Validate(c); err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+
+	challenge, err := ac.MfaService.GetChallenge(req.MfaToken)
+	if err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+	if !challenge.Enroll {
+		ac.FailAndAbort(c, "二次验证已绑定，无需重复绑定", nil)
+	}
+
+	enrollment, err := ac.MfaService.BeginEnroll(c, challenge.UserID, challenge.Username)
+	if err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+	ac.Success(c, enrollment)
+}
+
+// OidcProviders 获取第三方登录身份提供方列表
+// @Summary 获取第三方登录身份提供方列表
+// @Description 获取已配置的OIDC身份提供方，未开启第三方登录时返回空列表
+// @Tags 认证
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功返回身份提供方列表"
+// @Router /oidc/providers [get]
+func (ac *AuthController) OidcProviders(c *gin.Context) {
+	providers := []service.OidcProviderInfo{}
+	if ac.OidcService.IsOpen() {
+		providers = ac.OidcService.Providers()
+	}
+	ac.Success(c, providers)
+}
+
+// OidcAuthorize 获取第三方登录授权地址
+// @Summary 获取第三方登录授权地址
+// @Description 发起OIDC授权码(PKCE)登录，前端跳转到返回的授权地址，身份提供方回调后将 state 及 code 提交到 /oidc/callback
+// @Tags 认证
+// @Produce json
+// @Param provider query string true "身份提供方标识"
+// @Param tenantCode query string false "租户编码"
+// @Param deviceName query string false "设备名称"
+// @Success 200 {object} map[string]interface{} "成功返回授权地址"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /oidc/authorize [get]
+func (ac *AuthController) OidcAuthorize(c *gin.Context) {
+	var req models.OidcAuthorizeRequest
+	if err := req.Validate(c); err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+	authURL, err := ac.OidcService.Begin(c, req.Provider, ac.hostTenantCode(c, req.TenantCode), req.DeviceName)
+	if err != nil {
+		ac.FailAndAbort(c, "获取授权地址失败: "+err.Error(), err)
+	}
+	ac.Success(c, gin.H{
+		"authUrl": authURL,
+	})
+}
+
+// OidcCallback 第三方登录回调
+// @Summary 第三方登录回调
+// @Description 使用身份提供方回调的 state 及 code 完成登录，返回结构与 /login 一致(可能需要二次验证)
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param callbackReq body models.OidcCallbackRequest true "回调请求参数"
+// @Success 200 {object} map[string]interface{} "成功返回访问令牌"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /oidc/callback [post]
+func (ac *AuthController) OidcCallback(c *gin.Context) {
+	var req models.OidcCallbackRequest
+	if err := req.Validate(c); err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+
+	identityUser, state, err := ac.OidcService.Callback(c, req.State, req.Code)
+	if err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+
+	user := models.NewUser()
+	err = user.Find(c, func(d *gorm.DB) *gorm.DB {
+		return d.Where("id = ?", identityUser.ID).Preload("Tenant")
 	})
+	if err != nil {
+		ac.FailAndAbort(c, "用户查询错误", err)
+	}
+	if user.IsEmpty() {
+		ac.FailAndAbort(c, "用户不存在", nil)
+	}
+	if user.Status != 1 {
+		ac.FailAndAbort(c, "用户未启用", nil)
+	}
+
+	tenantID, tenantCode := ac.resolveTenant(c, user, state.TenantCode)
+	ac.Success(c, ac.completeLogin(c, user, tenantID, tenantCode, state.DeviceName))
 }
 
 // RefreshToken 刷新访问令牌
@@ -246,26 +562,21 @@ func (ac *AuthController) RefreshToken(c *gin.Context) {
 	}
 
 	// 从数据库中获取用户信息
-	var user models.User
-	if err = app.DB().WithContext(c).First(&user, claims.UserID).Error; err != nil {
-		ac.FailAndAbort(c, "用户不存在", err)
-	}
-
-	// 使用refresh token刷新access token
-	user.Password = ""
-	newAccessToken, err := app.TokenService.RefreshAccessTokenWithCache(refreshToken, &app.ClaimsUser{
-		UserID:   user.ID,
-		Username: user.Username,
+	user := models.NewUser()
+	err = user.Find(c, func(d *gorm.DB) *gorm.DB {
+		return d.Where("id = ?", claims.UserID).Preload("Tenant")
 	})
-	if err != nil {
-		ac.FailAndAbort(c, "refresh token刷新失败", err)
+	if err != nil || user.IsEmpty() {
+		ac.FailAndAbort(c, "用户不存在", err)
 	}
-	claims1, err := app.TokenService.ParseToken(newAccessToken)
-	if err != nil {
-		ac.FailAndAbort(c, "refresh token解析失败", err)
+	if user.Status != 1 {
+		ac.FailAndAbort(c, "用户未启用", nil)
 	}
 
-	// 取消旧的refresh token生成新的refresh token
+	// 沿用refresh token中的租户，并按登录规则重新校验租户关联关系
+	tenantID, tenantCode := ac.resolveTenant(c, user, claims.TenantCode)
+
+	// 先轮换refresh token，轮换成功(旧token未被使用过)后才签发access token，重放或并发复用的旧token不能换取access token
 	newRefreshToken, err := app.TokenService.RotateRefreshToken(refreshToken)
 	if err != nil {
 		ac.FailAndAbort(c, "轮换refresh token失败", err)
@@ -277,6 +588,23 @@ func (ac *AuthController) RefreshToken(c *gin.Context) {
 		ac.FailAndAbort(c, "解析新refresh token失败", err)
 	}
 
+	// 生成新的access token
+	user.Password = ""
+	newAccessToken, err := app.TokenService.GenerateTokenWithCache(&app.ClaimsUser{
+		UserID:     user.ID,
+		Username:   user.Username,
+		TenantID:   tenantID,
+		TenantCode: tenantCode,
+		SessionID:  claims.SessionID,
+	})
+	if err != nil {
+		ac.FailAndAbort(c, "refresh token刷新失败", err)
+	}
+	claims1, err := app.TokenService.ParseToken(newAccessToken)
+	if err != nil {
+		ac.FailAndAbort(c, "refresh token解析失败", err)
+	}
+
 	ac.Success(c, gin.H{
 		"accessToken":         newAccessToken,
 		"accessTokenExpires":  claims1.ExpiresAt.Unix(),
@@ -285,9 +613,192 @@ func (ac *AuthController) RefreshToken(c *gin.Context) {
 	})
 }
 
+// SwitchTenant 切换租户
+// @Summary 切换租户
+// @Description 在当前登录会话中切换到用户关联的其他租户，无需重新输入密码，返回新租户下的访问令牌及刷新令牌
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param switchReq body models.SwitchTenantRequest true "切换租户请求参数"
+// @Success 200 {object} map[string]interface{} "成功返回新的访问令牌"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /switchTenant [post]
+// @Security ApiKeyAuth
+func (ac *AuthController) SwitchTenant(c *gin.Context) {
+	var req models.SwitchTenantRequest
+	if err := req.Validate(c); err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+
+	claims := common.GetClaims(c)
+	if claims == nil {
+		ac.FailAndAbort(c, "用户未登录", nil)
+	}
+	if claims.SessionID == "" {
+		ac.FailAndAbort(c, "当前登录会话不支持切换租户，请重新登录", nil)
+	}
+
+	user := models.NewUser()
+	err := user.Find(c, func(d *gorm.DB) *gorm.DB {
+		return d.Where("id = ?", claims.UserID).Preload("Tenant")
+	})
+	if err != nil {
+		ac.FailAndAbort(c, "用户查询错误", err)
+	}
+	if user.IsEmpty() {
+		ac.FailAndAbort(c, "用户不存在", nil)
+	}
+	if user.Status != 1 {
+		ac.FailAndAbort(c, "用户未启用", nil)
+	}
+
+	// 与登录相同的规则校验用户与目标租户的关联关系
+	tenantID, tenantCode := ac.resolveTenant(c, user, ac.hostTenantCode(c, req.TenantCode))
+
+	// 当前会话切换到新租户，重新签发refresh token
+	refreshToken, err := app.TokenService.SwitchSessionTenant(claims.UserID, claims.SessionID, tenantID, tenantCode)
+	if err != nil {
+		ac.FailAndAbort(c, "切换租户失败", err)
+	}
+	refreshClaims, err := app.TokenService.ParseRefreshToken(refreshToken)
+	if err != nil {
+		ac.FailAndAbort(c, "解析refreshToken失败", err)
+	}
+
+	token, err := app.TokenService.GenerateTokenWithCache(&app.ClaimsUser{
+		UserID:     user.ID,
+		Username:   user.Username,
+		TenantID:   tenantID,
+		TenantCode: tenantCode,
+		SessionID:  claims.SessionID,
+	})
+	if err != nil {
+		ac.FailAndAbort(c, "生成token失败", err)
+	}
+	tokenClaims, err := app.TokenService.ParseToken(token)
+	if err != nil {
+		ac.FailAndAbort(c, "解析token失败", err)
+	}
+
+	// 撤销原租户下的access token
+	if oldToken, err := common.GetAccessToken(c); err == nil && oldToken != "" {
+		app.TokenService.RevokeTokenWithCache(oldToken)
+	}
+
+	ac.Success(c, gin.H{
+		"accessToken":         token,
+		"accessTokenExpires":  tokenClaims.ExpiresAt.Unix(),
+		"refreshToken":        refreshToken,
+		"refreshTokenExpires": refreshClaims.ExpiresAt.Unix(),
+		"tenantId":            tenantID,
+		"tenantCode":          tenantCode,
+	})
+}
+
+// Impersonate 模拟登录
+// @Summary 模拟登录
+// @Description 管理员以指定用户身份登录(用于排查问题)，签发短期有效且不可刷新的访问令牌，令牌携带操作人信息，操作日志同时记录双方身份
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param req body models.ImpersonateRequest true "模拟登录请求参数"
+// @Success 200 {object} map[string]interface{} "成功返回访问令牌"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /users/impersonate [post]
+// @Security ApiKeyAuth
+func (ac *AuthController) Impersonate(c *gin.Context) {
+	var req models.ImpersonateRequest
+	if err := req.Validate(c); err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+	if !app.ConfigYml.GetBool("impersonation.open") {
+		ac.FailAndAbort(c, "未开启模拟登录功能", nil)
+	}
+
+	claims := common.GetClaims(c)
+	if claims == nil {
+		ac.FailAndAbort(c, "用户未登录", nil)
+	}
+	if claims.ApiKeyID > 0 {
+		ac.FailAndAbort(c, "API密钥认证不支持模拟登录", nil)
+	}
+	if req.UserID == claims.UserID {
+		ac.FailAndAbort(c, "不能模拟登录自己", nil)
+	}
+	if common.IsSkipAuthUser(req.UserID) {
+		ac.FailAndAbort(c, "不能模拟登录超级管理员", nil)
+	}
+
+	user := models.NewUser()
+	err := user.Find(c, func(d *gorm.DB) *gorm.DB {
+		return d.Where("id = ?", req.UserID).Preload("Tenant")
+	})
+	if err != nil {
+		ac.FailAndAbort(c, "用户查询错误", err)
+	}
+	if user.IsEmpty() {
+		ac.FailAndAbort(c, "用户不存在", nil)
+	}
+	if user.Status != 1 {
+		ac.FailAndAbort(c, "用户未启用", nil)
+	}
+
+	// 租户管理员只能在当前租户内模拟登录，全局管理员可指定目标租户
+	tenantCode := ac.hostTenantCode(c, req.TenantCode)
+	if claims.TenantID > 0 {
+		if user.TenantID == 0 {
+			ac.FailAndAbort(c, "不能模拟登录全局用户", nil)
+		}
+		tenantCode = claims.TenantCode
+	}
+	tenantID, tenantCode := ac.resolveTenant(c, user, tenantCode)
+	if claims.TenantID > 0 && tenantID != claims.TenantID {
+		ac.FailAndAbort(c, "只能模拟登录当前租户的用户", nil)
+	}
+
+	expire := app.ConfigYml.GetInt("impersonation.expire")
+	if expire <= 0 {
+		expire = 900
+	}
+	token, err := app.TokenService.GenerateTokenWithExpire(&app.ClaimsUser{
+		UserID:           user.ID,
+		Username:         user.Username,
+		TenantID:         tenantID,
+		TenantCode:       tenantCode,
+		ImpersonatorID:   claims.UserID,
+		ImpersonatorName: claims.Username,
+	}, time.Duration(expire)*time.Second)
+	if err != nil {
+		ac.FailAndAbort(c, "生成token失败", err)
+	}
+	tokenClaims, err := app.TokenService.ParseToken(token)
+	if err != nil {
+		ac.FailAndAbort(c, "解析token失败", err)
+	}
+
+	app.ZapLog.Info("模拟登录",
+		zap.Uint("impersonatorID", claims.UserID),
+		zap.String("impersonatorName", claims.Username),
+		zap.Uint("userID", user.ID),
+		zap.String("username", user.Username),
+		zap.Uint("tenantID", tenantID),
+		zap.String("reason", req.Reason),
+		zap.String("ip", c.ClientIP()))
+
+	ac.Success(c, gin.H{
+		"accessToken":        token,
+		"accessTokenExpires": tokenClaims.ExpiresAt.Unix(),
+		"userId":             user.ID,
+		"username":           user.Username,
+		"tenantId":           tenantID,
+		"tenantCode":         tenantCode,
+		"impersonatorId":     claims.UserID,
+	})
+}
+
 // Logout 用户登出
 // @Summary 用户登出
-// @Description 用户登出，撤销access token和refresh token
+// @Description 用户登出，撤销access token及当前会话的refresh token
 // @Tags 认证
 // @Accept json
 // @Produce json
@@ -301,6 +812,20 @@ func (ac *AuthController) Logout(c *gin.Context) {
 		ac.FailAndAbort(c, "用户未登录", nil)
 		return
 	}
+	if claims.ApiKeyID > 0 {
+		ac.FailAndAbort(c, "API密钥认证无需登出，如需撤销请删除API密钥", nil)
+	}
+
+	// 模拟登录只撤销模拟登录的access token，不影响被模拟用户的会话
+	if claims.ImpersonatorID > 0 {
+		if tokenString, err := common.GetAccessToken(c); err == nil && tokenString != "" {
+			app.TokenService.RevokeTokenWithCache(tokenString)
+		}
+		ac.Success(c, gin.H{
+			"message": "已退出模拟登录",
+		})
+		return
+	}
 
 	// 撤销 access token
 	tokenString, err := common.GetAccessToken(c)
@@ -309,8 +834,12 @@ func (ac *AuthController) Logout(c *gin.Context) {
 		app.TokenService.RevokeTokenWithCache(tokenString)
 	}
 
-	// 撤销refresh token
-	err = app.TokenService.RevokeRefreshToken(claims.UserID)
+	// 撤销当前会话的refresh token，未携带会话ID的旧token撤销用户所有会话
+	if claims.SessionID != "" {
+		err = app.TokenService.RevokeSession(claims.UserID, claims.SessionID)
+	} else {
+		err = app.TokenService.RevokeRefreshToken(claims.UserID)
+	}
 	if err != nil {
 		ac.FailAndAbort(c, "登出失败", err)
 	}
@@ -320,28 +849,94 @@ func (ac *AuthController) Logout(c *gin.Context) {
 	})
 }
 
-// CaptchaId 获取验证码ID
-// @Summary 获取验证码ID
-// @Description 获取验证码ID用于生成验证码图片
+// ForgotPassword 申请重置密码
+// @Summary 申请重置密码
+// @Description 向账号绑定的邮箱发送重置密码链接，为避免泄露账号是否存在，邮箱未注册时同样返回成功
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param req body models.ForgotPasswordRequest true "邮箱"
+// @Success 200 {object} map[string]interface{} "已发送重置邮件"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /password/forgot [post]
+func (ac *AuthController) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := req.Validate(c); err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+	if !ac.PasswordResetService.IsOpen() {
+		ac.FailAndAbort(c, "未开启找回密码功能", nil)
+	}
+	err := ac.PasswordResetService.Request(c, req.Email, c.ClientIP())
+	if errors.Is(err, service.ErrResetRateLimited) {
+		ac.FailAndAbort(c, err.Error(), nil)
+	}
+	if err != nil {
+		ac.FailAndAbort(c, "申请重置密码失败", err)
+	}
+	ac.SuccessWithMessage(c, "如果该邮箱已注册，重置密码链接已发送至邮箱，请注意查收", nil)
+}
+
+// ResetPassword 重置密码
+// @Summary 重置密码
+// @Description 使用邮件中的重置令牌设置新密码，令牌单次有效，重置成功后注销该用户所有会话
 // @Tags 认证
+// @Accept json
 // @Produce json
-// @Success 200 {object} map[string]interface{} "成功返回验证码ID"
+// @Param req body models.ResetPasswordRequest true "重置令牌及新密码"
+// @Success 200 {object} map[string]interface{} "密码重置成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /password/reset [post]
+func (ac *AuthController) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := req.Validate(c); err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+	if !ac.PasswordResetService.IsOpen() {
+		ac.FailAndAbort(c, "未开启找回密码功能", nil)
+	}
+	if err := ac.PasswordResetService.Confirm(c, req.Token, req.Password); err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+	ac.SuccessWithMessage(c, "密码重置成功，请使用新密码登录", nil)
+}
+
+// Jwks 获取token验证公钥
+// @Summary 获取token验证公钥
+// @Description 以JWKS格式(RFC 7517)返回用于验证token签名的公钥，使用HMAC签名时返回空集合
+// @Tags 认证
+// @Produce json
+// @Success 200 {object} app.JSONWebKeySet "公钥集合"
+// @Router /.well-known/jwks.json [get]
+func (ac *AuthController) Jwks(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, app.TokenService.JWKS())
+}
+
+// CaptchaId 获取验证码
+// @Summary 获取验证码
+// @Description 生成验证码，返回验证码ID及展示数据(图片为PNG data URI；滑块验证码返回背景图、滑块图及滑块纵坐标，答案为滑块横坐标)
+// @Tags 认证
+// @Produce json
+// @Success 200 {object} app.CaptchaData "成功返回验证码"
 // @Router /captcha/id [get]
 func (ac *AuthController) GetCaptchaId(c *gin.Context) {
-	length := app.ConfigYml.GetInt("captcha.length")
-	ac.Success(c, gin.H{
-		"captchaId": captcha.NewLen(length),
-	})
+	data, err := app.CaptchaService.Generate(c)
+	if err != nil {
+		ac.FailAndAbort(c, "生成验证码失败", err)
+	}
+	ac.Success(c, data)
 }
 
 // CaptchaImage 获取验证码图片
 // @Summary 获取验证码图片
-// @Description 根据验证码ID获取验证码图片
+// @Description 根据验证码ID获取验证码图片(仅图形数字验证码)
 // @Tags 认证
 // @Produce json
 // @Param captchaId query string true "验证码ID"
-// @Param width query int false "图片宽度" default(130)
-// @Param height query int false "图片高度" default(30)
+// @Param width query int false "图片宽度" default(160)
+// @Param height query int false "图片高度" default(50)
+// @Param time query string false "非空时重新生成验证码"
 // @Success 200 {object} map[string]interface{} "成功返回验证码图片"
 // @Failure 400 {object} map[string]interface{} "请求参数错误"
 // @Router /captcha/image [get]
@@ -350,8 +945,47 @@ func (ac *AuthController) GetCaptchaImg(c *gin.Context) {
 	if err := req.Validate(c); err != nil {
 		ac.FailAndAbort(c, "获取验证码图片失败", err)
 	}
+	media := ac.captchaMedia(c)
 	if req.Time != "" {
-		captcha.Reload(req.CaptchaId)
+		if err := media.Reload(c, req.CaptchaId); err != nil {
+			ac.FailAndAbort(c, err.Error(), err)
+		}
+	}
+	c.Header("Content-Type", "image/png")
+	c.Header("Cache-Control", "no-store")
+	if err := media.WriteImage(c, c.Writer, req.CaptchaId, req.Width, req.Height); err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+}
+
+// GetCaptchaAudio 获取验证码语音
+// @Summary 获取验证码语音
+// @Description 根据验证码ID获取验证码语音(WAV，仅图形数字验证码)
+// @Tags 认证
+// @Produce audio/wav
+// @Param captchaId query string true "验证码ID"
+// @Param lang query string false "语言 en/zh/ru/ja/pt" default(zh)
+// @Success 200 {file} file "验证码语音"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /captcha/audio [get]
+func (ac *AuthController) GetCaptchaAudio(c *gin.Context) {
+	var req models.CaptchaAudioRequest
+	if err := req.Validate(c); err != nil {
+		ac.FailAndAbort(c, "获取验证码语音失败", err)
+	}
+	media := ac.captchaMedia(c)
+	c.Header("Content-Type", "audio/wav")
+	c.Header("Cache-Control", "no-store")
+	if err := media.WriteAudio(c, c.Writer, req.CaptchaId, req.Lang); err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+}
+
+// captchaMedia 获取支持图片及语音输出的验证码服务
+func (ac *AuthController) captchaMedia(c *gin.Context) app.CaptchaMedia {
+	media, ok := app.CaptchaService.(app.CaptchaMedia)
+	if !ok {
+		ac.FailAndAbort(c, "当前验证码类型不支持该操作", nil)
 	}
-	captcha.WriteImage(c.Writer, req.CaptchaId, req.Width, req.Height)
+	return media
 }
@@ -3,8 +3,11 @@ package controllers
 import (
 	"gin-fast/app/global/app"
 	"gin-fast/app/models"
+	"gin-fast/app/service"
+	"gin-fast/app/utils/captchahelper"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // ConfigController 配置控制器
@@ -27,7 +30,8 @@ func NewConfigController() *ConfigController {
 
 // GetConfig 获取配置信息
 // @Summary 获取配置信息
-// @Description 获取系统配置信息
+// @Description 获取系统配置信息，passwordPolicy 为生效的密码策略；通过租户域名访问时返回 tenant 为该租户信息，密码策略默认为该租户的策略
+// @Param tenantCode query string false "租户编码，指定时返回该租户生效的密码策略"
 // @Tags 配置管理
 // @Accept json
 // @Produce json
@@ -64,8 +68,57 @@ func (con ConfigController) GetConfig(ctx *gin.Context) {
 	captchaConfig := make(map[string]interface{})
 	captchaConfig["open"] = app.ConfigYml.GetBool("captcha.open")
 	captchaConfig["length"] = app.ConfigYml.GetInt("captcha.length")
+	captchaConfig["type"] = app.CaptchaService.Type()
 	result["captcha"] = captchaConfig
 
+	// 获取二次验证配置
+	mfaConfig := make(map[string]interface{})
+	mfaConfig["open"] = app.ConfigYml.GetBool("mfa.open")
+	result["mfa"] = mfaConfig
+
+	// 获取第三方登录配置
+	oidcService := service.NewOidcService()
+	oidcConfig := make(map[string]interface{})
+	oidcConfig["open"] = oidcService.IsOpen()
+	providers := []service.OidcProviderInfo{}
+	if oidcService.IsOpen() {
+		providers = oidcService.Providers()
+	}
+	oidcConfig["providers"] = providers
+	result["oidc"] = oidcConfig
+
+	// 通过租户域名访问时返回租户信息，登录页据此展示租户名称并预选租户
+	hostTenant := service.NewTenantHostService().HostTenant(ctx)
+	if hostTenant != nil {
+		result["tenant"] = map[string]interface{}{
+			"id":          hostTenant.ID,
+			"code":        hostTenant.Code,
+			"name":        hostTenant.Name,
+			"description": hostTenant.Description,
+		}
+	}
+
+	// 获取生效的密码策略，指定租户编码时返回该租户的密码策略
+	passwordPolicyService := service.NewPasswordPolicyService()
+	var policyTenantID uint
+	if hostTenant != nil {
+		policyTenantID = hostTenant.ID
+	} else if tenantCode := ctx.Query("tenantCode"); tenantCode != "" {
+		tenant := models.NewTenant()
+		err := tenant.Find(ctx, func(db *gorm.DB) *gorm.DB {
+			return db.Where("code = ?", tenantCode)
+		})
+		if err != nil {
+			con.Common.FailAndAbort(ctx, "查询租户错误", err)
+		}
+		policyTenantID = tenant.ID
+	}
+	passwordPolicy, err := passwordPolicyService.Effective(ctx, policyTenantID)
+	if err != nil {
+		con.Common.FailAndAbort(ctx, "获取密码策略失败", err)
+	}
+	result["passwordPolicy"] = passwordPolicy
+
 	// 返回成功响应
 	con.Common.Success(ctx, result)
 }
@@ -114,6 +167,13 @@ func (con ConfigController) UpdateConfig(ctx *gin.Context) {
 		con.Common.FailAndAbort(ctx, "保存配置文件失败", err)
 	}
 
+	// 按新配置重新创建验证码服务
+	captchaService, err := captchahelper.CreateCaptcha()
+	if err != nil {
+		con.Common.FailAndAbort(ctx, "初始化验证码服务失败", err)
+	}
+	app.CaptchaService = captchaService
+
 	// 返回成功响应
 	con.Common.SuccessWithMessage(ctx, "配置更新成功")
 }
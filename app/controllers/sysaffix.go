@@ -1,8 +1,11 @@
 package controllers
 
 import (
+	"errors"
 	"gin-fast/app/global/app"
 	"gin-fast/app/models"
+	"gin-fast/app/service"
+	"gin-fast/app/utils/common"
 	"gin-fast/app/utils/datascope"
 	"gin-fast/app/utils/filehelper"
 	"gin-fast/app/utils/tenanthelper"
@@ -22,12 +25,14 @@ import (
 // @Router /sysAffix [get]
 type SysAffixController struct {
 	Common
+	QuotaService *service.TenantQuotaService
 }
 
 // NewSysAffixController 创建文件附件控制器
 func NewSysAffixController() *SysAffixController {
 	return &SysAffixController{
-		Common: Common{},
+		Common:       Common{},
+		QuotaService: service.NewTenantQuotaService(),
 	}
 }
 
@@ -49,6 +54,16 @@ func (ac *SysAffixController) Upload(c *gin.Context) {
 		ac.FailAndAbort(c, err.Error(), err)
 	}
 
+	// 检查租户存储空间配额，上传前预先检查避免上传超出配额的文件，保存记录时在事务中再次检查
+	tenantID := common.GetCurrentTenantID(c)
+	file, err := c.FormFile("file")
+	if err != nil {
+		ac.FailAndAbort(c, "获取上传文件失败", err)
+	}
+	if err = ac.QuotaService.CheckStorage(app.DB().WithContext(c), tenantID, file.Size); err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+
 	// 处理文件上传
 	response, err := app.UploadService.HandleUpload(c, "file")
 	if err != nil {
@@ -64,8 +79,20 @@ func (ac *SysAffixController) Upload(c *gin.Context) {
 	affix.Suffix = response.FileType
 	affix.Ftype = filehelper.GetFileTypeBySuffix(response.FileType) // 根据后缀判断文件类型
 
-	// 保存到数据库
-	if err := affix.Create(c); err != nil {
+	// 保存到数据库，同时上传的其他文件已占用配额时删除本次上传的文件
+	err = app.DB().WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := ac.QuotaService.CheckStorage(tx, tenantID, response.Size); err != nil {
+			return err
+		}
+		return tx.Create(affix).Error
+	})
+	if err != nil {
+		if deleteErr := app.UploadService.DeleteFile(affix.Path); deleteErr != nil {
+			app.ZapLog.Error("删除物理文件失败", zap.Error(deleteErr))
+		}
+		if errors.Is(err, service.ErrTenantQuotaExceeded) {
+			ac.FailAndAbort(c, err.Error(), err)
+		}
 		ac.FailAndAbort(c, "保存文件记录失败", err)
 	}
 
@@ -4,6 +4,7 @@ import (
 	"gin-fast/app/global/app"
 	"gin-fast/app/models"
 	"gin-fast/app/service"
+	"gin-fast/app/utils/ginhelper"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -20,6 +21,7 @@ import (
 type SysApiController struct {
 	Common
 	CasbinService *service.PermissionService
+	ApiService    *service.SysApiService
 }
 
 // NewSysApiController 创建系统API控制器
@@ -27,6 +29,7 @@ func NewSysApiController() *SysApiController {
 	return &SysApiController{
 		Common:        Common{},
 		CasbinService: service.NewPermissionService(),
+		ApiService:    service.NewSysApiService(),
 	}
 }
 
@@ -273,3 +276,45 @@ func (sc *SysApiController) Delete(c *gin.Context) {
 
 	sc.SuccessWithMessage(c, "API删除成功", nil)
 }
+
+// SyncPreview 预览路由表与API表的差异
+// @Summary 预览API同步差异
+// @Description 比较已注册的系统及插件路由与API表，返回待新增、待补全分组及路由已不存在的API
+// @Tags API管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功返回差异"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /sysApi/syncPreview [get]
+// @Security ApiKeyAuth
+func (sc *SysApiController) SyncPreview(c *gin.Context) {
+	diff, err := sc.ApiService.Diff(c, ginhelper.Routes())
+	if err != nil {
+		sc.FailAndAbort(c, "比较API差异失败", err)
+	}
+	sc.Success(c, diff)
+}
+
+// Sync 按路由表同步API表
+// @Summary 同步API
+// @Description 按已注册的路由新增缺失的API并补全分组，可选删除路由已不存在且未关联菜单的API
+// @Tags API管理
+// @Accept json
+// @Produce json
+// @Param api body models.SysApiSyncRequest true "同步选项"
+// @Success 200 {object} map[string]interface{} "API同步成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /sysApi/sync [post]
+// @Security ApiKeyAuth
+func (sc *SysApiController) Sync(c *gin.Context) {
+	var req models.SysApiSyncRequest
+	if err := req.Validate(c); err != nil {
+		sc.FailAndAbort(c, err.Error(), err)
+	}
+	result, err := sc.ApiService.Sync(c, ginhelper.Routes(), req.RemoveStale)
+	if err != nil {
+		sc.FailAndAbort(c, "同步API失败", err)
+	}
+	sc.SuccessWithMessage(c, "API同步成功", result)
+}
@@ -93,6 +93,7 @@ func (sm *SysMenuController) GetRouters(c *gin.Context) {
 		// 需要检查权限，按原有逻辑处理
 		sysUserRoleList := models.NewSysUserRoleList()
 		err = sysUserRoleList.Find(c, func(d *gorm.DB) *gorm.DB {
+			d = d.Scopes(models.ActiveUserRoleScope(time.Now()))
 			if user.TenantID == 0 {
 				return d.Where("user_id = ?", claims.UserID)
 			} else {
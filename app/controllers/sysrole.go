@@ -1,12 +1,18 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"gin-fast/app/global/app"
 	"gin-fast/app/models"
 	"gin-fast/app/service"
+	"gin-fast/app/utils/common"
 	"gin-fast/app/utils/tenanthelper"
+	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -24,6 +30,9 @@ type SysRoleController struct {
 	Common
 	CasbinService *service.PermissionService
 	RoleService   *service.SysRoleService
+	FieldService  *service.FieldPermissionService
+	RbacService   *service.RbacService
+	QuotaService  *service.TenantQuotaService
 }
 
 // NewSysRoleController 创建新的系统角色控制器实例
@@ -32,12 +41,15 @@ func NewSysRoleController() *SysRoleController {
 		Common:        Common{},
 		CasbinService: service.NewPermissionService(),
 		RoleService:   service.NewSysRoleService(),
+		FieldService:  service.NewFieldPermissionService(),
+		RbacService:   service.NewRbacService(),
+		QuotaService:  service.NewTenantQuotaService(),
 	}
 }
 
 // GetUserPermission 根据用户ID获取角色菜单权限
 // @Summary 根据角色ID获取菜单权限
-// @Description 根据角色ID获取该角色拥有的菜单权限
+// @Description 根据角色ID获取该角色拥有的菜单权限及菜单API权限上的ABAC条件
 // @Tags 角色管理
 // @Accept json
 // @Produce json
@@ -59,10 +71,28 @@ func (sc *SysRoleController) GetUserPermission(c *gin.Context) {
 	if err != nil {
 		sc.FailAndAbort(c, "获取角色菜单权限失败", err)
 	}
+	menuIDs := sysRoleMenuList.Map(func(m *models.SysRoleMenu) uint {
+		return m.MenuID
+	})
+
+	// 菜单API权限上的ABAC条件
+	conditions := []models.SysRoleApiCondition{}
+	if len(menuIDs) > 0 {
+		menuList := models.NewSysMenuList()
+		err = menuList.Find(c, func(db *gorm.DB) *gorm.DB {
+			return db.Where("id in ?", menuIDs).Select("id").Preload("Apis")
+		})
+		if err != nil {
+			sc.FailAndAbort(c, "查询菜单失败", err)
+		}
+		conditions, err = sc.CasbinService.GetRoleApiConditions(c, uint(roleId), menuList.GetApis().Unique())
+		if err != nil {
+			sc.FailAndAbort(c, "获取角色权限条件失败", err)
+		}
+	}
 	sc.Success(c, gin.H{
-		"list": sysRoleMenuList.Map(func(m *models.SysRoleMenu) uint {
-			return m.MenuID
-		}),
+		"list":       menuIDs,
+		"conditions": conditions,
 	})
 }
 
@@ -217,7 +247,16 @@ func (sc *SysRoleController) Add(c *gin.Context) {
 	role.Description = req.Description
 	role.ParentID = req.ParentID
 
-	err = app.DB().WithContext(c).Create(role).Error
+	// 检查租户角色数配额并创建角色
+	err = app.DB().WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := sc.QuotaService.CheckRoles(tx, common.GetCurrentTenantID(c), 1); err != nil {
+			return err
+		}
+		return tx.Create(role).Error
+	})
+	if errors.Is(err, service.ErrTenantQuotaExceeded) {
+		sc.FailAndAbort(c, err.Error(), err)
+	}
 	if err != nil {
 		sc.FailAndAbort(c, "新增角色失败", err)
 	}
@@ -337,7 +376,7 @@ func (sc *SysRoleController) Delete(c *gin.Context) {
 
 // 为角色分配菜单权限
 // @Summary 为角色分配菜单权限
-// @Description 为指定角色分配菜单权限
+// @Description 为指定角色分配菜单权限。conditions 为菜单API权限设置ABAC条件(时间段、来源IP、租户套餐、请求参数)，为null时保留原有条件
 // @Tags 角色管理
 // @Accept json
 // @Produce json
@@ -385,6 +424,10 @@ func (sm *SysRoleController) AddRoleMenu(c *gin.Context) {
 			sm.FailAndAbort(c, fmt.Sprintf("菜单ID %d 不存在", menuID), nil)
 		}
 	}
+	apis := menuList.GetApis().Unique()
+	if err := sm.CasbinService.CheckRoleApiConditions(apis, req.Conditions); err != nil {
+		sm.FailAndAbort(c, err.Error(), err)
+	}
 
 	// 使用事务处理角色菜单权限分配
 	err = app.DB().WithContext(c).Transaction(func(tx *gorm.DB) error {
@@ -410,6 +453,13 @@ func (sm *SysRoleController) AddRoleMenu(c *gin.Context) {
 			}
 		}
 
+		// 先保存权限条件再调整策略，条件保存失败时回滚菜单权限且不修改策略
+		if req.Conditions != nil {
+			if err := sm.CasbinService.SetRoleApiConditions(c, req.RoleID, apis, req.Conditions); err != nil {
+				app.ZapLog.Error("设置角色权限条件失败", zap.Error(err), zap.Uint("roleId", req.RoleID))
+				return err
+			}
+		}
 		return nil
 	})
 
@@ -418,7 +468,6 @@ func (sm *SysRoleController) AddRoleMenu(c *gin.Context) {
 	}
 
 	// // 调整casbin权限
-	apis := menuList.GetApis().Unique()
 	if err := sm.CasbinService.AddPoliciesForRole(c, req.RoleID, apis); err != nil {
 		sm.FailAndAbort(c, "添加角色权限策略失败", err)
 	}
@@ -466,3 +515,227 @@ func (sc *SysRoleController) UpdateDataScope(c *gin.Context) {
 
 	sc.SuccessWithMessage(c, "角色数据权限更新成功", role)
 }
+
+// Explain 解释用户访问接口的权限判定过程
+// @Summary 权限判定解释
+// @Description 排查"您没有权限访问此资源"时使用，返回判定结果、匹配的策略、角色继承链、数据权限及可授予该权限的菜单和API
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Param userId query int true "用户ID"
+// @Param tenantCode query string false "租户编码，为空时使用用户所属租户"
+// @Param path query string true "请求路径，如 /api/users/list"
+// @Param method query string true "请求方法，如 GET"
+// @Success 200 {object} map[string]interface{} "成功返回权限判定解释"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /sysRole/explain [get]
+// @Security ApiKeyAuth
+func (sc *SysRoleController) Explain(c *gin.Context) {
+	var req models.SysRoleExplainRequest
+	if err := req.Validate(c); err != nil {
+		sc.FailAndAbort(c, err.Error(), err)
+	}
+	claims := common.GetClaims(c)
+	if claims == nil {
+		sc.FailAndAbort(c, "用户未登录", nil)
+	}
+
+	user := models.NewUser()
+	err := user.Find(c, func(d *gorm.DB) *gorm.DB {
+		return d.Where("id = ?", req.UserID)
+	})
+	if err != nil {
+		sc.FailAndAbort(c, "用户查询错误", err)
+	}
+	if user.IsEmpty() {
+		sc.FailAndAbort(c, "用户不存在", nil)
+	}
+
+	// 租户管理员只能查看当前租户内的用户，全局管理员可指定租户
+	tenantCode := req.TenantCode
+	if claims.TenantID > 0 {
+		if user.TenantID == 0 {
+			sc.FailAndAbort(c, "不能查看全局用户的权限", nil)
+		}
+		tenantCode = claims.TenantCode
+	}
+	tenantID := user.TenantID
+	if tenantCode != "" {
+		tenant := models.NewTenant()
+		if err = tenant.Find(c, func(d *gorm.DB) *gorm.DB {
+			return d.Where("code = ?", tenantCode)
+		}); err != nil {
+			sc.FailAndAbort(c, "查询租户错误", err)
+		}
+		if tenant.IsEmpty() {
+			sc.FailAndAbort(c, "租户不存在", nil)
+		}
+		tenantID = tenant.ID
+	}
+	// 租户用户需关联该租户，全局用户可访问所有租户
+	if user.TenantID > 0 && tenantID != user.TenantID {
+		userTenant := &models.SysUserTenant{}
+		if err = userTenant.Find(c, func(d *gorm.DB) *gorm.DB {
+			return d.Where("user_id = ? AND tenant_id = ?", user.ID, tenantID)
+		}); err != nil {
+			sc.FailAndAbort(c, "查询用户租户关联信息错误", err)
+		}
+		if userTenant.IsEmpty() {
+			sc.FailAndAbort(c, "用户不属于该租户", nil)
+		}
+	}
+
+	path := req.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	explanation, err := sc.CasbinService.Explain(c, user, tenantID, path, strings.ToUpper(req.Method))
+	if err != nil {
+		sc.FailAndAbort(c, "权限判定解释失败", err)
+	}
+	sc.Success(c, explanation)
+}
+
+// GetFields 获取角色字段权限
+// @Summary 获取角色字段权限
+// @Description 获取角色在各模型上的字段权限(1只读 2脱敏 3隐藏)，未设置的字段不受限制
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Param roleId path int true "角色ID"
+// @Success 200 {object} map[string]interface{} "成功返回角色字段权限"
+// @Failure 400 {object} map[string]interface{} "角色ID格式错误"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /sysRole/fields/{roleId} [get]
+// @Security ApiKeyAuth
+func (sc *SysRoleController) GetFields(c *gin.Context) {
+	roleId, err := strconv.ParseUint(c.Param("roleId"), 10, 64)
+	if err != nil {
+		sc.FailAndAbort(c, "Invalid role ID", err)
+	}
+	role := models.NewSysRole()
+	err = role.Find(c, func(d *gorm.DB) *gorm.DB {
+		return d.Where("id = ?", roleId)
+	})
+	if err != nil {
+		sc.FailAndAbort(c, "查询角色失败", err)
+	}
+	if role.IsEmpty() {
+		sc.FailAndAbort(c, "角色不存在", nil)
+	}
+	list, err := sc.FieldService.GetRoleFields(c, role.ID)
+	if err != nil {
+		sc.FailAndAbort(c, "获取角色字段权限失败", err)
+	}
+	sc.Success(c, gin.H{
+		"list": list,
+	})
+}
+
+// UpdateFields 设置角色字段权限
+// @Summary 设置角色字段权限
+// @Description 覆盖角色的全部字段权限。model 为表名(如 sys_users)，field 为返回数据中的字段名(如 phone)，action 1只读 2脱敏 3隐藏；用户有多个角色时取限制最宽松的权限，受限字段在更新时被忽略
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Param roleFields body models.SysRoleFieldUpdateRequest true "角色字段权限信息"
+// @Success 200 {object} map[string]interface{} "角色字段权限设置成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /sysRole/fields [post]
+// @Security ApiKeyAuth
+func (sc *SysRoleController) UpdateFields(c *gin.Context) {
+	var req models.SysRoleFieldUpdateRequest
+	if err := req.Validate(c); err != nil {
+		sc.FailAndAbort(c, err.Error(), err)
+	}
+
+	// 检查角色是否存在
+	role := models.NewSysRole()
+	err := role.Find(c, func(d *gorm.DB) *gorm.DB {
+		return d.Where("id = ?", req.RoleID)
+	})
+	if err != nil {
+		sc.FailAndAbort(c, "查询角色失败", err)
+	}
+	if role.IsEmpty() {
+		sc.FailAndAbort(c, "角色不存在", nil)
+	}
+
+	list, err := sc.FieldService.SetRoleFields(c, role.ID, req.Fields)
+	if err != nil {
+		sc.FailAndAbort(c, "设置角色字段权限失败", err)
+	}
+	sc.SuccessWithMessage(c, "角色字段权限设置成功", gin.H{
+		"list": list,
+	})
+}
+
+// Export 导出权限配置
+// @Summary 导出权限配置
+// @Description 导出API、菜单及其关联的API、当前租户的角色树(含菜单、数据权限及Casbin策略)为带版本的JSON文件，用于在不同环境之间迁移
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Success 200 {file} file "JSON文件"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /sysRole/export [get]
+// @Security ApiKeyAuth
+func (sc *SysRoleController) Export(c *gin.Context) {
+	bundle, err := sc.RbacService.Export(c)
+	if err != nil {
+		sc.FailAndAbort(c, "导出权限配置失败", err)
+	}
+	content, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		sc.FailAndAbort(c, "数据序列化失败", err)
+	}
+	// 设置响应头
+	filename := "rbac_export_" + time.Now().Format("20060102150405") + ".json"
+	c.Header("Content-Type", "application/json")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Transfer-Encoding", "binary")
+	c.Header("Cache-Control", "no-cache")
+
+	c.Data(http.StatusOK, "application/json", content)
+}
+
+// Import 导入权限配置
+// @Summary 导入权限配置
+// @Description 导入导出的权限配置文件到当前租户。API按请求方法及路径、菜单按路径(按钮按权限标识)、角色按名称匹配，存在则更新、不存在则新增，不删除文件中没有的记录；dryRun为true时只返回将要产生的变更
+// @Tags 角色管理
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "权限配置JSON文件"
+// @Param dryRun formData bool false "只预览变更，不写入"
+// @Success 200 {object} map[string]interface{} "成功返回变更"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /sysRole/import [post]
+// @Security ApiKeyAuth
+func (sc *SysRoleController) Import(c *gin.Context) {
+	var req models.SysRoleImportRequest
+	if err := req.Validate(c); err != nil {
+		sc.FailAndAbort(c, err.Error(), err)
+	}
+	src, err := req.File.Open()
+	if err != nil {
+		sc.FailAndAbort(c, "打开文件失败", err)
+	}
+	defer src.Close()
+
+	var bundle service.RbacBundle
+	if err = json.NewDecoder(src).Decode(&bundle); err != nil {
+		sc.FailAndAbort(c, "解析JSON数据失败", err)
+	}
+	diff, err := sc.RbacService.Import(c, &bundle, req.DryRun)
+	if err != nil {
+		sc.FailAndAbort(c, "导入权限配置失败: "+err.Error(), err)
+	}
+	if req.DryRun {
+		sc.Success(c, diff)
+		return
+	}
+	sc.SuccessWithMessage(c, "权限配置导入成功", diff)
+}
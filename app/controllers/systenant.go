@@ -3,6 +3,9 @@ package controllers
 import (
 	"gin-fast/app/global/app"
 	"gin-fast/app/models"
+	"gin-fast/app/service"
+	"gin-fast/app/utils/common"
+	"gin-fast/app/utils/tenanthelper"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -12,12 +15,18 @@ import (
 // TenantController 租户控制器
 type TenantController struct {
 	Common
+	TenantHostService      *service.TenantHostService
+	TenantProvisionService *service.TenantProvisionService
+	TenantQuotaService     *service.TenantQuotaService
 }
 
 // NewTenantController 创建租户控制器
 func NewTenantController() *TenantController {
 	return &TenantController{
-		Common: Common{},
+		Common:                 Common{},
+		TenantHostService:      service.NewTenantHostService(),
+		TenantProvisionService: service.NewTenantProvisionService(),
+		TenantQuotaService:     service.NewTenantQuotaService(),
 	}
 }
 
@@ -60,6 +69,72 @@ func (tc *TenantController) List(c *gin.Context) {
 	})
 }
 
+// Templates 租户初始化模板列表
+// @Summary 租户初始化模板列表
+// @Description 获取可用于新增租户的初始化模板及默认模板
+// @Tags 租户管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功返回模板列表"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /sysTenant/templates [get]
+// @Security ApiKeyAuth
+func (tc *TenantController) Templates(c *gin.Context) {
+	templates, err := tc.TenantProvisionService.Templates()
+	if err != nil {
+		tc.FailAndAbort(c, "获取租户模板失败", err)
+	}
+	tc.Success(c, gin.H{
+		"list":        templates,
+		"defaultName": app.ConfigYml.GetString("tenanttemplate.default"),
+	})
+}
+
+// Usage 租户资源配额及用量
+// @Summary 租户资源配额及用量
+// @Description 获取租户的用户数、角色数、附件存储空间及当日API调用次数的配额与当前用量，配额为0表示不限制
+// @Tags 租户管理
+// @Accept json
+// @Produce json
+// @Param id query int false "租户ID，为空时为当前租户，仅全局租户可查看其他租户"
+// @Success 200 {object} map[string]interface{} "成功返回租户资源用量"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /sysTenant/usage [get]
+// @Security ApiKeyAuth
+func (tc *TenantController) Usage(c *gin.Context) {
+	var req models.SysTenantUsageRequest
+	if err := req.Validate(c); err != nil {
+		tc.FailAndAbort(c, err.Error(), err)
+	}
+
+	// 租户下只能查看当前租户的用量
+	tenantID := common.GetCurrentTenantID(c)
+	if tenantID == 0 {
+		tenantID = req.ID
+	}
+	if tenantID == 0 {
+		tc.FailAndAbort(c, "请指定租户", nil)
+	}
+
+	tenant := models.NewTenant()
+	err := tenant.Find(c, func(d *gorm.DB) *gorm.DB {
+		return d.Where("id = ?", tenantID)
+	})
+	if err != nil {
+		tc.FailAndAbort(c, "查询租户失败", err)
+	}
+	if tenant.IsEmpty() {
+		tc.FailAndAbort(c, "租户不存在", nil)
+	}
+
+	usage, err := tc.TenantQuotaService.Usage(c, tenant.ID)
+	if err != nil {
+		tc.FailAndAbort(c, "统计租户资源用量失败", err)
+	}
+	tc.Success(c, usage)
+}
+
 // GetByID 根据ID获取租户信息
 // @Summary 根据ID获取租户信息
 // @Description 根据租户ID获取租户详细信息
@@ -97,7 +172,7 @@ func (tc *TenantController) GetByID(c *gin.Context) {
 
 // Add 新增租户
 // @Summary 新增租户
-// @Description 创建新租户
+// @Description 创建新租户，并按初始化模板(未指定时使用默认模板)在同一事务中创建角色、菜单权限、根部门、字典及初始管理员
 // @Tags 租户管理
 // @Accept json
 // @Produce json
@@ -112,6 +187,9 @@ func (tc *TenantController) Add(c *gin.Context) {
 	if err := req.Validate(c); err != nil {
 		tc.FailAndAbort(c, err.Error(), err)
 	}
+	// 域名统一小写保存，与请求Host匹配
+	req.Domain = tenanthelper.NormalizeHost(req.Domain)
+	req.PlatformDomain = tenanthelper.NormalizeHost(req.PlatformDomain)
 
 	// 检查租户编码(二级域名)是否已存在 - Code必须全局唯一
 	existTenant := models.NewTenant()
@@ -159,13 +237,24 @@ func (tc *TenantController) Add(c *gin.Context) {
 	tenant.Status = req.Status
 	tenant.Domain = req.Domain
 	tenant.PlatformDomain = req.PlatformDomain
+	tenant.Plan = req.Plan
+	tenant.MaxUsers = req.MaxUsers
+	tenant.MaxRoles = req.MaxRoles
+	tenant.MaxStorage = req.MaxStorage
+	tenant.MaxApiCalls = req.MaxApiCalls
 
-	err = app.DB().WithContext(c).Create(tenant).Error
+	// 创建租户并按模板初始化角色、部门、字典、管理员及权限策略
+	result, err := tc.TenantProvisionService.Create(c, tenant, req.Template, &service.TenantAdminAccount{
+		Username: req.AdminUsername,
+		Password: req.AdminPassword,
+		Email:    req.AdminEmail,
+	})
 	if err != nil {
-		tc.FailAndAbort(c, "新增租户失败", err)
+		tc.FailAndAbort(c, "新增租户失败: "+err.Error(), err)
 	}
+	tc.TenantHostService.Invalidate(tenant)
 
-	tc.SuccessWithMessage(c, "租户创建成功", tenant)
+	tc.SuccessWithMessage(c, "租户创建成功", result)
 }
 
 // Update 更新租户
@@ -185,6 +274,9 @@ func (tc *TenantController) Update(c *gin.Context) {
 	if err := req.Validate(c); err != nil {
 		tc.FailAndAbort(c, err.Error(), err)
 	}
+	// 域名统一小写保存，与请求Host匹配
+	req.Domain = tenanthelper.NormalizeHost(req.Domain)
+	req.PlatformDomain = tenanthelper.NormalizeHost(req.PlatformDomain)
 
 	// 检查租户是否存在
 	tenant := models.NewTenant()
@@ -237,17 +329,25 @@ func (tc *TenantController) Update(c *gin.Context) {
 	}
 
 	// 更新租户信息
+	oldTenant := *tenant
 	tenant.Name = req.Name
 	tenant.Code = req.Code
 	tenant.Description = req.Description
 	tenant.Status = req.Status
 	tenant.Domain = req.Domain
 	tenant.PlatformDomain = req.PlatformDomain
+	tenant.Plan = req.Plan
+	tenant.MaxUsers = req.MaxUsers
+	tenant.MaxRoles = req.MaxRoles
+	tenant.MaxStorage = req.MaxStorage
+	tenant.MaxApiCalls = req.MaxApiCalls
 
 	err = app.DB().WithContext(c).Save(tenant).Error
 	if err != nil {
 		tc.FailAndAbort(c, "更新租户失败", err)
 	}
+	tc.TenantHostService.Invalidate(&oldTenant, tenant)
+	tc.TenantQuotaService.Invalidate(tenant.ID)
 
 	tc.SuccessWithMessage(c, "租户更新成功", tenant)
 }
@@ -296,6 +396,8 @@ func (tc *TenantController) Delete(c *gin.Context) {
 	if err != nil {
 		tc.FailAndAbort(c, "删除租户失败", err)
 	}
+	tc.TenantHostService.Invalidate(tenant)
+	tc.TenantQuotaService.Invalidate(tenant.ID)
 
 	tc.SuccessWithMessage(c, "租户删除成功", nil)
 }
@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"gin-fast/app/models"
+	"gin-fast/app/service"
+	"gin-fast/app/utils/common"
+	"gin-fast/app/utils/ldaphelper"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SysTenantLdapController 租户目录服务配置控制器
+type SysTenantLdapController struct {
+	Common
+}
+
+// NewSysTenantLdapController 创建租户目录服务配置控制器
+func NewSysTenantLdapController() *SysTenantLdapController {
+	return &SysTenantLdapController{
+		Common: Common{},
+	}
+}
+
+// Get 获取当前租户目录服务配置
+// @Summary 获取当前租户目录服务配置
+// @Description 获取当前登录租户的LDAP / Active Directory登录配置，未配置时返回空，服务账号密码不返回
+// @Tags 租户管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功返回目录服务配置"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /sysTenantLdap/get [get]
+// @Security ApiKeyAuth
+func (lc *SysTenantLdapController) Get(c *gin.Context) {
+	tenantID := lc.tenantID(c)
+	config := models.NewSysTenantLdap()
+	if err := config.GetByTenantID(c, tenantID); err != nil {
+		lc.FailAndAbort(c, "获取目录服务配置失败", err)
+	}
+	if config.IsEmpty() {
+		lc.Success(c, nil)
+		return
+	}
+	lc.Success(c, gin.H{
+		"config":          config,
+		"hasBindPassword": config.BindPassword != "",
+	})
+}
+
+// Save 保存当前租户目录服务配置
+// @Summary 保存当前租户目录服务配置
+// @Description 保存当前登录租户的LDAP / Active Directory登录配置，服务账号密码为空时保留原密码；修改服务器地址、服务账号或TLS设置时必须重新填写密码
+// @Tags 租户管理
+// @Accept json
+// @Produce json
+// @Param config body models.SysTenantLdapSaveRequest true "目录服务配置"
+// @Success 200 {object} map[string]interface{} "保存成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /sysTenantLdap/save [put]
+// @Security ApiKeyAuth
+func (lc *SysTenantLdapController) Save(c *gin.Context) {
+	var req models.SysTenantLdapSaveRequest
+	if err := req.Validate(c); err != nil {
+		lc.FailAndAbort(c, err.Error(), err)
+	}
+	tenantID := lc.tenantID(c)
+	lc.checkGroupRoles(c, tenantID, req.GroupRoles)
+
+	config := lc.buildConfig(c, tenantID, &req)
+	if err := config.Save(c); err != nil {
+		lc.FailAndAbort(c, "保存目录服务配置失败", err)
+	}
+	lc.SuccessWithMessage(c, "保存成功", nil)
+}
+
+// Test 测试目录服务连接
+// @Summary 测试目录服务连接
+// @Description 使用提交的配置测试服务账号绑定，填写测试用户名及密码时同时测试用户认证并返回匹配的用户组及角色；修改服务器地址、服务账号或TLS设置时必须填写服务账号密码
+// @Tags 租户管理
+// @Accept json
+// @Produce json
+// @Param config body models.SysTenantLdapTestRequest true "目录服务配置及测试账号"
+// @Success 200 {object} map[string]interface{} "测试成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /sysTenantLdap/test [post]
+// @Security ApiKeyAuth
+func (lc *SysTenantLdapController) Test(c *gin.Context) {
+	var req models.SysTenantLdapTestRequest
+	if err := req.Validate(c); err != nil {
+		lc.FailAndAbort(c, err.Error(), err)
+	}
+	config := lc.buildConfig(c, lc.tenantID(c), &req.SysTenantLdapSaveRequest)
+	client := service.NewLdapClient(config)
+
+	if req.Username == "" || req.Password == "" {
+		if err := client.TestConnection(); err != nil {
+			lc.FailAndAbort(c, err.Error(), err)
+		}
+		lc.SuccessWithMessage(c, "连接成功", nil)
+		return
+	}
+
+	entry, err := client.Authenticate(req.Username, req.Password)
+	if err != nil {
+		lc.FailAndAbort(c, err.Error(), err)
+	}
+	lc.Success(c, gin.H{
+		"dn":     entry.DN,
+		"email":  entry.Email,
+		"name":   entry.Name,
+		"groups": entry.Groups,
+		"roleIds": config.GroupRoles.RoleIDs(func(group string) bool {
+			return ldaphelper.GroupMatch(entry.Groups, group)
+		}),
+	})
+}
+
+// tenantID 目录服务按租户配置，全局租户下不可配置
+func (lc *SysTenantLdapController) tenantID(c *gin.Context) uint {
+	claims := common.GetClaims(c)
+	if claims == nil || claims.TenantID == 0 {
+		lc.FailAndAbort(c, "请切换到具体租户后再配置目录服务", nil)
+	}
+	return claims.TenantID
+}
+
+// buildConfig 根据请求生成配置，未填写服务账号密码时沿用已保存的密码
+// 已保存的密码只能发往原服务器及原服务账号，修改服务器地址、服务账号或降低TLS安全性时必须重新填写密码，
+// 防止将服务器指向其他地址获取服务账号密码
+func (lc *SysTenantLdapController) buildConfig(c *gin.Context, tenantID uint, req *models.SysTenantLdapSaveRequest) *models.SysTenantLdap {
+	config := models.NewSysTenantLdap()
+	if err := config.GetByTenantID(c, tenantID); err != nil {
+		lc.FailAndAbort(c, "获取目录服务配置失败", err)
+	}
+	bindPassword := config.BindPassword
+	if req.BindPassword != "" {
+		bindPassword = req.BindPassword
+	} else if bindPassword != "" && (req.URL != config.URL || req.BindDN != config.BindDN ||
+		(config.StartTLS && !req.StartTLS) || (!config.InsecureSkipVerify && req.InsecureSkipVerify)) {
+		lc.FailAndAbort(c, "修改服务器地址、服务账号或TLS设置时请重新填写服务账号密码", nil)
+	}
+
+	config.TenantID = tenantID
+	config.Enabled = req.Enabled
+	config.URL = req.URL
+	config.StartTLS = req.StartTLS
+	config.InsecureSkipVerify = req.InsecureSkipVerify
+	config.BindDN = req.BindDN
+	config.BindPassword = bindPassword
+	config.BaseDN = req.BaseDN
+	config.UserFilter = req.UserFilter
+	config.EmailAttr = req.EmailAttr
+	config.NameAttr = req.NameAttr
+	config.GroupAttr = req.GroupAttr
+	config.GroupRoles = req.GroupRoles
+	config.RequireGroup = req.RequireGroup
+	config.LinkExisting = req.LinkExisting
+	return config
+}
+
+// checkGroupRoles 校验映射的角色均属于当前租户
+func (lc *SysTenantLdapController) checkGroupRoles(c *gin.Context, tenantID uint, groupRoles models.LdapGroupRoles) {
+	roleIDs := groupRoles.RoleIDs(func(group string) bool {
+		return true
+	})
+	for _, mapping := range groupRoles {
+		if mapping.Group == "" {
+			lc.FailAndAbort(c, "用户组不能为空", nil)
+		}
+	}
+	if len(roleIDs) == 0 {
+		return
+	}
+
+	roleList := models.NewSysRoleList()
+	err := roleList.Find(c, func(d *gorm.DB) *gorm.DB {
+		return d.Where("id IN ? AND tenant_id = ?", roleIDs, tenantID)
+	})
+	if err != nil {
+		lc.FailAndAbort(c, "查询角色失败", err)
+	}
+	if len(roleList) != len(roleIDs) {
+		lc.FailAndAbort(c, "映射的角色不存在或不属于当前租户", nil)
+	}
+}
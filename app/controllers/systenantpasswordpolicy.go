@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"gin-fast/app/models"
+	"gin-fast/app/service"
+	"gin-fast/app/utils/common"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SysTenantPasswordPolicyController 租户密码策略控制器
+type SysTenantPasswordPolicyController struct {
+	Common
+	PasswordPolicyService *service.PasswordPolicyService
+}
+
+// NewSysTenantPasswordPolicyController 创建租户密码策略控制器
+func NewSysTenantPasswordPolicyController() *SysTenantPasswordPolicyController {
+	return &SysTenantPasswordPolicyController{
+		Common:                Common{},
+		PasswordPolicyService: service.NewPasswordPolicyService(),
+	}
+}
+
+// Get 获取当前租户密码策略
+// @Summary 获取当前租户密码策略
+// @Description 获取当前登录租户的自定义密码策略(未配置时为空)、全局密码策略及当前生效的密码策略
+// @Tags 租户管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功返回密码策略"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /sysTenantPasswordPolicy/get [get]
+// @Security ApiKeyAuth
+func (pc *SysTenantPasswordPolicyController) Get(c *gin.Context) {
+	tenantID := pc.tenantID(c)
+	config := models.NewSysTenantPasswordPolicy()
+	if err := config.GetByTenantID(c, tenantID); err != nil {
+		pc.FailAndAbort(c, "获取密码策略失败", err)
+	}
+	effective, err := pc.PasswordPolicyService.Effective(c, tenantID)
+	if err != nil {
+		pc.FailAndAbort(c, "获取密码策略失败", err)
+	}
+	result := gin.H{
+		"config":    nil,
+		"global":    pc.PasswordPolicyService.Global(),
+		"effective": effective,
+	}
+	if !config.IsEmpty() {
+		result["config"] = config
+	}
+	pc.Success(c, result)
+}
+
+// Save 保存当前租户密码策略
+// @Summary 保存当前租户密码策略
+// @Description 保存当前登录租户的自定义密码策略，启用后覆盖全局密码策略，适用于默认租户为当前租户的用户
+// @Tags 租户管理
+// @Accept json
+// @Produce json
+// @Param config body models.SysTenantPasswordPolicySaveRequest true "密码策略"
+// @Success 200 {object} map[string]interface{} "保存成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /sysTenantPasswordPolicy/save [put]
+// @Security ApiKeyAuth
+func (pc *SysTenantPasswordPolicyController) Save(c *gin.Context) {
+	var req models.SysTenantPasswordPolicySaveRequest
+	if err := req.Validate(c); err != nil {
+		pc.FailAndAbort(c, err.Error(), err)
+	}
+	tenantID := pc.tenantID(c)
+	if req.MinLength < 0 || req.MinLength > 128 {
+		pc.FailAndAbort(c, "最小长度必须在0到128之间", nil)
+	}
+	if req.HistoryCount < 0 || req.HistoryCount > 24 {
+		pc.FailAndAbort(c, "禁止重复使用的历史密码数量必须在0到24之间", nil)
+	}
+	if req.MaxAgeDays < 0 {
+		pc.FailAndAbort(c, "密码最长使用天数不能小于0", nil)
+	}
+	forbiddenWords := make([]string, 0, len(req.ForbiddenWords))
+	for _, word := range req.ForbiddenWords {
+		if word = strings.TrimSpace(word); word != "" {
+			forbiddenWords = append(forbiddenWords, word)
+		}
+	}
+
+	config := models.NewSysTenantPasswordPolicy()
+	if err := config.GetByTenantID(c, tenantID); err != nil {
+		pc.FailAndAbort(c, "获取密码策略失败", err)
+	}
+	config.TenantID = tenantID
+	config.Enabled = req.Enabled
+	config.MinLength = req.MinLength
+	config.RequireUpper = req.RequireUpper
+	config.RequireLower = req.RequireLower
+	config.RequireDigit = req.RequireDigit
+	config.RequireSpecial = req.RequireSpecial
+	config.SpecialChars = req.SpecialChars
+	config.ForbiddenWords = forbiddenWords
+	config.DisallowUsername = req.DisallowUsername
+	config.HistoryCount = req.HistoryCount
+	config.MaxAgeDays = req.MaxAgeDays
+	if err := config.Save(c); err != nil {
+		pc.FailAndAbort(c, "保存密码策略失败", err)
+	}
+	pc.SuccessWithMessage(c, "保存成功", nil)
+}
+
+// Delete 删除当前租户密码策略
+// @Summary 删除当前租户密码策略
+// @Description 删除当前登录租户的自定义密码策略，恢复使用全局密码策略
+// @Tags 租户管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /sysTenantPasswordPolicy/delete [delete]
+// @Security ApiKeyAuth
+func (pc *SysTenantPasswordPolicyController) Delete(c *gin.Context) {
+	config := models.NewSysTenantPasswordPolicy()
+	config.TenantID = pc.tenantID(c)
+	if err := config.Delete(c); err != nil {
+		pc.FailAndAbort(c, "删除密码策略失败", err)
+	}
+	pc.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// tenantID 密码策略按租户配置，全局租户使用配置文件中的全局密码策略
+func (pc *SysTenantPasswordPolicyController) tenantID(c *gin.Context) uint {
+	claims := common.GetClaims(c)
+	if claims == nil || claims.TenantID == 0 {
+		pc.FailAndAbort(c, "请切换到具体租户后再配置密码策略", nil)
+	}
+	return claims.TenantID
+}
@@ -1,9 +1,11 @@
 package controllers
 
 import (
+	"errors"
 	"gin-fast/app/global/app"
 	"gin-fast/app/models"
 	"gin-fast/app/service"
+	"gin-fast/app/utils/tenanthelper"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,16 +13,21 @@ import (
 )
 
 // SysUserTenantController 用户租户关联控制器
+// 用于平台管理用户与租户的关联，均跨租户访问数据，不进行租户隔离
 type SysUserTenantController struct {
 	Common
-	CasbinService *service.PermissionService
+	CasbinService    *service.PermissionService
+	RoleGrantService *service.RoleGrantService
+	QuotaService     *service.TenantQuotaService
 }
 
 // NewSysUserTenantController 创建用户租户关联控制器
 func NewSysUserTenantController() *SysUserTenantController {
 	return &SysUserTenantController{
-		Common:        Common{},
-		CasbinService: service.NewPermissionService(),
+		Common:           Common{},
+		CasbinService:    service.NewPermissionService(),
+		RoleGrantService: service.NewRoleGrantService(),
+		QuotaService:     service.NewTenantQuotaService(),
 	}
 }
 
@@ -47,12 +54,12 @@ func (sut *SysUserTenantController) List(c *gin.Context) {
 	// 查询列表数据
 	sysUserTenantList := models.NewSysUserTenantList()
 	// 统计总数
-	total, err := sysUserTenantList.GetTotal(c, req.Handler())
+	total, err := sysUserTenantList.GetTotal(c, tenanthelper.WithoutTenant(), req.Handler())
 	if err != nil {
 		sut.FailAndAbort(c, "统计用户租户关联数量失败", err)
 	}
 	req.Order = "created_at desc"
-	err = sysUserTenantList.Find(c, func(d *gorm.DB) *gorm.DB {
+	err = sysUserTenantList.Find(c, tenanthelper.WithoutTenant(), func(d *gorm.DB) *gorm.DB {
 		return d.Preload("User", func(db *gorm.DB) *gorm.DB {
 			return db.Select("id, username, nick_name, tenant_id").Preload("Tenant")
 		})
@@ -88,7 +95,7 @@ func (sut *SysUserTenantController) GetByID(c *gin.Context) {
 
 	// 查询用户租户关联信息
 	sysUserTenant := &models.SysUserTenant{}
-	err := sysUserTenant.Find(c, func(d *gorm.DB) *gorm.DB {
+	err := sysUserTenant.Find(c, tenanthelper.WithoutTenant(), func(d *gorm.DB) *gorm.DB {
 		return d.Where("user_id = ? AND tenant_id = ?", req.UserID, req.TenantID)
 	})
 	if err != nil {
@@ -119,12 +126,13 @@ func (sut *SysUserTenantController) BatchAdd(c *gin.Context) {
 		sut.FailAndAbort(c, err.Error(), err)
 	}
 
-	// 创建用户租户关联列表
+	// 创建用户租户关联列表，newMembers 为新加入该租户的用户数(不含本租户下的用户)
 	var sysUserTenants []*models.SysUserTenant
+	newMembers := 0
 	for _, userID := range req.UserIDs {
 
 		user := models.NewUser()
-		err := user.Find(c, func(db *gorm.DB) *gorm.DB {
+		err := user.Find(c, tenanthelper.WithoutTenant(), func(db *gorm.DB) *gorm.DB {
 			return db.Select("id,tenant_id").Where("id = ?", userID)
 		})
 		if err != nil {
@@ -139,7 +147,7 @@ func (sut *SysUserTenantController) BatchAdd(c *gin.Context) {
 		}
 		// 检查用户租户关联是否已存在
 		existSysUserTenant := &models.SysUserTenant{}
-		err = existSysUserTenant.Find(c, func(d *gorm.DB) *gorm.DB {
+		err = existSysUserTenant.Find(c, tenanthelper.WithoutTenant(), func(d *gorm.DB) *gorm.DB {
 			return d.Where("user_id = ? AND tenant_id = ?", userID, req.TenantID)
 		})
 		if err != nil {
@@ -152,12 +160,23 @@ func (sut *SysUserTenantController) BatchAdd(c *gin.Context) {
 				TenantID:  req.TenantID,
 				CreatedAt: time.Now(),
 			})
+			if user.TenantID != req.TenantID {
+				newMembers++
+			}
 		}
 	}
 
-	// 批量创建用户租户关联
+	// 检查租户用户数配额并批量创建用户租户关联
 	if len(sysUserTenants) > 0 {
-		err := app.DB().WithContext(c).Create(sysUserTenants).Error
+		err := app.DB().WithContext(tenanthelper.SkipTenant(c)).Transaction(func(tx *gorm.DB) error {
+			if err := sut.QuotaService.CheckUsers(tx, req.TenantID, newMembers); err != nil {
+				return err
+			}
+			return tx.Create(sysUserTenants).Error
+		})
+		if errors.Is(err, service.ErrTenantQuotaExceeded) {
+			sut.FailAndAbort(c, err.Error(), err)
+		}
 		if err != nil {
 			sut.FailAndAbort(c, "批量新增用户租户关联失败", err)
 		}
@@ -186,7 +205,7 @@ func (sut *SysUserTenantController) BatchDelete(c *gin.Context) {
 
 	// 检查是否有默认租户关联，如果有则不允许删除
 	defaultSysUserTenant := &models.SysUserTenant{}
-	err := defaultSysUserTenant.Find(c, func(d *gorm.DB) *gorm.DB {
+	err := defaultSysUserTenant.Find(c, tenanthelper.WithoutTenant(), func(d *gorm.DB) *gorm.DB {
 		return d.Where("user_id IN ? AND tenant_id = ? AND is_default = ?", req.UserIDs, req.TenantID, true)
 	})
 	if err != nil {
@@ -196,7 +215,7 @@ func (sut *SysUserTenantController) BatchDelete(c *gin.Context) {
 		sut.FailAndAbort(c, "不能删除默认租户关联", nil)
 	}
 
-	err = app.DB().WithContext(c).Transaction(func(tx *gorm.DB) (e error) {
+	err = app.DB().WithContext(tenanthelper.SkipTenant(c)).Transaction(func(tx *gorm.DB) (e error) {
 		//批量删除用户租户关联
 		e = tx.Where("user_id IN ? AND tenant_id = ?", req.UserIDs, req.TenantID).Delete(&models.SysUserTenant{}).Error
 		if e != nil {
@@ -251,11 +270,11 @@ func (sut *SysUserTenantController) UserListAll(c *gin.Context) {
 	}
 
 	userList := models.NewUserList()
-	total, err := userList.GetTotal(c, req.Handle())
+	total, err := userList.GetTotal(c, tenanthelper.WithoutTenant(), req.Handle())
 	if err != nil {
 		sut.FailAndAbort(c, err.Error(), err)
 	}
-	err = userList.Find(c, req.Paginate(), req.Handle(), func(d *gorm.DB) *gorm.DB {
+	err = userList.Find(c, tenanthelper.WithoutTenant(), req.Paginate(), req.Handle(), func(d *gorm.DB) *gorm.DB {
 		return d.Omit("password").Preload("Roles").Preload("Department").Preload("Tenant")
 	})
 	if err != nil {
@@ -293,7 +312,7 @@ func (sut *SysUserTenantController) GetRolesAll(c *gin.Context) {
 	// 查询列表数据
 	sysRoleList := models.NewSysRoleList()
 
-	err := sysRoleList.Find(c, req.Handler())
+	err := sysRoleList.Find(c, tenanthelper.WithoutTenant(), req.Handler())
 	if err != nil {
 		sut.FailAndAbort(c, "获取角色列表失败", err)
 	}
@@ -357,7 +376,7 @@ func (sut *SysUserTenantController) SetUserRoles(c *gin.Context) {
 	// 验证角色是否属于指定的租户
 	if len(req.Roles) > 0 {
 		roleList := models.NewSysRoleList()
-		err := roleList.Find(c, func(db *gorm.DB) *gorm.DB {
+		err := roleList.Find(c, tenanthelper.WithoutTenant(), func(db *gorm.DB) *gorm.DB {
 			return db.Where("id IN ? AND tenant_id = ?", req.Roles, req.TenantID)
 		})
 		if err != nil {
@@ -368,8 +387,18 @@ func (sut *SysUserTenantController) SetUserRoles(c *gin.Context) {
 		}
 	}
 
+	// 限时授权通过授权接口单独管理，设置角色时保持不变
+	roleGrants, err := sut.RoleGrantService.TimeBound(tenanthelper.SkipTenant(c), req.UserID)
+	if err != nil {
+		sut.FailAndAbort(c, "设置用户角色失败", err)
+	}
+	grantRoleIDs := roleGrants.Map(func(m *models.SysUserRole) uint {
+		return m.RoleID
+	})
+	roles := sut.RoleGrantService.Permanent(req.Roles, roleGrants)
+
 	// 使用事务处理用户角色设置
-	err := app.DB().WithContext(c).Transaction(func(tx *gorm.DB) error {
+	err = app.DB().WithContext(tenanthelper.SkipTenant(c)).Transaction(func(tx *gorm.DB) error {
 		// 先删除该用户在指定租户下的所有角色关联
 		// 需要先查询出该租户下的所有角色ID
 		var tenantRoleIDs []uint
@@ -378,17 +407,21 @@ func (sut *SysUserTenantController) SetUserRoles(c *gin.Context) {
 			return err
 		}
 
-		// 删除用户在该租户下的角色关联
+		// 删除用户在该租户下的角色关联(保留未过期的限时授权)
 		if len(tenantRoleIDs) > 0 {
-			if err := tx.Where("user_id = ? AND role_id IN ?", req.UserID, tenantRoleIDs).Delete(&models.SysUserRole{}).Error; err != nil {
+			query := tx.Where("user_id = ? AND role_id IN ?", req.UserID, tenantRoleIDs)
+			if len(grantRoleIDs) > 0 {
+				query = query.Where("role_id NOT IN ?", grantRoleIDs)
+			}
+			if err := query.Delete(&models.SysUserRole{}).Error; err != nil {
 				return err
 			}
 		}
 
 		// 创建新的用户角色关联
-		if len(req.Roles) > 0 {
+		if len(roles) > 0 {
 			var userRoles []models.SysUserRole
-			for _, roleID := range req.Roles {
+			for _, roleID := range roles {
 				userRoles = append(userRoles, models.SysUserRole{
 					UserID: req.UserID,
 					RoleID: roleID,
@@ -407,7 +440,8 @@ func (sut *SysUserTenantController) SetUserRoles(c *gin.Context) {
 	}
 
 	// 同步更新Casbin权限
-	if err = sut.CasbinService.EditUserRoles(c, req.UserID, req.Roles, req.TenantID); err != nil {
+	roles = append(roles, sut.RoleGrantService.ActiveRoleIDs(roleGrants)...)
+	if err = sut.CasbinService.EditUserRoles(c, req.UserID, roles, req.TenantID); err != nil {
 		sut.FailAndAbort(c, "同步用户权限失败", err)
 	}
 
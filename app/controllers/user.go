@@ -9,7 +9,6 @@ import (
 	"gin-fast/app/models"
 	"gin-fast/app/service"
 	"gin-fast/app/utils/common"
-	"gin-fast/app/utils/passwordhelper"
 	"gin-fast/app/utils/tenanthelper"
 
 	"strconv"
@@ -29,6 +28,10 @@ type UserController struct {
 	Common
 	UserService   *service.User
 	CasbinService *service.PermissionService
+
+	PasswordPolicyService *service.PasswordPolicyService
+	RoleGrantService      *service.RoleGrantService
+	TenantQuotaService    *service.TenantQuotaService
 }
 
 // NewUserController 创建用户控制器
@@ -37,6 +40,10 @@ func NewUserController() *UserController {
 		Common:        Common{},
 		UserService:   service.NewUserService(),
 		CasbinService: service.NewPermissionService(),
+
+		PasswordPolicyService: service.NewPasswordPolicyService(),
+		RoleGrantService:      service.NewRoleGrantService(),
+		TenantQuotaService:    service.NewTenantQuotaService(),
 	}
 }
 
@@ -204,20 +211,25 @@ func (uc *UserController) Add(c *gin.Context) {
 		}
 	}
 
-	// 密码加密
-	hashedPassword, err := passwordhelper.HashPassword(req.Password)
-	if err != nil {
-		uc.FailAndAbort(c, "Failed to hash password", err)
+	// 按密码策略校验并加密密码
+	user.Username = req.UserName
+	user.TenantID = common.GetCurrentTenantID(c)
+	if err = uc.PasswordPolicyService.Apply(c, user, req.Password); err != nil {
+		uc.FailAndAbort(c, err.Error(), err)
 	}
 
 	// 使用事务创建用户和角色关联
 	err = app.DB().WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// 检查租户用户数配额
+		if err := uc.TenantQuotaService.CheckUsers(tx, common.GetCurrentTenantID(c), 1); err != nil {
+			return err
+		}
+
 		// 创建用户
 		user.Username = req.UserName
 		user.NickName = req.NickName
 		user.Phone = req.Phone
 		user.Email = req.Email
-		user.Password = string(hashedPassword)
 		user.Sex = req.Sex
 		user.DeptID = req.DeptId
 		user.Status = req.Status
@@ -226,6 +238,9 @@ func (uc *UserController) Add(c *gin.Context) {
 		if err := tx.Create(user).Error; err != nil {
 			return err
 		}
+		if err := uc.PasswordPolicyService.Record(c, tx, user); err != nil {
+			return err
+		}
 
 		// 创建用户角色关联
 		if len(req.Roles) > 0 {
@@ -257,6 +272,9 @@ func (uc *UserController) Add(c *gin.Context) {
 		return nil
 	})
 
+	if errors.Is(err, service.ErrTenantQuotaExceeded) {
+		uc.FailAndAbort(c, err.Error(), err)
+	}
 	if err != nil {
 		uc.FailAndAbort(c, "Failed to create user", err)
 	}
@@ -330,6 +348,24 @@ func (uc *UserController) Update(c *gin.Context) {
 		}
 	}
 
+	// 如果提供了新密码，则按密码策略校验并加密
+	if req.Password != "" {
+		user.Username = req.UserName
+		if err = uc.PasswordPolicyService.Apply(c, user, req.Password); err != nil {
+			uc.FailAndAbort(c, err.Error(), err)
+		}
+	}
+
+	// 限时授权通过授权接口单独管理，编辑用户时保持不变
+	roleGrants, err := uc.RoleGrantService.TimeBound(c, user.ID)
+	if err != nil {
+		uc.FailAndAbort(c, "更新用户失败", err)
+	}
+	grantRoleIDs := roleGrants.Map(func(m *models.SysUserRole) uint {
+		return m.RoleID
+	})
+	roles := uc.RoleGrantService.Permanent(req.Roles, roleGrants)
+
 	// 使用事务更新用户和角色关联
 	err = app.DB().WithContext(c).Transaction(func(tx *gorm.DB) error {
 		// 更新用户信息
@@ -342,29 +378,28 @@ func (uc *UserController) Update(c *gin.Context) {
 		user.Status = req.Status
 		user.Description = req.Description
 
-		// 如果提供了新密码，则加密并更新密码
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
 		if req.Password != "" {
-			// 加密新密码
-			hashedPassword, err := passwordhelper.HashPassword(req.Password)
-			if err != nil {
+			if err := uc.PasswordPolicyService.Record(c, tx, user); err != nil {
 				return err
 			}
-			user.Password = string(hashedPassword)
 		}
 
-		if err := tx.Save(user).Error; err != nil {
-			return err
+		// 删除现有的用户角色关联(保留未过期的限时授权)
+		query := tx.Where("user_id = ?", user.ID)
+		if len(grantRoleIDs) > 0 {
+			query = query.Where("role_id NOT IN ?", grantRoleIDs)
 		}
-
-		// 删除现有的用户角色关联
-		if err := tx.Where("user_id = ?", user.ID).Delete(&models.SysUserRole{}).Error; err != nil {
+		if err := query.Delete(&models.SysUserRole{}).Error; err != nil {
 			return err
 		}
 
 		// 创建新的用户角色关联
-		if len(req.Roles) > 0 {
-			userRoles := make([]models.SysUserRole, len(req.Roles))
-			for i, roleID := range req.Roles {
+		if len(roles) > 0 {
+			userRoles := make([]models.SysUserRole, len(roles))
+			for i, roleID := range roles {
 				userRoles[i] = models.SysUserRole{
 					UserID: user.ID,
 					RoleID: roleID,
@@ -382,7 +417,8 @@ func (uc *UserController) Update(c *gin.Context) {
 		uc.FailAndAbort(c, "更新用户失败", err)
 	}
 
-	if err = uc.CasbinService.EditUserRoles(c, user.ID, req.Roles); err != nil {
+	roles = append(roles, uc.RoleGrantService.ActiveRoleIDs(roleGrants)...)
+	if err = uc.CasbinService.EditUserRoles(c, user.ID, roles); err != nil {
 		uc.FailAndAbort(c, "更新用户失败", err)
 	}
 
@@ -423,8 +459,8 @@ func (uc *UserController) Delete(c *gin.Context) {
 		if err := tx.Where("user_id = ?", user.ID).Delete(&models.SysUserRole{}).Error; err != nil {
 			return err
 		}
-		// 删除用户租户关联
-		if err := tx.Where("user_id = ?", user.ID).Delete(&models.SysUserTenant{}).Error; err != nil {
+		// 删除用户租户关联(包括关联的其他租户)
+		if err := tx.Scopes(tenanthelper.WithoutTenant()).Where("user_id = ?", user.ID).Delete(&models.SysUserTenant{}).Error; err != nil {
 			return err
 		}
 		// 软删除用户
@@ -445,6 +481,53 @@ func (uc *UserController) Delete(c *gin.Context) {
 	uc.SuccessWithMessage(c, "删除成功", nil)
 }
 
+// GrantRole 限时角色授权
+// @Summary 限时角色授权
+// @Description 为用户授予限时角色，到达生效时间后生效、到达失效时间后自动回收；已有的同一角色授权将被替换
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param grant body models.UserRoleGrantRequest true "限时授权信息"
+// @Success 200 {object} map[string]interface{} "授权成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /users/roleGrant [post]
+// @Security ApiKeyAuth
+func (uc *UserController) GrantRole(c *gin.Context) {
+	var req models.UserRoleGrantRequest
+	if err := req.Validate(c); err != nil {
+		uc.FailAndAbort(c, err.Error(), err)
+	}
+	grant, err := uc.RoleGrantService.Grant(c, &req)
+	if err != nil {
+		uc.FailAndAbort(c, err.Error(), err)
+	}
+	uc.Success(c, grant)
+}
+
+// RevokeRole 撤销限时角色授权
+// @Summary 撤销限时角色授权
+// @Description 立即撤销用户的限时角色授权，长期授权的角色通过编辑用户调整
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param revoke body models.UserRoleRevokeRequest true "撤销授权信息"
+// @Success 200 {object} map[string]interface{} "撤销成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /users/roleGrant/revoke [post]
+// @Security ApiKeyAuth
+func (uc *UserController) RevokeRole(c *gin.Context) {
+	var req models.UserRoleRevokeRequest
+	if err := req.Validate(c); err != nil {
+		uc.FailAndAbort(c, err.Error(), err)
+	}
+	if err := uc.RoleGrantService.Revoke(c, req.UserID, req.RoleID); err != nil {
+		uc.FailAndAbort(c, err.Error(), err)
+	}
+	uc.SuccessWithMessage(c, "撤销成功", nil)
+}
+
 // UpdateAccount 更新用户账户信息（密码、手机号、邮箱）
 // @Summary 更新用户账户信息
 // @Description 更新用户密码、手机号及邮箱信息
@@ -498,14 +581,9 @@ func (uc *UserController) UpdateAccount(c *gin.Context) {
 		}
 	}
 	if req.Password != "" {
-		// 加密新密码
-		hashedPassword, err := passwordhelper.HashPassword(req.Password)
-		if err != nil {
-			uc.FailAndAbort(c, "密码加密失败", err)
+		if err = uc.PasswordPolicyService.Apply(c, user, req.Password); err != nil {
+			uc.FailAndAbort(c, err.Error(), err)
 		}
-
-		// 更新用户信息
-		user.Password = string(hashedPassword)
 	}
 
 	if req.Phone != "" {
@@ -515,7 +593,16 @@ func (uc *UserController) UpdateAccount(c *gin.Context) {
 		user.Email = req.Email
 	}
 
-	if err := app.DB().WithContext(c).Save(user).Error; err != nil {
+	err = app.DB().WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
+		if req.Password != "" {
+			return uc.PasswordPolicyService.Record(c, tx, user)
+		}
+		return nil
+	})
+	if err != nil {
 		uc.FailAndAbort(c, "更新用户信息失败", err)
 	}
 
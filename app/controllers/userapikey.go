@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"gin-fast/app/global/app"
+	"gin-fast/app/models"
+	"gin-fast/app/service"
+	"gin-fast/app/utils/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserApiKeyController 用户API密钥控制器
+type UserApiKeyController struct {
+	Common
+	ApiKeyService *service.ApiKeyService
+}
+
+// NewUserApiKeyController 创建用户API密钥控制器
+func NewUserApiKeyController() *UserApiKeyController {
+	return &UserApiKeyController{
+		Common:        Common{},
+		ApiKeyService: service.NewApiKeyService(),
+	}
+}
+
+// List 获取当前用户的API密钥列表
+// @Summary 获取当前用户的API密钥列表
+// @Description 获取当前登录用户的API密钥，仅返回密钥前缀，不返回完整密钥
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功返回API密钥列表"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /users/apiKeys [get]
+// @Security ApiKeyAuth
+func (ac *UserApiKeyController) List(c *gin.Context) {
+	claims := ac.sessionClaims(c)
+	list, err := ac.ApiKeyService.List(c, claims.UserID)
+	if err != nil {
+		ac.FailAndAbort(c, "获取API密钥列表失败", err)
+	}
+	ac.Success(c, gin.H{
+		"list":  list,
+		"total": len(list),
+	})
+}
+
+// Add 创建API密钥
+// @Summary 创建API密钥
+// @Description 为当前登录用户在当前租户下创建API密钥，完整密钥仅在创建时返回一次，调用接口时通过 X-Api-Key 请求头携带
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param apiKey body models.ApiKeyAddRequest true "API密钥信息"
+// @Success 200 {object} map[string]interface{} "成功返回API密钥"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /users/apiKeys/add [post]
+// @Security ApiKeyAuth
+func (ac *UserApiKeyController) Add(c *gin.Context) {
+	var req models.ApiKeyAddRequest
+	if err := req.Validate(c); err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+	claims := ac.sessionClaims(c)
+	if !ac.ApiKeyService.IsOpen() {
+		ac.FailAndAbort(c, "未开启API密钥访问", nil)
+	}
+	key, apiKey, err := ac.ApiKeyService.Create(c, claims, &req)
+	if err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+	ac.Success(c, gin.H{
+		"key":    key,
+		"apiKey": apiKey,
+	})
+}
+
+// Update 更新API密钥
+// @Summary 更新API密钥
+// @Description 更新当前用户API密钥的名称、授权范围、IP白名单、过期时间及状态，立即生效
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param apiKey body models.ApiKeyUpdateRequest true "API密钥信息"
+// @Success 200 {object} map[string]interface{} "更新成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /users/apiKeys/edit [put]
+// @Security ApiKeyAuth
+func (ac *UserApiKeyController) Update(c *gin.Context) {
+	var req models.ApiKeyUpdateRequest
+	if err := req.Validate(c); err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+	claims := ac.sessionClaims(c)
+	if err := ac.ApiKeyService.Update(c, claims.UserID, &req); err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+	ac.SuccessWithMessage(c, "更新成功", nil)
+}
+
+// Delete 删除API密钥
+// @Summary 删除API密钥
+// @Description 删除(撤销)当前用户的API密钥，立即失效
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param apiKey body models.ApiKeyDeleteRequest true "API密钥ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /users/apiKeys/delete [delete]
+// @Security ApiKeyAuth
+func (ac *UserApiKeyController) Delete(c *gin.Context) {
+	var req models.ApiKeyDeleteRequest
+	if err := req.Validate(c); err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+	claims := ac.sessionClaims(c)
+	if err := ac.ApiKeyService.Delete(c, claims.UserID, req.ID); err != nil {
+		ac.FailAndAbort(c, err.Error(), err)
+	}
+	ac.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// sessionClaims 获取当前登录用户声明，API密钥只能在登录会话中管理，避免受限密钥创建不受限的密钥
+func (ac *UserApiKeyController) sessionClaims(c *gin.Context) *app.Claims {
+	claims := common.GetClaims(c)
+	if claims == nil {
+		ac.FailAndAbort(c, "用户未登录", nil)
+	}
+	if claims.ApiKeyID > 0 {
+		ac.FailAndAbort(c, "不能使用API密钥管理API密钥", nil)
+	}
+	return claims
+}
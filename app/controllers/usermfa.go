@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"gin-fast/app/models"
+	"gin-fast/app/service"
+	"gin-fast/app/utils/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserMfaController 用户二次验证控制器
+type UserMfaController struct {
+	Common
+	MfaService *service.MfaService
+}
+
+// NewUserMfaController 创建用户二次验证控制器
+func NewUserMfaController() *UserMfaController {
+	return &UserMfaController{
+		Common:     Common{},
+		MfaService: service.NewMfaService(),
+	}
+}
+
+// Status 获取当前用户二次验证状态
+// @Summary 获取当前用户二次验证状态
+// @Description 获取当前登录用户是否已绑定、是否被强制要求二次验证及剩余恢复码数量
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功返回二次验证状态"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /users/mfa/status [get]
+// @Security ApiKeyAuth
+func (mc *UserMfaController) Status(c *gin.Context) {
+	claims := common.GetClaims(c)
+	status, err := mc.MfaService.GetStatus(c, claims.UserID, claims.TenantID)
+	if err != nil {
+		mc.FailAndAbort(c, "获取二次验证状态失败", err)
+	}
+	mc.Success(c, status)
+}
+
+// Enroll 绑定二次验证
+// @Summary 绑定二次验证
+// @Description 生成TOTP密钥、otpauth二维码地址及恢复码(仅返回一次)，需调用确认接口后才会启用
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功返回绑定信息"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /users/mfa/enroll [post]
+// @Security ApiKeyAuth
+func (mc *UserMfaController) Enroll(c *gin.Context) {
+	claims := common.GetClaims(c)
+	enrollment, err := mc.MfaService.BeginEnroll(c, claims.UserID, claims.Username)
+	if err != nil {
+		mc.FailAndAbort(c, err.Error(), err)
+	}
+	mc.Success(c, enrollment)
+}
+
+// Confirm 确认绑定二次验证
+// @Summary 确认绑定二次验证
+// @Description 使用验证器App生成的验证码确认绑定并启用二次验证
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param codeReq body models.MfaCodeRequest true "验证码"
+// @Success 200 {object} map[string]interface{} "绑定成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /users/mfa/confirm [post]
+// @Security ApiKeyAuth
+func (mc *UserMfaController) Confirm(c *gin.Context) {
+	var req models.MfaCodeRequest
+	if err := req.Validate(c); err != nil {
+		mc.FailAndAbort(c, err.Error(), err)
+	}
+	claims := common.GetClaims(c)
+	if err := mc.MfaService.ConfirmEnroll(c, claims.UserID, req.Code); err != nil {
+		mc.FailAndAbort(c, err.Error(), err)
+	}
+	mc.SuccessWithMessage(c, "二次验证已启用", nil)
+}
+
+// Disable 关闭二次验证
+// @Summary 关闭二次验证
+// @Description 校验验证码(或恢复码)后关闭当前用户的二次验证
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param codeReq body models.MfaCodeRequest true "验证码"
+// @Success 200 {object} map[string]interface{} "关闭成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /users/mfa/disable [post]
+// @Security ApiKeyAuth
+func (mc *UserMfaController) Disable(c *gin.Context) {
+	var req models.MfaCodeRequest
+	if err := req.Validate(c); err != nil {
+		mc.FailAndAbort(c, err.Error(), err)
+	}
+	claims := common.GetClaims(c)
+	enforced, err := mc.MfaService.IsEnforced(c, claims.UserID, claims.TenantID)
+	if err != nil {
+		mc.FailAndAbort(c, "查询二次验证配置失败", err)
+	}
+	if enforced {
+		mc.FailAndAbort(c, "当前租户或角色强制要求二次验证，无法关闭", nil)
+	}
+	if err = mc.MfaService.Disable(c, claims.UserID, req.Code); err != nil {
+		mc.FailAndAbort(c, err.Error(), err)
+	}
+	mc.SuccessWithMessage(c, "二次验证已关闭", nil)
+}
+
+// RecoveryCodes 重新生成恢复码
+// @Summary 重新生成恢复码
+// @Description 校验验证码后重新生成恢复码(仅返回一次)，原有恢复码全部失效
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param codeReq body models.MfaCodeRequest true "验证码"
+// @Success 200 {object} map[string]interface{} "成功返回新的恢复码"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /users/mfa/recoveryCodes [post]
+// @Security ApiKeyAuth
+func (mc *UserMfaController) RecoveryCodes(c *gin.Context) {
+	var req models.MfaCodeRequest
+	if err := req.Validate(c); err != nil {
+		mc.FailAndAbort(c, err.Error(), err)
+	}
+	claims := common.GetClaims(c)
+	codes, err := mc.MfaService.RegenerateRecoveryCodes(c, claims.UserID, req.Code)
+	if err != nil {
+		mc.FailAndAbort(c, err.Error(), err)
+	}
+	mc.Success(c, gin.H{
+		"recoveryCodes": codes,
+	})
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"gin-fast/app/global/app"
+	"gin-fast/app/models"
+	"gin-fast/app/utils/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserSessionController 用户登录会话(设备)管理控制器
+type UserSessionController struct {
+	Common
+}
+
+// NewUserSessionController 创建用户会话控制器
+func NewUserSessionController() *UserSessionController {
+	return &UserSessionController{
+		Common: Common{},
+	}
+}
+
+// List 获取当前用户的登录会话列表
+// @Summary 获取当前用户的登录会话列表
+// @Description 获取当前登录用户所有在线设备的会话信息，current 标识当前请求所在会话
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功返回会话列表"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /users/sessions [get]
+// @Security ApiKeyAuth
+func (sc *UserSessionController) List(c *gin.Context) {
+	claims := common.GetClaims(c)
+	sessions, err := app.TokenService.ListSessions(claims.UserID)
+	if err != nil {
+		sc.FailAndAbort(c, "获取会话列表失败", err)
+	}
+
+	list := make([]gin.H, 0, len(sessions))
+	for _, session := range sessions {
+		list = append(list, gin.H{
+			"sessionId":  session.SessionID,
+			"tenantCode": session.TenantCode,
+			"deviceName": session.DeviceName,
+			"ip":         session.IP,
+			"userAgent":  session.UserAgent,
+			"createdAt":  session.CreatedAt,
+			"lastUsedAt": session.LastUsedAt,
+			"expiresAt":  session.ExpiresAt,
+			"current":    session.SessionID == claims.SessionID,
+		})
+	}
+	sc.Success(c, gin.H{
+		"list":  list,
+		"total": len(list),
+	})
+}
+
+// Revoke 撤销指定会话
+// @Summary 撤销指定会话
+// @Description 撤销当前用户的指定会话，对应设备需重新登录
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param revokeReq body models.SessionRevokeRequest true "会话ID"
+// @Success 200 {object} map[string]interface{} "撤销成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /users/sessions/revoke [delete]
+// @Security ApiKeyAuth
+func (sc *UserSessionController) Revoke(c *gin.Context) {
+	var req models.SessionRevokeRequest
+	if err := req.Validate(c); err != nil {
+		sc.FailAndAbort(c, err.Error(), err)
+	}
+	claims := common.GetClaims(c)
+	if err := app.TokenService.RevokeSession(claims.UserID, req.SessionID); err != nil {
+		sc.FailAndAbort(c, "撤销会话失败", err)
+	}
+	// 撤销的是当前会话时同时撤销当前access token
+	if req.SessionID == claims.SessionID {
+		if tokenString, err := common.GetAccessToken(c); err == nil && tokenString != "" {
+			app.TokenService.RevokeTokenWithCache(tokenString)
+		}
+	}
+	sc.SuccessWithMessage(c, "会话已撤销", nil)
+}
+
+// RevokeAll 退出所有设备
+// @Summary 退出所有设备
+// @Description 撤销当前用户的所有会话(包括当前会话)，所有设备需重新登录
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "撤销成功"
+// @Failure 500 {object} map[string]interface{} "服务器内部错误"
+// @Router /users/sessions/revokeAll [post]
+// @Security ApiKeyAuth
+func (sc *UserSessionController) RevokeAll(c *gin.Context) {
+	claims := common.GetClaims(c)
+	if err := app.TokenService.RevokeRefreshToken(claims.UserID); err != nil {
+		sc.FailAndAbort(c, "退出所有设备失败", err)
+	}
+	if tokenString, err := common.GetAccessToken(c); err == nil && tokenString != "" {
+		app.TokenService.RevokeTokenWithCache(tokenString)
+	}
+	sc.SuccessWithMessage(c, "已退出所有设备", nil)
+}
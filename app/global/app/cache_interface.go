@@ -30,9 +30,27 @@ type CacheInterf interface {
 	// Expire 设置键的过期时间
 	Expire(ctx context.Context, key string, expiration time.Duration) error
 
+	// Incr 将键的整数值原子加1并返回新值，键不存在时从0开始并设置过期时间(已存在的键不修改过期时间)
+	Incr(ctx context.Context, key string, expiration time.Duration) (int64, error)
+
+	// Update 原子地读改写键值：fn 接收当前值(键不存在时为空字符串)并返回新值，返回空字符串时删除该键，返回错误时放弃修改
+	// 并发冲突时 fn 可能被多次调用，因此 fn 不应产生副作用，且执行期间不得再访问缓存
+	Update(ctx context.Context, key string, expiration time.Duration, fn func(value string) (string, error)) error
+
 	// GetAll 获取所有缓存项
 	GetAll(ctx context.Context) ([]CacheItem, error)
 
 	// Close 关闭连接
 	Close() error
 }
+
+// PubSubInterf 发布订阅接口
+// 由支持发布订阅的缓存实现(如Redis)，用于多实例间的消息通知
+type PubSubInterf interface {
+	// Publish 向频道发布消息
+	Publish(ctx context.Context, channel string, message string) error
+
+	// Subscribe 订阅频道，收到消息时调用 onMessage；连接断开重新订阅后调用 onResubscribe(期间的消息可能已丢失)
+	// 返回的函数用于取消订阅
+	Subscribe(channel string, onMessage func(message string), onResubscribe func()) (func() error, error)
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"context"
+	"io"
+)
+
+// CaptchaData 验证码展示数据
+type CaptchaData struct {
+	// CaptchaID 验证码ID，校验时与答案一起提交
+	CaptchaID string `json:"captchaId"`
+
+	// Type 验证码类型
+	Type string `json:"type"`
+
+	// Image 验证码图片(PNG data URI)，滑块验证码为带缺口的背景图
+	Image string `json:"image"`
+
+	// Width 图片宽度
+	Width int `json:"width"`
+
+	// Height 图片高度
+	Height int `json:"height"`
+
+	// Piece 滑块图片(PNG data URI)，仅滑块验证码
+	Piece string `json:"piece,omitempty"`
+
+	// PieceY 滑块在背景图中的纵坐标，仅滑块验证码，答案为滑块拖动后的横坐标
+	PieceY int `json:"pieceY,omitempty"`
+
+	// ExpiresAt 过期时间(Unix时间戳)
+	ExpiresAt int64 `json:"expiresAt"`
+}
+
+// Captcha 验证码接口
+// 由使用者定义，实现者只需要实现该接口即可(图形数字、算术、滑块等)，答案存储在 Cache 中以支持多实例部署
+type Captcha interface {
+	// Type 验证码类型
+	Type() string
+
+	// Generate 生成验证码
+	Generate(ctx context.Context) (*CaptchaData, error)
+
+	// Verify 校验验证码答案，无论校验是否通过验证码均失效
+	Verify(ctx context.Context, id string, answer string) bool
+}
+
+// CaptchaMedia 支持按验证码ID输出图片及语音的验证码(图形数字验证码)
+type CaptchaMedia interface {
+	// WriteImage 输出验证码图片(PNG)
+	WriteImage(ctx context.Context, w io.Writer, id string, width, height int) error
+
+	// WriteAudio 输出验证码语音(WAV)，lang 可选 en、zh、ru、ja、pt
+	WriteAudio(ctx context.Context, w io.Writer, id string, lang string) error
+
+	// Reload 重新生成验证码答案(验证码ID不变)
+	Reload(ctx context.Context, id string) error
+}
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"time"
+
 	"github.com/casbin/casbin/v2"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -15,8 +17,11 @@ type CasbinInterf interface {
 	// Enforce 检查权限
 	Enforce(sub, obj, act string, domain ...string) (bool, error)
 
+	// EnforceWithAttributes 携带请求属性检查权限，属性用于判定策略上的ABAC条件
+	EnforceWithAttributes(attrs *CasbinAttributes, sub, obj, act string, domain ...string) (bool, error)
+
 	// GetEnforcer 获取Casbin执行器
-	GetEnforcer() *casbin.Enforcer
+	GetEnforcer() *casbin.SyncedEnforcer
 
 	// CasbinMiddleware Casbin权限中间件
 	CasbinMiddleware() gin.HandlerFunc
@@ -45,6 +50,24 @@ type CasbinInterf interface {
 	// RemoveAllPoliciesForRole 删除角色的所有权限策略
 	RemoveAllPoliciesForRole(roleID uint, domain ...string) error
 
+	// GetPoliciesForRole 获取角色直接拥有的权限策略，返回 [obj, act] 列表
+	GetPoliciesForRole(roleID uint, domain ...string) ([][]string, error)
+
+	// GetConditionsForRole 获取角色权限策略上的ABAC条件
+	GetConditionsForRole(roleID uint, domain ...string) ([]CasbinPolicyCondition, error)
+
+	// SetConditionsForRole 覆盖角色权限策略上的ABAC条件
+	SetConditionsForRole(roleID uint, conditions []CasbinPolicyCondition, domain ...string) error
+
+	// ApplyRoleChanges 批量应用多个角色的权限策略、继承关系及ABAC条件变更，任一步骤失败时恢复已应用的变更
+	ApplyRoleChanges(changes []CasbinRoleChange, domain ...string) error
+
+	// SetAttributeResolver 设置补充请求属性(部门、租户套餐等)的函数，只在存在ABAC条件时调用
+	SetAttributeResolver(resolver CasbinAttributeResolver)
+
+	// RemoveDomain 删除域(租户)下的所有权限策略及角色关系
+	RemoveDomain(domain string) error
+
 	// GetRolesForUserByID 获取用户ID的所有角色
 	GetRolesForUserByID(userID uint, domain ...string) ([]uint, error)
 
@@ -62,4 +85,84 @@ type CasbinInterf interface {
 
 	// PrefixDomain
 	PrefixDomain(tenantID uint) string
+
+	// Explain 解释用户对资源的权限判定过程，domain 为空时与 CasbinMiddleware 一致按全局用户处理
+	Explain(userID uint, obj, act string, domain ...string) (*CasbinExplanation, error)
+}
+
+// CasbinExplanation 权限判定解释
+type CasbinExplanation struct {
+	Subject         string             `json:"subject"`         // 请求主体(带前缀的用户)
+	Object          string             `json:"object"`          // 请求路径
+	Action          string             `json:"action"`          // 请求方法
+	Domain          string             `json:"domain"`          // 请求域，空表示全局
+	Allowed         bool               `json:"allowed"`         // 是否允许访问
+	DecisivePolicy  []string           `json:"decisivePolicy"`  // 决定判定结果的策略
+	MatchedPolicies [][]string         `json:"matchedPolicies"` // 所有匹配请求的策略
+	Conditions      []*CasbinCondition `json:"conditions"`      // 与 MatchedPolicies 一一对应的ABAC条件，无条件时为null
+	RoleChain       []*CasbinRoleNode  `json:"roleChain"`       // 通过 g 关系获得的角色链
+}
+
+// CasbinRoleNode 角色链节点
+type CasbinRoleNode struct {
+	Subject string            `json:"subject"`        // 带前缀的角色
+	RoleID  uint              `json:"roleId"`         // 角色ID
+	Name    string            `json:"name,omitempty"` // 角色名称(由调用方填充)
+	Parents []*CasbinRoleNode `json:"parents"`        // 继承的父角色
+}
+
+// CasbinCondition 权限策略上的ABAC条件，各项均满足时策略才生效，未设置的项不限制
+type CasbinCondition struct {
+	Times  []CasbinTimeWindow `json:"times,omitempty"`  // 允许访问的时间段，满足其一即可
+	IPs    []string           `json:"ips,omitempty"`    // 允许访问的来源IP或CIDR(如 10.0.0.0/8)
+	Plans  []string           `json:"plans,omitempty"`  // 允许访问的租户套餐
+	Params []CasbinParamRule  `json:"params,omitempty"` // 请求参数条件，需全部满足
+}
+
+// IsEmpty 条件是否未设置任何限制
+func (c *CasbinCondition) IsEmpty() bool {
+	return c == nil || len(c.Times) == 0 && len(c.IPs) == 0 && len(c.Plans) == 0 && len(c.Params) == 0
+}
+
+// CasbinTimeWindow 时间段，结束时间早于开始时间时表示跨越零点
+type CasbinTimeWindow struct {
+	Start    string `json:"start"`              // 开始时间 HH:MM
+	End      string `json:"end"`                // 结束时间 HH:MM(不含)
+	Weekdays []int  `json:"weekdays,omitempty"` // 生效的星期(0周日 1-6周一至周六)，为空时每天生效
+}
+
+// CasbinParamRule 请求参数条件，参数不存在时不满足
+type CasbinParamRule struct {
+	Name  string `json:"name"`  // 路径参数或查询参数名，如 deptId
+	Value string `json:"value"` // 期望值，可引用当前用户属性 {userId}、{deptId}、{tenantId}
 }
+
+// CasbinPolicyCondition 角色的一条权限策略及其ABAC条件
+type CasbinPolicyCondition struct {
+	Obj       string           `json:"obj"`       // 策略资源(路径)
+	Act       string           `json:"act"`       // 策略动作(请求方法)
+	Condition *CasbinCondition `json:"condition"` // ABAC条件
+}
+
+// CasbinRoleChange 角色的权限变更，由 ApplyRoleChanges 批量应用
+type CasbinRoleChange struct {
+	RoleID        uint                    // 角色ID
+	Policies      [][]string              // 替换后的权限策略 [obj, act]，为nil时不修改
+	ParentChanged bool                    // 是否修改父角色
+	ParentID      uint                    // 父角色ID，0表示没有父角色
+	Conditions    []CasbinPolicyCondition // 替换后的ABAC条件，为nil时不修改
+}
+
+// CasbinAttributes 判定ABAC条件使用的请求属性
+type CasbinAttributes struct {
+	Time     time.Time         // 请求时间
+	IP       string            // 来源IP
+	UserID   uint              // 用户ID
+	DeptID   uint              // 用户所属部门ID
+	TenantID uint              // 租户ID
+	Plan     string            // 租户套餐
+	Params   map[string]string // 路径参数及查询参数
+}
+
+// CasbinAttributeResolver 补充请求属性的函数
+type CasbinAttributeResolver func(c *gin.Context, attrs *CasbinAttributes) error
@@ -0,0 +1,19 @@
+package app
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FieldPermissionInterf 字段权限接口
+// 按当前用户角色的字段权限处理响应数据，并在更新时忽略无权修改的字段
+type FieldPermissionInterf interface {
+	// Filter 处理响应数据：隐藏或脱敏受限字段，返回可直接序列化的数据
+	// 字段权限加载失败时返回错误，调用方不得返回原始数据
+	Filter(c *gin.Context, data interface{}) (interface{}, error)
+
+	// RestrictedFields 获取当前用户在模型(表名)中无权修改的字段(JSON字段名)
+	// ctx 为数据库操作的上下文，未登录或不受限制时返回空；字段权限加载失败时返回错误
+	RestrictedFields(ctx context.Context, model string) ([]string, error)
+}
@@ -0,0 +1,25 @@
+package app
+
+import "context"
+
+// MailMessage 邮件内容
+type MailMessage struct {
+	// To 收件人地址列表
+	To []string
+
+	// Subject 邮件主题
+	Subject string
+
+	// Body 邮件正文
+	Body string
+
+	// HTML 正文是否为HTML格式
+	HTML bool
+}
+
+// Mailer 邮件发送接口
+// 由使用者定义，实现者只需要实现该接口即可(SMTP、仅记录日志等)
+type Mailer interface {
+	// Send 发送邮件
+	Send(ctx context.Context, msg *MailMessage) error
+}
@@ -20,29 +20,47 @@ type TokenServiceInterface interface {
 	// GenerateTokenWithCache 生成JWT令牌并存储到缓存
 	GenerateTokenWithCache(user *ClaimsUser) (string, error)
 
+	// GenerateTokenWithExpire 生成指定有效期的JWT令牌并存储到缓存(不创建会话，无法刷新)
+	GenerateTokenWithExpire(user *ClaimsUser, expire time.Duration) (string, error)
+
 	// ValidateTokenWithCache 验证JWT令牌（带缓存检查）
 	ValidateTokenWithCache(tokenString string) (*Claims, error)
 
 	// RevokeToken 撤销Token（从缓存中移除）
 	RevokeTokenWithCache(tokenString string) error
 
-	// GenerateRefreshToken 生成Refresh Token
+	// GenerateRefreshToken 生成Refresh Token（创建一个不含设备信息的新会话）
 	GenerateRefreshToken(userID uint) (string, error)
 
+	// GenerateRefreshTokenWithSession 生成Refresh Token并创建登录会话，返回的token中携带会话ID
+	GenerateRefreshTokenWithSession(userID uint, session *SessionInfo) (string, error)
+
 	// ParseRefreshToken 解析Refresh Token
 	ParseRefreshToken(tokenString string) (*RefreshTokenClaims, error)
 
 	// ValidateRefreshToken 验证Refresh Token
 	ValidateRefreshToken(tokenString string) (*RefreshTokenClaims, error)
 
-	// RevokeRefreshToken 撤销Refresh Token
+	// RevokeRefreshToken 撤销用户所有会话的Refresh Token（退出所有设备）
 	RevokeRefreshToken(userID uint) error
 
+	// RevokeSession 撤销指定会话
+	RevokeSession(userID uint, sessionID string) error
+
+	// ListSessions 获取用户所有有效会话
+	ListSessions(userID uint) ([]*SessionInfo, error)
+
 	// RefreshAccessToken 使用Refresh Token刷新Access Token并记录在缓存中
 	RefreshAccessTokenWithCache(refreshTokenString string, user *ClaimsUser) (string, error)
 
 	// RotateRefreshToken 轮换Refresh Token（撤销旧的，生成新的，保持相同的剩余过期时间）
 	RotateRefreshToken(oldRefreshToken string) (string, error)
+
+	// SwitchSessionTenant 切换会话所属租户，返回携带新租户的Refresh Token
+	SwitchSessionTenant(userID uint, sessionID string, tenantID uint, tenantCode string) (string, error)
+
+	// JWKS 获取用于验证token的公钥集合(使用HMAC签名时为空)
+	JWKS() *JSONWebKeySet
 }
 
 // ClaimsUser 用户声明信息
@@ -51,6 +69,11 @@ type ClaimsUser struct {
 	Username   string `json:"username"`             // 用户名
 	TenantID   uint   `json:"tenantId,omitempty"`   // 租户ID
 	TenantCode string `json:"tenantCode,omitempty"` // 租户编码
+	SessionID  string `json:"sid,omitempty"`        // 会话ID
+	ApiKeyID   uint   `json:"akid,omitempty"`       // API密钥ID(通过API密钥认证时)
+
+	ImpersonatorID   uint   `json:"impersonatorId,omitempty"`   // 模拟登录的操作人ID(管理员以该用户身份登录时)
+	ImpersonatorName string `json:"impersonatorName,omitempty"` // 模拟登录的操作人用户名
 }
 
 // Claims JWT声明结构
@@ -61,10 +84,27 @@ type Claims struct {
 
 // RefreshTokenClaims Refresh Token声明结构
 type RefreshTokenClaims struct {
-	UserID uint `json:"userId"`
+	UserID     uint   `json:"userId"`
+	SessionID  string `json:"sid,omitempty"`        // 会话ID
+	TenantID   uint   `json:"tenantId,omitempty"`   // 租户ID
+	TenantCode string `json:"tenantCode,omitempty"` // 租户编码
 	jwt.RegisteredClaims
 }
 
+// SessionInfo 登录会话信息（每个设备一个会话）
+type SessionInfo struct {
+	SessionID  string    `json:"sessionId"`  // 会话ID
+	UserID     uint      `json:"userId"`     // 用户ID
+	TenantID   uint      `json:"tenantId"`   // 当前租户ID
+	TenantCode string    `json:"tenantCode"` // 当前租户编码
+	DeviceName string    `json:"deviceName"` // 设备名称
+	IP         string    `json:"ip"`         // 登录IP
+	UserAgent  string    `json:"userAgent"`  // 浏览器UA
+	CreatedAt  time.Time `json:"createdAt"`  // 创建时间
+	LastUsedAt time.Time `json:"lastUsedAt"` // 最近使用时间(登录或刷新token)
+	ExpiresAt  time.Time `json:"expiresAt"`  // 过期时间
+}
+
 // RefreshTokenInfo Refresh Token信息
 type RefreshTokenInfo struct {
 	UserID    uint      `json:"userId"`
@@ -80,3 +120,21 @@ type TokenInfo struct {
 	ExpiresAt time.Time `json:"expiresAt"`
 	CreatedAt time.Time `json:"createdAt"`
 }
+
+// JSONWebKey 公钥(JWK, RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty"`           // 密钥类型 RSA / EC / OKP
+	Kid string `json:"kid"`           // 密钥标识
+	Alg string `json:"alg"`           // 签名算法
+	Use string `json:"use"`           // 用途，固定为sig
+	N   string `json:"n,omitempty"`   // RSA模数
+	E   string `json:"e,omitempty"`   // RSA指数
+	Crv string `json:"crv,omitempty"` // 曲线名称
+	X   string `json:"x,omitempty"`   // EC/OKP公钥X坐标
+	Y   string `json:"y,omitempty"`   // EC公钥Y坐标
+}
+
+// JSONWebKeySet 公钥集合(JWKS)
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
@@ -20,7 +20,10 @@ var (
 	Cache            CacheInterf           // 缓存指针
 	TokenService     TokenServiceInterface // token管理
 	Response         ResponseHandler
-	UploadService    FileUploadService // 文件上传服务
+	UploadService    FileUploadService     // 文件上传服务
+	MailService      Mailer                // 邮件发送服务
+	CaptchaService   Captcha               // 验证码服务
+	FieldPermission  FieldPermissionInterf // 字段权限
 )
 
 /*
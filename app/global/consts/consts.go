@@ -4,8 +4,9 @@ package consts
 type ContextKey string
 
 const (
-	BindContextKeyName = "userToken"          // token解析值绑定上下文键名
-	ConfigFilePath     = "/config/config.yml" // 配置文件路径
+	BindContextKeyName       = "userToken"          // token解析值绑定上下文键名
+	HostTenantContextKeyName = "hostTenant"         // 按域名识别的租户绑定上下文键名
+	ConfigFilePath           = "/config/config.yml" // 配置文件路径
 	//服务器代码发生错误
 	ServerOccurredErrorCode int    = -500100
 	ServerOccurredErrorMsg  string = "服务器内部发生代码执行错误,请联系开发者排查错误日志"
@@ -17,6 +18,13 @@ const (
 	// 上传类型
 	UploadTypeLocal = "local"
 	UploadTypeQiniu = "qiniu"
+	// 邮件发送类型
+	MailTypeSmtp = "smtp"
+	MailTypeLog  = "log"
+	// 验证码类型
+	CaptchaTypeDigits = "digits"
+	CaptchaTypeMath   = "math"
+	CaptchaTypeSlider = "slider"
 	// 上传文件类型
 	UploadFileTypeImage    = "image"
 	UploadFileTypeVideo    = "video"
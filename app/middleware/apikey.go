@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"gin-fast/app/global/app"
+	"gin-fast/app/utils/common"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ApiKeyForbiddenMiddleware 禁止API密钥认证访问的账号安全接口(修改账号、二次验证及会话管理等)
+// 未限定范围的API密钥拥有用户的全部接口权限，这些操作只允许在登录会话中进行
+func ApiKeyForbiddenMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := common.GetClaims(c)
+		if claims == nil || claims.ApiKeyID == 0 {
+			c.Next()
+			return
+		}
+
+		app.ZapLog.Warn("API密钥尝试访问账号安全接口",
+			zap.Uint("apiKeyID", claims.ApiKeyID),
+			zap.Uint("userID", claims.UserID),
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path))
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    http.StatusForbidden,
+			"message": "API密钥认证禁止执行该操作",
+		})
+		c.Abort()
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"gin-fast/app/global/app"
 	"io"
 
-	"github.com/dchest/captcha"
 	"github.com/gin-gonic/gin"
 )
 
@@ -59,7 +58,7 @@ func CaptchaMiddleware() gin.HandlerFunc {
 		}
 
 		// 验证验证码
-		if !captcha.VerifyString(captchaId, captchaValue) {
+		if !app.CaptchaService.Verify(c, captchaId, captchaValue) {
 			app.Response.Fail(c, "验证码错误")
 			c.Abort()
 			return
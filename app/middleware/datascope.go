@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"gin-fast/app/utils/datascope"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DataScopeMiddleware 请求结束后再次清除请求中事务变更的数据权限缓存
+// 事务提交前清除的缓存可能被并发请求按旧数据重新写入，请求结束时事务已提交
+func DataScopeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		datascope.DeferInvalidate(c)
+		c.Next()
+		datascope.FlushInvalidate(c)
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"gin-fast/app/global/app"
+	"gin-fast/app/utils/common"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ImpersonationForbiddenMiddleware 禁止模拟登录会话访问的敏感接口(修改密码、二次验证、会话及API密钥管理等)
+func ImpersonationForbiddenMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := common.GetClaims(c)
+		if claims == nil || claims.ImpersonatorID == 0 {
+			c.Next()
+			return
+		}
+
+		app.ZapLog.Warn("模拟登录会话尝试访问敏感接口",
+			zap.Uint("impersonatorID", claims.ImpersonatorID),
+			zap.Uint("userID", claims.UserID),
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path))
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    http.StatusForbidden,
+			"message": "模拟登录会话禁止执行该操作",
+		})
+		c.Abort()
+	}
+}
@@ -1,8 +1,10 @@
 package middleware
 
 import (
+	"errors"
 	"gin-fast/app/global/app"
 	"gin-fast/app/global/consts"
+	"gin-fast/app/service"
 	"gin-fast/app/utils/common"
 	"net/http"
 
@@ -10,9 +12,36 @@ import (
 	"go.uber.org/zap"
 )
 
+// ApiKeyHeader API密钥请求头
+const ApiKeyHeader = "X-Api-Key"
+
+var apiKeyService = service.NewApiKeyService()
+
 // JWTAuthMiddleware JWT认证中间件
+// 同时支持通过 X-Api-Key 请求头携带的API密钥认证，密钥解析为所属用户及租户的声明
 func JWTAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if apiKey := c.GetHeader(ApiKeyHeader); apiKey != "" {
+			claims, err := apiKeyService.Authenticate(c, apiKey, c.ClientIP(), c.Request.Method, c.Request.URL.Path)
+			if err != nil {
+				app.ZapLog.Error("Invalid api key", zap.Error(err))
+				status := http.StatusUnauthorized
+				if errors.Is(err, service.ErrApiKeyScopeDenied) {
+					// 403 超出密钥授权范围
+					status = http.StatusForbidden
+				}
+				c.JSON(status, gin.H{"message": err.Error()})
+				c.Abort()
+				return
+			}
+			if !matchHostTenant(c, claims) {
+				return
+			}
+			c.Set(consts.BindContextKeyName, claims)
+			c.Next()
+			return
+		}
+
 		tokenString, err := common.GetAccessToken(c)
 		if err != nil {
 			app.ZapLog.Error("Get access token failed", zap.Error(err))
@@ -31,9 +60,29 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		if !matchHostTenant(c, claims) {
+			return
+		}
 		// 将用户信息存储到上下文中
 		c.Set(consts.BindContextKeyName, claims)
 		// 继续处理请求
 		c.Next()
 	}
 }
+
+// matchHostTenant 通过租户域名访问时，访问令牌的租户需与域名识别的租户一致，不一致时中止请求
+func matchHostTenant(c *gin.Context, claims *app.Claims) bool {
+	tenant := tenantHostService.HostTenant(c)
+	if tenant == nil || tenant.ID == claims.TenantID {
+		return true
+	}
+	app.ZapLog.Warn("访问令牌租户与域名不匹配",
+		zap.Uint("userID", claims.UserID),
+		zap.Uint("tenantID", claims.TenantID),
+		zap.Uint("hostTenantID", tenant.ID),
+		zap.String("host", c.Request.Host))
+	// 403 禁止访问
+	c.JSON(http.StatusForbidden, gin.H{"message": "当前域名与登录租户不匹配，请重新登录"})
+	c.Abort()
+	return false
+}
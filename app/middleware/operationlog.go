@@ -67,6 +67,7 @@ func shouldSkipLog(c *gin.Context) bool {
 		"/api/refreshToken",  // 刷新token
 		"/api/captcha/id",    // 生成验证码ID
 		"/api/captcha/image", // 获取验证码图片
+		"/api/captcha/audio", // 获取验证码语音
 		"/api/config/get",    // 获取配置信息
 	}
 
@@ -88,6 +89,8 @@ func recordOperationLog(c *gin.Context, startTime time.Time, requestBody, respon
 	var userID uint
 	var username string
 	var tenantID uint
+	var impersonatorID uint
+	var impersonatorName string
 	operationType := getOperationType(c)
 
 	// 尝试从JWT token获取用户信息
@@ -96,6 +99,12 @@ func recordOperationLog(c *gin.Context, startTime time.Time, requestBody, respon
 		userID = claims.UserID
 		username = claims.Username
 		tenantID = claims.TenantID
+		// 模拟登录会话同时记录实际操作人
+		impersonatorID = claims.ImpersonatorID
+		impersonatorName = claims.ImpersonatorName
+		if c.Request.URL.Path == "/api/users/impersonate" {
+			operationType = models.OperationImpersonate
+		}
 	} else {
 		// 如果是登录操作，尝试从请求体中获取用户名
 		if c.Request.URL.Path == "/api/login" && c.Request.Method == "POST" {
@@ -130,6 +139,9 @@ func recordOperationLog(c *gin.Context, startTime time.Time, requestBody, respon
 		ErrorMsg:   getErrorMessage(c, responseBody),
 		Location:   getLocationByIP(c.ClientIP()),
 		TenantID:   tenantID,
+
+		ImpersonatorID:   impersonatorID,
+		ImpersonatorName: impersonatorName,
 	}
 
 	// 异步保存日志
@@ -232,6 +244,13 @@ func sanitizeRequestData(data []byte) string {
 			if _, exists := jsonData["newPassword"]; exists {
 				jsonData["newPassword"] = "***"
 			}
+			if _, exists := jsonData["adminPassword"]; exists {
+				jsonData["adminPassword"] = "***"
+			}
+			// 脱敏二次验证令牌
+			if _, exists := jsonData["mfaToken"]; exists {
+				jsonData["mfaToken"] = "***"
+			}
 
 			// 重新序列化
 			if sanitized, err := json.Marshal(jsonData); err == nil {
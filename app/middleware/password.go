@@ -2,22 +2,20 @@ package middleware
 
 import (
 	"net/http"
-	"regexp"
-	"strconv"
 	"strings"
 
-	"gin-fast/app/global/app"
+	"gin-fast/app/service"
+	"gin-fast/app/utils/common"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 )
 
 // PasswordValidatorMiddleware 密码验证中间件
+// 按当前租户生效的密码策略校验字符规则，用户名及历史密码在保存密码时校验
 func PasswordValidatorMiddleware() gin.HandlerFunc {
+	passwordPolicyService := service.NewPasswordPolicyService()
 	return func(c *gin.Context) {
-		// 获取配置中的密码安全设置
-		minPasswordLength := app.ConfigYml.GetInt("safe.minpasswordlength")
-		requireSpecialChar := app.ConfigYml.GetBool("safe.requirespecialchar")
 
 		// 获取请求方法
 		method := c.Request.Method
@@ -49,30 +47,25 @@ func PasswordValidatorMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 验证密码长度
-		if len(password) < minPasswordLength {
+		// 获取当前租户生效的密码策略
+		policy, err := passwordPolicyService.Effective(c, common.GetCurrentTenantID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "获取密码策略失败",
+			})
+			c.Abort()
+			return
+		}
+		if err = policy.Validate(password, ""); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"code":    400,
-				"message": "密码长度不能少于" + strconv.Itoa(minPasswordLength) + "位",
+				"message": err.Error(),
 			})
 			c.Abort()
 			return
 		}
 
-		// 如果配置要求特殊字符，则验证密码是否包含特殊字符
-		if requireSpecialChar {
-			// 定义特殊字符正则表达式
-			specialCharRegex := regexp.MustCompile(`[!@#$%]`)
-			if !specialCharRegex.MatchString(password) {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"code":    400,
-					"message": "密码必须包含特殊字符 !@#$%",
-				})
-				c.Abort()
-				return
-			}
-		}
-
 		c.Next()
 	}
 }
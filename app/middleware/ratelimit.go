@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"gin-fast/app/global/app"
+	"gin-fast/app/service"
+	"gin-fast/app/utils/common"
+	"gin-fast/app/utils/ratelimithelper"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+var rateLimitService = service.NewRateLimitService()
+
+// RateLimitMiddleware 按IP限流中间件
+func RateLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !rateLimitService.IsOpen() {
+			c.Next()
+			return
+		}
+		rule := rateLimitService.Rule(c.Request.Method, c.Request.URL.Path)
+		if !allowRequest(c, rule, ratelimithelper.DimensionIP, c.ClientIP()) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// AuthRateLimitMiddleware 按用户及租户限流中间件，需在JWT认证中间件之后使用
+func AuthRateLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := common.GetClaims(c)
+		if !rateLimitService.IsOpen() || claims == nil {
+			c.Next()
+			return
+		}
+		rule := rateLimitService.Rule(c.Request.Method, c.Request.URL.Path)
+		if !allowRequest(c, rule, ratelimithelper.DimensionUser, strconv.FormatUint(uint64(claims.UserID), 10)) {
+			return
+		}
+		if claims.TenantID > 0 && !allowRequest(c, rule, ratelimithelper.DimensionTenant, strconv.FormatUint(uint64(claims.TenantID), 10)) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// allowRequest 检查请求是否超出限流规则，超出时返回429并中止请求
+// 缓存异常时放行，避免影响正常访问
+func allowRequest(c *gin.Context, rule *ratelimithelper.Rule, dimension, id string) bool {
+	allowed, retryAfter, err := rateLimitService.Allow(c, rule, dimension, id)
+	if err != nil {
+		app.ZapLog.Error("请求限流计数失败", zap.Error(err), zap.String("rule", rule.Name))
+		return true
+	}
+	if allowed {
+		return true
+	}
+
+	app.ZapLog.Warn("请求超出限流",
+		zap.String("rule", rule.Name),
+		zap.String("dimension", dimension),
+		zap.String("id", id),
+		zap.String("path", c.Request.URL.Path))
+	c.Header("Retry-After", strconv.Itoa(ratelimithelper.RetryAfterSeconds(retryAfter)))
+	app.Response.Fail(c, "请求过于频繁，请稍后再试", http.StatusTooManyRequests)
+	return false
+}
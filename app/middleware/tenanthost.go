@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"gin-fast/app/global/app"
+	"gin-fast/app/global/consts"
+	"gin-fast/app/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+var tenantHostService = service.NewTenantHostService()
+
+// TenantHostMiddleware 按请求域名识别租户
+// 识别出的租户绑定到上下文，登录时默认选择该租户，访问令牌的租户需与之一致
+func TenantHostMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !tenantHostService.IsOpen() {
+			c.Next()
+			return
+		}
+
+		tenant, err := tenantHostService.Resolve(c, c.Request.Host)
+		if err != nil {
+			app.ZapLog.Error("按域名识别租户失败", zap.Error(err), zap.String("host", c.Request.Host))
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "识别租户失败"})
+			c.Abort()
+			return
+		}
+		if tenant == nil {
+			c.Next()
+			return
+		}
+		if tenant.Status != 1 {
+			c.JSON(http.StatusForbidden, gin.H{"message": "租户未启用"})
+			c.Abort()
+			return
+		}
+		c.Set(consts.HostTenantContextKeyName, tenant)
+		c.Next()
+	}
+}
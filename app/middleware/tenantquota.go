@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"gin-fast/app/global/app"
+	"gin-fast/app/service"
+	"gin-fast/app/utils/common"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+var tenantQuotaService = service.NewTenantQuotaService()
+
+// TenantQuotaMiddleware 租户每日API调用次数计量及配额检查中间件，需在JWT认证中间件之后使用
+// 超出当日配额时返回429；缓存异常时放行，避免影响正常访问
+func TenantQuotaMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := common.GetClaims(c)
+		if claims == nil || claims.TenantID == 0 {
+			c.Next()
+			return
+		}
+		allowed, err := tenantQuotaService.CountApiCall(c, claims.TenantID)
+		if err != nil {
+			app.ZapLog.Error("租户API调用计数失败", zap.Error(err), zap.Uint("tenantId", claims.TenantID))
+			c.Next()
+			return
+		}
+		if !allowed {
+			app.ZapLog.Warn("租户API调用次数超出配额",
+				zap.Uint("tenantId", claims.TenantID),
+				zap.String("path", c.Request.URL.Path))
+			app.Response.Fail(c, "租户今日API调用次数已达上限", http.StatusTooManyRequests)
+			return
+		}
+		c.Next()
+	}
+}
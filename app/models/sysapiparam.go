@@ -90,3 +90,13 @@ type SysApiGetRequest struct {
 func (r *SysApiGetRequest) Validate(c *gin.Context) error {
 	return r.Check(c, r)
 }
+
+// SysApiSyncRequest 按路由表同步API请求结构
+type SysApiSyncRequest struct {
+	Validator
+	RemoveStale bool `form:"removeStale" json:"removeStale"` // 是否删除路由已不存在且未关联菜单的API
+}
+
+func (r *SysApiSyncRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
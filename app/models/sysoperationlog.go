@@ -25,6 +25,9 @@ type SysOperationLog struct {
 	ErrorMsg     string `gorm:"column:error_msg;type:text;comment:错误信息" json:"errorMsg"`
 	Location     string `gorm:"column:location;size:100;comment:操作地点" json:"location"`
 	TenantID     uint   `gorm:"type:int(11);column:tenant_id;comment:租户ID" json:"tenantID"`
+
+	ImpersonatorID   uint   `gorm:"column:impersonator_id;default:0;comment:模拟登录操作人ID" json:"impersonatorId"`
+	ImpersonatorName string `gorm:"column:impersonator_name;size:50;comment:模拟登录操作人用户名" json:"impersonatorName"`
 }
 
 // TableName 设置表名
@@ -46,6 +49,8 @@ const (
 	OperationLogout = "logout" // 登出
 	OperationExport = "export" // 导出
 	OperationImport = "import" // 导入
+
+	OperationImpersonate = "impersonate" // 模拟登录
 )
 
 type SysOperationLogList []*SysOperationLog
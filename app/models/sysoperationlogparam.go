@@ -16,6 +16,8 @@ type SysOperationLogListRequest struct {
 	StartTime string `form:"startTime"` // 开始时间
 	EndTime   string `form:"endTime"`   // 结束时间
 	IP        string `form:"ip"`        // IP地址
+
+	ImpersonatorID uint `form:"impersonatorId"` // 模拟登录操作人ID
 }
 
 func (r *SysOperationLogListRequest) Validate(c *gin.Context) error {
@@ -44,6 +46,9 @@ func (r *SysOperationLogListRequest) Handle() func(db *gorm.DB) *gorm.DB {
 		if r.IP != "" {
 			db = db.Where("ip LIKE ?", "%"+r.IP+"%")
 		}
+		if r.ImpersonatorID > 0 {
+			db = db.Where("impersonator_id = ?", r.ImpersonatorID)
+		}
 		if r.StartTime != "" && r.EndTime != "" {
 			db = db.Where("created_at BETWEEN ? AND ?", r.StartTime, r.EndTime)
 		}
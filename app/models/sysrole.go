@@ -9,11 +9,14 @@ import (
 	"gorm.io/gorm"
 )
 
-// 默认可以查看子级创建的数据
-// 1: 全部；
-// 2: 自定义；
-// 3: 本部门；
-// 4: 本部门及子级
+// 数据权限，未设置时只能查看本人的数据
+const (
+	DataScopeAll             int8 = 1 // 全部
+	DataScopeCustom          int8 = 2 // 自定义部门
+	DataScopeDept            int8 = 3 // 本部门
+	DataScopeDeptAndChildren int8 = 4 // 本部门及子级
+	DataScopeSelf            int8 = 5 // 本人
+)
 
 // SysRole 系统角色模型
 type SysRole struct {
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"context"
+	"gin-fast/app/global/app"
+	"gin-fast/app/utils/fieldpermhelper"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SysRoleField 角色字段权限模型
+type SysRoleField struct {
+	RoleID    uint      `gorm:"primaryKey;column:role_id;comment:角色ID" json:"roleId"`
+	Model     string    `gorm:"primaryKey;column:model;size:100;comment:模型(表名)" json:"model"`
+	Field     string    `gorm:"primaryKey;column:field;size:100;comment:字段(JSON字段名)" json:"field"`
+	Action    int8      `gorm:"column:action;not null;default:1;comment:权限 1只读 2脱敏 3隐藏" json:"action"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updatedAt"`
+}
+
+// TableName 设置表名
+func (SysRoleField) TableName() string {
+	return "sys_role_field"
+}
+
+func NewSysRoleField() *SysRoleField {
+	return &SysRoleField{}
+}
+
+type SysRoleFieldList []*SysRoleField
+
+func NewSysRoleFieldList() SysRoleFieldList {
+	return SysRoleFieldList{}
+}
+
+// IsEmpty 检查角色字段权限列表是否为空
+func (list SysRoleFieldList) IsEmpty() bool {
+	return len(list) == 0
+}
+
+// Find
+func (list *SysRoleFieldList) Find(c context.Context, funcs ...func(*gorm.DB) *gorm.DB) (err error) {
+	err = app.DB().WithContext(c).Scopes(funcs...).Find(list).Error
+	return
+}
+
+// Rules 转换为字段权限规则
+func (list SysRoleFieldList) Rules() []fieldpermhelper.Rule {
+	rules := make([]fieldpermhelper.Rule, len(list))
+	for i, v := range list {
+		rules[i] = fieldpermhelper.Rule{
+			RoleID: v.RoleID,
+			Model:  v.Model,
+			Field:  v.Field,
+			Action: fieldpermhelper.Action(v.Action),
+		}
+	}
+	return rules
+}
@@ -1,6 +1,12 @@
 package models
 
 import (
+	"errors"
+	"gin-fast/app/global/app"
+	"gin-fast/app/utils/fieldpermhelper"
+	"mime/multipart"
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -87,6 +93,14 @@ type SysRoleMenuAssignRequest struct {
 	Validator
 	RoleID uint   `form:"roleId" json:"roleId" validate:"required" message:"角色ID不能为空"`
 	MenuID []uint `form:"menuId" json:"menuId" validate:"required" message:"菜单ID列表不能为空"`
+	// Conditions 菜单API权限上的ABAC条件，为null时保留原有条件
+	Conditions []SysRoleApiCondition `form:"conditions" json:"conditions"`
+}
+
+// SysRoleApiCondition 角色API权限的ABAC条件
+type SysRoleApiCondition struct {
+	ApiID     uint                 `json:"apiId"`     // API ID，需属于分配的菜单
+	Condition *app.CasbinCondition `json:"condition"` // 条件，为空时不限制
 }
 
 func (r *SysRoleMenuAssignRequest) Validate(c *gin.Context) error {
@@ -131,3 +145,60 @@ func (r *SysRoleListAllRequest) Handler() func(db *gorm.DB) *gorm.DB {
 		return db
 	}
 }
+
+// SysRoleExplainRequest 权限判定解释请求结构
+type SysRoleExplainRequest struct {
+	Validator
+	UserID     uint   `form:"userId" validate:"required" message:"用户ID不能为空"`
+	TenantCode string `form:"tenantCode"` // 租户编码，为空时使用用户所属租户
+	Path       string `form:"path" validate:"required" message:"请求路径不能为空"`
+	Method     string `form:"method" validate:"required" message:"请求方法不能为空"`
+}
+
+func (r *SysRoleExplainRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
+
+// SysRoleFieldItem 角色字段权限项
+type SysRoleFieldItem struct {
+	Model  string `json:"model"`  // 模型(表名)，如 sys_users
+	Field  string `json:"field"`  // 字段(JSON字段名)，如 phone
+	Action int8   `json:"action"` // 权限 1只读 2脱敏 3隐藏
+}
+
+// SysRoleFieldUpdateRequest 角色字段权限设置请求结构(覆盖角色的全部字段权限)
+type SysRoleFieldUpdateRequest struct {
+	Validator
+	RoleID uint               `form:"roleId" json:"roleId" validate:"required" message:"角色ID不能为空"`
+	Fields []SysRoleFieldItem `form:"fields" json:"fields"`
+}
+
+func (r *SysRoleFieldUpdateRequest) Validate(c *gin.Context) error {
+	err := r.Check(c, r)
+	if err != nil {
+		return err
+	}
+	for i := range r.Fields {
+		item := &r.Fields[i]
+		item.Model = strings.TrimSpace(item.Model)
+		item.Field = strings.TrimSpace(item.Field)
+		if item.Model == "" || item.Field == "" {
+			return errors.New("模型和字段不能为空")
+		}
+		if !fieldpermhelper.Action(item.Action).Valid() {
+			return errors.New("字段权限必须为1(只读)、2(脱敏)或3(隐藏)")
+		}
+	}
+	return nil
+}
+
+// SysRoleImportRequest 导入权限配置请求结构
+type SysRoleImportRequest struct {
+	Validator
+	File   *multipart.FileHeader `form:"file" validate:"required" message:"请上传权限配置文件"`
+	DryRun bool                  `form:"dryRun"` // 只预览变更，不写入
+}
+
+func (r *SysRoleImportRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
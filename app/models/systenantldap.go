@@ -0,0 +1,135 @@
+package models
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"gin-fast/app/global/app"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SysTenantLdap 租户目录服务(LDAP / Active Directory)登录配置
+type SysTenantLdap struct {
+	TenantID           uint           `gorm:"primaryKey;type:int(11);column:tenant_id;comment:租户ID" json:"tenantId"`
+	Enabled            bool           `gorm:"type:tinyint(1);column:enabled;default:0;comment:是否启用 0停用 1启用" json:"enabled"`
+	URL                string         `gorm:"column:url;size:255;not null;comment:服务地址(ldap://或ldaps://)" json:"url"`
+	StartTLS           bool           `gorm:"type:tinyint(1);column:start_tls;default:0;comment:是否使用StartTLS" json:"startTls"`
+	InsecureSkipVerify bool           `gorm:"type:tinyint(1);column:insecure_skip_verify;default:0;comment:是否跳过证书校验" json:"insecureSkipVerify"`
+	BindDN             string         `gorm:"column:bind_dn;size:255;comment:服务账号DN" json:"bindDn"`
+	BindPassword       string         `gorm:"column:bind_password;size:255;comment:服务账号密码" json:"-"`
+	BaseDN             string         `gorm:"column:base_dn;size:255;not null;comment:用户查询基础DN" json:"baseDn"`
+	UserFilter         string         `gorm:"column:user_filter;size:255;comment:用户查询过滤器(%s为用户名)" json:"userFilter"`
+	EmailAttr          string         `gorm:"column:email_attr;size:64;comment:邮箱属性" json:"emailAttr"`
+	NameAttr           string         `gorm:"column:name_attr;size:64;comment:显示名称属性" json:"nameAttr"`
+	GroupAttr          string         `gorm:"column:group_attr;size:64;comment:所属组属性" json:"groupAttr"`
+	GroupRoles         LdapGroupRoles `gorm:"column:group_roles;type:text;comment:用户组与角色映射(JSON)" json:"groupRoles"`
+	RequireGroup       bool           `gorm:"type:tinyint(1);column:require_group;default:0;comment:是否仅允许映射用户组中的用户登录" json:"requireGroup"`
+	LinkExisting       bool           `gorm:"type:tinyint(1);column:link_existing;default:0;comment:是否按用户名绑定租户内已有用户" json:"linkExisting"`
+	CreatedAt          time.Time      `gorm:"column:created_at" json:"createdAt"`
+	UpdatedAt          time.Time      `gorm:"column:updated_at" json:"updatedAt"`
+}
+
+// LdapGroupRole 目录用户组与系统角色的映射
+type LdapGroupRole struct {
+	Group   string `json:"group"`   // 用户组DN或CN
+	RoleIDs []uint `json:"roleIds"` // 映射的角色ID
+}
+
+// LdapGroupRoles 用户组与角色映射列表，以JSON存储
+type LdapGroupRoles []LdapGroupRole
+
+// Value 实现 driver.Valuer 接口，用于数据库写入
+func (g LdapGroupRoles) Value() (driver.Value, error) {
+	if len(g) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(g)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现 sql.Scanner 接口，用于数据库读取
+func (g *LdapGroupRoles) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*g = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return errors.New("不支持的用户组角色映射类型")
+	}
+	if len(data) == 0 {
+		*g = nil
+		return nil
+	}
+	return json.Unmarshal(data, g)
+}
+
+// RoleIDs 获取用户组映射的角色ID(去重)
+// match 判断用户是否属于映射中的用户组
+func (g LdapGroupRoles) RoleIDs(match func(group string) bool) []uint {
+	seen := make(map[uint]struct{})
+	var roleIDs []uint
+	for _, mapping := range g {
+		if !match(mapping.Group) {
+			continue
+		}
+		for _, roleID := range mapping.RoleIDs {
+			if _, ok := seen[roleID]; ok {
+				continue
+			}
+			seen[roleID] = struct{}{}
+			roleIDs = append(roleIDs, roleID)
+		}
+	}
+	return roleIDs
+}
+
+// TableName 设置表名
+func (SysTenantLdap) TableName() string {
+	return "sys_tenant_ldap"
+}
+
+func NewSysTenantLdap() *SysTenantLdap {
+	return &SysTenantLdap{}
+}
+
+// IsEmpty 检查记录是否为空
+func (m *SysTenantLdap) IsEmpty() bool {
+	return m == nil || m.TenantID == 0
+}
+
+// Find 查找租户目录服务配置
+func (m *SysTenantLdap) Find(c context.Context, funcs ...func(*gorm.DB) *gorm.DB) (err error) {
+	err = app.DB().WithContext(c).Scopes(funcs...).First(m).Error
+	if err == gorm.ErrRecordNotFound {
+		err = nil // 将记录未找到的错误转换为nil，通过IsEmpty()方法判断
+	}
+	return
+}
+
+// GetByTenantID 根据租户ID获取目录服务配置
+func (m *SysTenantLdap) GetByTenantID(c context.Context, tenantID uint) error {
+	return m.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("tenant_id = ?", tenantID)
+	})
+}
+
+// Save 保存(新增或更新)目录服务配置
+func (m *SysTenantLdap) Save(c context.Context) error {
+	return app.DB().WithContext(c).Save(m).Error
+}
+
+// Delete 删除目录服务配置
+func (m *SysTenantLdap) Delete(c context.Context) error {
+	return app.DB().WithContext(c).Where("tenant_id = ?", m.TenantID).Delete(&SysTenantLdap{}).Error
+}
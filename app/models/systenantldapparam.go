@@ -0,0 +1,39 @@
+package models
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// SysTenantLdapSaveRequest 保存租户目录服务配置请求结构
+type SysTenantLdapSaveRequest struct {
+	Validator
+	Enabled            bool           `form:"enabled" json:"enabled"`
+	URL                string         `form:"url" json:"url" validate:"required" message:"服务地址不能为空"`
+	StartTLS           bool           `form:"startTls" json:"startTls"`
+	InsecureSkipVerify bool           `form:"insecureSkipVerify" json:"insecureSkipVerify"`
+	BindDN             string         `form:"bindDn" json:"bindDn"`
+	BindPassword       string         `form:"bindPassword" json:"bindPassword"` // 为空时保留原密码
+	BaseDN             string         `form:"baseDn" json:"baseDn" validate:"required" message:"基础DN不能为空"`
+	UserFilter         string         `form:"userFilter" json:"userFilter"`
+	EmailAttr          string         `form:"emailAttr" json:"emailAttr"`
+	NameAttr           string         `form:"nameAttr" json:"nameAttr"`
+	GroupAttr          string         `form:"groupAttr" json:"groupAttr"`
+	GroupRoles         LdapGroupRoles `form:"groupRoles" json:"groupRoles"`
+	RequireGroup       bool           `form:"requireGroup" json:"requireGroup"`
+	LinkExisting       bool           `form:"linkExisting" json:"linkExisting"`
+}
+
+func (r *SysTenantLdapSaveRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
+
+// SysTenantLdapTestRequest 测试目录服务连接请求结构
+type SysTenantLdapTestRequest struct {
+	SysTenantLdapSaveRequest
+	Username string `form:"username" json:"username"` // 测试用户名，与密码同时填写时测试用户认证
+	Password string `form:"password" json:"password"`
+}
+
+func (r *SysTenantLdapTestRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
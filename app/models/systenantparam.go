@@ -14,6 +14,15 @@ type SysTenantAddRequest struct {
 	Status         int8   `form:"status" json:"status" validate:"required|in:0,1" message:"状态值必须为0或1"`
 	Domain         string `form:"domain" json:"domain"`
 	PlatformDomain string `form:"platformDomain" json:"platformDomain"`
+	Plan           string `form:"plan" json:"plan"`                                                           // 租户套餐，可在权限策略的ABAC条件中使用
+	Template       string `form:"template" json:"template"`                                                   // 初始化模板，为空时使用默认模板
+	AdminUsername  string `form:"adminUsername" json:"adminUsername"`                                         // 初始管理员用户名，为空时使用模板中的默认用户名
+	AdminPassword  string `form:"adminPassword" json:"adminPassword"`                                         // 初始管理员密码，为空时随机生成并在响应中返回
+	AdminEmail     string `form:"adminEmail" json:"adminEmail"`                                               // 初始管理员邮箱
+	MaxUsers       int    `form:"maxUsers" json:"maxUsers" validate:"min:0" message:"最大用户数不能小于0"`             // 最大用户数，0表示不限制
+	MaxRoles       int    `form:"maxRoles" json:"maxRoles" validate:"min:0" message:"最大角色数不能小于0"`             // 最大角色数，0表示不限制
+	MaxStorage     int64  `form:"maxStorage" json:"maxStorage" validate:"min:0" message:"最大存储空间不能小于0"`        // 最大存储空间(字节)，0表示不限制
+	MaxApiCalls    int    `form:"maxApiCalls" json:"maxApiCalls" validate:"min:0" message:"每日最大API调用次数不能小于0"` // 每日最大API调用次数，0表示不限制
 }
 
 func (r *SysTenantAddRequest) Validate(c *gin.Context) error {
@@ -30,12 +39,27 @@ type SysTenantUpdateRequest struct {
 	Status         int8   `form:"status" json:"status" validate:"required|in:0,1" message:"状态值必须为0或1"`
 	Domain         string `form:"domain" json:"domain"`
 	PlatformDomain string `form:"platformDomain" json:"platformDomain"`
+	Plan           string `form:"plan" json:"plan"`                                                           // 租户套餐
+	MaxUsers       int    `form:"maxUsers" json:"maxUsers" validate:"min:0" message:"最大用户数不能小于0"`             // 最大用户数，0表示不限制
+	MaxRoles       int    `form:"maxRoles" json:"maxRoles" validate:"min:0" message:"最大角色数不能小于0"`             // 最大角色数，0表示不限制
+	MaxStorage     int64  `form:"maxStorage" json:"maxStorage" validate:"min:0" message:"最大存储空间不能小于0"`        // 最大存储空间(字节)，0表示不限制
+	MaxApiCalls    int    `form:"maxApiCalls" json:"maxApiCalls" validate:"min:0" message:"每日最大API调用次数不能小于0"` // 每日最大API调用次数，0表示不限制
 }
 
 func (r *SysTenantUpdateRequest) Validate(c *gin.Context) error {
 	return r.Check(c, r)
 }
 
+// SysTenantUsageRequest 租户资源用量请求结构
+type SysTenantUsageRequest struct {
+	Validator
+	ID uint `form:"id" json:"id"` // 租户ID，为空时为当前租户，仅全局租户可查看其他租户
+}
+
+func (r *SysTenantUsageRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
+
 // SysTenantDeleteRequest 删除租户请求结构
 type SysTenantDeleteRequest struct {
 	Validator
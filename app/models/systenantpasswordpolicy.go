@@ -0,0 +1,84 @@
+package models
+
+import (
+	"context"
+	"gin-fast/app/global/app"
+	"gin-fast/app/utils/passwordhelper"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SysTenantPasswordPolicy 租户密码策略(启用后覆盖全局密码策略)
+type SysTenantPasswordPolicy struct {
+	TenantID         uint       `gorm:"primaryKey;type:int(11);column:tenant_id;comment:租户ID" json:"tenantId"`
+	Enabled          bool       `gorm:"type:tinyint(1);column:enabled;default:0;comment:是否启用 0停用 1启用" json:"enabled"`
+	MinLength        int        `gorm:"column:min_length;default:0;comment:最小长度" json:"minLength"`
+	RequireUpper     bool       `gorm:"type:tinyint(1);column:require_upper;default:0;comment:是否必须包含大写字母" json:"requireUpper"`
+	RequireLower     bool       `gorm:"type:tinyint(1);column:require_lower;default:0;comment:是否必须包含小写字母" json:"requireLower"`
+	RequireDigit     bool       `gorm:"type:tinyint(1);column:require_digit;default:0;comment:是否必须包含数字" json:"requireDigit"`
+	RequireSpecial   bool       `gorm:"type:tinyint(1);column:require_special;default:0;comment:是否必须包含特殊字符" json:"requireSpecial"`
+	SpecialChars     string     `gorm:"column:special_chars;size:100;comment:特殊字符集合" json:"specialChars"`
+	ForbiddenWords   StringList `gorm:"column:forbidden_words;type:text;comment:禁止包含的词(JSON数组)" json:"forbiddenWords"`
+	DisallowUsername bool       `gorm:"type:tinyint(1);column:disallow_username;default:0;comment:是否禁止包含用户名" json:"disallowUsername"`
+	HistoryCount     int        `gorm:"column:history_count;default:0;comment:禁止重复使用最近N次的密码" json:"historyCount"`
+	MaxAgeDays       int        `gorm:"column:max_age_days;default:0;comment:密码最长使用天数" json:"maxAgeDays"`
+	CreatedAt        time.Time  `gorm:"column:created_at" json:"createdAt"`
+	UpdatedAt        time.Time  `gorm:"column:updated_at" json:"updatedAt"`
+}
+
+// TableName 设置表名
+func (SysTenantPasswordPolicy) TableName() string {
+	return "sys_tenant_password_policy"
+}
+
+func NewSysTenantPasswordPolicy() *SysTenantPasswordPolicy {
+	return &SysTenantPasswordPolicy{}
+}
+
+// IsEmpty 检查记录是否为空
+func (m *SysTenantPasswordPolicy) IsEmpty() bool {
+	return m == nil || m.TenantID == 0
+}
+
+// Policy 转换为密码策略
+func (m *SysTenantPasswordPolicy) Policy() *passwordhelper.Policy {
+	return &passwordhelper.Policy{
+		MinLength:        m.MinLength,
+		RequireUpper:     m.RequireUpper,
+		RequireLower:     m.RequireLower,
+		RequireDigit:     m.RequireDigit,
+		RequireSpecial:   m.RequireSpecial,
+		SpecialChars:     m.SpecialChars,
+		ForbiddenWords:   m.ForbiddenWords,
+		DisallowUsername: m.DisallowUsername,
+		HistoryCount:     m.HistoryCount,
+		MaxAgeDays:       m.MaxAgeDays,
+	}
+}
+
+// Find 查找租户密码策略
+func (m *SysTenantPasswordPolicy) Find(c context.Context, funcs ...func(*gorm.DB) *gorm.DB) (err error) {
+	err = app.DB().WithContext(c).Scopes(funcs...).First(m).Error
+	if err == gorm.ErrRecordNotFound {
+		err = nil // 将记录未找到的错误转换为nil，通过IsEmpty()方法判断
+	}
+	return
+}
+
+// GetByTenantID 根据租户ID获取密码策略
+func (m *SysTenantPasswordPolicy) GetByTenantID(c context.Context, tenantID uint) error {
+	return m.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("tenant_id = ?", tenantID)
+	})
+}
+
+// Save 保存(新增或更新)租户密码策略
+func (m *SysTenantPasswordPolicy) Save(c context.Context) error {
+	return app.DB().WithContext(c).Save(m).Error
+}
+
+// Delete 删除租户密码策略(恢复使用全局密码策略)
+func (m *SysTenantPasswordPolicy) Delete(c context.Context) error {
+	return app.DB().WithContext(c).Where("tenant_id = ?", m.TenantID).Delete(&SysTenantPasswordPolicy{}).Error
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// SysTenantPasswordPolicySaveRequest 保存租户密码策略请求结构
+type SysTenantPasswordPolicySaveRequest struct {
+	Validator
+	Enabled          bool     `form:"enabled" json:"enabled"`
+	MinLength        int      `form:"minLength" json:"minLength"`
+	RequireUpper     bool     `form:"requireUpper" json:"requireUpper"`
+	RequireLower     bool     `form:"requireLower" json:"requireLower"`
+	RequireDigit     bool     `form:"requireDigit" json:"requireDigit"`
+	RequireSpecial   bool     `form:"requireSpecial" json:"requireSpecial"`
+	SpecialChars     string   `form:"specialChars" json:"specialChars"` // 为空时使用默认特殊字符集合
+	ForbiddenWords   []string `form:"forbiddenWords" json:"forbiddenWords"`
+	DisallowUsername bool     `form:"disallowUsername" json:"disallowUsername"`
+	HistoryCount     int      `form:"historyCount" json:"historyCount"`
+	MaxAgeDays       int      `form:"maxAgeDays" json:"maxAgeDays"`
+}
+
+func (r *SysTenantPasswordPolicySaveRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
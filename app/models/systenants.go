@@ -16,6 +16,11 @@ type Tenant struct {
 	Status         int8   `gorm:"column:status;default:1;comment:状态 0停用 1启用" json:"status"`
 	Domain         string `gorm:"column:domain;size:255;comment:绑定域名(完整域名，非空时应唯一)" json:"domain"`
 	PlatformDomain string `gorm:"column:platform_domain;size:255;comment:平台基础域名(如:yourplatform.com)" json:"platformDomain"`
+	Plan           string `gorm:"column:plan;size:50;default:'';comment:租户套餐" json:"plan"`
+	MaxUsers       int    `gorm:"column:max_users;default:0;comment:最大用户数(0不限制)" json:"maxUsers"`
+	MaxRoles       int    `gorm:"column:max_roles;default:0;comment:最大角色数(0不限制)" json:"maxRoles"`
+	MaxStorage     int64  `gorm:"column:max_storage;default:0;comment:最大存储空间(字节，0不限制)" json:"maxStorage"`
+	MaxApiCalls    int    `gorm:"column:max_api_calls;default:0;comment:每日最大API调用次数(0不限制)" json:"maxApiCalls"`
 	CreatedBy      uint   `gorm:"column:created_by;comment:创建人" json:"createdBy"`
 }
 
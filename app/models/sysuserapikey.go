@@ -0,0 +1,91 @@
+package models
+
+import (
+	"context"
+	"gin-fast/app/global/app"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SysUserApiKey 用户API密钥(供脚本、集成等机器客户端调用接口)
+type SysUserApiKey struct {
+	ID         uint       `gorm:"primarykey;column:id" json:"id"`
+	UserID     uint       `gorm:"type:int(11);column:user_id;index;not null;comment:用户ID" json:"userId"`
+	TenantID   uint       `gorm:"type:int(11);column:tenant_id;default:0;comment:租户ID" json:"tenantId"`
+	TenantCode string     `gorm:"column:tenant_code;size:100;comment:租户编码" json:"tenantCode"`
+	Name       string     `gorm:"column:name;size:100;not null;comment:密钥名称" json:"name"`
+	Prefix     string     `gorm:"column:prefix;size:20;not null;comment:密钥前缀(用于识别)" json:"prefix"`
+	KeyHash    string     `gorm:"column:key_hash;size:64;not null;uniqueIndex;comment:密钥摘要(SHA-256)" json:"-"`
+	Scopes     StringList `gorm:"column:scopes;type:text;comment:授权范围(JSON数组)" json:"scopes"`
+	AllowedIPs StringList `gorm:"column:allowed_ips;type:text;comment:IP白名单(JSON数组)" json:"allowedIps"`
+	Status     int8       `gorm:"column:status;default:1;comment:状态 0停用 1启用" json:"status"`
+	ExpiresAt  *time.Time `gorm:"column:expires_at;comment:过期时间，为空表示永不过期" json:"expiresAt"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at;comment:最近使用时间" json:"lastUsedAt"`
+	LastUsedIP string     `gorm:"column:last_used_ip;size:64;comment:最近使用IP" json:"lastUsedIp"`
+	CreatedAt  time.Time  `gorm:"column:created_at" json:"createdAt"`
+	UpdatedAt  time.Time  `gorm:"column:updated_at" json:"updatedAt"`
+}
+
+// TableName 设置表名
+func (SysUserApiKey) TableName() string {
+	return "sys_user_api_key"
+}
+
+func NewSysUserApiKey() *SysUserApiKey {
+	return &SysUserApiKey{}
+}
+
+// IsEmpty 检查记录是否为空
+func (m *SysUserApiKey) IsEmpty() bool {
+	return m == nil || m.ID == 0
+}
+
+// IsExpired 检查密钥是否已过期
+func (m *SysUserApiKey) IsExpired() bool {
+	return m.ExpiresAt != nil && !m.ExpiresAt.After(time.Now())
+}
+
+// Find 查找API密钥
+func (m *SysUserApiKey) Find(c context.Context, funcs ...func(*gorm.DB) *gorm.DB) (err error) {
+	err = app.DB().WithContext(c).Scopes(funcs...).First(m).Error
+	if err == gorm.ErrRecordNotFound {
+		err = nil // 将记录未找到的错误转换为nil，通过IsEmpty()方法判断
+	}
+	return
+}
+
+// GetByHash 根据密钥摘要获取API密钥
+func (m *SysUserApiKey) GetByHash(c context.Context, keyHash string) error {
+	return m.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("key_hash = ?", keyHash)
+	})
+}
+
+// Create 创建API密钥
+func (m *SysUserApiKey) Create(c context.Context) error {
+	return app.DB().WithContext(c).Create(m).Error
+}
+
+// Save 保存API密钥
+func (m *SysUserApiKey) Save(c context.Context) error {
+	return app.DB().WithContext(c).Save(m).Error
+}
+
+// Delete 删除API密钥
+func (m *SysUserApiKey) Delete(c context.Context) error {
+	return app.DB().WithContext(c).Delete(m).Error
+}
+
+// SysUserApiKeyList API密钥列表
+type SysUserApiKeyList []*SysUserApiKey
+
+func NewSysUserApiKeyList() SysUserApiKeyList {
+	return SysUserApiKeyList{}
+}
+
+// Find 查找API密钥列表
+func (list *SysUserApiKeyList) Find(c context.Context, funcs ...func(*gorm.DB) *gorm.DB) (err error) {
+	err = app.DB().WithContext(c).Scopes(funcs...).Find(list).Error
+	return
+}
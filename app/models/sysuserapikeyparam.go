@@ -0,0 +1,43 @@
+package models
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// ApiKeyAddRequest 创建API密钥请求结构
+type ApiKeyAddRequest struct {
+	Validator
+	Name       string   `form:"name" json:"name" validate:"required" message:"密钥名称不能为空"`
+	Scopes     []string `form:"scopes" json:"scopes"`         // 授权范围，如 ["GET /api/users/*"]，为空表示与用户权限一致
+	AllowedIPs []string `form:"allowedIps" json:"allowedIps"` // IP白名单，支持CIDR，为空表示不限制
+	ExpiresAt  JSONTime `form:"expiresAt" json:"expiresAt"`   // 过期时间，为空表示永不过期
+}
+
+func (r *ApiKeyAddRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
+
+// ApiKeyUpdateRequest 更新API密钥请求结构(密钥本身不可修改)
+type ApiKeyUpdateRequest struct {
+	Validator
+	ID         uint     `form:"id" json:"id" validate:"required" message:"密钥ID不能为空"`
+	Name       string   `form:"name" json:"name" validate:"required" message:"密钥名称不能为空"`
+	Scopes     []string `form:"scopes" json:"scopes"`
+	AllowedIPs []string `form:"allowedIps" json:"allowedIps"`
+	ExpiresAt  JSONTime `form:"expiresAt" json:"expiresAt"`
+	Status     *int8    `form:"status" json:"status" validate:"in:0,1" message:"状态值必须为0或1"` // 为空时不修改
+}
+
+func (r *ApiKeyUpdateRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
+
+// ApiKeyDeleteRequest 删除API密钥请求结构
+type ApiKeyDeleteRequest struct {
+	Validator
+	ID uint `form:"id" json:"id" validate:"required" message:"密钥ID不能为空"`
+}
+
+func (r *ApiKeyDeleteRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"context"
+	"gin-fast/app/global/app"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SysUserIdentity 用户第三方身份绑定(OIDC等外部身份提供方)
+type SysUserIdentity struct {
+	ID          uint       `gorm:"primarykey;column:id" json:"id"`
+	UserID      uint       `gorm:"type:int(11);column:user_id;index;not null;comment:用户ID" json:"userId"`
+	Provider    string     `gorm:"column:provider;size:64;not null;uniqueIndex:idx_provider_subject;comment:身份提供方标识" json:"provider"`
+	Subject     string     `gorm:"column:subject;size:255;not null;uniqueIndex:idx_provider_subject;comment:提供方用户唯一标识(sub)" json:"subject"`
+	Email       string     `gorm:"column:email;size:100;comment:提供方返回的邮箱" json:"email"`
+	LastLoginAt *time.Time `gorm:"column:last_login_at;comment:最近登录时间" json:"lastLoginAt"`
+	CreatedAt   time.Time  `gorm:"column:created_at" json:"createdAt"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at" json:"updatedAt"`
+}
+
+// TableName 设置表名
+func (SysUserIdentity) TableName() string {
+	return "sys_user_identity"
+}
+
+func NewSysUserIdentity() *SysUserIdentity {
+	return &SysUserIdentity{}
+}
+
+// IsEmpty 检查记录是否为空
+func (m *SysUserIdentity) IsEmpty() bool {
+	return m == nil || m.ID == 0
+}
+
+// Find 查找第三方身份绑定
+func (m *SysUserIdentity) Find(c context.Context, funcs ...func(*gorm.DB) *gorm.DB) (err error) {
+	err = app.DB().WithContext(c).Scopes(funcs...).First(m).Error
+	if err == gorm.ErrRecordNotFound {
+		err = nil // 将记录未找到的错误转换为nil，通过IsEmpty()方法判断
+	}
+	return
+}
+
+// GetBySubject 根据提供方及用户唯一标识获取绑定
+func (m *SysUserIdentity) GetBySubject(c context.Context, provider, subject string) error {
+	return m.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("provider = ? AND subject = ?", provider, subject)
+	})
+}
+
+// GetByUserID 根据提供方及用户ID获取绑定
+func (m *SysUserIdentity) GetByUserID(c context.Context, provider string, userID uint) error {
+	return m.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("provider = ? AND user_id = ?", provider, userID)
+	})
+}
+
+// Save 保存(新增或更新)第三方身份绑定
+func (m *SysUserIdentity) Save(c context.Context) error {
+	return app.DB().WithContext(c).Save(m).Error
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// OidcAuthorizeRequest 获取OIDC授权地址请求结构
+type OidcAuthorizeRequest struct {
+	Validator
+	Provider   string `form:"provider" validate:"required" message:"身份提供方不能为空"`
+	TenantCode string `form:"tenantCode"`
+	DeviceName string `form:"deviceName"`
+}
+
+func (r *OidcAuthorizeRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
+
+// OidcCallbackRequest OIDC回调登录请求结构
+type OidcCallbackRequest struct {
+	Validator
+	State string `form:"state" validate:"required" message:"state不能为空"`
+	Code  string `form:"code" validate:"required" message:"授权码不能为空"`
+}
+
+func (r *OidcCallbackRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"gin-fast/app/global/app"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SysUserMfa 用户二次验证(TOTP)绑定信息
+type SysUserMfa struct {
+	UserID        uint       `gorm:"primaryKey;type:int(11);column:user_id;comment:用户ID" json:"userId"`
+	Secret        string     `gorm:"column:secret;size:64;not null;comment:TOTP密钥(Base32)" json:"-"`
+	Enabled       bool       `gorm:"type:tinyint(1);column:enabled;default:0;comment:是否已启用 0未启用 1已启用" json:"enabled"`
+	RecoveryCodes string     `gorm:"column:recovery_codes;type:text;comment:恢复码摘要(JSON数组)" json:"-"`
+	LastUsedStep  int64      `gorm:"column:last_used_step;default:0;comment:最近一次使用的时间步(防重放)" json:"-"`
+	ConfirmedAt   *time.Time `gorm:"column:confirmed_at;comment:绑定确认时间" json:"confirmedAt"`
+	CreatedAt     time.Time  `gorm:"column:created_at" json:"createdAt"`
+	UpdatedAt     time.Time  `gorm:"column:updated_at" json:"updatedAt"`
+}
+
+// TableName 设置表名
+func (SysUserMfa) TableName() string {
+	return "sys_user_mfa"
+}
+
+func NewSysUserMfa() *SysUserMfa {
+	return &SysUserMfa{}
+}
+
+// IsEmpty 检查记录是否为空
+func (m *SysUserMfa) IsEmpty() bool {
+	return m == nil || m.UserID == 0
+}
+
+// Find 查找用户二次验证信息
+func (m *SysUserMfa) Find(c context.Context, funcs ...func(*gorm.DB) *gorm.DB) (err error) {
+	err = app.DB().WithContext(c).Scopes(funcs...).First(m).Error
+	if err == gorm.ErrRecordNotFound {
+		err = nil // 将记录未找到的错误转换为nil，通过IsEmpty()方法判断
+	}
+	return
+}
+
+// GetByUserID 根据用户ID获取二次验证信息
+func (m *SysUserMfa) GetByUserID(c context.Context, userID uint) error {
+	return m.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_id = ?", userID)
+	})
+}
+
+// Save 保存(新增或更新)二次验证信息
+func (m *SysUserMfa) Save(c context.Context) error {
+	return app.DB().WithContext(c).Save(m).Error
+}
+
+// Delete 删除二次验证信息
+func (m *SysUserMfa) Delete(c context.Context) error {
+	return app.DB().WithContext(c).Where("user_id = ?", m.UserID).Delete(&SysUserMfa{}).Error
+}
+
+// GetRecoveryCodes 获取恢复码摘要列表
+func (m *SysUserMfa) GetRecoveryCodes() []string {
+	var codes []string
+	if m.RecoveryCodes == "" {
+		return codes
+	}
+	_ = json.Unmarshal([]byte(m.RecoveryCodes), &codes)
+	return codes
+}
+
+// SetRecoveryCodes 设置恢复码摘要列表
+func (m *SysUserMfa) SetRecoveryCodes(hashes []string) {
+	if len(hashes) == 0 {
+		m.RecoveryCodes = ""
+		return
+	}
+	data, _ := json.Marshal(hashes)
+	m.RecoveryCodes = string(data)
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// MfaLoginRequest 登录二次验证请求结构
+type MfaLoginRequest struct {
+	Validator
+	MfaToken string `form:"mfaToken" validate:"required" message:"二次验证令牌不能为空"`
+	Code     string `form:"code" validate:"required" message:"验证码不能为空"`
+}
+
+func (r *MfaLoginRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
+
+// MfaLoginEnrollRequest 登录时强制绑定二次验证请求结构
+type MfaLoginEnrollRequest struct {
+	Validator
+	MfaToken string `form:"mfaToken" validate:"required" message:"二次验证令牌不能为空"`
+}
+
+func (r *MfaLoginEnrollRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
+
+// MfaCodeRequest 二次验证码请求结构(确认绑定、关闭、重置恢复码)
+type MfaCodeRequest struct {
+	Validator
+	Code string `form:"code" validate:"required" message:"验证码不能为空"`
+}
+
+func (r *MfaCodeRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"context"
+	"gin-fast/app/global/app"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SysUserPasswordHistory 用户历史密码(用于禁止重复使用最近的密码)
+type SysUserPasswordHistory struct {
+	ID        uint      `gorm:"primarykey;column:id" json:"id"`
+	UserID    uint      `gorm:"type:int(11);column:user_id;index;not null;comment:用户ID" json:"userId"`
+	Password  string    `gorm:"column:password;size:255;not null;comment:密码摘要" json:"-"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"createdAt"`
+}
+
+// TableName 设置表名
+func (SysUserPasswordHistory) TableName() string {
+	return "sys_user_password_history"
+}
+
+// SysUserPasswordHistoryList 历史密码列表
+type SysUserPasswordHistoryList []*SysUserPasswordHistory
+
+func NewSysUserPasswordHistoryList() SysUserPasswordHistoryList {
+	return SysUserPasswordHistoryList{}
+}
+
+// Find 查找历史密码列表
+func (list *SysUserPasswordHistoryList) Find(c context.Context, funcs ...func(*gorm.DB) *gorm.DB) (err error) {
+	err = app.DB().WithContext(c).Scopes(funcs...).Find(list).Error
+	return
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"context"
 	"gin-fast/app/global/app"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -11,6 +12,11 @@ import (
 type SysUserRole struct {
 	UserID uint `gorm:"primaryKey;column:user_id;default:0;comment:用户ID" json:"userId"`
 	RoleID uint `gorm:"primaryKey;column:role_id;default:0;comment:角色ID" json:"roleId"`
+	// 限时授权的生效及失效时间，均为空表示长期有效
+	ValidFrom  *time.Time `gorm:"column:valid_from;comment:生效时间" json:"validFrom"`
+	ValidUntil *time.Time `gorm:"column:valid_until;comment:失效时间" json:"validUntil"`
+
+	Role *SysRole `gorm:"foreignKey:RoleID" json:"role,omitempty"`
 }
 
 // TableName 设置表名
@@ -22,6 +28,47 @@ func NewSysUserRole() *SysUserRole {
 	return &SysUserRole{}
 }
 
+func (m *SysUserRole) Find(c context.Context, funcs ...func(*gorm.DB) *gorm.DB) error {
+	err := app.DB().WithContext(c).Scopes(funcs...).First(m).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	return err
+}
+
+func (m *SysUserRole) IsEmpty() bool {
+	return m == nil || m.UserID == 0
+}
+
+// IsTimeBound 是否为限时授权
+func (m *SysUserRole) IsTimeBound() bool {
+	return m.ValidFrom != nil || m.ValidUntil != nil
+}
+
+// IsActive 指定时间授权是否生效
+func (m *SysUserRole) IsActive(now time.Time) bool {
+	if m.ValidFrom != nil && m.ValidFrom.After(now) {
+		return false
+	}
+	return m.ValidUntil == nil || m.ValidUntil.After(now)
+}
+
+// ActiveUserRoleScope 筛选指定时间生效的用户角色(排除未到生效时间及已过期的限时授权)
+func ActiveUserRoleScope(now time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(valid_from IS NULL OR valid_from <= ?) AND (valid_until IS NULL OR valid_until > ?)", now, now)
+	}
+}
+
+// PreloadActiveRoles 预加载用户当前生效的角色
+func PreloadActiveRoles(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		subQuery := db.Session(&gorm.Session{NewDB: true}).Model(&SysUserRole{}).Select("role_id").
+			Where("user_id = ?", userID).Scopes(ActiveUserRoleScope(time.Now()))
+		return db.Preload("Roles", "id IN (?)", subQuery)
+	}
+}
+
 type SysUserRoleList []*SysUserRole
 
 func NewSysUserRoleList() SysUserRoleList {
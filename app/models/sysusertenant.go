@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type SysUserTenant struct {
@@ -22,6 +23,14 @@ func (SysUserTenant) TableName() string {
 	return "sys_user_tenant"
 }
 
+// TenantCondition 租户隔离条件：本租户的关联及当前用户本人的关联(切换租户时需要获取用户关联的所有租户)
+func (SysUserTenant) TenantCondition(tenantID, userID uint) clause.Expression {
+	return clause.Expr{
+		SQL:  "(? = ? OR ? = ?)",
+		Vars: []interface{}{clause.Column{Table: clause.CurrentTable, Name: "tenant_id"}, tenantID, clause.Column{Table: clause.CurrentTable, Name: "user_id"}, userID},
+	}
+}
+
 // Find 查找用户租户关联记录
 func (sut *SysUserTenant) Find(c context.Context, funcs ...func(*gorm.DB) *gorm.DB) (err error) {
 	err = app.DB().WithContext(c).Scopes(funcs...).First(sut).Error
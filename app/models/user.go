@@ -3,8 +3,11 @@ package models
 import (
 	"context"
 	"gin-fast/app/global/app"
+	"gin-fast/app/utils/tenanthelper"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // User 用户模型
@@ -25,6 +28,8 @@ type User struct {
 	Department  SysDepartment `gorm:"foreignKey:dept_id;references:id" json:"department"`
 	TenantID    uint          `gorm:"type:int(11);column:tenant_id;comment:租户ID" json:"tenantID"`
 	Tenant      Tenant        `gorm:"foreignKey:tenant_id;references:id" json:"tenant"`
+
+	PasswordChangedAt *time.Time `gorm:"column:password_changed_at;comment:密码修改时间" json:"passwordChangedAt"`
 }
 
 // TableName 设置User表名
@@ -32,6 +37,15 @@ func (User) TableName() string {
 	return "sys_users"
 }
 
+// TenantCondition 租户隔离条件：本租户的用户、在 sys_user_tenant 中关联了本租户的用户及当前用户本人
+func (User) TenantCondition(tenantID, userID uint) clause.Expression {
+	id := clause.Column{Table: clause.CurrentTable, Name: "id"}
+	return clause.Expr{
+		SQL:  "(? = ? OR ? = ? OR ? IN (SELECT user_id FROM sys_user_tenant WHERE tenant_id = ?))",
+		Vars: []interface{}{clause.Column{Table: clause.CurrentTable, Name: "tenant_id"}, tenantID, id, userID, id, tenantID},
+	}
+}
+
 func NewUser() *User {
 	return &User{}
 }
@@ -50,22 +64,23 @@ func (u *User) GetUserByID(ctx context.Context, id uint) (err error) {
 	})
 }
 
+// GetUserByUsername 根据用户名获取用户，用户名全局唯一，不进行租户隔离
 func (u *User) GetUserByUsername(ctx context.Context, username string) (err error) {
-	return u.Find(ctx, func(db *gorm.DB) *gorm.DB {
+	return u.Find(ctx, tenanthelper.WithoutTenant(), func(db *gorm.DB) *gorm.DB {
 		return db.Where("username = ?", username)
 	})
 }
 
-// GetUserByPhone 根据手机号获取用户
+// GetUserByPhone 根据手机号获取用户，手机号全局唯一，不进行租户隔离
 func (u *User) GetUserByPhone(ctx context.Context, phone string) (err error) {
-	return u.Find(ctx, func(db *gorm.DB) *gorm.DB {
+	return u.Find(ctx, tenanthelper.WithoutTenant(), func(db *gorm.DB) *gorm.DB {
 		return db.Where("phone = ?", phone)
 	})
 }
 
-// GetUserByEmail 根据邮箱获取用户
+// GetUserByEmail 根据邮箱获取用户，邮箱全局唯一，不进行租户隔离
 func (u *User) GetUserByEmail(ctx context.Context, email string) (err error) {
-	return u.Find(ctx, func(db *gorm.DB) *gorm.DB {
+	return u.Find(ctx, tenanthelper.WithoutTenant(), func(db *gorm.DB) *gorm.DB {
 		return db.Where("email = ?", email)
 	})
 }
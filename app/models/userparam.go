@@ -1,6 +1,9 @@
 package models
 
 import (
+	"errors"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -52,12 +55,34 @@ type LoginRequest struct {
 	Username   string `form:"username" validate:"required" message:"用户名不能为空"`
 	Password   string `form:"password" validate:"required" message:"密码不能为空"`
 	TenantCode string `form:"tenantCode"`
+	DeviceName string `form:"deviceName"` // 设备名称，用于会话管理中区分登录设备
 }
 
 func (r *LoginRequest) Validate(c *gin.Context) error {
 	return r.Check(c, r)
 }
 
+// PasswordChangeLoginRequest 密码过期登录时修改密码请求结构
+type PasswordChangeLoginRequest struct {
+	Validator
+	PasswordToken string `form:"passwordToken" validate:"required" message:"修改密码令牌不能为空"`
+	Password      string `form:"password" validate:"required" message:"新密码不能为空"`
+}
+
+func (r *PasswordChangeLoginRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
+
+// SwitchTenantRequest 切换租户请求结构
+type SwitchTenantRequest struct {
+	Validator
+	TenantCode string `form:"tenantCode" validate:"required" message:"租户编码不能为空"`
+}
+
+func (r *SwitchTenantRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
+
 type CaptchaImgRequest struct {
 	Validator
 	CaptchaId string `form:"captchaId" validate:"required" message:"验证码ID不能为空"`
@@ -80,6 +105,24 @@ func (r *CaptchaImgRequest) Validate(c *gin.Context) error {
 	return nil
 }
 
+// CaptchaAudioRequest 获取验证码语音请求结构
+type CaptchaAudioRequest struct {
+	Validator
+	CaptchaId string `form:"captchaId" validate:"required" message:"验证码ID不能为空"`
+	Lang      string `form:"lang"`
+}
+
+func (r *CaptchaAudioRequest) Validate(c *gin.Context) error {
+	err := r.Check(c, r)
+	if err != nil {
+		return err
+	}
+	if r.Lang == "" {
+		r.Lang = "zh"
+	}
+	return nil
+}
+
 type UserListRequest struct {
 	BasePaging
 	Validator
@@ -151,7 +194,46 @@ func (r *DeleteRequest) Validate(c *gin.Context) error {
 // 用户权限信息
 type UserProfile struct {
 	User
-	Permissions []string `json:"permissions"`
+	Permissions []string        `json:"permissions"`
+	RoleGrants  SysUserRoleList `json:"roleGrants"` // 未过期的限时角色授权，按失效时间排序
+}
+
+// UserRoleGrantRequest 限时角色授权请求结构
+type UserRoleGrantRequest struct {
+	Validator
+	UserID     uint     `form:"userId" json:"userId" validate:"required" message:"用户ID不能为空"`
+	RoleID     uint     `form:"roleId" json:"roleId" validate:"required" message:"角色ID不能为空"`
+	ValidFrom  JSONTime `form:"validFrom" json:"validFrom"`   // 生效时间，为空表示立即生效
+	ValidUntil JSONTime `form:"validUntil" json:"validUntil"` // 失效时间，为空表示长期有效
+}
+
+func (r *UserRoleGrantRequest) Validate(c *gin.Context) error {
+	if err := r.Check(c, r); err != nil {
+		return err
+	}
+	if r.ValidFrom.IsZero() && r.ValidUntil.IsZero() {
+		return errors.New("生效时间和失效时间不能同时为空")
+	}
+	if !r.ValidUntil.IsZero() {
+		if !r.ValidUntil.After(time.Now()) {
+			return errors.New("失效时间必须晚于当前时间")
+		}
+		if !r.ValidFrom.IsZero() && !r.ValidUntil.After(r.ValidFrom.Time) {
+			return errors.New("失效时间必须晚于生效时间")
+		}
+	}
+	return nil
+}
+
+// UserRoleRevokeRequest 撤销限时角色授权请求结构
+type UserRoleRevokeRequest struct {
+	Validator
+	UserID uint `form:"userId" json:"userId" validate:"required" message:"用户ID不能为空"`
+	RoleID uint `form:"roleId" json:"roleId" validate:"required" message:"角色ID不能为空"`
+}
+
+func (r *UserRoleRevokeRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
 }
 
 // UpdateAccountRequest 更新用户账户信息请求结构
@@ -178,3 +260,36 @@ type UpdateBasicInfoRequest struct {
 func (r *UpdateBasicInfoRequest) Validate(c *gin.Context) error {
 	return r.Check(c, r)
 }
+
+// ForgotPasswordRequest 申请重置密码请求结构
+type ForgotPasswordRequest struct {
+	Validator
+	Email string `form:"email" validate:"required|email" message:"required:邮箱不能为空|email:邮箱格式不正确"`
+}
+
+func (r *ForgotPasswordRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
+
+// ResetPasswordRequest 重置密码请求结构
+type ResetPasswordRequest struct {
+	Validator
+	Token    string `form:"token" validate:"required" message:"重置令牌不能为空"`
+	Password string `form:"password" validate:"required" message:"新密码不能为空"`
+}
+
+func (r *ResetPasswordRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
+
+// ImpersonateRequest 模拟登录请求结构
+type ImpersonateRequest struct {
+	Validator
+	UserID     uint   `form:"userId" validate:"required" message:"目标用户ID不能为空"`
+	TenantCode string `form:"tenantCode"`
+	Reason     string `form:"reason" validate:"required" message:"模拟登录原因不能为空"`
+}
+
+func (r *ImpersonateRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
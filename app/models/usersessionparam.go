@@ -0,0 +1,15 @@
+package models
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// SessionRevokeRequest 撤销会话请求结构
+type SessionRevokeRequest struct {
+	Validator
+	SessionID string `form:"sessionId" validate:"required" message:"会话ID不能为空"`
+}
+
+func (r *SessionRevokeRequest) Validate(c *gin.Context) error {
+	return r.Check(c, r)
+}
@@ -13,22 +13,27 @@ import (
 	"gin-fast/app/middleware"
 )
 
-var userControllers = controllers.NewUserController()                       // 用户控制器
-var authControllers = controllers.NewAuthController()                       // 认证控制器
-var sysMenuControllers = controllers.NewSysMenuController()                 // 菜单控制器
-var sysDepartmentControllers = controllers.NewSysDepartmentController()     // 部门控制器
-var sysRoleControllers = controllers.NewSysRoleController()                 // 角色控制器
-var sysDictControllers = controllers.NewSysDictController()                 // 字典控制器
-var sysDictItemControllers = controllers.NewSysDictItemController()         // 字典项控制器
-var sysApiControllers = controllers.NewSysApiController()                   // API控制器
-var sysAffixControllers = controllers.NewSysAffixController()               // 文件管理
-var configControllers = controllers.NewConfigController()                   // 配置控制器
-var sysOperationLogControllers = controllers.NewSysOperationLogController() // 操作日志控制器
-var sysTenantControllers = controllers.NewTenantController()                // 租户控制器
-var sysUserTenantControllers = controllers.NewSysUserTenantController()     // 用户租户关联控制器
-var codeGenControllers = controllers.NewCodeGenController()                 // 代码生成控制器
-var sysGenControllers = controllers.NewSysGenController()                   // 代码生成配置控制器
-var pluginsManagerControllers = controllers.NewPluginsManagerController()   // 插件管理控制器
+var userControllers = controllers.NewUserController()                                       // 用户控制器
+var authControllers = controllers.NewAuthController()                                       // 认证控制器
+var userMfaControllers = controllers.NewUserMfaController()                                 // 用户二次验证控制器
+var userSessionControllers = controllers.NewUserSessionController()                         // 用户会话控制器
+var userApiKeyControllers = controllers.NewUserApiKeyController()                           // 用户API密钥控制器
+var sysMenuControllers = controllers.NewSysMenuController()                                 // 菜单控制器
+var sysDepartmentControllers = controllers.NewSysDepartmentController()                     // 部门控制器
+var sysRoleControllers = controllers.NewSysRoleController()                                 // 角色控制器
+var sysDictControllers = controllers.NewSysDictController()                                 // 字典控制器
+var sysDictItemControllers = controllers.NewSysDictItemController()                         // 字典项控制器
+var sysApiControllers = controllers.NewSysApiController()                                   // API控制器
+var sysAffixControllers = controllers.NewSysAffixController()                               // 文件管理
+var configControllers = controllers.NewConfigController()                                   // 配置控制器
+var sysOperationLogControllers = controllers.NewSysOperationLogController()                 // 操作日志控制器
+var sysTenantControllers = controllers.NewTenantController()                                // 租户控制器
+var sysUserTenantControllers = controllers.NewSysUserTenantController()                     // 用户租户关联控制器
+var sysTenantLdapControllers = controllers.NewSysTenantLdapController()                     // 租户目录服务配置控制器
+var sysTenantPasswordPolicyControllers = controllers.NewSysTenantPasswordPolicyController() // 租户密码策略控制器
+var codeGenControllers = controllers.NewCodeGenController()                                 // 代码生成控制器
+var sysGenControllers = controllers.NewSysGenController()                                   // 代码生成配置控制器
+var pluginsManagerControllers = controllers.NewPluginsManagerController()                   // 插件管理控制器
 
 // InitRoutes 初始化路由
 func InitRoutes(engine *gin.Engine) {
@@ -67,23 +72,54 @@ func InitRoutes(engine *gin.Engine) {
 		engine.Use(middleware.TimeoutMiddleware(time.Duration(handlerTimeout) * time.Second))
 	}
 
+	// 公开token验证公钥(JWKS)，供其他服务验证token
+	engine.GET("/.well-known/jwks.json", authControllers.Jwks)
+
 	api := engine.Group("/api")
+	// 按请求域名识别租户
+	api.Use(middleware.TenantHostMiddleware())
+	// 按IP限流
+	api.Use(middleware.RateLimitMiddleware())
+	// 请求结束(事务已提交)后清除事务中变更的数据权限缓存
+	api.Use(middleware.DataScopeMiddleware())
 	{
 		// 公开路由
 		public := api.Group("")
 		public.POST("/login", middleware.CaptchaMiddleware(), authControllers.Login)
+		// 登录二次验证
+		public.POST("/login/mfa", authControllers.LoginMfa)
+		// 登录时强制绑定二次验证
+		public.POST("/login/mfa/enroll", authControllers.LoginMfaEnroll)
+		// 密码过期时修改密码并继续登录
+		public.POST("/login/password", authControllers.LoginPassword)
 		public.POST("/refreshToken", authControllers.RefreshToken)
+		// 切换租户(仅需登录，不校验接口权限)
+		public.POST("/switchTenant", middleware.JWTAuthMiddleware(), authControllers.SwitchTenant)
+		// 第三方登录(OIDC)身份提供方列表
+		public.GET("/oidc/providers", authControllers.OidcProviders)
+		// 获取第三方登录授权地址
+		public.GET("/oidc/authorize", authControllers.OidcAuthorize)
+		// 第三方登录回调
+		public.POST("/oidc/callback", authControllers.OidcCallback)
+		// 申请重置密码(发送重置邮件)
+		public.POST("/password/forgot", middleware.CaptchaMiddleware(), authControllers.ForgotPassword)
+		// 使用重置令牌设置新密码
+		public.POST("/password/reset", authControllers.ResetPassword)
 		// 生成验证码ID
 		public.GET("/captcha/id", authControllers.GetCaptchaId)
 		// 获取验证码图片
 		public.GET("/captcha/image", authControllers.GetCaptchaImg)
+		// 获取验证码语音
+		public.GET("/captcha/audio", authControllers.GetCaptchaAudio)
 		// 获取配置信息
 		public.GET("/config/get", configControllers.GetConfig)
 
 		// 受保护的路由
 		protected := api.Group("")
 		protected.Use(middleware.JWTAuthMiddleware())
-		protected.Use(middleware.DemoAccountMiddleware()) // 添加演示账号中间件
+		protected.Use(middleware.AuthRateLimitMiddleware()) // 按用户及租户限流
+		protected.Use(middleware.TenantQuotaMiddleware())   // 租户每日API调用次数配额
+		protected.Use(middleware.DemoAccountMiddleware())   // 添加演示账号中间件
 		protected.Use(middleware.CasbinMiddleware())
 		{
 			// 用户管理路由组
@@ -97,19 +133,49 @@ func InitRoutes(engine *gin.Engine) {
 				// 根据ID获取用户信息
 				users.GET("/:id", userControllers.GetUserByID)
 				// 新增用户
-				users.POST("/add", middleware.PasswordValidatorMiddleware(), userControllers.Add)
+				users.POST("/add", middleware.ImpersonationForbiddenMiddleware(), middleware.PasswordValidatorMiddleware(), userControllers.Add)
 				// 更新用户信息
-				users.PUT("/edit", middleware.PasswordValidatorMiddleware(), userControllers.Update)
+				users.PUT("/edit", middleware.ImpersonationForbiddenMiddleware(), middleware.PasswordValidatorMiddleware(), userControllers.Update)
 				// 删除用户
 				users.DELETE("/delete", userControllers.Delete)
+				// 限时角色授权
+				users.POST("/roleGrant", middleware.ImpersonationForbiddenMiddleware(), userControllers.GrantRole)
+				// 撤销限时角色授权
+				users.POST("/roleGrant/revoke", middleware.ImpersonationForbiddenMiddleware(), userControllers.RevokeRole)
 				// 用户登出
 				users.POST("/logout", authControllers.Logout)
 				// 更新当前登录用户密码、邮箱及手机号
-				users.PUT("/updateAccount", middleware.PasswordValidatorMiddleware(), userControllers.UpdateAccount)
+				users.PUT("/updateAccount", middleware.ApiKeyForbiddenMiddleware(), middleware.ImpersonationForbiddenMiddleware(), middleware.PasswordValidatorMiddleware(), userControllers.UpdateAccount)
 				// 上传用户头像
 				users.POST("/uploadAvatar", userControllers.UploadAvatar)
 				// 更新当前登录用户基本信息
 				users.PUT("/updateBasicInfo", userControllers.UpdateBasicInfo)
+				// 获取当前登录用户二次验证状态
+				users.GET("/mfa/status", userMfaControllers.Status)
+				// 绑定二次验证(生成密钥及恢复码)
+				users.POST("/mfa/enroll", middleware.ApiKeyForbiddenMiddleware(), middleware.ImpersonationForbiddenMiddleware(), userMfaControllers.Enroll)
+				// 确认绑定二次验证
+				users.POST("/mfa/confirm", middleware.ApiKeyForbiddenMiddleware(), middleware.ImpersonationForbiddenMiddleware(), userMfaControllers.Confirm)
+				// 关闭二次验证
+				users.POST("/mfa/disable", middleware.ApiKeyForbiddenMiddleware(), middleware.ImpersonationForbiddenMiddleware(), userMfaControllers.Disable)
+				// 重新生成恢复码
+				users.POST("/mfa/recoveryCodes", middleware.ApiKeyForbiddenMiddleware(), middleware.ImpersonationForbiddenMiddleware(), userMfaControllers.RecoveryCodes)
+				// 获取当前登录用户的会话(设备)列表
+				users.GET("/sessions", userSessionControllers.List)
+				// 撤销指定会话
+				users.DELETE("/sessions/revoke", middleware.ApiKeyForbiddenMiddleware(), middleware.ImpersonationForbiddenMiddleware(), userSessionControllers.Revoke)
+				// 退出所有设备
+				users.POST("/sessions/revokeAll", middleware.ApiKeyForbiddenMiddleware(), middleware.ImpersonationForbiddenMiddleware(), userSessionControllers.RevokeAll)
+				// 获取当前登录用户的API密钥列表
+				users.GET("/apiKeys", userApiKeyControllers.List)
+				// 创建API密钥
+				users.POST("/apiKeys/add", middleware.ApiKeyForbiddenMiddleware(), middleware.ImpersonationForbiddenMiddleware(), userApiKeyControllers.Add)
+				// 更新API密钥
+				users.PUT("/apiKeys/edit", middleware.ApiKeyForbiddenMiddleware(), middleware.ImpersonationForbiddenMiddleware(), userApiKeyControllers.Update)
+				// 删除API密钥
+				users.DELETE("/apiKeys/delete", middleware.ApiKeyForbiddenMiddleware(), middleware.ImpersonationForbiddenMiddleware(), userApiKeyControllers.Delete)
+				// 模拟登录(以指定用户身份登录，用于排查问题)
+				users.POST("/impersonate", middleware.ImpersonationForbiddenMiddleware(), authControllers.Impersonate)
 			}
 
 			// 系统菜单路由组
@@ -175,6 +241,16 @@ func InitRoutes(engine *gin.Engine) {
 				sysRole.DELETE("/delete", sysRoleControllers.Delete)
 				// 更新角色数据权限
 				sysRole.PUT("/dataScope", sysRoleControllers.UpdateDataScope)
+				// 权限判定解释(排查无权限访问的原因)
+				sysRole.GET("/explain", sysRoleControllers.Explain)
+				// 获取角色字段权限
+				sysRole.GET("/fields/:roleId", sysRoleControllers.GetFields)
+				// 设置角色字段权限(隐藏、脱敏、只读)
+				sysRole.POST("/fields", sysRoleControllers.UpdateFields)
+				// 导出权限配置(角色、菜单、API及策略)
+				sysRole.GET("/export", sysRoleControllers.Export)
+				// 导入权限配置，支持预览变更
+				sysRole.POST("/import", sysRoleControllers.Import)
 
 			}
 
@@ -229,6 +305,10 @@ func InitRoutes(engine *gin.Engine) {
 				sysApi.PUT("/edit", sysApiControllers.Update)
 				// 删除API
 				sysApi.DELETE("/delete", sysApiControllers.Delete)
+				// 预览路由表与API表的差异
+				sysApi.GET("/syncPreview", sysApiControllers.SyncPreview)
+				// 按路由表同步API
+				sysApi.POST("/sync", sysApiControllers.Sync)
 			}
 
 			// 系统文件附件路由组
@@ -273,6 +353,10 @@ func InitRoutes(engine *gin.Engine) {
 			{
 				// 租户列表
 				sysTenant.GET("/list", sysTenantControllers.List)
+				// 租户初始化模板列表
+				sysTenant.GET("/templates", sysTenantControllers.Templates)
+				// 租户资源配额及用量
+				sysTenant.GET("/usage", sysTenantControllers.Usage)
 				// 根据ID获取租户信息
 				sysTenant.GET("/:id", sysTenantControllers.GetByID)
 				// 新增租户
@@ -283,6 +367,28 @@ func InitRoutes(engine *gin.Engine) {
 				sysTenant.DELETE("/:id", sysTenantControllers.Delete)
 			}
 
+			// 租户目录服务(LDAP)配置路由组
+			sysTenantLdap := protected.Group("/sysTenantLdap")
+			{
+				// 获取当前租户目录服务配置
+				sysTenantLdap.GET("/get", sysTenantLdapControllers.Get)
+				// 保存当前租户目录服务配置
+				sysTenantLdap.PUT("/save", sysTenantLdapControllers.Save)
+				// 测试目录服务连接
+				sysTenantLdap.POST("/test", sysTenantLdapControllers.Test)
+			}
+
+			// 租户密码策略路由组
+			sysTenantPasswordPolicy := protected.Group("/sysTenantPasswordPolicy")
+			{
+				// 获取当前租户密码策略
+				sysTenantPasswordPolicy.GET("/get", sysTenantPasswordPolicyControllers.Get)
+				// 保存当前租户密码策略
+				sysTenantPasswordPolicy.PUT("/save", sysTenantPasswordPolicyControllers.Save)
+				// 删除当前租户密码策略(恢复使用全局策略)
+				sysTenantPasswordPolicy.DELETE("/delete", sysTenantPasswordPolicyControllers.Delete)
+			}
+
 			// 用户租户关联管理路由组
 			sysUserTenant := protected.Group("/sysUserTenant")
 			{
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin-fast/app/global/app"
+	"gin-fast/app/models"
+	"gin-fast/app/utils/apikeyhelper"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// ErrApiKeyScopeDenied 请求不在API密钥的授权范围内
+var ErrApiKeyScopeDenied = errors.New("API密钥无权访问该接口")
+
+// apiKeyCacheEntry 已校验的API密钥信息(缓存，避免每次请求查询数据库)
+type apiKeyCacheEntry struct {
+	ID         uint       `json:"id"`
+	UserID     uint       `json:"userId"`
+	Username   string     `json:"username"`
+	TenantID   uint       `json:"tenantId"`
+	TenantCode string     `json:"tenantCode"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIPs []string   `json:"allowedIps"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+}
+
+type ApiKeyService struct {
+}
+
+// NewApiKeyService 创建API密钥服务
+func NewApiKeyService() *ApiKeyService {
+	return &ApiKeyService{}
+}
+
+// IsOpen 是否开启API密钥访问
+func (s *ApiKeyService) IsOpen() bool {
+	return app.ConfigYml.GetBool("apikey.open")
+}
+
+// List 获取用户的API密钥列表
+func (s *ApiKeyService) List(c context.Context, userID uint) (models.SysUserApiKeyList, error) {
+	list := models.NewSysUserApiKeyList()
+	err := list.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_id = ?", userID).Order("id DESC")
+	})
+	return list, err
+}
+
+// Create 为当前登录用户在当前租户下创建API密钥，返回明文密钥(仅返回一次)
+func (s *ApiKeyService) Create(c context.Context, claims *app.Claims, req *models.ApiKeyAddRequest) (string, *models.SysUserApiKey, error) {
+	if err := s.check(req.Name, req.Scopes, req.AllowedIPs, req.ExpiresAt); err != nil {
+		return "", nil, err
+	}
+	if maxKeys := app.ConfigYml.GetInt("apikey.maxperuser"); maxKeys > 0 {
+		var count int64
+		err := app.DB().WithContext(c).Model(&models.SysUserApiKey{}).Where("user_id = ?", claims.UserID).Count(&count).Error
+		if err != nil {
+			return "", nil, err
+		}
+		if count >= int64(maxKeys) {
+			return "", nil, fmt.Errorf("每个用户最多创建%d个API密钥", maxKeys)
+		}
+	}
+
+	key, prefix, hash, err := apikeyhelper.Generate()
+	if err != nil {
+		return "", nil, err
+	}
+	apiKey := models.NewSysUserApiKey()
+	apiKey.UserID = claims.UserID
+	apiKey.TenantID = claims.TenantID
+	apiKey.TenantCode = claims.TenantCode
+	apiKey.Name = req.Name
+	apiKey.Prefix = prefix
+	apiKey.KeyHash = hash
+	apiKey.Scopes = req.Scopes
+	apiKey.AllowedIPs = req.AllowedIPs
+	apiKey.Status = 1
+	apiKey.ExpiresAt = s.expiresAt(req.ExpiresAt)
+	if err = apiKey.Create(c); err != nil {
+		return "", nil, err
+	}
+	return key, apiKey, nil
+}
+
+// Update 更新用户的API密钥(名称、授权范围、IP白名单、过期时间及状态)
+func (s *ApiKeyService) Update(c context.Context, userID uint, req *models.ApiKeyUpdateRequest) error {
+	if err := s.check(req.Name, req.Scopes, req.AllowedIPs, req.ExpiresAt); err != nil {
+		return err
+	}
+	apiKey, err := s.get(c, userID, req.ID)
+	if err != nil {
+		return err
+	}
+	apiKey.Name = req.Name
+	apiKey.Scopes = req.Scopes
+	apiKey.AllowedIPs = req.AllowedIPs
+	apiKey.ExpiresAt = s.expiresAt(req.ExpiresAt)
+	if req.Status != nil {
+		apiKey.Status = *req.Status
+	}
+	if err = apiKey.Save(c); err != nil {
+		return err
+	}
+	app.Cache.Del(context.Background(), s.cacheKey(apiKey.KeyHash))
+	return nil
+}
+
+// Delete 删除(撤销)用户的API密钥，立即失效
+func (s *ApiKeyService) Delete(c context.Context, userID uint, id uint) error {
+	apiKey, err := s.get(c, userID, id)
+	if err != nil {
+		return err
+	}
+	if err = apiKey.Delete(c); err != nil {
+		return err
+	}
+	app.Cache.Del(context.Background(), s.cacheKey(apiKey.KeyHash))
+	return nil
+}
+
+// Authenticate 校验API密钥并返回对应用户及租户的声明，供认证中间件使用
+// 密钥信息短暂缓存，停用用户或租户后最长在缓存过期后生效；删除或修改密钥立即生效
+func (s *ApiKeyService) Authenticate(c context.Context, key, ip, method, path string) (*app.Claims, error) {
+	if !s.IsOpen() {
+		return nil, errors.New("未开启API密钥访问")
+	}
+	if !apikeyhelper.IsKey(key) {
+		return nil, apikeyhelper.ErrInvalidKey
+	}
+	hash := apikeyhelper.Hash(key)
+
+	entry := &apiKeyCacheEntry{}
+	data, err := app.Cache.Get(context.Background(), s.cacheKey(hash))
+	if err != nil || data == "" || json.Unmarshal([]byte(data), entry) != nil {
+		if entry, err = s.load(c, hash); err != nil {
+			return nil, err
+		}
+		if data, err := json.Marshal(entry); err == nil {
+			app.Cache.Set(context.Background(), s.cacheKey(hash), string(data), s.cacheExpire())
+		}
+	}
+
+	if entry.ExpiresAt != nil && !entry.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("API密钥已过期")
+	}
+	if !apikeyhelper.MatchIP(entry.AllowedIPs, ip) {
+		return nil, errors.New("当前IP不允许使用该API密钥")
+	}
+	if !apikeyhelper.MatchScope(entry.Scopes, method, path) {
+		return nil, ErrApiKeyScopeDenied
+	}
+	s.touch(c, entry.ID, ip)
+
+	return &app.Claims{
+		ClaimsUser: app.ClaimsUser{
+			UserID:     entry.UserID,
+			Username:   entry.Username,
+			TenantID:   entry.TenantID,
+			TenantCode: entry.TenantCode,
+			ApiKeyID:   entry.ID,
+		},
+	}, nil
+}
+
+// load 从数据库加载API密钥，并校验用户、租户状态及用户与租户的关联关系
+func (s *ApiKeyService) load(c context.Context, hash string) (*apiKeyCacheEntry, error) {
+	apiKey := models.NewSysUserApiKey()
+	if err := apiKey.GetByHash(c, hash); err != nil {
+		return nil, err
+	}
+	if apiKey.IsEmpty() || apiKey.Status != 1 {
+		return nil, apikeyhelper.ErrInvalidKey
+	}
+
+	user := models.NewUser()
+	if err := user.GetUserByID(c, apiKey.UserID); err != nil {
+		return nil, err
+	}
+	if user.IsEmpty() || user.Status != 1 {
+		return nil, errors.New("API密钥所属用户不存在或未启用")
+	}
+
+	if apiKey.TenantID > 0 {
+		tenant := models.NewTenant()
+		err := tenant.Find(c, func(db *gorm.DB) *gorm.DB {
+			return db.Where("id = ?", apiKey.TenantID)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if tenant.IsEmpty() || tenant.Status != 1 {
+			return nil, errors.New("API密钥所属租户不存在或未启用")
+		}
+		// 非全局用户需仍关联该租户
+		if user.TenantID > 0 {
+			userTenant := &models.SysUserTenant{}
+			err = userTenant.Find(c, func(db *gorm.DB) *gorm.DB {
+				return db.Where("user_id = ? AND tenant_id = ?", user.ID, apiKey.TenantID)
+			})
+			if err != nil {
+				return nil, err
+			}
+			if userTenant.IsEmpty() {
+				return nil, errors.New("API密钥所属用户已不在该租户中")
+			}
+		}
+	}
+
+	return &apiKeyCacheEntry{
+		ID:         apiKey.ID,
+		UserID:     user.ID,
+		Username:   user.Username,
+		TenantID:   apiKey.TenantID,
+		TenantCode: apiKey.TenantCode,
+		Scopes:     apiKey.Scopes,
+		AllowedIPs: apiKey.AllowedIPs,
+		ExpiresAt:  apiKey.ExpiresAt,
+	}, nil
+}
+
+// touch 记录最近使用时间及IP，每分钟最多写入一次
+func (s *ApiKeyService) touch(c context.Context, id uint, ip string) {
+	usedKey := "api_key_used:" + strconv.FormatUint(uint64(id), 10)
+	if exists, err := app.Cache.Exists(context.Background(), usedKey); err != nil || exists > 0 {
+		return
+	}
+	app.Cache.Set(context.Background(), usedKey, "1", time.Minute)
+	err := app.DB().WithContext(c).Model(&models.SysUserApiKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_used_at": time.Now(),
+		"last_used_ip": ip,
+	}).Error
+	if err != nil {
+		app.ZapLog.Warn("更新API密钥使用时间失败: " + err.Error())
+	}
+}
+
+// get 获取用户自己的API密钥
+func (s *ApiKeyService) get(c context.Context, userID uint, id uint) (*models.SysUserApiKey, error) {
+	apiKey := models.NewSysUserApiKey()
+	err := apiKey.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("id = ? AND user_id = ?", id, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if apiKey.IsEmpty() {
+		return nil, errors.New("API密钥不存在")
+	}
+	return apiKey, nil
+}
+
+// check 校验名称、授权范围、IP白名单及过期时间
+func (s *ApiKeyService) check(name string, scopes, allowedIPs []string, expiresAt models.JSONTime) error {
+	if utf8.RuneCountInString(name) > 100 {
+		return errors.New("密钥名称不能超过100个字符")
+	}
+	for _, scope := range scopes {
+		if _, _, err := apikeyhelper.ParseScope(scope); err != nil {
+			return err
+		}
+	}
+	for _, ip := range allowedIPs {
+		if err := apikeyhelper.ParseIP(ip); err != nil {
+			return err
+		}
+	}
+	if !expiresAt.IsZero() && !expiresAt.After(time.Now()) {
+		return errors.New("过期时间必须晚于当前时间")
+	}
+	return nil
+}
+
+func (s *ApiKeyService) expiresAt(t models.JSONTime) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	expiresAt := t.ToTime()
+	return &expiresAt
+}
+
+func (s *ApiKeyService) cacheKey(hash string) string {
+	return "api_key:" + hash
+}
+
+func (s *ApiKeyService) cacheExpire() time.Duration {
+	expire := app.ConfigYml.GetInt("apikey.cacheexpire")
+	if expire <= 0 {
+		expire = 60
+	}
+	return time.Duration(expire) * time.Second
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"gin-fast/app/global/app"
+	"gin-fast/app/models"
+	"gin-fast/app/utils/oidchelper"
+	"gin-fast/app/utils/passwordhelper"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PasswordAuthenticatorName 本地密码认证方式名称
+const PasswordAuthenticatorName = "password"
+
+// ErrInvalidCredentials 用户名或密码错误，登录时据此累计失败次数
+var ErrInvalidCredentials = errors.New("用户名或密码错误")
+
+// Authenticator 用户名密码登录的认证方式
+type Authenticator interface {
+	// Name 认证方式标识
+	Name() string
+	// Supports 是否由该认证方式处理本次登录
+	// user 为按用户名查询到的本地用户(可能为空)，tenant 为登录的目标租户(可能为空)
+	Supports(c context.Context, user *models.User, tenant *models.Tenant) (bool, error)
+	// Authenticate 校验密码，返回认证通过的本地用户，用户名或密码错误时返回 ErrInvalidCredentials
+	Authenticate(c context.Context, user *models.User, tenant *models.Tenant, username, password string) (*models.User, error)
+}
+
+type AuthService struct {
+	Authenticators []Authenticator // 按顺序匹配，第一个支持本次登录的认证方式生效
+}
+
+// NewAuthService 创建登录认证服务
+func NewAuthService() *AuthService {
+	return &AuthService{
+		Authenticators: []Authenticator{
+			NewLdapAuthenticator(),
+			NewPasswordAuthenticator(),
+		},
+	}
+}
+
+// Authenticator 选择本次登录使用的认证方式，没有可用的认证方式时返回nil
+func (s *AuthService) Authenticator(c context.Context, user *models.User, tenant *models.Tenant) (Authenticator, error) {
+	for _, authenticator := range s.Authenticators {
+		ok, err := authenticator.Supports(c, user, tenant)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return authenticator, nil
+		}
+	}
+	return nil, nil
+}
+
+// LoginTenant 获取登录的目标租户：指定租户编码时按编码查询，否则为用户默认租户
+// 租户的启用状态及用户关联关系由登录流程统一校验，user 需预加载 Tenant
+func (s *AuthService) LoginTenant(c context.Context, user *models.User, tenantCode string) (*models.Tenant, error) {
+	tenant := models.NewTenant()
+	if tenantCode != "" {
+		err := tenant.Find(c, func(d *gorm.DB) *gorm.DB {
+			return d.Where("code = ?", tenantCode)
+		})
+		return tenant, err
+	}
+	if !user.IsEmpty() {
+		return &user.Tenant, nil
+	}
+	return tenant, nil
+}
+
+// PasswordAuthenticator 本地密码(bcrypt)认证
+type PasswordAuthenticator struct{}
+
+// NewPasswordAuthenticator 创建本地密码认证
+func NewPasswordAuthenticator() *PasswordAuthenticator {
+	return &PasswordAuthenticator{}
+}
+
+// Name 认证方式标识
+func (a *PasswordAuthenticator) Name() string {
+	return PasswordAuthenticatorName
+}
+
+// Supports 本地已存在的用户均可使用本地密码认证
+func (a *PasswordAuthenticator) Supports(c context.Context, user *models.User, tenant *models.Tenant) (bool, error) {
+	return !user.IsEmpty(), nil
+}
+
+// Authenticate 校验本地密码
+func (a *PasswordAuthenticator) Authenticate(c context.Context, user *models.User, tenant *models.Tenant, username, password string) (*models.User, error) {
+	if err := passwordhelper.ComparePassword(user.Password, password); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// createExternalUser 创建外部身份(第三方登录、目录服务)登录的用户
+// 用户不使用本地密码，设置随机密码；同时写入用户租户关联及角色
+func createExternalUser(c context.Context, permissionService *PermissionService, user *models.User, tenantID uint, roleIDs []uint) error {
+	randomPassword, err := oidchelper.RandomString(32)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := passwordhelper.HashPassword(randomPassword)
+	if err != nil {
+		return err
+	}
+
+	err = app.DB().WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := NewTenantQuotaService().CheckUsers(tx, tenantID, 1); err != nil {
+			return err
+		}
+		user.Password = hashedPassword
+		user.Status = 1
+		user.TenantID = tenantID
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		// 创建用户角色关联
+		if len(roleIDs) > 0 {
+			userRoles := make([]models.SysUserRole, len(roleIDs))
+			for i, roleID := range roleIDs {
+				userRoles[i] = models.SysUserRole{
+					UserID: user.ID,
+					RoleID: roleID,
+				}
+			}
+			if err := tx.Create(&userRoles).Error; err != nil {
+				return err
+			}
+		}
+
+		// 写入用户租户关联表
+		if tenantID > 0 {
+			err := tx.Create(&models.SysUserTenant{
+				UserID:    user.ID,
+				TenantID:  tenantID,
+				IsDefault: true,
+				CreatedAt: time.Now(),
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(roleIDs) > 0 {
+		var tenantIDs []uint
+		if tenantID > 0 {
+			tenantIDs = append(tenantIDs, tenantID)
+		}
+		if err = permissionService.AddRoleForUser(c, user.ID, roleIDs, tenantIDs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
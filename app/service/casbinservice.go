@@ -2,10 +2,17 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"gin-fast/app/global/app"
+	"gin-fast/app/global/consts"
 	"gin-fast/app/models"
+	"gin-fast/app/utils/casbinhelper"
 	"gin-fast/app/utils/common"
+	"gin-fast/app/utils/datascope"
+	"slices"
 
+	"github.com/casbin/casbin/v2/util"
+	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
@@ -42,8 +49,9 @@ func (ps *PermissionService) HandleTenantID(c context.Context, tenantID ...uint)
 func (ps *PermissionService) DeleteRoleApis(c context.Context, roleID uint, tenantID ...uint) (err error) {
 	domain := ps.HandleTenantID(c, tenantID...)
 
-	// 删除该角色的所有权限
+	// 删除该角色的所有权限及权限上的条件
 	app.CasbinV2.RemoveAllPoliciesForRole(roleID, domain...)
+	err = app.CasbinV2.SetConditionsForRole(roleID, nil, domain...)
 	return
 }
 
@@ -85,6 +93,85 @@ func (ps *PermissionService) AddPoliciesForRole(c context.Context, roleID uint,
 	return
 }
 
+// CheckRoleApiConditions 检查角色API权限的ABAC条件，API需属于角色分配的菜单
+func (ps *PermissionService) CheckRoleApiConditions(sysapilist models.SysApiList, conditions []models.SysRoleApiCondition) error {
+	apis := make(map[uint]bool, len(sysapilist))
+	for _, api := range sysapilist {
+		apis[api.ID] = true
+	}
+	for _, item := range conditions {
+		if !apis[item.ApiID] {
+			return fmt.Errorf("API ID %d 不属于分配的菜单", item.ApiID)
+		}
+		if err := casbinhelper.ValidateCondition(item.Condition); err != nil {
+			return fmt.Errorf("API ID %d 的条件无效: %v", item.ApiID, err)
+		}
+	}
+	return nil
+}
+
+// GetRoleApiConditions 获取角色API权限的ABAC条件
+func (ps *PermissionService) GetRoleApiConditions(c context.Context, roleID uint, sysapilist models.SysApiList, tenantID ...uint) ([]models.SysRoleApiCondition, error) {
+	conditions, err := app.CasbinV2.GetConditionsForRole(roleID, ps.HandleTenantID(c, tenantID...)...)
+	if err != nil {
+		return nil, err
+	}
+	policies := make(map[string]*app.CasbinCondition, len(conditions))
+	for _, item := range conditions {
+		policies[item.Obj+"|"+item.Act] = item.Condition
+	}
+	res := []models.SysRoleApiCondition{}
+	for _, api := range sysapilist {
+		if cond, ok := policies[common.ConvertPathToWildcard(api.Path)+"|"+api.Method]; ok {
+			res = append(res, models.SysRoleApiCondition{ApiID: api.ID, Condition: cond})
+		}
+	}
+	return res, nil
+}
+
+// SetRoleApiConditions 覆盖角色API权限的ABAC条件
+// 条件按策略(路径和方法)保存，路径和方法相同的多个API共用同一条件
+func (ps *PermissionService) SetRoleApiConditions(c context.Context, roleID uint, sysapilist models.SysApiList, conditions []models.SysRoleApiCondition, tenantID ...uint) error {
+	apis := make(map[uint]*models.SysApi, len(sysapilist))
+	for _, api := range sysapilist {
+		apis[api.ID] = api
+	}
+	var policies []app.CasbinPolicyCondition
+	for _, item := range conditions {
+		api, ok := apis[item.ApiID]
+		if !ok {
+			return fmt.Errorf("API ID %d 不属于分配的菜单", item.ApiID)
+		}
+		policies = append(policies, app.CasbinPolicyCondition{
+			Obj:       common.ConvertPathToWildcard(api.Path),
+			Act:       api.Method,
+			Condition: item.Condition,
+		})
+	}
+	return app.CasbinV2.SetConditionsForRole(roleID, policies, ps.HandleTenantID(c, tenantID...)...)
+}
+
+// ResolveAttributes 补充ABAC条件使用的请求属性：用户所属部门及租户套餐
+func (ps *PermissionService) ResolveAttributes(c *gin.Context, attrs *app.CasbinAttributes) error {
+	if attrs.UserID > 0 {
+		user := models.NewUser()
+		if err := user.GetUserByID(c, attrs.UserID); err != nil {
+			return err
+		}
+		attrs.DeptID = user.DeptID
+	}
+	if attrs.TenantID > 0 {
+		tenant := models.NewTenant()
+		if err := tenant.Find(c, func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "plan").Where("id = ?", attrs.TenantID)
+		}); err != nil {
+			return err
+		}
+		attrs.Plan = tenant.Plan
+	}
+	return nil
+}
+
 // 添加角色继承关系
 func (ps *PermissionService) AddRoleInheritance(c context.Context, roleID uint, parentRoleID uint, tenantID ...uint) (err error) {
 
@@ -319,3 +406,157 @@ func (ps *PermissionService) UpdateRoleApiPermissionsByApiID(c context.Context,
 
 	return
 }
+
+// PermissionExplanation 用户权限判定解释
+type PermissionExplanation struct {
+	*app.CasbinExplanation
+	UserID    uint                  `json:"userId"`
+	Username  string                `json:"username"`
+	TenantID  uint                  `json:"tenantId"`
+	SkipAuth  bool                  `json:"skipAuth"`  // 是否为跳过权限检查的用户
+	DataScope *DataScopeExplanation `json:"dataScope"` // 数据权限
+	Grants    []*PermissionGrant    `json:"grants"`    // 可授予该请求权限的API及菜单
+}
+
+// DataScopeExplanation 数据权限解释
+type DataScopeExplanation struct {
+	Roles models.SysRoleList `json:"roles"` // 用户角色及其数据权限设置
+	SQL   string             `json:"sql"`   // 数据权限作用于查询后生成的SQL
+}
+
+// PermissionGrant 可授予请求权限的API
+type PermissionGrant struct {
+	Api     *models.SysApi `json:"api"`     // 匹配请求的API及其关联菜单
+	RoleIDs []uint         `json:"roleIds"` // 用户角色链中已分配关联菜单的角色
+}
+
+// Explain 解释用户在租户下访问指定路径和方法的权限判定过程
+// tenantID 为0时按全局用户处理
+func (ps *PermissionService) Explain(c *gin.Context, user *models.User, tenantID uint, path, method string) (*PermissionExplanation, error) {
+	var domain []string
+	if tenantID > 0 {
+		domain = []string{ps.PrefixDomain(tenantID)}
+	}
+	explanation, err := app.CasbinV2.Explain(user.ID, path, method, domain...)
+	if err != nil {
+		return nil, err
+	}
+	result := &PermissionExplanation{
+		CasbinExplanation: explanation,
+		UserID:            user.ID,
+		Username:          user.Username,
+		TenantID:          tenantID,
+		SkipAuth:          common.IsSkipAuthUser(user.ID),
+	}
+	if result.SkipAuth {
+		result.Allowed = true
+	}
+
+	// 填充角色链中的角色名称
+	roleIDs := ps.roleChainIDs(explanation.RoleChain)
+	if len(roleIDs) > 0 {
+		roles := models.NewSysRoleList()
+		if err = roles.Find(c, func(db *gorm.DB) *gorm.DB {
+			return db.Where("id IN ?", roleIDs)
+		}); err != nil {
+			return nil, err
+		}
+		names := make(map[uint]string, len(roles))
+		for _, role := range roles {
+			names[role.ID] = role.Name
+		}
+		ps.fillRoleNames(explanation.RoleChain, names)
+	}
+
+	if result.DataScope, err = ps.explainDataScope(c, user, tenantID); err != nil {
+		return nil, err
+	}
+	if result.Grants, err = ps.explainGrants(c, path, method, roleIDs); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// explainDataScope 以目标用户身份计算数据权限
+func (ps *PermissionService) explainDataScope(c *gin.Context, user *models.User, tenantID uint) (*DataScopeExplanation, error) {
+	userWithRoles := models.NewUser()
+	if err := userWithRoles.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(models.PreloadActiveRoles(user.ID)).Where("id = ?", user.ID)
+	}); err != nil {
+		return nil, err
+	}
+
+	// 复制上下文并替换为目标用户的声明，避免影响当前请求
+	ctx := c.Copy()
+	ctx.Set(consts.BindContextKeyName, &app.Claims{ClaimsUser: app.ClaimsUser{
+		UserID:   user.ID,
+		Username: user.Username,
+		TenantID: tenantID,
+	}})
+	sql := app.DB().ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.User{}).Scopes(datascope.GetDataScope(ctx)).Find(&models.UserList{})
+	})
+	return &DataScopeExplanation{Roles: userWithRoles.Roles, SQL: sql}, nil
+}
+
+// explainGrants 查找匹配请求的API及其关联菜单，并标记用户角色链中已分配这些菜单的角色
+func (ps *PermissionService) explainGrants(c *gin.Context, path, method string, roleIDs []uint) ([]*PermissionGrant, error) {
+	apis := models.NewSysApiList()
+	if err := apis.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("SysMenuList")
+	}); err != nil {
+		return nil, err
+	}
+
+	grants := []*PermissionGrant{}
+	for _, api := range apis {
+		// 与生成策略时的路径处理及模型匹配器保持一致
+		if !util.KeyMatch2(path, common.ConvertPathToWildcard(api.Path)) || !util.RegexMatch(method, api.Method) {
+			continue
+		}
+		grant := &PermissionGrant{Api: api, RoleIDs: []uint{}}
+		if len(roleIDs) > 0 && !api.SysMenuList.IsEmpty() {
+			menuIDs := make([]uint, 0, len(api.SysMenuList))
+			for _, menu := range api.SysMenuList {
+				menuIDs = append(menuIDs, menu.ID)
+			}
+			roleMenus := models.NewSysRoleMenuList()
+			if err := roleMenus.Find(c, func(db *gorm.DB) *gorm.DB {
+				return db.Where("role_id IN ? AND menu_id IN ?", roleIDs, menuIDs)
+			}); err != nil {
+				return nil, err
+			}
+			for _, rm := range roleMenus {
+				if !slices.Contains(grant.RoleIDs, rm.RoleID) {
+					grant.RoleIDs = append(grant.RoleIDs, rm.RoleID)
+				}
+			}
+		}
+		grants = append(grants, grant)
+	}
+	return grants, nil
+}
+
+// roleChainIDs 获取角色链中的所有角色ID(去重)
+func (ps *PermissionService) roleChainIDs(nodes []*app.CasbinRoleNode) []uint {
+	var ids []uint
+	var walk func(nodes []*app.CasbinRoleNode)
+	walk = func(nodes []*app.CasbinRoleNode) {
+		for _, node := range nodes {
+			if node.RoleID > 0 && !slices.Contains(ids, node.RoleID) {
+				ids = append(ids, node.RoleID)
+			}
+			walk(node.Parents)
+		}
+	}
+	walk(nodes)
+	return ids
+}
+
+// fillRoleNames 填充角色链中的角色名称
+func (ps *PermissionService) fillRoleNames(nodes []*app.CasbinRoleNode, names map[uint]string) {
+	for _, node := range nodes {
+		node.Name = names[node.RoleID]
+		ps.fillRoleNames(node.Parents, names)
+	}
+}
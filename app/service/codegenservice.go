@@ -7,6 +7,7 @@ import (
 	"gin-fast/app/global/app"
 	"gin-fast/app/models"
 	"gin-fast/app/utils/common"
+	"gin-fast/app/utils/datascope"
 	"gin-fast/app/utils/gormhelper"
 	"os"
 	"path/filepath"
@@ -827,6 +828,8 @@ func (cgs *CodeGenService) generateServiceCode(ctx *models.CodeGenContext) strin
 		"PrimaryKey":      ctx.PrimaryKey,
 		"HasCreatedBy":    ctx.HasCreatedBy,
 		"HasTenantID":     ctx.HasTenantID,
+		// 有created_by字段或在配置中声明了数据权限规则的表，列表查询使用数据权限过滤
+		"HasDataScope": ctx.HasCreatedBy || datascope.HasRule(ctx.TableName),
 	}
 
 	for key, value := range ctx.ExtraParams {
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"gin-fast/app/global/app"
+	"gin-fast/app/models"
+	"gin-fast/app/utils/common"
+	"gin-fast/app/utils/fieldpermhelper"
+	"gin-fast/app/utils/tenanthelper"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// fieldRulesContextKey 当前请求用户生效的字段权限在上下文中的键
+const fieldRulesContextKey = "fieldPermissionRules"
+
+// FieldPermissionService 字段权限服务
+// 用户的字段权限由其所有角色合并得到，每个请求只加载一次；免检用户不受字段权限限制
+type FieldPermissionService struct{}
+
+// 编译时检查是否实现了接口
+var _ app.FieldPermissionInterf = (*FieldPermissionService)(nil)
+
+// NewFieldPermissionService 创建字段权限服务
+func NewFieldPermissionService() *FieldPermissionService {
+	return &FieldPermissionService{}
+}
+
+// Filter 按当前用户的字段权限隐藏或脱敏响应数据
+func (s *FieldPermissionService) Filter(c *gin.Context, data interface{}) (interface{}, error) {
+	rules, err := s.rules(c)
+	if err != nil {
+		return nil, err
+	}
+	return rules.Apply(data), nil
+}
+
+// RestrictedFields 获取当前用户在模型中无权修改的字段
+func (s *FieldPermissionService) RestrictedFields(ctx context.Context, model string) ([]string, error) {
+	c := common.TryConvertToGinContext(ctx)
+	if c == nil {
+		return nil, nil
+	}
+	rules, err := s.rules(c)
+	if err != nil {
+		return nil, err
+	}
+	return rules.Restricted(model), nil
+}
+
+// GetRoleFields 获取角色的字段权限
+func (s *FieldPermissionService) GetRoleFields(c context.Context, roleID uint) (models.SysRoleFieldList, error) {
+	list := models.NewSysRoleFieldList()
+	err := list.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("role_id = ?", roleID).Order("model, field")
+	})
+	return list, err
+}
+
+// SetRoleFields 设置角色的字段权限(覆盖原有设置)
+func (s *FieldPermissionService) SetRoleFields(c context.Context, roleID uint, items []models.SysRoleFieldItem) (models.SysRoleFieldList, error) {
+	list := models.NewSysRoleFieldList()
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		key := item.Model + "." + item.Field
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		list = append(list, &models.SysRoleField{
+			RoleID: roleID,
+			Model:  item.Model,
+			Field:  item.Field,
+			Action: item.Action,
+		})
+	}
+	err := app.DB().WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ?", roleID).Delete(&models.SysRoleField{}).Error; err != nil {
+			return err
+		}
+		if list.IsEmpty() {
+			return nil
+		}
+		return tx.CreateInBatches(list, 100).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// rules 获取当前请求用户生效的字段权限，加载失败时返回错误(不视为无限制)
+func (s *FieldPermissionService) rules(c *gin.Context) (fieldpermhelper.Rules, error) {
+	if cached, ok := c.Get(fieldRulesContextKey); ok {
+		return cached.(fieldpermhelper.Rules), nil
+	}
+	claims := common.GetClaims(c)
+	if claims == nil || common.IsSkipAuthUser(claims.UserID) {
+		return nil, nil
+	}
+	rules, err := s.load(c, claims.UserID, claims.TenantID)
+	if err != nil {
+		app.ZapLog.Error("加载字段权限失败", zap.Uint("userId", claims.UserID), zap.Error(err))
+		return nil, err
+	}
+	c.Set(fieldRulesContextKey, rules)
+	return rules, nil
+}
+
+// load 加载并合并用户在当前租户下所有角色的字段权限
+// 用户角色表不区分租户，需按角色所属租户过滤，其他租户的角色不能放宽当前租户的限制
+func (s *FieldPermissionService) load(c context.Context, userID, tenantID uint) (fieldpermhelper.Rules, error) {
+	db := app.DB().WithContext(tenanthelper.SkipTenant(c))
+	subQuery := db.Model(&models.SysUserRole{}).Select("role_id").
+		Where("user_id = ?", userID).Scopes(models.ActiveUserRoleScope(time.Now()))
+	var roleIDs []uint
+	if err := db.Model(&models.SysRole{}).Where("id IN (?) AND tenant_id = ?", subQuery, tenantID).Pluck("id", &roleIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(roleIDs) == 0 {
+		return fieldpermhelper.Rules{}, nil
+	}
+	fields := models.NewSysRoleFieldList()
+	if err := fields.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("role_id IN ?", roleIDs)
+	}); err != nil {
+		return nil, err
+	}
+	return fieldpermhelper.Merge(roleIDs, fields.Rules()), nil
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin-fast/app/global/app"
+	"gin-fast/app/models"
+	"gin-fast/app/utils/ldaphelper"
+	"strings"
+	"time"
+)
+
+// LdapAuthenticator 租户目录服务(LDAP / Active Directory)认证
+// 租户启用目录服务后，本地不存在的用户及已绑定目录身份的用户通过目录服务认证，首次登录时自动创建用户
+type LdapAuthenticator struct {
+	PermissionService *PermissionService
+}
+
+// NewLdapAuthenticator 创建目录服务认证
+func NewLdapAuthenticator() *LdapAuthenticator {
+	return &LdapAuthenticator{
+		PermissionService: NewPermissionService(),
+	}
+}
+
+// Name 认证方式标识
+func (a *LdapAuthenticator) Name() string {
+	return "ldap"
+}
+
+// IsOpen 是否开启目录服务登录
+func (a *LdapAuthenticator) IsOpen() bool {
+	return app.ConfigYml.GetBool("ldap.open")
+}
+
+// Supports 目标租户启用目录服务时处理：本地不存在的用户、已绑定目录身份的用户，以及允许按用户名绑定时租户内的已有用户
+func (a *LdapAuthenticator) Supports(c context.Context, user *models.User, tenant *models.Tenant) (bool, error) {
+	if !a.IsOpen() || tenant == nil || tenant.IsEmpty() || tenant.Status != 1 {
+		return false, nil
+	}
+	config := models.NewSysTenantLdap()
+	if err := config.GetByTenantID(c, tenant.ID); err != nil {
+		return false, err
+	}
+	if config.IsEmpty() || !config.Enabled {
+		return false, nil
+	}
+	if user.IsEmpty() {
+		return true, nil
+	}
+
+	identity := models.NewSysUserIdentity()
+	if err := identity.GetByUserID(c, ldapIdentityProvider(tenant.ID), user.ID); err != nil {
+		return false, err
+	}
+	if !identity.IsEmpty() {
+		return true, nil
+	}
+	return config.LinkExisting && user.TenantID == tenant.ID, nil
+}
+
+// Authenticate 通过目录服务校验密码，并同步本地用户
+func (a *LdapAuthenticator) Authenticate(c context.Context, user *models.User, tenant *models.Tenant, username, password string) (*models.User, error) {
+	config := models.NewSysTenantLdap()
+	if err := config.GetByTenantID(c, tenant.ID); err != nil {
+		return nil, err
+	}
+	if config.IsEmpty() || !config.Enabled {
+		return nil, errors.New("租户未启用目录服务登录")
+	}
+
+	entry, err := NewLdapClient(config).Authenticate(username, password)
+	if err != nil {
+		if errors.Is(err, ldaphelper.ErrInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		app.ZapLog.Warn("目录服务认证失败: " + err.Error())
+		return nil, errors.New("目录服务认证失败，请联系管理员")
+	}
+
+	roleIDs := config.GroupRoles.RoleIDs(func(group string) bool {
+		return ldaphelper.GroupMatch(entry.Groups, group)
+	})
+	if config.RequireGroup && len(roleIDs) == 0 {
+		return nil, errors.New("该账号不属于允许登录的用户组，请联系管理员")
+	}
+
+	return a.syncUser(c, user, tenant, username, entry, roleIDs)
+}
+
+// syncUser 同步目录用户：已绑定时更新用户信息，未绑定时绑定已有用户或创建用户
+// DN以小写形式作为身份唯一标识，用户在目录中移动后按用户ID找到原有绑定并更新
+func (a *LdapAuthenticator) syncUser(c context.Context, user *models.User, tenant *models.Tenant, username string, entry *ldaphelper.Entry, roleIDs []uint) (*models.User, error) {
+	provider := ldapIdentityProvider(tenant.ID)
+	subject := strings.ToLower(entry.DN)
+
+	identity := models.NewSysUserIdentity()
+	if !user.IsEmpty() {
+		if err := identity.GetByUserID(c, provider, user.ID); err != nil {
+			return nil, err
+		}
+	} else {
+		// 本地用户名被修改过时按DN找到已绑定的用户
+		if err := identity.GetBySubject(c, provider, subject); err != nil {
+			return nil, err
+		}
+		if !identity.IsEmpty() {
+			user = models.NewUser()
+			if err := user.GetUserByID(c, identity.UserID); err != nil {
+				return nil, err
+			}
+			if user.IsEmpty() {
+				return nil, errors.New("绑定的用户不存在")
+			}
+		}
+	}
+
+	if user.IsEmpty() {
+		// 首次登录，创建用户并按用户组分配角色
+		user = models.NewUser()
+		user.Username = username
+		user.NickName = entry.Name
+		if user.NickName == "" {
+			user.NickName = username
+		}
+		user.Email = entry.Email
+		user.Description = "通过目录服务登录自动创建"
+		if err := createExternalUser(c, a.PermissionService, user, tenant.ID, roleIDs); err != nil {
+			return nil, err
+		}
+	} else if (entry.Name != "" && entry.Name != user.NickName) || (entry.Email != "" && entry.Email != user.Email) {
+		// 以目录中的信息为准更新昵称及邮箱
+		if entry.Name != "" {
+			user.NickName = entry.Name
+		}
+		if entry.Email != "" {
+			user.Email = entry.Email
+		}
+		err := app.DB().WithContext(c).Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"nick_name": user.NickName,
+			"email":     user.Email,
+		}).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	identity.UserID = user.ID
+	identity.Provider = provider
+	identity.Subject = subject
+	identity.Email = entry.Email
+	identity.LastLoginAt = &now
+	if err := identity.Save(c); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// NewLdapClient 根据租户配置创建目录服务客户端
+func NewLdapClient(config *models.SysTenantLdap) *ldaphelper.Client {
+	timeout := app.ConfigYml.GetInt("ldap.timeout")
+	if timeout <= 0 {
+		timeout = 10
+	}
+	return ldaphelper.NewClient(ldaphelper.Config{
+		URL:                config.URL,
+		StartTLS:           config.StartTLS,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+		BindDN:             config.BindDN,
+		BindPassword:       config.BindPassword,
+		BaseDN:             config.BaseDN,
+		UserFilter:         config.UserFilter,
+		EmailAttr:          config.EmailAttr,
+		NameAttr:           config.NameAttr,
+		GroupAttr:          config.GroupAttr,
+		Timeout:            time.Duration(timeout) * time.Second,
+	}, nil)
+}
+
+// ldapIdentityProvider 目录身份的提供方标识，同一DN在不同租户的目录中相互独立
+func ldapIdentityProvider(tenantID uint) string {
+	return fmt.Sprintf("ldap:%d", tenantID)
+}
@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"gin-fast/app/global/app"
+	"gin-fast/app/models"
+	"gin-fast/app/utils/totphelper"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MfaChallenge 登录二次验证挑战信息(存储在缓存中)
+type MfaChallenge struct {
+	UserID     uint   `json:"userId"`
+	Username   string `json:"username"`
+	TenantID   uint   `json:"tenantId"`
+	TenantCode string `json:"tenantCode"`
+	DeviceName string `json:"deviceName"`
+	Enroll     bool   `json:"enroll"` // 是否需要先完成绑定(强制开启但尚未绑定)
+}
+
+// MfaEnrollment 绑定二次验证时返回给用户的信息(仅返回一次)
+type MfaEnrollment struct {
+	Secret          string   `json:"secret"`
+	ProvisioningURI string   `json:"provisioningUri"`
+	RecoveryCodes   []string `json:"recoveryCodes"`
+}
+
+type MfaService struct {
+}
+
+// NewMfaService 创建二次验证服务
+func NewMfaService() *MfaService {
+	return &MfaService{}
+}
+
+// IsOpen 是否开启二次验证功能
+func (s *MfaService) IsOpen() bool {
+	return app.ConfigYml.GetBool("mfa.open")
+}
+
+// IsEnforced 检查用户在指定租户下是否被强制要求二次验证(按租户或角色配置)
+func (s *MfaService) IsEnforced(c context.Context, userID uint, tenantID uint) (bool, error) {
+	for _, id := range app.ConfigYml.GetUintSlice("mfa.enforcetenantids") {
+		if id == tenantID {
+			return true, nil
+		}
+	}
+
+	enforceRoleIDs := app.ConfigYml.GetUintSlice("mfa.enforceroleids")
+	if len(enforceRoleIDs) == 0 {
+		return false, nil
+	}
+	userRoles := models.NewSysUserRoleList()
+	err := userRoles.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_id = ? AND role_id IN ?", userID, enforceRoleIDs)
+	})
+	if err != nil {
+		return false, err
+	}
+	return !userRoles.IsEmpty(), nil
+}
+
+// CheckLogin 检查登录是否需要二次验证，返回(是否需要二次验证, 是否已绑定)
+func (s *MfaService) CheckLogin(c context.Context, userID uint, tenantID uint) (required bool, enrolled bool, err error) {
+	if !s.IsOpen() {
+		return false, false, nil
+	}
+	mfa := models.NewSysUserMfa()
+	if err = mfa.GetByUserID(c, userID); err != nil {
+		return
+	}
+	enrolled = !mfa.IsEmpty() && mfa.Enabled
+	if enrolled {
+		return true, true, nil
+	}
+	required, err = s.IsEnforced(c, userID, tenantID)
+	return
+}
+
+/*****************************************登录挑战令牌****************************************************/
+
+func (s *MfaService) challengeKey(token string) string {
+	return "mfa_challenge:" + token
+}
+
+func (s *MfaService) challengeFailKey(token string) string {
+	return "mfa_challenge_fail:" + token
+}
+
+// challengeExpire 挑战令牌有效期
+func (s *MfaService) challengeExpire() time.Duration {
+	expire := app.ConfigYml.GetInt("mfa.challengeexpire")
+	if expire <= 0 {
+		expire = 300
+	}
+	return time.Duration(expire) * time.Second
+}
+
+// CreateChallenge 创建登录二次验证挑战令牌
+func (s *MfaService) CreateChallenge(challenge *MfaChallenge) (string, time.Time, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(buf)
+	data, err := json.Marshal(challenge)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expire := s.challengeExpire()
+	if err = app.Cache.Set(context.Background(), s.challengeKey(token), string(data), expire); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, time.Now().Add(expire), nil
+}
+
+// GetChallenge 获取登录二次验证挑战信息
+func (s *MfaService) GetChallenge(token string) (*MfaChallenge, error) {
+	data, err := app.Cache.Get(context.Background(), s.challengeKey(token))
+	if err != nil || data == "" {
+		return nil, errors.New("二次验证令牌无效或已过期")
+	}
+	challenge := &MfaChallenge{}
+	if err = json.Unmarshal([]byte(data), challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// UpdateChallenge 更新挑战信息(保持原令牌)
+func (s *MfaService) UpdateChallenge(token string, challenge *MfaChallenge) error {
+	data, err := json.Marshal(challenge)
+	if err != nil {
+		return err
+	}
+	return app.Cache.Set(context.Background(), s.challengeKey(token), string(data), s.challengeExpire())
+}
+
+// DeleteChallenge 删除挑战令牌
+func (s *MfaService) DeleteChallenge(token string) {
+	app.Cache.Del(context.Background(), s.challengeKey(token), s.challengeFailKey(token))
+}
+
+// RecordChallengeFailure 记录一次验证失败，返回剩余尝试次数，次数用尽时令牌作废
+func (s *MfaService) RecordChallengeFailure(token string) int {
+	maxAttempts := app.ConfigYml.GetInt("mfa.maxattempts")
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	failKey := s.challengeFailKey(token)
+	var failCount int
+	if countStr, err := app.Cache.Get(context.Background(), failKey); err == nil && countStr != "" {
+		failCount, _ = strconv.Atoi(countStr)
+	}
+	failCount++
+	app.Cache.Set(context.Background(), failKey, strconv.Itoa(failCount), s.challengeExpire())
+	if failCount >= maxAttempts {
+		s.DeleteChallenge(token)
+		return 0
+	}
+	return maxAttempts - failCount
+}
+
+/*****************************************绑定管理****************************************************/
+
+// newRecoveryCodes 生成恢复码，返回明文及摘要
+func (s *MfaService) newRecoveryCodes() ([]string, []string, error) {
+	count := app.ConfigYml.GetInt("mfa.recoverycodecount")
+	if count <= 0 {
+		count = 10
+	}
+	codes, err := totphelper.GenerateRecoveryCodes(count)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = totphelper.HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
+
+// BeginEnroll 开始绑定：生成新的密钥和恢复码，需调用 ConfirmEnroll 校验验证码后才会启用
+func (s *MfaService) BeginEnroll(c context.Context, userID uint, account string) (*MfaEnrollment, error) {
+	mfa := models.NewSysUserMfa()
+	if err := mfa.GetByUserID(c, userID); err != nil {
+		return nil, err
+	}
+	if !mfa.IsEmpty() && mfa.Enabled {
+		return nil, errors.New("二次验证已启用，请先关闭后再重新绑定")
+	}
+
+	secret, err := totphelper.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	mfa.UserID = userID
+	mfa.Secret = secret
+	mfa.Enabled = false
+	mfa.LastUsedStep = 0
+	mfa.ConfirmedAt = nil
+	mfa.SetRecoveryCodes(hashes)
+	if err = mfa.Save(c); err != nil {
+		return nil, err
+	}
+
+	return &MfaEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totphelper.ProvisioningURI(secret, app.ConfigYml.GetString("mfa.issuer"), account),
+		RecoveryCodes:   codes,
+	}, nil
+}
+
+// ConfirmEnroll 确认绑定：校验验证器App生成的验证码后启用二次验证
+func (s *MfaService) ConfirmEnroll(c context.Context, userID uint, code string) error {
+	mfa := models.NewSysUserMfa()
+	if err := mfa.GetByUserID(c, userID); err != nil {
+		return err
+	}
+	if mfa.IsEmpty() {
+		return errors.New("请先获取二次验证密钥")
+	}
+	if mfa.Enabled {
+		return errors.New("二次验证已启用")
+	}
+	step, ok := totphelper.ValidateCode(mfa.Secret, code, time.Now())
+	if !ok {
+		return errors.New("验证码错误")
+	}
+	now := time.Now()
+	mfa.Enabled = true
+	mfa.LastUsedStep = step
+	mfa.ConfirmedAt = &now
+	return mfa.Save(c)
+}
+
+// Verify 校验已启用用户的验证码，支持TOTP验证码及一次性恢复码
+func (s *MfaService) Verify(c context.Context, userID uint, code string) error {
+	mfa := models.NewSysUserMfa()
+	if err := mfa.GetByUserID(c, userID); err != nil {
+		return err
+	}
+	if mfa.IsEmpty() || !mfa.Enabled {
+		return errors.New("未启用二次验证")
+	}
+
+	// TOTP验证码，同一时间步只能使用一次
+	if step, ok := totphelper.ValidateCode(mfa.Secret, code, time.Now()); ok {
+		if step <= mfa.LastUsedStep {
+			return errors.New("验证码已使用，请等待下一个验证码")
+		}
+		mfa.LastUsedStep = step
+		return mfa.Save(c)
+	}
+
+	// 恢复码，使用后即失效
+	hash := totphelper.HashRecoveryCode(code)
+	hashes := mfa.GetRecoveryCodes()
+	for i, h := range hashes {
+		if h == hash {
+			mfa.SetRecoveryCodes(append(hashes[:i], hashes[i+1:]...))
+			return mfa.Save(c)
+		}
+	}
+	return errors.New("验证码错误")
+}
+
+// Disable 关闭二次验证
+func (s *MfaService) Disable(c context.Context, userID uint, code string) error {
+	if err := s.Verify(c, userID, code); err != nil {
+		return err
+	}
+	mfa := &models.SysUserMfa{UserID: userID}
+	return mfa.Delete(c)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，原有恢复码全部失效
+func (s *MfaService) RegenerateRecoveryCodes(c context.Context, userID uint, code string) ([]string, error) {
+	if err := s.Verify(c, userID, code); err != nil {
+		return nil, err
+	}
+	mfa := models.NewSysUserMfa()
+	if err := mfa.GetByUserID(c, userID); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	mfa.SetRecoveryCodes(hashes)
+	if err = mfa.Save(c); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// GetStatus 获取用户二次验证状态
+func (s *MfaService) GetStatus(c context.Context, userID uint, tenantID uint) (map[string]interface{}, error) {
+	mfa := models.NewSysUserMfa()
+	if err := mfa.GetByUserID(c, userID); err != nil {
+		return nil, err
+	}
+	enforced, err := s.IsEnforced(c, userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"open":                   s.IsOpen(),
+		"enabled":                !mfa.IsEmpty() && mfa.Enabled,
+		"enforced":               enforced,
+		"confirmedAt":            mfa.ConfirmedAt,
+		"recoveryCodesRemaining": len(mfa.GetRecoveryCodes()),
+	}, nil
+}
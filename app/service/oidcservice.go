@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin-fast/app/global/app"
+	"gin-fast/app/models"
+	"gin-fast/app/utils/oidchelper"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OidcState 授权请求状态(存储在缓存中，回调时校验)
+type OidcState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"codeVerifier"`
+	TenantCode   string `json:"tenantCode"`
+	DeviceName   string `json:"deviceName"`
+}
+
+// OidcProviderInfo 对外展示的身份提供方信息
+type OidcProviderInfo struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+}
+
+// oidcProviders 提供方客户端缓存(缓存发现元数据及JWKS)，配置变更后按新配置重新创建
+var oidcProviders sync.Map // name => *oidcProviderEntry
+
+type oidcProviderEntry struct {
+	config   oidchelper.Config
+	provider *oidchelper.Provider
+}
+
+type OidcService struct {
+	PermissionService *PermissionService
+}
+
+// NewOidcService 创建OIDC登录服务
+func NewOidcService() *OidcService {
+	return &OidcService{
+		PermissionService: NewPermissionService(),
+	}
+}
+
+// IsOpen 是否开启OIDC登录
+func (s *OidcService) IsOpen() bool {
+	return app.ConfigYml.GetBool("oidc.open")
+}
+
+// Providers 获取已配置的身份提供方列表
+func (s *OidcService) Providers() []OidcProviderInfo {
+	providers, _ := app.ConfigYml.Get("oidc.providers").(map[string]interface{})
+	list := make([]OidcProviderInfo, 0, len(providers))
+	for name := range providers {
+		title := app.ConfigYml.GetString(s.configKey(name, "title"))
+		if title == "" {
+			title = name
+		}
+		list = append(list, OidcProviderInfo{Name: name, Title: title})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// Begin 发起授权，返回身份提供方的授权地址
+func (s *OidcService) Begin(c context.Context, providerName, tenantCode, deviceName string) (string, error) {
+	provider, err := s.provider(providerName)
+	if err != nil {
+		return "", err
+	}
+	state, err := oidchelper.RandomString(32)
+	if err != nil {
+		return "", err
+	}
+	oidcState := &OidcState{Provider: providerName, TenantCode: tenantCode, DeviceName: deviceName}
+	if oidcState.Nonce, err = oidchelper.RandomString(32); err != nil {
+		return "", err
+	}
+	if oidcState.CodeVerifier, err = oidchelper.RandomString(48); err != nil {
+		return "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(c, state, oidcState.Nonce, oidcState.CodeVerifier)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(oidcState)
+	if err != nil {
+		return "", err
+	}
+	if err = app.Cache.Set(context.Background(), s.stateKey(state), string(data), s.stateExpire()); err != nil {
+		return "", err
+	}
+	return authURL, nil
+}
+
+// Callback 处理授权回调：校验state、使用授权码换取并校验ID Token，返回绑定的本地用户
+// state 只能使用一次
+func (s *OidcService) Callback(c context.Context, state, code string) (*models.User, *OidcState, error) {
+	data, err := app.Cache.Get(context.Background(), s.stateKey(state))
+	if err != nil || data == "" {
+		return nil, nil, errors.New("登录请求无效或已过期，请重新登录")
+	}
+	app.Cache.Del(context.Background(), s.stateKey(state))
+	oidcState := &OidcState{}
+	if err = json.Unmarshal([]byte(data), oidcState); err != nil {
+		return nil, nil, err
+	}
+
+	provider, err := s.provider(oidcState.Provider)
+	if err != nil {
+		return nil, nil, err
+	}
+	token, err := provider.Exchange(c, code, oidcState.CodeVerifier)
+	if err != nil {
+		app.ZapLog.Warn("oidc授权码换取token失败: " + err.Error())
+		return nil, nil, errors.New("身份提供方认证失败")
+	}
+	claims, err := provider.VerifyIDToken(c, token.IDToken, oidcState.Nonce)
+	if err != nil {
+		app.ZapLog.Warn("oidc id_token校验失败: " + err.Error())
+		return nil, nil, errors.New("身份提供方认证失败")
+	}
+	// ID Token中未包含邮箱时从用户信息接口获取
+	if claims.Email == "" && token.AccessToken != "" {
+		if info, err := provider.UserInfo(c, token.AccessToken); err == nil {
+			if sub, _ := info["sub"].(string); sub == claims.Subject {
+				claims.Email, _ = info["email"].(string)
+				claims.EmailVerified, _ = info["email_verified"].(bool)
+				if claims.PreferredUsername == "" {
+					claims.PreferredUsername, _ = info["preferred_username"].(string)
+				}
+				if claims.Name == "" {
+					claims.Name, _ = info["name"].(string)
+				}
+			}
+		}
+	}
+
+	user, err := s.resolveUser(c, oidcState.Provider, claims)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, oidcState, nil
+}
+
+// resolveUser 根据提供方身份查找本地用户：已绑定的身份 > 按已验证邮箱绑定 > 自动创建
+func (s *OidcService) resolveUser(c context.Context, providerName string, claims *oidchelper.IDTokenClaims) (*models.User, error) {
+	identity := models.NewSysUserIdentity()
+	if err := identity.GetBySubject(c, providerName, claims.Subject); err != nil {
+		return nil, err
+	}
+
+	user := models.NewUser()
+	if !identity.IsEmpty() {
+		if err := user.GetUserByID(c, identity.UserID); err != nil {
+			return nil, err
+		}
+		if user.IsEmpty() {
+			return nil, errors.New("绑定的用户不存在")
+		}
+	} else {
+		if claims.Email != "" && claims.EmailVerified && app.ConfigYml.GetBool(s.configKey(providerName, "linkbyemail")) {
+			if err := user.GetUserByEmail(c, claims.Email); err != nil {
+				return nil, err
+			}
+		}
+		if user.IsEmpty() {
+			if !app.ConfigYml.GetBool(s.configKey(providerName, "autoprovision")) {
+				return nil, errors.New("该账号未绑定系统用户，请联系管理员")
+			}
+			var err error
+			if user, err = s.provisionUser(c, providerName, claims); err != nil {
+				return nil, err
+			}
+		}
+		identity.UserID = user.ID
+		identity.Provider = providerName
+		identity.Subject = claims.Subject
+	}
+
+	now := time.Now()
+	identity.Email = claims.Email
+	identity.LastLoginAt = &now
+	if err := identity.Save(c); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// provisionUser 自动创建用户，加入配置的默认租户及默认角色
+func (s *OidcService) provisionUser(c context.Context, providerName string, claims *oidchelper.IDTokenClaims) (*models.User, error) {
+	email := claims.Email
+	if email != "" {
+		existUser := models.NewUser()
+		if err := existUser.GetUserByEmail(c, email); err != nil {
+			return nil, err
+		}
+		if !existUser.IsEmpty() {
+			return nil, errors.New("邮箱已被其他用户使用，请联系管理员绑定账号")
+		}
+	}
+
+	username, err := s.availableUsername(c, providerName, claims)
+	if err != nil {
+		return nil, err
+	}
+	nickName := claims.Name
+	if nickName == "" {
+		nickName = username
+	}
+
+	user := models.NewUser()
+	user.Username = username
+	user.NickName = nickName
+	user.Email = email
+	user.Description = "通过" + providerName + "登录自动创建"
+	tenantID := uint(app.ConfigYml.GetInt(s.configKey(providerName, "defaulttenantid")))
+	roleIDs := app.ConfigYml.GetUintSlice(s.configKey(providerName, "defaultroleids"))
+	if err = createExternalUser(c, s.PermissionService, user, tenantID, roleIDs); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// availableUsername 生成未被占用的用户名，优先使用提供方返回的用户名及邮箱前缀
+func (s *OidcService) availableUsername(c context.Context, providerName string, claims *oidchelper.IDTokenClaims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" && claims.Email != "" {
+		base = strings.Split(claims.Email, "@")[0]
+	}
+	if base == "" {
+		base = providerName + "_" + claims.Subject
+	}
+	if len(base) > 40 {
+		base = base[:40]
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		user := models.NewUser()
+		if err := user.GetUserByUsername(c, candidate); err != nil {
+			return "", err
+		}
+		if user.IsEmpty() {
+			return candidate, nil
+		}
+		suffix, err := oidchelper.RandomString(4)
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "_" + strings.ToLower(suffix)
+	}
+	return "", errors.New("生成用户名失败")
+}
+
+// provider 获取提供方客户端
+func (s *OidcService) provider(name string) (*oidchelper.Provider, error) {
+	if !s.IsOpen() {
+		return nil, errors.New("未开启第三方登录")
+	}
+	issuer := app.ConfigYml.GetString(s.configKey(name, "issuer"))
+	if name == "" || issuer == "" {
+		return nil, fmt.Errorf("身份提供方 %s 不存在", name)
+	}
+	config := oidchelper.Config{
+		Issuer:       issuer,
+		ClientID:     app.ConfigYml.GetString(s.configKey(name, "clientid")),
+		ClientSecret: app.ConfigYml.GetString(s.configKey(name, "clientsecret")),
+		RedirectURL:  app.ConfigYml.GetString(s.configKey(name, "redirecturl")),
+		Scopes:       app.ConfigYml.GetStringSlice(s.configKey(name, "scopes")),
+	}
+	if entry, ok := oidcProviders.Load(name); ok && sameOidcConfig(entry.(*oidcProviderEntry).config, config) {
+		return entry.(*oidcProviderEntry).provider, nil
+	}
+	provider := oidchelper.NewProvider(config, nil)
+	oidcProviders.Store(name, &oidcProviderEntry{config: config, provider: provider})
+	return provider, nil
+}
+
+func sameOidcConfig(a, b oidchelper.Config) bool {
+	return a.Issuer == b.Issuer && a.ClientID == b.ClientID && a.ClientSecret == b.ClientSecret &&
+		a.RedirectURL == b.RedirectURL && strings.Join(a.Scopes, " ") == strings.Join(b.Scopes, " ")
+}
+
+func (s *OidcService) configKey(provider, key string) string {
+	return "oidc.providers." + provider + "." + key
+}
+
+func (s *OidcService) stateKey(state string) string {
+	return "oidc_state:" + state
+}
+
+func (s *OidcService) stateExpire() time.Duration {
+	expire := app.ConfigYml.GetInt("oidc.stateexpire")
+	if expire <= 0 {
+		expire = 600
+	}
+	return time.Duration(expire) * time.Second
+}
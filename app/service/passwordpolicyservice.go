@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"gin-fast/app/global/app"
+	"gin-fast/app/models"
+	"gin-fast/app/utils/passwordhelper"
+	"gin-fast/app/utils/tenanthelper"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// passwordChangeExpire 密码过期后登录修改密码的令牌有效期
+const passwordChangeExpire = 10 * time.Minute
+
+// PasswordChangeChallenge 密码过期登录时的修改密码挑战信息(存储在缓存中)
+type PasswordChangeChallenge struct {
+	UserID     uint   `json:"userId"`
+	TenantID   uint   `json:"tenantId"`
+	TenantCode string `json:"tenantCode"`
+	DeviceName string `json:"deviceName"`
+}
+
+// PasswordPolicyService 密码策略服务
+// 全局策略来自配置 safe，租户启用自定义策略后覆盖全局策略；用户按所属租户(tenant_id)适用策略
+type PasswordPolicyService struct {
+}
+
+// NewPasswordPolicyService 创建密码策略服务
+func NewPasswordPolicyService() *PasswordPolicyService {
+	return &PasswordPolicyService{}
+}
+
+// Global 获取全局密码策略
+func (s *PasswordPolicyService) Global() *passwordhelper.Policy {
+	return &passwordhelper.Policy{
+		MinLength:        app.ConfigYml.GetInt("safe.minpasswordlength"),
+		RequireUpper:     app.ConfigYml.GetBool("safe.requireuppercase"),
+		RequireLower:     app.ConfigYml.GetBool("safe.requirelowercase"),
+		RequireDigit:     app.ConfigYml.GetBool("safe.requiredigit"),
+		RequireSpecial:   app.ConfigYml.GetBool("safe.requirespecialchar"),
+		SpecialChars:     app.ConfigYml.GetString("safe.specialchars"),
+		ForbiddenWords:   app.ConfigYml.GetStringSlice("safe.forbiddenwords"),
+		DisallowUsername: app.ConfigYml.GetBool("safe.disallowusername"),
+		HistoryCount:     app.ConfigYml.GetInt("safe.passwordhistory"),
+		MaxAgeDays:       app.ConfigYml.GetInt("safe.passwordmaxage"),
+	}
+}
+
+// Effective 获取租户生效的密码策略，租户未启用自定义策略时使用全局策略
+func (s *PasswordPolicyService) Effective(c context.Context, tenantID uint) (*passwordhelper.Policy, error) {
+	if tenantID > 0 {
+		// 按指定租户获取，用户可能在关联的其他租户下修改密码
+		tenantPolicy := models.NewSysTenantPasswordPolicy()
+		if err := tenantPolicy.GetByTenantID(tenanthelper.SkipTenant(c), tenantID); err != nil {
+			return nil, err
+		}
+		if !tenantPolicy.IsEmpty() && tenantPolicy.Enabled {
+			return tenantPolicy.Policy(), nil
+		}
+	}
+	return s.Global(), nil
+}
+
+// Apply 按用户所属租户的策略校验新密码(含历史密码)，通过后设置加密后的密码及修改时间，不保存
+// 保存用户后需调用 Record 记录历史密码
+func (s *PasswordPolicyService) Apply(c context.Context, user *models.User, password string) error {
+	policy, err := s.Effective(c, user.TenantID)
+	if err != nil {
+		return err
+	}
+	if err = policy.Validate(password, user.Username); err != nil {
+		return err
+	}
+	if user.ID > 0 && policy.HistoryCount > 0 {
+		if err = s.checkHistory(c, user, password, policy.HistoryCount); err != nil {
+			return err
+		}
+	}
+
+	hashedPassword, err := passwordhelper.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	user.Password = hashedPassword
+	user.PasswordChangedAt = &now
+	return nil
+}
+
+// Record 记录用户当前密码到历史密码，并清理超出策略数量的记录
+func (s *PasswordPolicyService) Record(c context.Context, tx *gorm.DB, user *models.User) error {
+	policy, err := s.Effective(c, user.TenantID)
+	if err != nil {
+		return err
+	}
+	if policy.HistoryCount <= 0 {
+		return tx.Where("user_id = ?", user.ID).Delete(&models.SysUserPasswordHistory{}).Error
+	}
+	history := &models.SysUserPasswordHistory{UserID: user.ID, Password: user.Password}
+	if err = tx.Create(history).Error; err != nil {
+		return err
+	}
+	var staleIDs []uint
+	err = tx.Model(&models.SysUserPasswordHistory{}).Where("user_id = ?", user.ID).
+		Order("id DESC").Offset(policy.HistoryCount).Pluck("id", &staleIDs).Error
+	if err != nil || len(staleIDs) == 0 {
+		return err
+	}
+	return tx.Where("id IN ?", staleIDs).Delete(&models.SysUserPasswordHistory{}).Error
+}
+
+// SetPassword 校验并修改用户密码(找回密码、密码过期修改等场景)
+func (s *PasswordPolicyService) SetPassword(c context.Context, user *models.User, password string) error {
+	if err := s.Apply(c, user, password); err != nil {
+		return err
+	}
+	return app.DB().WithContext(c).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(user).Updates(map[string]interface{}{
+			"password":            user.Password,
+			"password_changed_at": user.PasswordChangedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return s.Record(c, tx, user)
+	})
+}
+
+// IsExpired 检查用户密码是否已超过最长使用天数，未记录修改时间的用户按创建时间计算
+func (s *PasswordPolicyService) IsExpired(c context.Context, user *models.User) (bool, error) {
+	policy, err := s.Effective(c, user.TenantID)
+	if err != nil {
+		return false, err
+	}
+	if policy.MaxAgeDays <= 0 {
+		return false, nil
+	}
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return time.Since(changedAt) > time.Duration(policy.MaxAgeDays)*24*time.Hour, nil
+}
+
+// checkHistory 检查新密码是否与当前密码或最近的历史密码相同
+func (s *PasswordPolicyService) checkHistory(c context.Context, user *models.User, password string, count int) error {
+	current := models.NewUser()
+	if err := current.GetUserByID(c, user.ID); err != nil {
+		return err
+	}
+	hashes := []string{current.Password}
+
+	historyList := models.NewSysUserPasswordHistoryList()
+	err := historyList.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_id = ?", user.ID).Order("id DESC").Limit(count)
+	})
+	if err != nil {
+		return err
+	}
+	for _, history := range historyList {
+		hashes = append(hashes, history.Password)
+	}
+	for _, hash := range hashes {
+		if hash != "" && passwordhelper.ComparePassword(hash, password) == nil {
+			return errors.New("新密码不能与最近使用过的密码相同")
+		}
+	}
+	return nil
+}
+
+// CreateChallenge 创建密码过期修改密码的挑战令牌
+func (s *PasswordPolicyService) CreateChallenge(challenge *PasswordChangeChallenge) (string, time.Time, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(buf)
+	data, err := json.Marshal(challenge)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if err = app.Cache.Set(context.Background(), s.challengeKey(token), string(data), passwordChangeExpire); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, time.Now().Add(passwordChangeExpire), nil
+}
+
+// GetChallenge 获取密码过期修改密码的挑战信息
+func (s *PasswordPolicyService) GetChallenge(token string) (*PasswordChangeChallenge, error) {
+	data, err := app.Cache.Get(context.Background(), s.challengeKey(token))
+	if err != nil || data == "" {
+		return nil, errors.New("修改密码令牌无效或已过期，请重新登录")
+	}
+	challenge := &PasswordChangeChallenge{}
+	if err = json.Unmarshal([]byte(data), challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// DeleteChallenge 删除密码过期修改密码的挑战令牌
+func (s *PasswordPolicyService) DeleteChallenge(token string) {
+	app.Cache.Del(context.Background(), s.challengeKey(token))
+}
+
+func (s *PasswordPolicyService) challengeKey(token string) string {
+	return "password_change:" + token
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"gin-fast/app/global/app"
+	"gin-fast/app/models"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrResetTokenInvalid 重置密码令牌无效或已过期
+var ErrResetTokenInvalid = errors.New("重置链接无效或已过期，请重新申请")
+
+// ErrResetRateLimited 申请重置密码过于频繁
+var ErrResetRateLimited = errors.New("申请过于频繁，请稍后再试")
+
+// PasswordResetService 找回密码服务
+// 重置令牌只保存摘要，单次有效；同一用户重新申请后之前的令牌失效
+type PasswordResetService struct {
+	PasswordPolicyService *PasswordPolicyService
+}
+
+// NewPasswordResetService 创建找回密码服务
+func NewPasswordResetService() *PasswordResetService {
+	return &PasswordResetService{
+		PasswordPolicyService: NewPasswordPolicyService(),
+	}
+}
+
+// IsOpen 是否开启找回密码功能
+func (s *PasswordResetService) IsOpen() bool {
+	return app.ConfigYml.GetBool("passwordreset.open")
+}
+
+// Request 申请重置密码，向用户邮箱发送重置链接
+// 邮箱未注册或用户未启用时同样返回成功，避免泄露账号是否存在
+func (s *PasswordResetService) Request(c context.Context, email string, ip string) error {
+	email = strings.TrimSpace(email)
+	if err := s.checkRate("ip:"+ip, "email:"+strings.ToLower(email)); err != nil {
+		return err
+	}
+
+	user := models.NewUser()
+	if err := user.GetUserByEmail(c, email); err != nil {
+		return err
+	}
+	if user.IsEmpty() || user.Status != 1 {
+		return nil
+	}
+	// 演示账号不允许修改密码
+	if app.ConfigYml.GetBool("server.demoaccount.enabled") && slices.Contains(app.ConfigYml.GetUintSlice("server.demoaccount.userids"), user.ID) {
+		return nil
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	hash := s.hash(token)
+	expire := s.tokenExpire()
+	userID := strconv.FormatUint(uint64(user.ID), 10)
+
+	// 使之前申请的令牌失效
+	if oldHash, err := app.Cache.Get(context.Background(), s.userKey(user.ID)); err == nil && oldHash != "" {
+		app.Cache.Del(context.Background(), s.tokenKey(oldHash))
+	}
+	if err := app.Cache.Set(context.Background(), s.tokenKey(hash), userID, expire); err != nil {
+		return err
+	}
+	if err := app.Cache.Set(context.Background(), s.userKey(user.ID), hash, expire); err != nil {
+		return err
+	}
+
+	// 异步发送，避免响应时间暴露账号是否存在
+	msg := s.message(user, token, expire)
+	go func() {
+		if err := app.MailService.Send(context.Background(), msg); err != nil {
+			app.ZapLog.Error("发送重置密码邮件失败: " + err.Error())
+		}
+	}()
+	return nil
+}
+
+// Confirm 使用重置令牌设置新密码，成功后令牌失效并注销该用户所有会话
+// 新密码不满足密码策略时令牌仍然有效，可修改后重新提交
+func (s *PasswordResetService) Confirm(c context.Context, token string, password string) error {
+	hash := s.hash(token)
+	userIDStr, err := app.Cache.Get(context.Background(), s.tokenKey(hash))
+	if err != nil || userIDStr == "" {
+		return ErrResetTokenInvalid
+	}
+	userID, err := strconv.ParseUint(userIDStr, 10, 64)
+	if err != nil {
+		return ErrResetTokenInvalid
+	}
+	if currentHash, _ := app.Cache.Get(context.Background(), s.userKey(uint(userID))); currentHash != hash {
+		return ErrResetTokenInvalid
+	}
+
+	user := models.NewUser()
+	if err = user.GetUserByID(c, uint(userID)); err != nil {
+		return err
+	}
+	if user.IsEmpty() || user.Status != 1 {
+		return ErrResetTokenInvalid
+	}
+	if err = s.PasswordPolicyService.SetPassword(c, user, password); err != nil {
+		return err
+	}
+	// 令牌单次有效
+	app.Cache.Del(context.Background(), s.tokenKey(hash), s.userKey(user.ID))
+
+	// 注销所有会话并解除登录锁定
+	if err = app.TokenService.RevokeRefreshToken(user.ID); err != nil {
+		app.ZapLog.Warn("重置密码后注销会话失败: " + err.Error())
+	}
+	app.Cache.Del(context.Background(), "account_locked:"+user.Username, "login_fail_count:"+user.Username)
+	return nil
+}
+
+// checkRate 检查申请频率，任一维度(IP、邮箱)在时间窗口内超过次数限制即拒绝
+func (s *PasswordResetService) checkRate(keys ...string) error {
+	limit := app.ConfigYml.GetInt("passwordreset.ratelimit")
+	if limit <= 0 {
+		return nil
+	}
+	window := time.Duration(app.ConfigYml.GetInt("passwordreset.ratewindow")) * time.Second
+	if window <= 0 {
+		window = time.Hour
+	}
+	counts := make([]int, len(keys))
+	for i, key := range keys {
+		if countStr, err := app.Cache.Get(context.Background(), s.rateKey(key)); err == nil && countStr != "" {
+			counts[i], _ = strconv.Atoi(countStr)
+		}
+		if counts[i] >= limit {
+			return ErrResetRateLimited
+		}
+	}
+	for i, key := range keys {
+		app.Cache.Set(context.Background(), s.rateKey(key), strconv.Itoa(counts[i]+1), window)
+	}
+	return nil
+}
+
+// message 生成重置密码邮件
+func (s *PasswordResetService) message(user *models.User, token string, expire time.Duration) *app.MailMessage {
+	link := app.ConfigYml.GetString("passwordreset.url")
+	if strings.Contains(link, "?") {
+		link += "&token=" + token
+	} else {
+		link += "?token=" + token
+	}
+	systemName := app.ConfigYml.GetString("system.systemname")
+	body := "您好，" + user.Username + "：\n\n" +
+		"您正在申请重置" + systemName + "的登录密码，请在" + strconv.Itoa(int(expire.Minutes())) + "分钟内点击以下链接设置新密码：\n\n" +
+		link + "\n\n" +
+		"该链接仅可使用一次。如果这不是您本人的操作，请忽略本邮件，您的密码不会被修改。"
+	return &app.MailMessage{
+		To:      []string{user.Email},
+		Subject: systemName + " - 重置密码",
+		Body:    body,
+	}
+}
+
+func (s *PasswordResetService) hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *PasswordResetService) tokenKey(hash string) string {
+	return "password_reset:" + hash
+}
+
+func (s *PasswordResetService) userKey(userID uint) string {
+	return "password_reset_user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+func (s *PasswordResetService) rateKey(key string) string {
+	return "password_reset_limit:" + key
+}
+
+// tokenExpire 重置令牌有效期
+func (s *PasswordResetService) tokenExpire() time.Duration {
+	expire := app.ConfigYml.GetInt("passwordreset.expire")
+	if expire <= 0 {
+		expire = 1800
+	}
+	return time.Duration(expire) * time.Second
+}
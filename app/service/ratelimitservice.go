@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"gin-fast/app/global/app"
+	"gin-fast/app/utils/ratelimithelper"
+	"sync/atomic"
+	"time"
+)
+
+// rateLimitRules 解析后的路由限流规则及默认规则，首次使用时解析，配置文件变化后调用 Reload 重新解析
+var rateLimitRules atomic.Pointer[rateLimitConfig]
+
+// rateLimitConfig 解析后的限流配置
+type rateLimitConfig struct {
+	rules []*ratelimithelper.Rule // 已排序的路由规则
+	def   *ratelimithelper.Rule   // 全局默认规则
+}
+
+// RateLimitService 请求限流服务
+// 按IP、用户、租户维度限流，路由规则覆盖全局默认限制；计数存储在缓存中，使用redis缓存时多实例共享
+type RateLimitService struct {
+}
+
+// NewRateLimitService 创建请求限流服务
+func NewRateLimitService() *RateLimitService {
+	return &RateLimitService{}
+}
+
+// IsOpen 是否开启请求限流
+func (s *RateLimitService) IsOpen() bool {
+	return app.ConfigYml.GetBool("ratelimit.open")
+}
+
+// Rule 获取请求适用的限流规则：匹配的路由规则，未匹配时为全局默认规则
+func (s *RateLimitService) Rule(method, path string) *ratelimithelper.Rule {
+	config := s.config()
+	if rule := ratelimithelper.MatchRule(config.rules, method, path); rule != nil {
+		return rule
+	}
+	return config.def
+}
+
+// Reload 重新解析限流规则，配置文件变化后调用
+func (s *RateLimitService) Reload() {
+	rateLimitRules.Store(s.parse())
+}
+
+// config 获取解析后的限流配置，尚未解析时解析配置
+func (s *RateLimitService) config() *rateLimitConfig {
+	if config := rateLimitRules.Load(); config != nil {
+		return config
+	}
+	config := s.parse()
+	rateLimitRules.Store(config)
+	return config
+}
+
+// parse 解析限流配置，路由规则排序后匹配结果与配置顺序无关
+func (s *RateLimitService) parse() *rateLimitConfig {
+	rules := s.rules()
+	ratelimithelper.SortRules(rules)
+	return &rateLimitConfig{
+		rules: rules,
+		def: &ratelimithelper.Rule{
+			Name:   "default",
+			Window: s.window("ratelimit.window"),
+			IP:     app.ConfigYml.GetInt("ratelimit.ip"),
+			User:   app.ConfigYml.GetInt("ratelimit.user"),
+			Tenant: app.ConfigYml.GetInt("ratelimit.tenant"),
+		},
+	}
+}
+
+// Allow 记录一次请求并检查规则在指定维度的限制，id 为IP、用户ID或租户ID
+func (s *RateLimitService) Allow(c context.Context, rule *ratelimithelper.Rule, dimension, id string) (bool, time.Duration, error) {
+	limit := rule.Limit(dimension)
+	if limit <= 0 {
+		return true, 0, nil
+	}
+	return ratelimithelper.NewLimiter(app.Cache).Allow(c, rule.Name+":"+dimension+":"+id, limit, rule.Window)
+}
+
+// rules 从配置读取路由限流规则
+func (s *RateLimitService) rules() []*ratelimithelper.Rule {
+	configs, _ := app.ConfigYml.Get("ratelimit.rules").(map[string]interface{})
+	rules := make([]*ratelimithelper.Rule, 0, len(configs))
+	for name := range configs {
+		key := "ratelimit.rules." + name + "."
+		// 规则未配置时间窗口时使用全局时间窗口
+		window := s.window("ratelimit.window")
+		if app.ConfigYml.GetInt(key+"window") > 0 {
+			window = s.window(key + "window")
+		}
+		rules = append(rules, &ratelimithelper.Rule{
+			Name:    name,
+			Path:    app.ConfigYml.GetString(key + "path"),
+			Methods: app.ConfigYml.GetStringSlice(key + "methods"),
+			Window:  window,
+			IP:      app.ConfigYml.GetInt(key + "ip"),
+			User:    app.ConfigYml.GetInt(key + "user"),
+			Tenant:  app.ConfigYml.GetInt(key + "tenant"),
+		})
+	}
+	return rules
+}
+
+// window 读取时间窗口配置(单位秒)，未配置时为60秒
+func (s *RateLimitService) window(key string) time.Duration {
+	window := app.ConfigYml.GetInt(key)
+	if window <= 0 {
+		window = 60
+	}
+	return time.Duration(window) * time.Second
+}
@@ -0,0 +1,849 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin-fast/app/global/app"
+	"gin-fast/app/models"
+	"gin-fast/app/utils/casbinhelper"
+	"gin-fast/app/utils/common"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RbacBundleVersion 当前权限配置包的版本，结构不兼容时递增
+const RbacBundleVersion = 1
+
+// 导入变更类型
+const (
+	RbacActionCreate = "create"
+	RbacActionUpdate = "update"
+)
+
+// errRbacDryRun 预览导入时用于回滚事务
+var errRbacDryRun = errors.New("rbac import dry run")
+
+// RbacBundle 权限配置包，用于在不同环境之间迁移角色、菜单、API及其关联关系
+// 记录之间通过自然键关联：API为"请求方法 路径"，菜单为路径(按钮为权限标识)，角色为名称
+type RbacBundle struct {
+	Version    int         `json:"version"`    // 配置包版本
+	ExportedAt time.Time   `json:"exportedAt"` // 导出时间
+	Apis       []*RbacApi  `json:"apis"`       // API
+	Menus      []*RbacMenu `json:"menus"`      // 菜单，父级菜单在子菜单之前
+	Roles      []*RbacRole `json:"roles"`      // 当前租户的角色，父级角色在子角色之前
+}
+
+// RbacApi 配置包中的API
+type RbacApi struct {
+	Path     string `json:"path"`
+	Method   string `json:"method"`
+	Title    string `json:"title"`
+	ApiGroup string `json:"apiGroup"`
+}
+
+// RbacMenu 配置包中的菜单
+type RbacMenu struct {
+	Key        string   `json:"key"`    // 匹配键：菜单路径，按钮为权限标识
+	Parent     string   `json:"parent"` // 父级菜单的匹配键，顶层为空
+	Path       string   `json:"path"`
+	Name       string   `json:"name"`
+	Component  string   `json:"component"`
+	Title      string   `json:"title"`
+	IsFull     bool     `json:"isFull"`
+	Hide       bool     `json:"hide"`
+	Disable    bool     `json:"disable"`
+	KeepAlive  bool     `json:"keepAlive"`
+	Affix      bool     `json:"affix"`
+	Redirect   string   `json:"redirect"`
+	IsLink     bool     `json:"isLink"`
+	Link       string   `json:"link"`
+	Iframe     bool     `json:"iframe"`
+	SvgIcon    string   `json:"svgIcon"`
+	Icon       string   `json:"icon"`
+	Sort       int      `json:"sort"`
+	Type       int8     `json:"type"`
+	Permission string   `json:"permission"`
+	Apis       []string `json:"apis"` // 关联的API，格式为"请求方法 路径"
+}
+
+// RbacRole 配置包中的角色
+type RbacRole struct {
+	Name         string                      `json:"name"`
+	Parent       string                      `json:"parent"` // 父级角色名称，顶层为空
+	Sort         int                         `json:"sort"`
+	Status       int8                        `json:"status"`
+	Description  string                      `json:"description"`
+	DataScope    int8                        `json:"dataScope"`
+	CheckedDepts []string                    `json:"checkedDepts"` // 自定义数据权限的部门名称
+	Menus        []string                    `json:"menus"`        // 分配的菜单匹配键
+	Policies     [][]string                  `json:"policies"`     // Casbin 权限策略 [路径, 请求方法]
+	Conditions   []app.CasbinPolicyCondition `json:"conditions"`   // 权限策略上的ABAC条件，导入时替换角色已有的条件
+}
+
+// RbacDiff 导入变更，只包含新增及有变化的记录
+type RbacDiff struct {
+	Apis     []*RbacChange `json:"apis"`
+	Menus    []*RbacChange `json:"menus"`
+	Roles    []*RbacChange `json:"roles"`
+	Warnings []string      `json:"warnings"` // 无法匹配而被忽略的关联
+}
+
+// RbacChange 单条记录的变更
+type RbacChange struct {
+	Key    string   `json:"key"`              // 自然键
+	Action string   `json:"action"`           // create 新增，update 更新
+	Fields []string `json:"fields,omitempty"` // 更新的字段
+}
+
+// IsEmpty 是否没有任何变更
+func (d *RbacDiff) IsEmpty() bool {
+	return len(d.Apis) == 0 && len(d.Menus) == 0 && len(d.Roles) == 0
+}
+
+// RbacService 权限配置导入导出服务
+// 菜单及API为全局数据，只有平台(租户ID为0)导入时才新增或更新；角色、角色菜单及 Casbin 策略属于当前租户
+type RbacService struct {
+	CasbinService *PermissionService
+}
+
+// NewRbacService 创建权限配置导入导出服务
+func NewRbacService() *RbacService {
+	return &RbacService{
+		CasbinService: NewPermissionService(),
+	}
+}
+
+// Export 导出当前租户的权限配置
+func (s *RbacService) Export(c *gin.Context) (*RbacBundle, error) {
+	tenantID := common.GetCurrentTenantID(c)
+	bundle := &RbacBundle{Version: RbacBundleVersion, ExportedAt: time.Now()}
+
+	apis := models.NewSysApiList()
+	if err := apis.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Order("path, method")
+	}); err != nil {
+		return nil, err
+	}
+	for _, api := range apis {
+		bundle.Apis = append(bundle.Apis, &RbacApi{Path: api.Path, Method: api.Method, Title: api.Title, ApiGroup: api.ApiGroup})
+	}
+
+	menus := models.NewSysMenuList()
+	if err := menus.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Apis").Order("sort, id")
+	}); err != nil {
+		return nil, err
+	}
+	menuKeys := make(map[uint]string, len(menus))
+	for _, menu := range menus {
+		menuKeys[menu.ID] = s.menuKey(menu.Path, menu.Permission)
+	}
+	for _, menu := range treeOrder(menus, func(m *models.SysMenu) (uint, uint) { return m.ID, m.ParentID }) {
+		item := &RbacMenu{
+			Key: menuKeys[menu.ID], Parent: menuKeys[menu.ParentID],
+			Path: menu.Path, Name: menu.Name, Component: menu.Component, Title: menu.Title,
+			IsFull: menu.IsFull, Hide: menu.Hide, Disable: menu.Disable, KeepAlive: menu.KeepAlive, Affix: menu.Affix,
+			Redirect: menu.Redirect, IsLink: menu.IsLink, Link: menu.Link, Iframe: menu.Iframe,
+			SvgIcon: menu.SvgIcon, Icon: menu.Icon, Sort: menu.Sort, Type: menu.Type, Permission: menu.Permission,
+			Apis: []string{},
+		}
+		for _, api := range menu.Apis {
+			item.Apis = append(item.Apis, s.apiKey(api.Method, api.Path))
+		}
+		sort.Strings(item.Apis)
+		bundle.Menus = append(bundle.Menus, item)
+	}
+
+	roles := models.NewSysRoleList()
+	if err := roles.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("tenant_id = ?", tenantID).Order("sort, id")
+	}); err != nil {
+		return nil, err
+	}
+	roleMenus, err := s.roleMenus(app.DB().WithContext(c), roles.GetRoleIDs())
+	if err != nil {
+		return nil, err
+	}
+	depts, err := s.departments(c, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	deptNames := make(map[uint]string, len(depts))
+	for name, id := range depts {
+		deptNames[id] = name
+	}
+	roleNames := make(map[uint]string, len(roles))
+	for _, role := range roles {
+		roleNames[role.ID] = role.Name
+	}
+	domain := s.CasbinService.GetDomain(c)
+	for _, role := range treeOrder(roles, func(r *models.SysRole) (uint, uint) { return r.ID, r.ParentID }) {
+		item := &RbacRole{
+			Name: role.Name, Parent: roleNames[role.ParentID], Sort: role.Sort, Status: role.Status,
+			Description: role.Description, DataScope: role.DataScope,
+			CheckedDepts: []string{}, Menus: []string{},
+		}
+		for _, id := range s.parseIDs(role.CheckedDepts) {
+			if name, ok := deptNames[id]; ok {
+				item.CheckedDepts = append(item.CheckedDepts, name)
+			}
+		}
+		for _, menuID := range roleMenus[role.ID] {
+			if key, ok := menuKeys[menuID]; ok {
+				item.Menus = append(item.Menus, key)
+			}
+		}
+		sort.Strings(item.Menus)
+		if item.Policies, err = app.CasbinV2.GetPoliciesForRole(role.ID, domain...); err != nil {
+			return nil, err
+		}
+		s.sortPolicies(item.Policies)
+		if item.Conditions, err = app.CasbinV2.GetConditionsForRole(role.ID, domain...); err != nil {
+			return nil, err
+		}
+		bundle.Roles = append(bundle.Roles, item)
+	}
+	return bundle, nil
+}
+
+// Import 导入权限配置到当前租户，所有数据库变更在同一事务中完成，成功后批量更新 Casbin 策略
+// 记录按自然键匹配，存在则更新、不存在则新增，不删除配置包中没有的记录；租户导入时菜单及API只按自然键匹配已有记录，
+// 缺失或不一致的记录记入警告；
+// 配置包中的菜单API、角色菜单、角色策略及策略条件会替换已有的关联。预览时在事务中执行后回滚，返回将要产生的变更
+func (s *RbacService) Import(c *gin.Context, bundle *RbacBundle, dryRun bool) (*RbacDiff, error) {
+	if bundle.Version != RbacBundleVersion {
+		return nil, fmt.Errorf("不支持的配置包版本: %d", bundle.Version)
+	}
+	im := &rbacImporter{
+		service:  s,
+		c:        c,
+		tenantID: common.GetCurrentTenantID(c),
+		domain:   s.CasbinService.GetDomain(c),
+		diff:     &RbacDiff{Apis: []*RbacChange{}, Menus: []*RbacChange{}, Roles: []*RbacChange{}, Warnings: []string{}},
+	}
+	err := app.DB().WithContext(c).Transaction(func(tx *gorm.DB) error {
+		im.tx = tx
+		if err := im.importApis(bundle.Apis); err != nil {
+			return err
+		}
+		if err := im.importMenus(bundle.Menus); err != nil {
+			return err
+		}
+		if err := im.importRoles(bundle.Roles); err != nil {
+			return err
+		}
+		if dryRun {
+			return errRbacDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errRbacDryRun) {
+		return nil, err
+	}
+	if dryRun {
+		return im.diff, nil
+	}
+
+	// Casbin 不参与数据库事务，在提交后批量更新，失败时恢复原有策略
+	if len(im.changes) > 0 {
+		if err = app.CasbinV2.ApplyRoleChanges(im.changes, im.domain...); err != nil {
+			return nil, err
+		}
+	}
+	return im.diff, nil
+}
+
+// rbacImporter 单次导入的状态
+type rbacImporter struct {
+	service  *RbacService
+	c        *gin.Context
+	tx       *gorm.DB
+	tenantID uint
+	domain   []string
+	diff     *RbacDiff
+	apis     map[string]uint        // API自然键 -> ID
+	menus    map[string]uint        // 菜单匹配键 -> ID
+	changes  []app.CasbinRoleChange // 事务提交后需要同步到 Casbin 的角色变更
+}
+
+func (im *rbacImporter) warn(format string, args ...interface{}) {
+	im.diff.Warnings = append(im.diff.Warnings, fmt.Sprintf(format, args...))
+}
+
+// globalReadonly 是否不能修改全局的菜单及API，租户导入时只能匹配已有记录
+func (im *rbacImporter) globalReadonly() bool {
+	return im.tenantID != 0
+}
+
+func (im *rbacImporter) importApis(items []*RbacApi) error {
+	existing := models.NewSysApiList()
+	if err := im.tx.Find(&existing).Error; err != nil {
+		return err
+	}
+	im.apis = make(map[string]uint, len(existing)+len(items))
+	current := make(map[string]*models.SysApi, len(existing))
+	for _, api := range existing {
+		key := im.service.apiKey(api.Method, api.Path)
+		if _, ok := current[key]; !ok {
+			current[key] = api
+			im.apis[key] = api.ID
+		}
+	}
+
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		item.Method = strings.ToUpper(strings.TrimSpace(item.Method))
+		item.Path = strings.TrimSpace(item.Path)
+		if item.Method == "" || item.Path == "" {
+			return errors.New("API的请求方法及路径不能为空")
+		}
+		key := im.service.apiKey(item.Method, item.Path)
+		if seen[key] {
+			return fmt.Errorf("API %s 重复", key)
+		}
+		seen[key] = true
+
+		api, ok := current[key]
+		if !ok && im.globalReadonly() {
+			im.warn("API %s 不存在，租户导入不能新增API", key)
+			continue
+		}
+		if !ok {
+			api = &models.SysApi{Path: item.Path, Method: item.Method, Title: item.Title, ApiGroup: item.ApiGroup}
+			if err := im.tx.Omit(clause.Associations).Create(api).Error; err != nil {
+				return fmt.Errorf("创建API %s 失败: %w", key, err)
+			}
+			im.apis[key] = api.ID
+			im.diff.Apis = append(im.diff.Apis, &RbacChange{Key: key, Action: RbacActionCreate})
+			continue
+		}
+		fields := newRbacFields()
+		fields.set("title", "title", api.Title, item.Title)
+		fields.set("apiGroup", "api_group", api.ApiGroup, item.ApiGroup)
+		if im.globalReadonly() {
+			if fields.changed() {
+				im.warn("API %s 的字段 %s 与配置包不一致，租户导入不能修改API", key, strings.Join(fields.names, ", "))
+			}
+			continue
+		}
+		if err := fields.update(im.tx, &models.SysApi{}, api.ID); err != nil {
+			return fmt.Errorf("更新API %s 失败: %w", key, err)
+		}
+		if fields.changed() {
+			im.diff.Apis = append(im.diff.Apis, &RbacChange{Key: key, Action: RbacActionUpdate, Fields: fields.names})
+		}
+	}
+	return nil
+}
+
+func (im *rbacImporter) importMenus(items []*RbacMenu) error {
+	existing := models.NewSysMenuList()
+	if err := im.tx.Preload("Apis").Find(&existing).Error; err != nil {
+		return err
+	}
+	im.menus = make(map[string]uint, len(existing)+len(items))
+	current := make(map[string]*models.SysMenu, len(existing))
+	for _, menu := range existing {
+		key := im.service.menuKey(menu.Path, menu.Permission)
+		if _, ok := current[key]; key != "" && !ok {
+			current[key] = menu
+			im.menus[key] = menu.ID
+		}
+	}
+
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		key := im.service.menuKey(item.Path, item.Permission)
+		if key == "" || (item.Key != "" && key != item.Key) {
+			return fmt.Errorf("菜单 %s 的匹配键与路径及权限标识不一致", item.Title)
+		}
+		if seen[key] {
+			return fmt.Errorf("菜单 %s 重复", key)
+		}
+		seen[key] = true
+
+		menu, ok := current[key]
+		if !ok && im.globalReadonly() {
+			im.warn("菜单 %s 不存在，租户导入不能新增菜单", key)
+			continue
+		}
+
+		var parentID uint
+		if item.Parent != "" {
+			id, ok := im.menus[item.Parent]
+			if !ok && !im.globalReadonly() {
+				return fmt.Errorf("菜单 %s 的父级菜单 %s 不存在", key, item.Parent)
+			}
+			parentID = id
+		}
+		apiIDs := make([]uint, 0, len(item.Apis))
+		for _, apiKey := range item.Apis {
+			if id, ok := im.apis[apiKey]; ok {
+				apiIDs = append(apiIDs, id)
+			} else {
+				im.warn("菜单 %s 关联的API %s 不存在", key, apiKey)
+			}
+		}
+
+		if !ok {
+			menu = &models.SysMenu{
+				ParentID: parentID, Path: item.Path, Name: item.Name, Component: item.Component, Title: item.Title,
+				IsFull: item.IsFull, Hide: item.Hide, Disable: item.Disable, KeepAlive: item.KeepAlive, Affix: item.Affix,
+				Redirect: item.Redirect, IsLink: item.IsLink, Link: item.Link, Iframe: item.Iframe,
+				SvgIcon: item.SvgIcon, Icon: item.Icon, Sort: item.Sort, Type: item.Type, Permission: item.Permission,
+			}
+			if err := im.tx.Omit(clause.Associations).Create(menu).Error; err != nil {
+				return fmt.Errorf("创建菜单 %s 失败: %w", key, err)
+			}
+			im.menus[key] = menu.ID
+			if err := im.replaceMenuApis(menu.ID, apiIDs); err != nil {
+				return err
+			}
+			im.diff.Menus = append(im.diff.Menus, &RbacChange{Key: key, Action: RbacActionCreate})
+			continue
+		}
+
+		fields := newRbacFields()
+		fields.set("parentId", "parent_id", menu.ParentID, parentID)
+		fields.set("name", "name", menu.Name, item.Name)
+		fields.set("component", "component", menu.Component, item.Component)
+		fields.set("title", "title", menu.Title, item.Title)
+		fields.set("isFull", "is_full", menu.IsFull, item.IsFull)
+		fields.set("hide", "hide", menu.Hide, item.Hide)
+		fields.set("disable", "disable", menu.Disable, item.Disable)
+		fields.set("keepAlive", "keep_alive", menu.KeepAlive, item.KeepAlive)
+		fields.set("affix", "affix", menu.Affix, item.Affix)
+		fields.set("redirect", "redirect", menu.Redirect, item.Redirect)
+		fields.set("isLink", "is_link", menu.IsLink, item.IsLink)
+		fields.set("link", "link", menu.Link, item.Link)
+		fields.set("iframe", "iframe", menu.Iframe, item.Iframe)
+		fields.set("svgIcon", "svg_icon", menu.SvgIcon, item.SvgIcon)
+		fields.set("icon", "icon", menu.Icon, item.Icon)
+		fields.set("sort", "sort", menu.Sort, item.Sort)
+		fields.set("type", "type", menu.Type, item.Type)
+		currentApiIDs := make([]uint, 0, len(menu.Apis))
+		for _, api := range menu.Apis {
+			currentApiIDs = append(currentApiIDs, api.ID)
+		}
+		apisChanged := !sameIDs(currentApiIDs, apiIDs)
+		if im.globalReadonly() {
+			if apisChanged {
+				fields.names = append(fields.names, "apis")
+			}
+			if fields.changed() {
+				im.warn("菜单 %s 的字段 %s 与配置包不一致，租户导入不能修改菜单", key, strings.Join(fields.names, ", "))
+			}
+			continue
+		}
+		if err := fields.update(im.tx, &models.SysMenu{}, menu.ID); err != nil {
+			return fmt.Errorf("更新菜单 %s 失败: %w", key, err)
+		}
+		if apisChanged {
+			fields.names = append(fields.names, "apis")
+			if err := im.replaceMenuApis(menu.ID, apiIDs); err != nil {
+				return err
+			}
+		}
+		if fields.changed() {
+			im.diff.Menus = append(im.diff.Menus, &RbacChange{Key: key, Action: RbacActionUpdate, Fields: fields.names})
+		}
+	}
+	return nil
+}
+
+func (im *rbacImporter) replaceMenuApis(menuID uint, apiIDs []uint) error {
+	if err := im.tx.Where("menu_id = ?", menuID).Delete(&models.SysMenuApi{}).Error; err != nil {
+		return err
+	}
+	if len(apiIDs) == 0 {
+		return nil
+	}
+	menuApis := make([]*models.SysMenuApi, len(apiIDs))
+	for i, apiID := range apiIDs {
+		menuApis[i] = &models.SysMenuApi{MenuID: menuID, ApiID: apiID}
+	}
+	return im.tx.CreateInBatches(menuApis, 100).Error
+}
+
+func (im *rbacImporter) importRoles(items []*RbacRole) error {
+	existing := models.NewSysRoleList()
+	if err := im.tx.Where("tenant_id = ?", im.tenantID).Find(&existing).Error; err != nil {
+		return err
+	}
+	roles := make(map[string]uint, len(existing)+len(items))
+	current := make(map[string]*models.SysRole, len(existing))
+	for _, role := range existing {
+		if _, ok := current[role.Name]; !ok {
+			current[role.Name] = role
+			roles[role.Name] = role.ID
+		}
+	}
+	roleMenus, err := im.service.roleMenus(im.tx, existing.GetRoleIDs())
+	if err != nil {
+		return err
+	}
+	depts, err := im.service.departments(im.c, im.tenantID)
+	if err != nil {
+		return err
+	}
+
+	// 检查新增角色后是否超出租户角色数配额，名称重复的角色在下面报错，这里只计一次
+	created := make(map[string]bool, len(items))
+	for _, item := range items {
+		name := strings.TrimSpace(item.Name)
+		if _, ok := current[name]; !ok && name != "" {
+			created[name] = true
+		}
+	}
+	if err := NewTenantQuotaService().CheckRoles(im.tx, im.tenantID, len(created)); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		item.Name = strings.TrimSpace(item.Name)
+		if item.Name == "" {
+			return errors.New("角色名称不能为空")
+		}
+		if seen[item.Name] {
+			return fmt.Errorf("角色 %s 重复", item.Name)
+		}
+		seen[item.Name] = true
+
+		var parentID uint
+		if item.Parent != "" {
+			id, ok := roles[item.Parent]
+			if !ok {
+				return fmt.Errorf("角色 %s 的父级角色 %s 不存在", item.Name, item.Parent)
+			}
+			parentID = id
+		}
+		deptIDs := make([]string, 0, len(item.CheckedDepts))
+		for _, name := range item.CheckedDepts {
+			if id, ok := depts[name]; ok {
+				deptIDs = append(deptIDs, strconv.FormatUint(uint64(id), 10))
+			} else {
+				im.warn("角色 %s 数据权限中的部门 %s 不存在", item.Name, name)
+			}
+		}
+		checkedDepts := strings.Join(deptIDs, ",")
+		menuIDs := make([]uint, 0, len(item.Menus))
+		for _, key := range item.Menus {
+			if id, ok := im.menus[key]; ok {
+				menuIDs = append(menuIDs, id)
+			} else {
+				im.warn("角色 %s 分配的菜单 %s 不存在", item.Name, key)
+			}
+		}
+		policies := im.service.uniquePolicies(item.Policies)
+		conditions, err := im.conditions(item, policies)
+		if err != nil {
+			return err
+		}
+
+		role, ok := current[item.Name]
+		if !ok {
+			role = &models.SysRole{
+				Name: item.Name, ParentID: parentID, Sort: item.Sort, Status: item.Status, Description: item.Description,
+				DataScope: item.DataScope, CheckedDepts: checkedDepts, TenantID: im.tenantID,
+			}
+			if err := im.tx.Omit(clause.Associations).Create(role).Error; err != nil {
+				return fmt.Errorf("创建角色 %s 失败: %w", item.Name, err)
+			}
+			roles[item.Name] = role.ID
+			if err := im.replaceRoleMenus(role.ID, menuIDs); err != nil {
+				return err
+			}
+			im.syncRole(role.ID, parentID, true, policies, conditions)
+			im.diff.Roles = append(im.diff.Roles, &RbacChange{Key: item.Name, Action: RbacActionCreate})
+			continue
+		}
+
+		fields := newRbacFields()
+		fields.set("parentId", "parent_id", role.ParentID, parentID)
+		fields.set("sort", "sort", role.Sort, item.Sort)
+		fields.set("status", "status", role.Status, item.Status)
+		fields.set("description", "description", role.Description, item.Description)
+		fields.set("dataScope", "data_scope", role.DataScope, item.DataScope)
+		if !sameIDs(im.service.parseIDs(role.CheckedDepts), im.service.parseIDs(checkedDepts)) {
+			fields.set("checkedDepts", "checked_depts", role.CheckedDepts, checkedDepts)
+		}
+		parentChanged := role.ParentID != parentID
+		if err := fields.update(im.tx, &models.SysRole{}, role.ID); err != nil {
+			return fmt.Errorf("更新角色 %s 失败: %w", item.Name, err)
+		}
+		if !sameIDs(roleMenus[role.ID], menuIDs) {
+			fields.names = append(fields.names, "menus")
+			if err := im.replaceRoleMenus(role.ID, menuIDs); err != nil {
+				return err
+			}
+		}
+		currentPolicies, err := app.CasbinV2.GetPoliciesForRole(role.ID, im.domain...)
+		if err != nil {
+			return err
+		}
+		policiesChanged := !im.service.samePolicies(currentPolicies, policies)
+		if policiesChanged {
+			fields.names = append(fields.names, "policies")
+		}
+		currentConditions, err := app.CasbinV2.GetConditionsForRole(role.ID, im.domain...)
+		if err != nil {
+			return err
+		}
+		conditionsChanged := !im.service.sameConditions(currentConditions, conditions)
+		if conditionsChanged {
+			fields.names = append(fields.names, "conditions")
+		}
+		if parentChanged || policiesChanged || conditionsChanged {
+			im.syncRole(role.ID, parentID, parentChanged, policiesIf(policiesChanged, policies), conditionsIf(conditionsChanged, conditions))
+		}
+		if fields.changed() {
+			im.diff.Roles = append(im.diff.Roles, &RbacChange{Key: item.Name, Action: RbacActionUpdate, Fields: fields.names})
+		}
+	}
+	return nil
+}
+
+func (im *rbacImporter) replaceRoleMenus(roleID uint, menuIDs []uint) error {
+	if err := im.tx.Where("role_id = ?", roleID).Delete(&models.SysRoleMenu{}).Error; err != nil {
+		return err
+	}
+	if len(menuIDs) == 0 {
+		return nil
+	}
+	roleMenus := make([]*models.SysRoleMenu, len(menuIDs))
+	for i, menuID := range menuIDs {
+		roleMenus[i] = &models.SysRoleMenu{RoleID: roleID, MenuID: menuID}
+	}
+	return im.tx.CreateInBatches(roleMenus, 100).Error
+}
+
+// conditions 校验配置包中角色的ABAC条件，忽略空条件及不属于角色策略的条件
+func (im *rbacImporter) conditions(item *RbacRole, policies [][]string) ([]app.CasbinPolicyCondition, error) {
+	granted := make(map[string]bool, len(policies))
+	for _, policy := range policies {
+		granted[policy[0]+"|"+policy[1]] = true
+	}
+	res := []app.CasbinPolicyCondition{}
+	for _, cond := range item.Conditions {
+		if cond.Condition.IsEmpty() {
+			continue
+		}
+		if !granted[cond.Obj+"|"+cond.Act] {
+			im.warn("角色 %s 的条件 %s %s 没有对应的权限策略", item.Name, cond.Act, cond.Obj)
+			continue
+		}
+		if err := casbinhelper.ValidateCondition(cond.Condition); err != nil {
+			return nil, fmt.Errorf("角色 %s 的条件 %s %s 无效: %v", item.Name, cond.Act, cond.Obj, err)
+		}
+		res = append(res, cond)
+	}
+	return res, nil
+}
+
+// syncRole 记录事务提交后需要同步到 Casbin 的角色继承关系、权限条件及权限策略
+// policies 为 nil 时不修改策略，conditions 为 nil 时不修改条件
+func (im *rbacImporter) syncRole(roleID, parentID uint, parentChanged bool, policies [][]string, conditions []app.CasbinPolicyCondition) {
+	im.changes = append(im.changes, app.CasbinRoleChange{
+		RoleID:        roleID,
+		Policies:      policies,
+		ParentChanged: parentChanged,
+		ParentID:      parentID,
+		Conditions:    conditions,
+	})
+}
+
+func policiesIf(changed bool, policies [][]string) [][]string {
+	if !changed {
+		return nil
+	}
+	return policies
+}
+
+func conditionsIf(changed bool, conditions []app.CasbinPolicyCondition) []app.CasbinPolicyCondition {
+	if !changed {
+		return nil
+	}
+	return conditions
+}
+
+// rbacFields 记录变更的字段
+type rbacFields struct {
+	names   []string               // 变更的字段(json名称)
+	columns map[string]interface{} // 变更的列及新值
+}
+
+func newRbacFields() *rbacFields {
+	return &rbacFields{names: []string{}, columns: map[string]interface{}{}}
+}
+
+func (f *rbacFields) set(name, column string, oldValue, newValue interface{}) {
+	if oldValue != newValue {
+		f.names = append(f.names, name)
+		f.columns[column] = newValue
+	}
+}
+
+func (f *rbacFields) changed() bool {
+	return len(f.names) > 0
+}
+
+func (f *rbacFields) update(tx *gorm.DB, model interface{}, id uint) error {
+	if len(f.columns) == 0 {
+		return nil
+	}
+	return tx.Model(model).Where("id = ?", id).Updates(f.columns).Error
+}
+
+func (s *RbacService) apiKey(method, path string) string {
+	return method + " " + path
+}
+
+// menuKey 菜单匹配键，按钮没有路径时使用权限标识
+func (s *RbacService) menuKey(path, permission string) string {
+	if path != "" {
+		return path
+	}
+	return permission
+}
+
+// treeOrder 按树的先序排列节点，保证父节点在子节点之前，父节点不存在的作为顶层
+func treeOrder[T any](items []T, node func(T) (id, parentID uint)) []T {
+	exists := make(map[uint]bool, len(items))
+	for _, item := range items {
+		id, _ := node(item)
+		exists[id] = true
+	}
+	children := make(map[uint][]T)
+	for _, item := range items {
+		id, parentID := node(item)
+		if !exists[parentID] || parentID == id {
+			parentID = 0
+		}
+		children[parentID] = append(children[parentID], item)
+	}
+	res := make([]T, 0, len(items))
+	var walk func(parentID uint)
+	walk = func(parentID uint) {
+		for _, item := range children[parentID] {
+			res = append(res, item)
+			id, _ := node(item)
+			walk(id)
+		}
+	}
+	walk(0)
+	return res
+}
+
+// roleMenus 获取角色分配的菜单ID
+func (s *RbacService) roleMenus(db *gorm.DB, roleIDs []uint) (map[uint][]uint, error) {
+	res := make(map[uint][]uint, len(roleIDs))
+	if len(roleIDs) == 0 {
+		return res, nil
+	}
+	list := models.NewSysRoleMenuList()
+	if err := db.Where("role_id IN ?", roleIDs).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for _, roleMenu := range list {
+		res[roleMenu.RoleID] = append(res[roleMenu.RoleID], roleMenu.MenuID)
+	}
+	return res, nil
+}
+
+// departments 获取租户下部门名称到ID的映射，重名的部门取第一个
+func (s *RbacService) departments(c *gin.Context, tenantID uint) (map[string]uint, error) {
+	list := models.NewSysDepartmentList()
+	if err := list.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("tenant_id = ?", tenantID).Order("id")
+	}); err != nil {
+		return nil, err
+	}
+	res := make(map[string]uint, len(list))
+	for _, dept := range list {
+		if _, ok := res[dept.Name]; !ok {
+			res[dept.Name] = dept.ID
+		}
+	}
+	return res, nil
+}
+
+func (s *RbacService) parseIDs(str string) []uint {
+	var ids []uint
+	for _, part := range strings.Split(str, ",") {
+		if id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+func (s *RbacService) uniquePolicies(policies [][]string) [][]string {
+	seen := make(map[string]bool, len(policies))
+	res := make([][]string, 0, len(policies))
+	for _, policy := range policies {
+		if len(policy) < 2 {
+			continue
+		}
+		key := policy[0] + "|" + policy[1]
+		if !seen[key] {
+			seen[key] = true
+			res = append(res, []string{policy[0], policy[1]})
+		}
+	}
+	s.sortPolicies(res)
+	return res
+}
+
+func (s *RbacService) sortPolicies(policies [][]string) {
+	sort.Slice(policies, func(i, j int) bool {
+		return slices.Compare(policies[i], policies[j]) < 0
+	})
+}
+
+func (s *RbacService) samePolicies(a, b [][]string) bool {
+	a = s.uniquePolicies(a)
+	b = s.uniquePolicies(b)
+	return slices.EqualFunc(a, b, slices.Equal[[]string])
+}
+
+// sameConditions 两组条件按策略比较是否相同，忽略顺序及空条件
+func (s *RbacService) sameConditions(a, b []app.CasbinPolicyCondition) bool {
+	index := func(conditions []app.CasbinPolicyCondition) map[string]string {
+		res := make(map[string]string, len(conditions))
+		for _, item := range conditions {
+			if item.Condition.IsEmpty() {
+				continue
+			}
+			data, _ := json.Marshal(item.Condition)
+			res[item.Obj+"|"+item.Act] = string(data)
+		}
+		return res
+	}
+	return maps.Equal(index(a), index(b))
+}
+
+// sameIDs 两组ID去重后是否相同
+func sameIDs(a, b []uint) bool {
+	set := make(map[uint]bool, len(a))
+	for _, id := range a {
+		set[id] = true
+	}
+	other := make(map[uint]bool, len(b))
+	for _, id := range b {
+		if !set[id] {
+			return false
+		}
+		other[id] = true
+	}
+	return len(set) == len(other)
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"gin-fast/app/global/app"
+	"gin-fast/app/models"
+	"gin-fast/app/utils/common"
+	"gin-fast/app/utils/datascope"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// RoleGrantService 限时角色授权服务
+// 限时授权保存在 sys_user_role 的 valid_from/valid_until 中，Casbin 的 g 关系只在授权生效期间存在，
+// 由后台定时任务在到达生效时间时添加、到达失效时间时回收。多实例同时运行时结果一致
+type RoleGrantService struct{}
+
+// NewRoleGrantService 创建限时角色授权服务
+func NewRoleGrantService() *RoleGrantService {
+	return &RoleGrantService{}
+}
+
+// Grant 为用户授予限时角色，已有的授权(包括长期授权)将被替换
+func (s *RoleGrantService) Grant(c context.Context, req *models.UserRoleGrantRequest) (*models.SysUserRole, error) {
+	user := models.NewUser()
+	if err := user.GetUserByID(c, req.UserID); err != nil {
+		return nil, err
+	}
+	if user.IsEmpty() {
+		return nil, errors.New("用户不存在")
+	}
+	role, err := s.role(c, req.RoleID)
+	if err != nil {
+		return nil, err
+	}
+
+	grant := &models.SysUserRole{
+		UserID:     req.UserID,
+		RoleID:     req.RoleID,
+		ValidFrom:  s.timePtr(req.ValidFrom),
+		ValidUntil: s.timePtr(req.ValidUntil),
+	}
+	err = app.DB().WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND role_id = ?", grant.UserID, grant.RoleID).Delete(&models.SysUserRole{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(grant).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 未到生效时间的授权由定时任务添加，此处移除原有的长期授权
+	domain := s.domain(role)
+	if grant.IsActive(time.Now()) {
+		err = app.CasbinV2.AddRolesForUserByID(grant.UserID, []uint{grant.RoleID}, domain...)
+	} else {
+		err = app.CasbinV2.DeleteRolesForUserByID(grant.UserID, []uint{grant.RoleID}, domain...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	grant.Role = role
+	return grant, nil
+}
+
+// Revoke 撤销用户的限时角色授权
+func (s *RoleGrantService) Revoke(c context.Context, userID, roleID uint) error {
+	grant := models.NewSysUserRole()
+	if err := grant.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Role").Where("user_id = ? AND role_id = ?", userID, roleID)
+	}); err != nil {
+		return err
+	}
+	if grant.IsEmpty() || !grant.IsTimeBound() {
+		return errors.New("限时授权不存在")
+	}
+	if err := app.DB().WithContext(c).Where("user_id = ? AND role_id = ?", userID, roleID).Delete(&models.SysUserRole{}).Error; err != nil {
+		return err
+	}
+	return app.CasbinV2.DeleteRolesForUserByID(userID, []uint{roleID}, s.domain(grant.Role)...)
+}
+
+// TimeBound 获取用户未过期的限时授权，按失效时间排序(长期生效的排在最后)
+func (s *RoleGrantService) TimeBound(c context.Context, userID uint) (models.SysUserRoleList, error) {
+	grants := models.NewSysUserRoleList()
+	err := grants.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Role").
+			Where("user_id = ? AND (valid_from IS NOT NULL OR valid_until IS NOT NULL)", userID).
+			Where("valid_until IS NULL OR valid_until > ?", time.Now()).
+			Order("CASE WHEN valid_until IS NULL THEN 1 ELSE 0 END, valid_until, valid_from")
+	})
+	return grants, err
+}
+
+// Permanent 从角色ID中排除已有限时授权的角色，得到需要长期授权的角色
+func (s *RoleGrantService) Permanent(roleIDs []uint, grants models.SysUserRoleList) []uint {
+	granted := make(map[uint]bool, len(grants))
+	for _, grant := range grants {
+		granted[grant.RoleID] = true
+	}
+	var res []uint
+	for _, roleID := range roleIDs {
+		if !granted[roleID] {
+			res = append(res, roleID)
+		}
+	}
+	return res
+}
+
+// ActiveRoleIDs 获取限时授权中当前生效的角色ID
+func (s *RoleGrantService) ActiveRoleIDs(grants models.SysUserRoleList) []uint {
+	now := time.Now()
+	var roleIDs []uint
+	for _, grant := range grants {
+		if grant.IsActive(now) {
+			roleIDs = append(roleIDs, grant.RoleID)
+		}
+	}
+	return roleIDs
+}
+
+// Sweep 回收已过期的授权，并为到达生效时间的授权添加角色关系
+func (s *RoleGrantService) Sweep(c context.Context) error {
+	now := time.Now()
+	expired := models.NewSysUserRoleList()
+	if err := expired.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Role").Where("valid_until IS NOT NULL AND valid_until <= ?", now)
+	}); err != nil {
+		return err
+	}
+	for _, grant := range expired {
+		// 先回收角色关系再删除授权记录，回收失败时下次继续处理
+		if err := app.CasbinV2.DeleteRolesForUserByID(grant.UserID, []uint{grant.RoleID}, s.domain(grant.Role)...); err != nil {
+			return err
+		}
+		err := app.DB().WithContext(c).
+			Where("user_id = ? AND role_id = ? AND valid_until <= ?", grant.UserID, grant.RoleID, now).
+			Delete(&models.SysUserRole{}).Error
+		if err != nil {
+			return err
+		}
+		app.ZapLog.Info("限时角色授权已过期",
+			zap.Uint("userId", grant.UserID),
+			zap.Uint("roleId", grant.RoleID),
+			zap.Time("validUntil", *grant.ValidUntil))
+	}
+
+	scheduled := models.NewSysUserRoleList()
+	if err := scheduled.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Role").Where("valid_from IS NOT NULL").Scopes(models.ActiveUserRoleScope(now))
+	}); err != nil {
+		return err
+	}
+	for _, grant := range scheduled {
+		domain := s.domain(grant.Role)
+		ok, err := app.CasbinV2.HasRoleForUser(grant.UserID, grant.RoleID, domain...)
+		if err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+		if err = app.CasbinV2.AddRolesForUserByID(grant.UserID, []uint{grant.RoleID}, domain...); err != nil {
+			return err
+		}
+		// 授权生效不修改数据，需手动清除数据权限缓存
+		datascope.Invalidate(c)
+		app.ZapLog.Info("限时角色授权已生效",
+			zap.Uint("userId", grant.UserID),
+			zap.Uint("roleId", grant.RoleID),
+			zap.Time("validFrom", *grant.ValidFrom))
+	}
+	return nil
+}
+
+// StartSweeper 启动定期处理限时授权的goroutine，间隔小于等于0时不启动
+func (s *RoleGrantService) StartSweeper() {
+	interval := app.ConfigYml.GetInt("rolegrant.sweepinterval")
+	if interval <= 0 {
+		app.ZapLog.Info("Role grant sweeper disabled")
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer ticker.Stop()
+		app.ZapLog.Info("Started role grant sweeper", zap.Int("interval_seconds", interval))
+		for {
+			if err := s.Sweep(context.Background()); err != nil {
+				app.ZapLog.Error("处理限时角色授权失败", zap.Error(err))
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// role 获取可授权的角色，租户下只能授予本租户的角色
+func (s *RoleGrantService) role(c context.Context, roleID uint) (*models.SysRole, error) {
+	role := models.NewSysRole()
+	if err := role.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("id = ?", roleID)
+	}); err != nil {
+		return nil, err
+	}
+	if role.IsEmpty() {
+		return nil, errors.New("角色不存在")
+	}
+	if tenantID := common.GetCurrentTenantID(common.TryConvertToGinContext(c)); tenantID > 0 && role.TenantID != tenantID {
+		return nil, errors.New("角色不存在")
+	}
+	return role, nil
+}
+
+// domain 角色关系所在的域，与分配角色时一致：租户角色在租户域下，全局角色在全局域下
+func (s *RoleGrantService) domain(role *models.SysRole) []string {
+	if role == nil || role.TenantID == 0 {
+		return nil
+	}
+	return []string{app.CasbinV2.PrefixDomain(role.TenantID)}
+}
+
+func (s *RoleGrantService) timePtr(t models.JSONTime) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	v := t.ToTime()
+	return &v
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"gin-fast/app/global/app"
+	"gin-fast/app/models"
+	"gin-fast/app/utils/common"
+	"gin-fast/app/utils/ginhelper"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ApiSyncDiff 路由表与API表的差异
+type ApiSyncDiff struct {
+	Added     models.SysApiList `json:"added"`     // 已注册但API表中不存在的路由
+	Updated   models.SysApiList `json:"updated"`   // 分组为空、将按路由补全分组的API
+	Stale     models.SysApiList `json:"stale"`     // 路由已不存在的API(含关联菜单，已关联菜单的API同步时不会删除)
+	Unchanged int               `json:"unchanged"` // 无变化的API数量
+}
+
+// ApiSyncResult 同步结果
+type ApiSyncResult struct {
+	*ApiSyncDiff
+	Removed models.SysApiList `json:"removed"` // 已删除的失效API
+}
+
+// SysApiService API管理服务
+type SysApiService struct{}
+
+// NewSysApiService 创建API管理服务
+func NewSysApiService() *SysApiService {
+	return &SysApiService{}
+}
+
+// Diff 比较已注册的路由与API表，路径参数名不同视为同一路由(如 /:id 与 /:roleId)
+func (s *SysApiService) Diff(c context.Context, routes gin.RoutesInfo) (*ApiSyncDiff, error) {
+	existing := models.NewSysApiList()
+	if err := existing.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("SysMenuList").Order("id")
+	}); err != nil {
+		return nil, err
+	}
+	existingMap := make(map[string]*models.SysApi, len(existing))
+	for _, api := range existing {
+		existingMap[s.key(api.Path, api.Method)] = api
+	}
+	groups := s.groups(existing)
+
+	diff := &ApiSyncDiff{Added: models.SysApiList{}, Updated: models.SysApiList{}, Stale: models.SysApiList{}}
+	routeMap := make(map[string]bool)
+	for _, route := range routes {
+		if !s.isSyncRoute(route.Path) {
+			continue
+		}
+		key := s.key(route.Path, route.Method)
+		if routeMap[key] {
+			continue
+		}
+		routeMap[key] = true
+
+		group := s.inferGroup(route.Path, groups)
+		api, ok := existingMap[key]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, &models.SysApi{
+				Title:    ginhelper.HandlerName(route.Handler),
+				Path:     route.Path,
+				Method:   route.Method,
+				ApiGroup: group,
+			})
+		case api.ApiGroup == "" && group != "":
+			api.ApiGroup = group
+			diff.Updated = append(diff.Updated, api)
+		default:
+			diff.Unchanged++
+		}
+	}
+	for _, api := range existing {
+		if s.isSyncRoute(api.Path) && !routeMap[s.key(api.Path, api.Method)] {
+			diff.Stale = append(diff.Stale, api)
+		}
+	}
+	sort.Slice(diff.Added, func(i, j int) bool {
+		if diff.Added[i].Path == diff.Added[j].Path {
+			return diff.Added[i].Method < diff.Added[j].Method
+		}
+		return diff.Added[i].Path < diff.Added[j].Path
+	})
+	return diff, nil
+}
+
+// Sync 按路由表同步API表：新增缺失的API、补全分组；removeStale 为true时删除未关联菜单的失效API
+func (s *SysApiService) Sync(c context.Context, routes gin.RoutesInfo, removeStale bool) (*ApiSyncResult, error) {
+	diff, err := s.Diff(c, routes)
+	if err != nil {
+		return nil, err
+	}
+	result := &ApiSyncResult{ApiSyncDiff: diff, Removed: models.SysApiList{}}
+	err = app.DB().WithContext(c).Transaction(func(tx *gorm.DB) error {
+		for _, api := range diff.Added {
+			if err := tx.Create(api).Error; err != nil {
+				return err
+			}
+		}
+		for _, api := range diff.Updated {
+			if err := tx.Model(&models.SysApi{}).Where("id = ?", api.ID).Update("api_group", api.ApiGroup).Error; err != nil {
+				return err
+			}
+		}
+		if !removeStale {
+			return nil
+		}
+		for _, api := range diff.Stale {
+			// 已关联菜单的API需先在菜单中取消关联，避免影响已分配的角色权限
+			if !api.SysMenuList.IsEmpty() {
+				continue
+			}
+			if err := tx.Where("id = ?", api.ID).Delete(&models.SysApi{}).Error; err != nil {
+				return err
+			}
+			result.Removed = append(result.Removed, api)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SyncOnStartup 启动时同步API表(仅新增及补全分组，失效API只记录日志)
+func (s *SysApiService) SyncOnStartup(routes gin.RoutesInfo) {
+	if !app.ConfigYml.GetBool("apisync.startup") {
+		return
+	}
+	result, err := s.Sync(context.Background(), routes, false)
+	if err != nil {
+		app.ZapLog.Error("同步API表失败", zap.Error(err))
+		return
+	}
+	app.ZapLog.Info("同步API表完成",
+		zap.Int("added", len(result.Added)),
+		zap.Int("updated", len(result.Updated)),
+		zap.Int("stale", len(result.Stale)))
+	for _, api := range result.Stale {
+		app.ZapLog.Warn("API对应的路由已不存在", zap.String("path", api.Path), zap.String("method", api.Method))
+	}
+}
+
+// isSyncRoute 是否为需要同步的路由
+func (s *SysApiService) isSyncRoute(path string) bool {
+	if !strings.HasPrefix(path, s.prefix()) {
+		return false
+	}
+	for _, exclude := range app.ConfigYml.GetStringSlice("apisync.excludes") {
+		if pattern, ok := strings.CutSuffix(exclude, "*"); ok {
+			if strings.HasPrefix(path, pattern) {
+				return false
+			}
+		} else if path == exclude {
+			return false
+		}
+	}
+	return true
+}
+
+// groups 统计API表中各路径及其上级目录下使用最多的分组
+func (s *SysApiService) groups(list models.SysApiList) map[string]string {
+	counts := make(map[string]map[string]int)
+	for _, api := range list {
+		if api.ApiGroup == "" {
+			continue
+		}
+		for dir := api.Path; dir != ""; dir = s.dir(dir) {
+			if counts[dir] == nil {
+				counts[dir] = make(map[string]int)
+			}
+			counts[dir][api.ApiGroup]++
+		}
+	}
+	groups := make(map[string]string, len(counts))
+	for dir, groupCounts := range counts {
+		var best string
+		for group, count := range groupCounts {
+			if count > groupCounts[best] || (count == groupCounts[best] && group < best) {
+				best = group
+			}
+		}
+		groups[dir] = best
+	}
+	return groups
+}
+
+// inferGroup 推断路由分组：优先使用API表中同一路由组(最近的上级目录)的分组，否则使用路由组路径
+// 如 /api/sysRole/explain 使用 /api/sysRole 下API的分组，无则为 sysRole
+func (s *SysApiService) inferGroup(path string, groups map[string]string) string {
+	prefix := strings.TrimSuffix(s.prefix(), "/")
+	for dir := s.dir(path); len(dir) > len(prefix); dir = s.dir(dir) {
+		if group, ok := groups[dir]; ok {
+			return group
+		}
+	}
+	group := strings.Trim(strings.TrimPrefix(s.dir(path), prefix), "/")
+	if group == "" {
+		group = strings.Trim(strings.TrimPrefix(path, prefix), "/")
+	}
+	return group
+}
+
+// prefix 需要同步的路由前缀
+func (s *SysApiService) prefix() string {
+	prefix := app.ConfigYml.GetString("apisync.prefix")
+	if prefix == "" {
+		prefix = "/api"
+	}
+	return prefix
+}
+
+// dir 获取路径的上级目录，/api/users/list 返回 /api/users
+func (s *SysApiService) dir(path string) string {
+	if i := strings.LastIndex(path, "/"); i > 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+func (s *SysApiService) key(path, method string) string {
+	return strings.ToUpper(method) + " " + common.ConvertPathToWildcard(path)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"gin-fast/app/global/app"
+	"gin-fast/app/global/consts"
+	"gin-fast/app/models"
+	"gin-fast/app/utils/tenanthelper"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TenantHostService 按请求域名识别租户
+// 域名匹配租户绑定域名(Domain)，或匹配 {租户编码}.{平台基础域名(PlatformDomain)}
+type TenantHostService struct {
+}
+
+// NewTenantHostService 创建域名识别租户服务
+func NewTenantHostService() *TenantHostService {
+	return &TenantHostService{}
+}
+
+// IsOpen 是否开启按域名识别租户
+func (s *TenantHostService) IsOpen() bool {
+	return app.ConfigYml.GetBool("tenanthost.open")
+}
+
+// Resolve 按请求Host查找租户(含未启用的租户)，未绑定租户时返回nil
+func (s *TenantHostService) Resolve(c context.Context, host string) (*models.Tenant, error) {
+	host = tenanthelper.NormalizeHost(host)
+	if host == "" {
+		return nil, nil
+	}
+
+	tenant := models.NewTenant()
+	data, err := app.Cache.Get(context.Background(), s.cacheKey(host))
+	if err != nil || data == "" || json.Unmarshal([]byte(data), tenant) != nil {
+		if tenant, err = s.load(c, host); err != nil {
+			return nil, err
+		}
+		// 未绑定租户的域名同样缓存，避免每次请求查询数据库
+		if data, err := json.Marshal(tenant); err == nil {
+			app.Cache.Set(context.Background(), s.cacheKey(host), string(data), s.cacheExpire())
+		}
+	}
+	if tenant.IsEmpty() {
+		return nil, nil
+	}
+	return tenant, nil
+}
+
+// HostTenant 获取中间件识别出的当前域名租户，未识别时返回nil
+func (s *TenantHostService) HostTenant(c *gin.Context) *models.Tenant {
+	value, exists := c.Get(consts.HostTenantContextKeyName)
+	if !exists {
+		return nil
+	}
+	tenant, _ := value.(*models.Tenant)
+	return tenant
+}
+
+// Invalidate 清除租户域名的识别缓存，租户新增、修改或删除后调用
+func (s *TenantHostService) Invalidate(tenants ...*models.Tenant) {
+	keys := []string{}
+	for _, tenant := range tenants {
+		if tenant == nil {
+			continue
+		}
+		if tenant.Domain != "" {
+			keys = append(keys, s.cacheKey(tenanthelper.NormalizeHost(tenant.Domain)))
+		}
+		if tenant.Code != "" && tenant.PlatformDomain != "" {
+			keys = append(keys, s.cacheKey(tenanthelper.NormalizeHost(tenant.Code+"."+tenant.PlatformDomain)))
+		}
+	}
+	if len(keys) > 0 {
+		app.Cache.Del(context.Background(), keys...)
+	}
+}
+
+// load 从数据库查找域名对应的租户，绑定域名优先于平台子域名
+func (s *TenantHostService) load(c context.Context, host string) (*models.Tenant, error) {
+	tenant := models.NewTenant()
+	err := tenant.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("domain = ?", host)
+	})
+	if err != nil || !tenant.IsEmpty() {
+		return tenant, err
+	}
+
+	code, platformDomain := tenanthelper.SplitSubdomain(host)
+	if code == "" {
+		return tenant, nil
+	}
+	err = tenant.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("code = ? AND platform_domain = ?", code, platformDomain)
+	})
+	return tenant, err
+}
+
+func (s *TenantHostService) cacheKey(host string) string {
+	return "tenant_host:" + host
+}
+
+func (s *TenantHostService) cacheExpire() time.Duration {
+	expire := app.ConfigYml.GetInt("tenanthost.cacheexpire")
+	if expire <= 0 {
+		expire = 60
+	}
+	return time.Duration(expire) * time.Second
+}
@@ -0,0 +1,434 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin-fast/app/global/app"
+	"gin-fast/app/global/consts"
+	"gin-fast/app/models"
+	"gin-fast/app/utils/common"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// TenantTemplate 租户初始化模板，新增租户时在同一事务中创建模板中的部门、角色、字典及管理员
+type TenantTemplate struct {
+	Name       string                    `json:"name"`       // 模板名称(配置中的键)
+	Title      string                    `json:"title"`      // 模板标题
+	Department *TenantTemplateDepartment `json:"department"` // 根部门
+	Roles      []*TenantTemplateRole     `json:"roles"`      // 角色，按顺序创建，父角色需在子角色之前
+	Dicts      []*TenantTemplateDict     `json:"dicts"`      // 字典
+	Admin      *TenantTemplateAdmin      `json:"admin"`      // 初始管理员
+}
+
+// TenantTemplateDepartment 模板根部门
+type TenantTemplateDepartment struct {
+	Name   string `json:"name"`
+	Leader string `json:"leader"`
+	Phone  string `json:"phone"`
+	Email  string `json:"email"`
+}
+
+// TenantTemplateRole 模板角色
+type TenantTemplateRole struct {
+	Key         string `json:"key"`         // 模板内角色标识，用于父角色及管理员角色引用
+	Name        string `json:"name"`        // 角色名称
+	Description string `json:"description"` // 描述
+	Parent      string `json:"parent"`      // 父角色标识
+	Sort        int    `json:"sort"`        // 排序
+	DataScope   int8   `json:"dataScope"`   // 数据权限
+	Menus       []uint `json:"menus"`       // 菜单ID，包含其下级菜单及按钮
+}
+
+// TenantTemplateDict 模板字典
+type TenantTemplateDict struct {
+	Code        string                    `json:"code"`
+	Name        string                    `json:"name"`
+	Description string                    `json:"description"`
+	Items       []*TenantTemplateDictItem `json:"items"`
+}
+
+// TenantTemplateDictItem 模板字典项
+type TenantTemplateDictItem struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// TenantTemplateAdmin 模板初始管理员
+type TenantTemplateAdmin struct {
+	Username string   `json:"username"` // 默认用户名，{code} 替换为租户编码
+	NickName string   `json:"nickName"` // 昵称
+	Roles    []string `json:"roles"`    // 角色标识
+}
+
+// TenantAdminAccount 新增租户时指定的初始管理员账号，未指定密码时按密码策略随机生成
+type TenantAdminAccount struct {
+	Username string
+	Password string
+	Email    string
+}
+
+// TenantProvisionResult 租户初始化结果
+type TenantProvisionResult struct {
+	*models.Tenant
+	Template      string `json:"template,omitempty"`      // 使用的模板
+	AdminUsername string `json:"adminUsername,omitempty"` // 初始管理员用户名
+	AdminPassword string `json:"adminPassword,omitempty"` // 随机生成的初始管理员密码(仅返回一次)
+}
+
+// TenantProvisionService 租户初始化服务
+// 模板来自配置 tenanttemplate.templates；字典为全局数据，模板中已存在的字典编码不会重复创建
+type TenantProvisionService struct {
+	CasbinService         *PermissionService
+	PasswordPolicyService *PasswordPolicyService
+}
+
+// NewTenantProvisionService 创建租户初始化服务
+func NewTenantProvisionService() *TenantProvisionService {
+	return &TenantProvisionService{
+		CasbinService:         NewPermissionService(),
+		PasswordPolicyService: NewPasswordPolicyService(),
+	}
+}
+
+// Templates 获取所有租户模板，按名称排序
+func (s *TenantProvisionService) Templates() ([]*TenantTemplate, error) {
+	configs, _ := app.ConfigYml.Get("tenanttemplate.templates").(map[string]interface{})
+	templates := make([]*TenantTemplate, 0, len(configs))
+	for name := range configs {
+		template, err := s.Template(name)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates, nil
+}
+
+// Template 获取租户模板，name 为空时使用默认模板；未配置默认模板时返回nil
+func (s *TenantProvisionService) Template(name string) (*TenantTemplate, error) {
+	if name == "" {
+		name = app.ConfigYml.GetString("tenanttemplate.default")
+		if name == "" {
+			return nil, nil
+		}
+	}
+	config := app.ConfigYml.Get("tenanttemplate.templates." + strings.ToLower(name))
+	if config == nil {
+		return nil, fmt.Errorf("租户模板 %s 不存在", name)
+	}
+	// 配置为嵌套的map及切片，经JSON转换为模板结构(字段名不区分大小写)
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	template := &TenantTemplate{}
+	if err = json.Unmarshal(data, template); err != nil {
+		return nil, fmt.Errorf("租户模板 %s 配置错误: %v", name, err)
+	}
+	template.Name = strings.ToLower(name)
+	return template, nil
+}
+
+// Create 创建租户并按模板初始化，templateName 为空时使用默认模板
+// 数据库操作在同一事务中完成，Casbin策略添加到 PrefixDomain(租户ID) 域下，任一步骤失败时回滚并清理该域的策略
+func (s *TenantProvisionService) Create(c *gin.Context, tenant *models.Tenant, templateName string, account *TenantAdminAccount) (*TenantProvisionResult, error) {
+	result := &TenantProvisionResult{Tenant: tenant}
+	template, err := s.Template(templateName)
+	if err != nil {
+		return nil, err
+	}
+	var menus models.SysMenuList
+	var admin *models.User
+	if template != nil {
+		result.Template = template.Name
+		if menus, err = s.check(c, template); err != nil {
+			return nil, err
+		}
+		if admin, result.AdminPassword, err = s.newAdmin(c, tenant, template, account); err != nil {
+			return nil, err
+		}
+		if admin != nil {
+			result.AdminUsername = admin.Username
+		}
+	}
+
+	err = app.DB().WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(tenant).Error; err != nil {
+			return err
+		}
+		if template == nil {
+			return nil
+		}
+		// 以新租户的身份写入数据，由GORM钩子填充租户ID及创建人
+		var operatorID uint
+		if claims := common.GetClaims(c); claims != nil {
+			operatorID = claims.UserID
+		}
+		ctx := context.WithValue(context.Background(), consts.BindContextKeyName, &app.Claims{
+			ClaimsUser: app.ClaimsUser{UserID: operatorID, TenantID: tenant.ID, TenantCode: tenant.Code},
+		})
+		return s.provision(ctx, tx.WithContext(ctx), tenant, template, menus, admin)
+	})
+	if err != nil {
+		if tenant.ID > 0 && template != nil {
+			if removeErr := app.CasbinV2.RemoveDomain(s.CasbinService.PrefixDomain(tenant.ID)); removeErr != nil {
+				app.ZapLog.Error("清理租户权限策略失败", zap.Uint("tenantId", tenant.ID), zap.Error(removeErr))
+			}
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// check 校验模板中的角色及菜单，返回所有菜单(含关联API)
+func (s *TenantProvisionService) check(c context.Context, template *TenantTemplate) (models.SysMenuList, error) {
+	keys := make(map[string]bool, len(template.Roles))
+	for _, role := range template.Roles {
+		if role.Key == "" || role.Name == "" {
+			return nil, errors.New("租户模板中的角色标识和名称不能为空")
+		}
+		if keys[role.Key] {
+			return nil, fmt.Errorf("租户模板中的角色 %s 重复", role.Key)
+		}
+		if role.Parent != "" && !keys[role.Parent] {
+			return nil, fmt.Errorf("租户模板中角色 %s 的父角色 %s 不存在或未在其之前定义", role.Key, role.Parent)
+		}
+		keys[role.Key] = true
+	}
+	if template.Admin != nil {
+		for _, key := range template.Admin.Roles {
+			if !keys[key] {
+				return nil, fmt.Errorf("租户模板中管理员的角色 %s 不存在", key)
+			}
+		}
+	}
+
+	menus := models.NewSysMenuList()
+	if err := menus.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Apis")
+	}); err != nil {
+		return nil, err
+	}
+	exists := make(map[uint]bool, len(menus))
+	for _, menu := range menus {
+		exists[menu.ID] = true
+	}
+	for _, role := range template.Roles {
+		for _, menuID := range role.Menus {
+			if !exists[menuID] {
+				return nil, fmt.Errorf("租户模板中角色 %s 的菜单ID %d 不存在", role.Key, menuID)
+			}
+		}
+	}
+	return menus, nil
+}
+
+// newAdmin 创建初始管理员(未保存)，按密码策略校验并加密密码；未指定密码时返回随机生成的密码
+func (s *TenantProvisionService) newAdmin(c context.Context, tenant *models.Tenant, template *TenantTemplate, account *TenantAdminAccount) (*models.User, string, error) {
+	if template.Admin == nil {
+		return nil, "", nil
+	}
+	if account == nil {
+		account = &TenantAdminAccount{}
+	}
+	username := account.Username
+	if username == "" {
+		username = strings.ReplaceAll(template.Admin.Username, "{code}", tenant.Code)
+	}
+	if username == "" {
+		return nil, "", errors.New("租户管理员用户名不能为空")
+	}
+
+	exist := models.NewUser()
+	if err := exist.GetUserByUsername(c, username); err != nil {
+		return nil, "", err
+	}
+	if !exist.IsEmpty() {
+		return nil, "", fmt.Errorf("租户管理员用户名 %s 已存在", username)
+	}
+	if account.Email != "" {
+		exist = models.NewUser()
+		if err := exist.GetUserByEmail(c, account.Email); err != nil {
+			return nil, "", err
+		}
+		if !exist.IsEmpty() {
+			return nil, "", errors.New("租户管理员邮箱已被其他用户使用")
+		}
+	}
+
+	// 新租户尚未设置自定义密码策略，按全局策略生成及校验
+	password, generated := account.Password, ""
+	if password == "" {
+		var err error
+		if password, err = s.PasswordPolicyService.Global().Generate(username); err != nil {
+			return nil, "", err
+		}
+		generated = password
+	}
+
+	admin := models.NewUser()
+	admin.Username = username
+	admin.NickName = template.Admin.NickName
+	admin.Email = account.Email
+	admin.Status = 1
+	if err := s.PasswordPolicyService.Apply(c, admin, password); err != nil {
+		return nil, "", err
+	}
+	return admin, generated, nil
+}
+
+// provision 在事务中按模板初始化租户数据及权限策略
+func (s *TenantProvisionService) provision(c context.Context, tx *gorm.DB, tenant *models.Tenant, template *TenantTemplate, menus models.SysMenuList, admin *models.User) error {
+	var deptID uint
+	if template.Department != nil && template.Department.Name != "" {
+		parentID, status, sort := uint(0), int8(1), 0
+		dept := &models.SysDepartment{
+			ParentID: &parentID,
+			Name:     template.Department.Name,
+			Status:   &status,
+			Leader:   template.Department.Leader,
+			Phone:    template.Department.Phone,
+			Email:    template.Department.Email,
+			Sort:     &sort,
+			TenantID: tenant.ID,
+		}
+		if err := tx.Create(dept).Error; err != nil {
+			return err
+		}
+		deptID = dept.ID
+	}
+
+	roleIDs := make(map[string]uint, len(template.Roles))
+	roleApis := make(map[uint]models.SysApiList, len(template.Roles))
+	for _, item := range template.Roles {
+		role := &models.SysRole{
+			Name:        item.Name,
+			Description: item.Description,
+			Sort:        item.Sort,
+			Status:      1,
+			ParentID:    roleIDs[item.Parent],
+			DataScope:   item.DataScope,
+			TenantID:    tenant.ID,
+		}
+		if err := tx.Create(role).Error; err != nil {
+			return err
+		}
+		roleIDs[item.Key] = role.ID
+
+		granted := menus.GetMenusWithChildern(item.Menus...)
+		granted = menus.GetMenusWithParents(granted.Map(func(m *models.SysMenu) uint { return m.ID })...)
+		if granted.IsEmpty() {
+			continue
+		}
+		roleMenus := make([]*models.SysRoleMenu, len(granted))
+		for i, menu := range granted {
+			roleMenus[i] = &models.SysRoleMenu{RoleID: role.ID, MenuID: menu.ID}
+		}
+		if err := tx.CreateInBatches(roleMenus, 100).Error; err != nil {
+			return err
+		}
+		roleApis[role.ID] = granted.GetApis().Unique()
+	}
+
+	for _, item := range template.Dicts {
+		if err := s.createDict(tx, item); err != nil {
+			return err
+		}
+	}
+
+	var adminRoleIDs []uint
+	if admin != nil {
+		admin.TenantID = tenant.ID
+		admin.DeptID = deptID
+		if err := tx.Create(admin).Error; err != nil {
+			return err
+		}
+		if err := s.PasswordPolicyService.Record(c, tx, admin); err != nil {
+			return err
+		}
+		for _, key := range template.Admin.Roles {
+			adminRoleIDs = append(adminRoleIDs, roleIDs[key])
+			if err := tx.Create(&models.SysUserRole{UserID: admin.ID, RoleID: roleIDs[key]}).Error; err != nil {
+				return err
+			}
+		}
+		err := tx.Create(&models.SysUserTenant{
+			UserID:    admin.ID,
+			TenantID:  tenant.ID,
+			IsDefault: true,
+			CreatedAt: time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	// 添加租户域下的权限策略，数据库事务回滚时由调用方清理
+	// 新租户的域应为空，先清除之前初始化失败且未清理干净时残留的策略及权限条件
+	if err := app.CasbinV2.RemoveDomain(s.CasbinService.PrefixDomain(tenant.ID)); err != nil {
+		return err
+	}
+	for _, item := range template.Roles {
+		roleID := roleIDs[item.Key]
+		if err := s.CasbinService.AddPoliciesForRole(c, roleID, roleApis[roleID], tenant.ID); err != nil {
+			return err
+		}
+		if item.Parent != "" {
+			if err := s.CasbinService.AddRoleInheritance(c, roleID, roleIDs[item.Parent], tenant.ID); err != nil {
+				return err
+			}
+		}
+	}
+	if len(adminRoleIDs) > 0 {
+		if err := s.CasbinService.AddRoleForUser(c, admin.ID, adminRoleIDs, tenant.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createDict 创建字典及字典项，字典编码已存在时跳过
+func (s *TenantProvisionService) createDict(tx *gorm.DB, item *TenantTemplateDict) error {
+	if item.Code == "" {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&models.SysDict{}).Where("code = ?", item.Code).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	status := int8(1)
+	dict := &models.SysDict{
+		Name:        &item.Name,
+		Code:        &item.Code,
+		Status:      &status,
+		Description: &item.Description,
+	}
+	if err := tx.Create(dict).Error; err != nil {
+		return err
+	}
+	for _, dictItem := range item.Items {
+		if err := tx.Create(&models.SysDictItem{
+			Name:   &dictItem.Name,
+			Value:  &dictItem.Value,
+			Status: &status,
+			DictID: &dict.ID,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
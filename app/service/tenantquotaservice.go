@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin-fast/app/global/app"
+	"gin-fast/app/models"
+	"gin-fast/app/utils/tenanthelper"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrTenantQuotaExceeded 超出租户配额，检查配额返回的错误可用 errors.Is 判断，错误信息为具体的提示
+var ErrTenantQuotaExceeded = errors.New("超出租户配额")
+
+// quotaError 超出租户配额的具体提示
+type quotaError string
+
+func (e quotaError) Error() string {
+	return string(e)
+}
+
+func (e quotaError) Is(target error) bool {
+	return target == ErrTenantQuotaExceeded
+}
+
+// TenantQuotaService 租户资源配额及用量统计
+// 配额保存在租户中(0表示不限制)，全局租户(租户ID为0)不限制；用户、角色及存储空间按数据库实时统计，API调用次数按日在缓存中计数
+// 检查用户、角色及存储空间配额时需与新增记录在同一事务中，检查时锁定租户记录直到事务结束，避免并发新增超出配额
+type TenantQuotaService struct {
+}
+
+// NewTenantQuotaService 创建租户资源配额服务
+func NewTenantQuotaService() *TenantQuotaService {
+	return &TenantQuotaService{}
+}
+
+// TenantUsage 租户资源用量
+type TenantUsage struct {
+	TenantID uint                `json:"tenantId"`
+	Users    *tenanthelper.Quota `json:"users"`    // 用户数(含关联到该租户的其他租户用户)
+	Roles    *tenanthelper.Quota `json:"roles"`    // 角色数
+	Storage  *tenanthelper.Quota `json:"storage"`  // 附件存储空间(字节)
+	ApiCalls *tenanthelper.Quota `json:"apiCalls"` // 当日API调用次数
+}
+
+// tenantLimits 缓存的租户配额
+type tenantLimits struct {
+	MaxUsers    int   `json:"maxUsers"`
+	MaxRoles    int   `json:"maxRoles"`
+	MaxStorage  int64 `json:"maxStorage"`
+	MaxApiCalls int   `json:"maxApiCalls"`
+}
+
+// Usage 获取租户各项资源的配额及当前用量
+func (s *TenantQuotaService) Usage(c context.Context, tenantID uint) (*TenantUsage, error) {
+	limits, err := s.limits(c, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	db := app.DB().WithContext(c)
+	users, err := s.countUsers(db, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.countRoles(db, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	storage, err := s.sumStorage(db, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return &TenantUsage{
+		TenantID: tenantID,
+		Users:    tenanthelper.NewQuota(int64(limits.MaxUsers), users),
+		Roles:    tenanthelper.NewQuota(int64(limits.MaxRoles), roles),
+		Storage:  tenanthelper.NewQuota(limits.MaxStorage, storage),
+		ApiCalls: tenanthelper.NewQuota(int64(limits.MaxApiCalls), s.apiCalls(c, tenantID)),
+	}, nil
+}
+
+// CheckUsers 检查租户新增 n 个用户(含关联其他租户的用户)后是否超出配额，tx 为新增用户的事务
+func (s *TenantQuotaService) CheckUsers(tx *gorm.DB, tenantID uint, n int) error {
+	limits, err := s.limits(tx.Statement.Context, tenantID)
+	if err != nil || limits.MaxUsers <= 0 {
+		return err
+	}
+	if err = s.lockTenant(tx, tenantID); err != nil {
+		return err
+	}
+	used, err := s.countUsers(tx, tenantID)
+	if err != nil {
+		return err
+	}
+	if !tenanthelper.NewQuota(int64(limits.MaxUsers), used).Allow(int64(n)) {
+		return quotaError(fmt.Sprintf("租户用户数已达上限(%d)", limits.MaxUsers))
+	}
+	return nil
+}
+
+// CheckRoles 检查租户新增 n 个角色后是否超出配额，tx 为新增角色的事务
+func (s *TenantQuotaService) CheckRoles(tx *gorm.DB, tenantID uint, n int) error {
+	limits, err := s.limits(tx.Statement.Context, tenantID)
+	if err != nil || limits.MaxRoles <= 0 {
+		return err
+	}
+	if err = s.lockTenant(tx, tenantID); err != nil {
+		return err
+	}
+	used, err := s.countRoles(tx, tenantID)
+	if err != nil {
+		return err
+	}
+	if !tenanthelper.NewQuota(int64(limits.MaxRoles), used).Allow(int64(n)) {
+		return quotaError(fmt.Sprintf("租户角色数已达上限(%d)", limits.MaxRoles))
+	}
+	return nil
+}
+
+// CheckStorage 检查租户上传 size 字节的文件后是否超出存储空间配额，tx 为保存文件记录的事务
+func (s *TenantQuotaService) CheckStorage(tx *gorm.DB, tenantID uint, size int64) error {
+	limits, err := s.limits(tx.Statement.Context, tenantID)
+	if err != nil || limits.MaxStorage <= 0 {
+		return err
+	}
+	if err = s.lockTenant(tx, tenantID); err != nil {
+		return err
+	}
+	used, err := s.sumStorage(tx, tenantID)
+	if err != nil {
+		return err
+	}
+	if !tenanthelper.NewQuota(limits.MaxStorage, used).Allow(size) {
+		return quotaError(fmt.Sprintf("租户存储空间不足，已使用 %s / %s", tenanthelper.FormatBytes(used), tenanthelper.FormatBytes(limits.MaxStorage)))
+	}
+	return nil
+}
+
+// CountApiCall 记录租户的一次API调用，返回当日调用次数是否仍在配额内
+// 未设置配额的租户同样计数，用于统计用量
+func (s *TenantQuotaService) CountApiCall(c context.Context, tenantID uint) (bool, error) {
+	if tenantID == 0 {
+		return true, nil
+	}
+	limits, err := s.limits(c, tenantID)
+	if err != nil {
+		return true, err
+	}
+	// 计数保留两天，覆盖跨日查询及时区差异
+	count, err := app.Cache.Incr(c, s.apiCallsKey(tenantID, time.Now()), 48*time.Hour)
+	if err != nil {
+		return true, err
+	}
+	return limits.MaxApiCalls <= 0 || count <= int64(limits.MaxApiCalls), nil
+}
+
+// Invalidate 清除租户配额缓存，修改租户配额或删除租户后调用
+func (s *TenantQuotaService) Invalidate(tenantIDs ...uint) {
+	keys := make([]string, 0, len(tenantIDs))
+	for _, tenantID := range tenantIDs {
+		keys = append(keys, s.cacheKey(tenantID))
+	}
+	if len(keys) > 0 {
+		app.Cache.Del(context.Background(), keys...)
+	}
+}
+
+// limits 获取租户配额，租户不存在或为全局租户时不限制
+func (s *TenantQuotaService) limits(c context.Context, tenantID uint) (*tenantLimits, error) {
+	limits := &tenantLimits{}
+	if tenantID == 0 {
+		return limits, nil
+	}
+	data, err := app.Cache.Get(context.Background(), s.cacheKey(tenantID))
+	if err == nil && data != "" && json.Unmarshal([]byte(data), limits) == nil {
+		return limits, nil
+	}
+
+	tenant := models.NewTenant()
+	err = tenant.Find(c, func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, max_users, max_roles, max_storage, max_api_calls").Where("id = ?", tenantID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	limits = &tenantLimits{
+		MaxUsers:    tenant.MaxUsers,
+		MaxRoles:    tenant.MaxRoles,
+		MaxStorage:  tenant.MaxStorage,
+		MaxApiCalls: tenant.MaxApiCalls,
+	}
+	if data, err := json.Marshal(limits); err == nil {
+		app.Cache.Set(context.Background(), s.cacheKey(tenantID), string(data), s.cacheExpire())
+	}
+	return limits, nil
+}
+
+// lockTenant 锁定租户记录直到事务结束，同一租户的配额检查及新增依次执行
+func (s *TenantQuotaService) lockTenant(tx *gorm.DB, tenantID uint) error {
+	db := tx.WithContext(tenanthelper.SkipTenant(tx.Statement.Context))
+	if db.Dialector.Name() == "sqlserver" {
+		// SQL Server 不支持 FOR UPDATE，使用表提示加更新锁
+		db = db.Table(models.Tenant{}.TableName() + " WITH (UPDLOCK, ROWLOCK)")
+	} else {
+		db = db.Model(&models.Tenant{}).Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate})
+	}
+	var ids []uint
+	return db.Where("id = ?", tenantID).Pluck("id", &ids).Error
+}
+
+// countUsers 统计租户的用户数：租户下的用户及通过用户租户关联加入该租户的用户
+func (s *TenantQuotaService) countUsers(db *gorm.DB, tenantID uint) (int64, error) {
+	var count int64
+	err := db.WithContext(tenanthelper.SkipTenant(db.Statement.Context)).Model(&models.User{}).
+		Where("tenant_id = ? OR id IN (SELECT user_id FROM sys_user_tenant WHERE tenant_id = ?)", tenantID, tenantID).
+		Count(&count).Error
+	return count, err
+}
+
+// countRoles 统计租户的角色数
+func (s *TenantQuotaService) countRoles(db *gorm.DB, tenantID uint) (int64, error) {
+	var count int64
+	err := db.WithContext(tenanthelper.SkipTenant(db.Statement.Context)).Model(&models.SysRole{}).
+		Where("tenant_id = ?", tenantID).
+		Count(&count).Error
+	return count, err
+}
+
+// sumStorage 统计租户附件占用的存储空间(字节)，已删除的附件不计入
+func (s *TenantQuotaService) sumStorage(db *gorm.DB, tenantID uint) (int64, error) {
+	var size int64
+	err := db.WithContext(tenanthelper.SkipTenant(db.Statement.Context)).Model(&models.SysAffix{}).
+		Select("COALESCE(SUM(size), 0)").
+		Where("tenant_id = ?", tenantID).
+		Scan(&size).Error
+	return size, err
+}
+
+// apiCalls 获取租户当日的API调用次数，缓存中没有计数时为0
+func (s *TenantQuotaService) apiCalls(c context.Context, tenantID uint) int64 {
+	data, err := app.Cache.Get(c, s.apiCallsKey(tenantID, time.Now()))
+	if err != nil || data == "" {
+		return 0
+	}
+	count, _ := strconv.ParseInt(data, 10, 64)
+	return count
+}
+
+func (s *TenantQuotaService) cacheKey(tenantID uint) string {
+	return "tenant_quota:" + strconv.FormatUint(uint64(tenantID), 10)
+}
+
+func (s *TenantQuotaService) apiCallsKey(tenantID uint, t time.Time) string {
+	return "tenant_quota:api_calls:" + strconv.FormatUint(uint64(tenantID), 10) + ":" + tenanthelper.DayKey(t)
+}
+
+func (s *TenantQuotaService) cacheExpire() time.Duration {
+	expire := app.ConfigYml.GetInt("tenantquota.cacheexpire")
+	if expire <= 0 {
+		expire = 60
+	}
+	return time.Duration(expire) * time.Second
+}
@@ -28,7 +28,7 @@ func (u *User) GetUserProfile(c *gin.Context, userID uint) (profile *models.User
 
 	user := models.NewUser()
 	err = user.Find(c, func(d *gorm.DB) *gorm.DB {
-		return d.Preload("Department").Preload("Roles").Preload("Tenant").Where("id = ?", userID)
+		return d.Preload("Department").Scopes(models.PreloadActiveRoles(userID)).Preload("Tenant").Where("id = ?", userID)
 	})
 	if err != nil {
 		return
@@ -85,9 +85,15 @@ func (u *User) GetUserProfile(c *gin.Context, userID uint) (profile *models.User
 			}
 		}
 	}
+	// 限时角色授权及其到期时间
+	roleGrants, err := NewRoleGrantService().TimeBound(c, userID)
+	if err != nil {
+		return
+	}
 	profile = &models.UserProfile{
 		User:        *user,
 		Permissions: permissions,
+		RoleGrants:  roleGrants,
 	}
 	return
 }
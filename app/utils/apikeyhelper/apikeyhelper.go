@@ -0,0 +1,118 @@
+package apikeyhelper
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/casbin/casbin/v2/util"
+)
+
+// KeyPrefix API密钥固定前缀，便于识别及密钥扫描
+const KeyPrefix = "gfk_"
+
+// displayPrefixLen 用于展示及识别的密钥前缀长度(含固定前缀)
+const displayPrefixLen = len(KeyPrefix) + 8
+
+// ErrInvalidKey API密钥无效(不存在、已停用、已过期或不满足访问限制)
+var ErrInvalidKey = errors.New("API密钥无效")
+
+// Generate 生成API密钥，返回明文密钥(仅展示一次)、展示前缀及摘要
+func Generate() (key, prefix, hash string, err error) {
+	buf := make([]byte, 30)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", "", err
+	}
+	key = KeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return key, key[:displayPrefixLen], Hash(key), nil
+}
+
+// Hash 计算密钥摘要(SHA-256)，数据库中只保存摘要
+// 密钥为高强度随机数，无需使用慢哈希
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsKey 判断字符串是否具有API密钥格式
+func IsKey(key string) bool {
+	return strings.HasPrefix(key, KeyPrefix) && len(key) > displayPrefixLen
+}
+
+// ParseScope 解析授权范围，格式为 "方法 路径"，如 "GET /api/users/*"、"* /api/sysDict/:id"
+// 方法为 * 表示所有方法，多个方法以 | 分隔；路径规则与权限策略一致(keyMatch2)
+func ParseScope(scope string) (methods []string, path string, err error) {
+	fields := strings.Fields(scope)
+	if len(fields) != 2 {
+		return nil, "", fmt.Errorf("授权范围格式错误: %s，应为 \"方法 路径\"", scope)
+	}
+	if !strings.HasPrefix(fields[1], "/") {
+		return nil, "", fmt.Errorf("授权范围路径必须以 / 开头: %s", scope)
+	}
+	for _, method := range strings.Split(fields[0], "|") {
+		if method == "" {
+			return nil, "", fmt.Errorf("授权范围方法不能为空: %s", scope)
+		}
+		methods = append(methods, strings.ToUpper(method))
+	}
+	return methods, fields[1], nil
+}
+
+// MatchScope 判断请求是否在授权范围内，未设置授权范围时不限制
+func MatchScope(scopes []string, method, path string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	for _, scope := range scopes {
+		methods, pattern, err := ParseScope(scope)
+		if err != nil || !util.KeyMatch2(path, pattern) {
+			continue
+		}
+		for _, m := range methods {
+			if m == "*" || m == method {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ParseIP 校验IP白名单条目，支持单个IP及CIDR网段
+func ParseIP(entry string) error {
+	if strings.Contains(entry, "/") {
+		if _, _, err := net.ParseCIDR(entry); err != nil {
+			return fmt.Errorf("IP网段格式错误: %s", entry)
+		}
+		return nil
+	}
+	if net.ParseIP(entry) == nil {
+		return fmt.Errorf("IP格式错误: %s", entry)
+	}
+	return nil
+}
+
+// MatchIP 判断客户端IP是否在白名单中，未设置白名单时不限制
+func MatchIP(allowed []string, ip string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	clientIP := net.ParseIP(ip)
+	if clientIP == nil {
+		return false
+	}
+	for _, entry := range allowed {
+		if strings.Contains(entry, "/") {
+			if _, network, err := net.ParseCIDR(entry); err == nil && network.Contains(clientIP) {
+				return true
+			}
+		} else if allowedIP := net.ParseIP(entry); allowedIP != nil && allowedIP.Equal(clientIP) {
+			return true
+		}
+	}
+	return false
+}
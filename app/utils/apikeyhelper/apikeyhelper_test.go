@@ -0,0 +1,70 @@
+package apikeyhelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	key, prefix, hash, err := Generate()
+	require.NoError(t, err)
+	assert.True(t, IsKey(key))
+	assert.Equal(t, key[:len(prefix)], prefix)
+	assert.Equal(t, Hash(key), hash)
+	assert.Len(t, hash, 64)
+
+	other, _, otherHash, err := Generate()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+	assert.NotEqual(t, hash, otherHash)
+
+	assert.False(t, IsKey("eyJhbGciOiJIUzI1NiJ9.e30.sig"))
+	assert.False(t, IsKey(KeyPrefix))
+}
+
+func TestParseScope(t *testing.T) {
+	methods, path, err := ParseScope("get|post /api/users/*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GET", "POST"}, methods)
+	assert.Equal(t, "/api/users/*", path)
+
+	_, _, err = ParseScope("/api/users")
+	assert.Error(t, err)
+	_, _, err = ParseScope("GET api/users")
+	assert.Error(t, err)
+	_, _, err = ParseScope("GET| /api/users")
+	assert.Error(t, err)
+}
+
+func TestMatchScope(t *testing.T) {
+	scopes := []string{"GET /api/users/*", "* /api/sysDict/:id", "POST|PUT /api/sysApi/add"}
+
+	assert.True(t, MatchScope(scopes, "GET", "/api/users/list"))
+	assert.False(t, MatchScope(scopes, "DELETE", "/api/users/delete"))
+	assert.True(t, MatchScope(scopes, "DELETE", "/api/sysDict/12"))
+	assert.False(t, MatchScope(scopes, "GET", "/api/sysDict/12/items"))
+	assert.True(t, MatchScope(scopes, "PUT", "/api/sysApi/add"))
+	assert.False(t, MatchScope(scopes, "GET", "/api/sysApi/add"))
+	assert.False(t, MatchScope([]string{"invalid"}, "GET", "/api/users/list"))
+
+	// 未设置授权范围时不限制
+	assert.True(t, MatchScope(nil, "DELETE", "/api/users/delete"))
+}
+
+func TestMatchIP(t *testing.T) {
+	allowed := []string{"10.0.0.0/8", "192.168.1.10", "2001:db8::/32"}
+
+	assert.True(t, MatchIP(allowed, "10.1.2.3"))
+	assert.True(t, MatchIP(allowed, "192.168.1.10"))
+	assert.False(t, MatchIP(allowed, "192.168.1.11"))
+	assert.True(t, MatchIP(allowed, "2001:db8::1"))
+	assert.False(t, MatchIP(allowed, "not-an-ip"))
+	assert.True(t, MatchIP(nil, "8.8.8.8"))
+
+	assert.NoError(t, ParseIP("10.0.0.0/8"))
+	assert.NoError(t, ParseIP("::1"))
+	assert.Error(t, ParseIP("10.0.0.0/33"))
+	assert.Error(t, ParseIP("localhost"))
+}
@@ -3,7 +3,9 @@ package cachehelper
 import (
 	"container/heap"
 	"context"
+	"errors"
 	"gin-fast/app/global/app"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -73,11 +75,16 @@ func (m *memoryHelper) SetVal(ctx context.Context, key string, value interface{}
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	expirationTime := time.Now().Add(expiration)
+	m.set(key, value, expiration)
+	return nil
+}
+
+// set 写入键值，调用方需持有写锁
+func (m *memoryHelper) set(key string, value interface{}, expiration time.Duration) {
 	item := &cacheItem{
 		key:        key,
 		value:      value,
-		expiration: expirationTime,
+		expiration: time.Now().Add(expiration),
 	}
 
 	// 如果键已存在，先从堆中移除
@@ -92,8 +99,6 @@ func (m *memoryHelper) SetVal(ctx context.Context, key string, value interface{}
 	if m.expiryQueue.Len() > 0 && m.expiryQueue[0] == item {
 		m.resetCleanupTimer()
 	}
-
-	return nil
 }
 
 func (m *memoryHelper) Get(ctx context.Context, key string) (string, error) {
@@ -188,6 +193,70 @@ func (m *memoryHelper) Expire(ctx context.Context, key string, expiration time.D
 	return nil
 }
 
+// Incr 将键的整数值原子加1并返回新值，键不存在或已过期时从0开始并设置过期时间
+func (m *memoryHelper) Incr(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	item, exists := m.data[key]
+	if !exists || time.Now().After(item.expiration) {
+		if exists {
+			heap.Remove(&m.expiryQueue, item.index)
+		}
+		item = &cacheItem{
+			key:        key,
+			value:      "1",
+			expiration: time.Now().Add(expiration),
+		}
+		m.data[key] = item
+		heap.Push(&m.expiryQueue, item)
+		if m.expiryQueue[0] == item {
+			m.resetCleanupTimer()
+		}
+		return 1, nil
+	}
+
+	str, ok := item.value.(string)
+	if !ok {
+		return 0, errors.New("value is not an integer")
+	}
+	n, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return 0, errors.New("value is not an integer")
+	}
+	n++
+	item.value = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+// Update 在写锁内原子地读改写键值，键不存在或已过期时 fn 接收空字符串
+func (m *memoryHelper) Update(ctx context.Context, key string, expiration time.Duration, fn func(value string) (string, error)) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var current string
+	if item, exists := m.data[key]; exists && !time.Now().After(item.expiration) {
+		str, ok := item.value.(string)
+		if !ok {
+			return errors.New("value is not a string")
+		}
+		current = str
+	}
+	value, err := fn(current)
+	if err != nil {
+		return err
+	}
+	if value != "" {
+		m.set(key, value, expiration)
+		return nil
+	}
+	if item, exists := m.data[key]; exists {
+		heap.Remove(&m.expiryQueue, item.index)
+		delete(m.data, key)
+	}
+	return nil
+}
+
 // GetAll 获取所有缓存项
 func (m *memoryHelper) GetAll(ctx context.Context) ([]app.CacheItem, error) {
 	m.mutex.RLock()
@@ -2,6 +2,7 @@ package cachehelper
 
 import (
 	"context"
+	"errors"
 	"reflect"
 	"sync"
 	"testing"
@@ -385,6 +386,100 @@ func TestMemoryHelper_Expire(t *testing.T) {
 	}
 }
 
+// TestMemoryHelper_Incr 测试原子自增
+func TestMemoryHelper_Incr(t *testing.T) {
+	cache := NewMemoryHelper()
+	defer cache.Close()
+
+	ctx := context.Background()
+	key := "incr_key"
+
+	// 并发自增结果应准确
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.Incr(ctx, key, time.Second)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	result, err := cache.Get(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, "50", result)
+
+	// 已存在的键不修改过期时间，过期后从1开始
+	err = cache.Expire(ctx, key, 100*time.Millisecond)
+	assert.NoError(t, err)
+	n, err := cache.Incr(ctx, key, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(51), n)
+	time.Sleep(150 * time.Millisecond)
+	n, err = cache.Incr(ctx, key, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	// 非整数值返回错误
+	err = cache.Set(ctx, "incr_text", "abc", time.Second)
+	assert.NoError(t, err)
+	_, err = cache.Incr(ctx, "incr_text", time.Second)
+	assert.Error(t, err)
+}
+
+// TestMemoryHelper_Update 测试原子读改写
+func TestMemoryHelper_Update(t *testing.T) {
+	cache := NewMemoryHelper()
+	defer cache.Close()
+
+	ctx := context.Background()
+	key := "update_key"
+
+	// 并发追加不丢失修改
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := cache.Update(ctx, key, time.Second, func(value string) (string, error) {
+				return value + "a", nil
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	result, err := cache.Get(ctx, key)
+	assert.NoError(t, err)
+	assert.Len(t, result, 50)
+
+	// fn 返回错误时不修改
+	err = cache.Update(ctx, key, time.Second, func(value string) (string, error) {
+		return "b", errors.New("abort")
+	})
+	assert.Error(t, err)
+	result, _ = cache.Get(ctx, key)
+	assert.Len(t, result, 50)
+
+	// 返回空字符串时删除键
+	err = cache.Update(ctx, key, time.Second, func(value string) (string, error) {
+		return "", nil
+	})
+	assert.NoError(t, err)
+	count, _ := cache.Exists(ctx, key)
+	assert.Equal(t, int64(0), count)
+
+	// 已过期的键视为不存在
+	assert.NoError(t, cache.Set(ctx, key, "old", 50*time.Millisecond))
+	time.Sleep(100 * time.Millisecond)
+	err = cache.Update(ctx, key, time.Second, func(value string) (string, error) {
+		assert.Empty(t, value)
+		return "new", nil
+	})
+	assert.NoError(t, err)
+	result, _ = cache.Get(ctx, key)
+	assert.Equal(t, "new", result)
+}
+
 // TestMemoryHelper_ConcurrentAccess 测试并发访问安全性
 func TestMemoryHelper_ConcurrentAccess(t *testing.T) {
 	cache := NewMemoryHelper()
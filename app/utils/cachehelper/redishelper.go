@@ -2,18 +2,25 @@ package cachehelper
 
 import (
 	"context"
+	"errors"
 	"gin-fast/app/global/app"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
+// maxUpdateRetries Update 遇到并发修改时的最大重试次数
+const maxUpdateRetries = 10
+
 // redisHelper Redis助手实现
 type redisHelper struct {
 	client *redis.Client
 	ctx    context.Context
 }
 
+// 编译时检查是否实现了发布订阅接口
+var _ app.PubSubInterf = (*redisHelper)(nil)
+
 // NewRedisHelper 创建Redis助手实例
 func NewRedisHelper(addr, password string, db int) (app.CacheInterf, error) {
 	rdb := redis.NewClient(&redis.Options{
@@ -60,6 +67,50 @@ func (r *redisHelper) Expire(ctx context.Context, key string, expiration time.Du
 	return r.client.Expire(ctx, key, expiration).Err()
 }
 
+// incrScript 自增并为新建的键设置过期时间，在同一脚本中执行保证计数键不会缺少过期时间
+// 没有过期时间的已有键(如旧版本遗留)同时补上过期时间
+var incrScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if n == 1 or redis.call('PTTL', KEYS[1]) == -1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// Incr 将键的整数值原子加1并返回新值，新建的键设置过期时间
+func (r *redisHelper) Incr(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return incrScript.Run(ctx, r.client, []string{key}, expiration.Milliseconds()).Int64()
+}
+
+// Update 通过 WATCH/MULTI 原子地读改写键值，键在读取后被其他客户端修改时重试
+func (r *redisHelper) Update(ctx context.Context, key string, expiration time.Duration, fn func(value string) (string, error)) error {
+	txf := func(tx *redis.Tx) error {
+		value, err := tx.Get(ctx, key).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		if value, err = fn(value); err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if value == "" {
+				pipe.Del(ctx, key)
+			} else {
+				pipe.Set(ctx, key, value, expiration)
+			}
+			return nil
+		})
+		return err
+	}
+	for i := 0; i < maxUpdateRetries; i++ {
+		err := r.client.Watch(ctx, txf, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return errors.New("cache update conflict: too many retries")
+}
+
 // GetAll 获取所有缓存项（Redis实现中不支持直接获取所有键值对）
 func (r *redisHelper) GetAll(ctx context.Context) ([]app.CacheItem, error) {
 	// Redis没有简单的方法获取所有键值对，这里返回空数组
@@ -67,6 +118,44 @@ func (r *redisHelper) GetAll(ctx context.Context) ([]app.CacheItem, error) {
 	return []app.CacheItem{}, nil
 }
 
+// Publish 向频道发布消息
+func (r *redisHelper) Publish(ctx context.Context, channel string, message string) error {
+	return r.client.Publish(ctx, channel, message).Err()
+}
+
+// Subscribe 订阅频道，连接断开后由客户端自动重连并重新订阅
+func (r *redisHelper) Subscribe(channel string, onMessage func(message string), onResubscribe func()) (func() error, error) {
+	pubsub := r.client.Subscribe(r.ctx, channel)
+	// 等待订阅确认
+	if _, err := pubsub.Receive(r.ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	go func() {
+		for {
+			msg, err := pubsub.Receive(r.ctx)
+			if err != nil {
+				if errors.Is(err, redis.ErrClosed) {
+					return
+				}
+				// 连接异常，下次接收时重连
+				time.Sleep(time.Second)
+				continue
+			}
+			switch m := msg.(type) {
+			case *redis.Subscription:
+				// 重连后重新订阅成功
+				if m.Kind == "subscribe" && onResubscribe != nil {
+					onResubscribe()
+				}
+			case *redis.Message:
+				onMessage(m.Payload)
+			}
+		}
+	}()
+	return pubsub.Close, nil
+}
+
 // Close 关闭连接
 func (r *redisHelper) Close() error {
 	return r.client.Close()
@@ -0,0 +1,52 @@
+package captchahelper
+
+import (
+	"errors"
+	"gin-fast/app/global/app"
+	"gin-fast/app/global/consts"
+	"time"
+)
+
+// 验证码难度
+const (
+	DifficultyEasy   = "easy"
+	DifficultyNormal = "normal"
+	DifficultyHard   = "hard"
+)
+
+// GetCaptchaType 获取验证码类型
+func GetCaptchaType() string {
+	return app.ConfigYml.GetString("captcha.type")
+}
+
+// CreateCaptcha 创建验证码服务，未配置类型时使用图形数字验证码
+func CreateCaptcha() (app.Captcha, error) {
+	difficulty := app.ConfigYml.GetString("captcha.difficulty")
+	store := NewStore(app.Cache, time.Duration(app.ConfigYml.GetInt("captcha.expire"))*time.Second)
+	switch GetCaptchaType() {
+	case consts.CaptchaTypeDigits, "":
+		length := app.ConfigYml.GetInt("captcha.length")
+		if length <= 0 {
+			length = digitsLength(difficulty)
+		}
+		return NewDigitsCaptcha(store, length, 160, 50), nil
+	case consts.CaptchaTypeMath:
+		return NewMathCaptcha(store, difficulty, 160, 50), nil
+	case consts.CaptchaTypeSlider:
+		return NewSliderCaptcha(store, difficulty, 300, 150), nil
+	default:
+		return nil, errors.New("不支持的验证码类型")
+	}
+}
+
+// digitsLength 未配置长度时按难度确定图形数字验证码长度
+func digitsLength(difficulty string) int {
+	switch difficulty {
+	case DifficultyEasy:
+		return 4
+	case DifficultyHard:
+		return 6
+	default:
+		return 5
+	}
+}
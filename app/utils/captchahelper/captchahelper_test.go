@@ -0,0 +1,97 @@
+package captchahelper
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"gin-fast/app/utils/cachehelper"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) *Store {
+	cache := cachehelper.NewMemoryHelper()
+	t.Cleanup(func() { cache.Close() })
+	return NewStore(cache, time.Minute)
+}
+
+func TestDigitsCaptcha(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	c := NewDigitsCaptcha(store, 5, 160, 50)
+
+	data, err := c.Generate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "digits", data.Type)
+	assert.Contains(t, data.Image, "data:image/png;base64,")
+	answer := store.Get(ctx, data.CaptchaID)
+	assert.Len(t, answer, 5)
+
+	var image, audio bytes.Buffer
+	require.NoError(t, c.WriteImage(ctx, &image, data.CaptchaID, 160, 50))
+	require.NoError(t, c.WriteAudio(ctx, &audio, data.CaptchaID, "zh"))
+	assert.NotZero(t, image.Len())
+	assert.NotZero(t, audio.Len())
+
+	// 验证码单次有效
+	assert.True(t, c.Verify(ctx, data.CaptchaID, " "+answer+" "))
+	assert.False(t, c.Verify(ctx, data.CaptchaID, answer))
+	assert.ErrorIs(t, c.WriteImage(ctx, &image, data.CaptchaID, 160, 50), ErrCaptchaNotFound)
+}
+
+func TestDigitsCaptchaWrongAnswerInvalidates(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	c := NewDigitsCaptcha(store, 4, 160, 50)
+
+	data, err := c.Generate(ctx)
+	require.NoError(t, err)
+	answer := store.Get(ctx, data.CaptchaID)
+	assert.False(t, c.Verify(ctx, data.CaptchaID, "x"))
+	assert.False(t, c.Verify(ctx, data.CaptchaID, answer))
+
+	assert.ErrorIs(t, c.Reload(ctx, "missing"), ErrCaptchaNotFound)
+}
+
+func TestMathCaptcha(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	for _, difficulty := range []string{DifficultyEasy, DifficultyNormal, DifficultyHard} {
+		c := NewMathCaptcha(store, difficulty, 160, 50)
+		for i := 0; i < 20; i++ {
+			expression, result := c.expression()
+			assert.GreaterOrEqual(t, result, 0, expression)
+		}
+
+		data, err := c.Generate(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "math", data.Type)
+		answer := store.Get(ctx, data.CaptchaID)
+		assert.True(t, c.Verify(ctx, data.CaptchaID, answer))
+	}
+}
+
+func TestSliderCaptcha(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	c := NewSliderCaptcha(store, DifficultyNormal, 300, 150)
+
+	data, err := c.Generate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "slider", data.Type)
+	assert.NotEmpty(t, data.Piece)
+	x, err := strconv.Atoi(store.Get(ctx, data.CaptchaID))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, x, c.pieceSize)
+	assert.LessOrEqual(t, x+c.pieceSize, 300)
+	assert.True(t, c.Verify(ctx, data.CaptchaID, strconv.FormatFloat(float64(x)+4.5, 'f', 1, 64)))
+
+	data, err = c.Generate(ctx)
+	require.NoError(t, err)
+	x, _ = strconv.Atoi(store.Get(ctx, data.CaptchaID))
+	assert.False(t, c.Verify(ctx, data.CaptchaID, strconv.Itoa(x+6)))
+}
@@ -0,0 +1,117 @@
+package captchahelper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"gin-fast/app/global/app"
+	"gin-fast/app/global/consts"
+	"io"
+	"strings"
+
+	"github.com/dchest/captcha"
+)
+
+// ErrCaptchaNotFound 验证码不存在或已过期
+var ErrCaptchaNotFound = errors.New("验证码不存在或已过期")
+
+// DigitsCaptcha 图形数字验证码(支持语音)
+type DigitsCaptcha struct {
+	store  *Store
+	length int
+	width  int
+	height int
+}
+
+// NewDigitsCaptcha 创建图形数字验证码
+func NewDigitsCaptcha(store *Store, length, width, height int) *DigitsCaptcha {
+	if length <= 0 {
+		length = captcha.DefaultLen
+	}
+	return &DigitsCaptcha{store: store, length: length, width: width, height: height}
+}
+
+// Type 验证码类型
+func (d *DigitsCaptcha) Type() string {
+	return consts.CaptchaTypeDigits
+}
+
+// Generate 生成验证码
+func (d *DigitsCaptcha) Generate(ctx context.Context) (*app.CaptchaData, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	digits := captcha.RandomDigits(d.length)
+	if err = d.store.Set(ctx, id, digitsToString(digits)); err != nil {
+		return nil, err
+	}
+	image, err := pngDataURI(captcha.NewImage(id, digits, d.width, d.height))
+	if err != nil {
+		return nil, err
+	}
+	return &app.CaptchaData{
+		CaptchaID: id,
+		Type:      d.Type(),
+		Image:     image,
+		Width:     d.width,
+		Height:    d.height,
+		ExpiresAt: d.store.ExpiresAt(),
+	}, nil
+}
+
+// Verify 校验验证码答案
+func (d *DigitsCaptcha) Verify(ctx context.Context, id string, answer string) bool {
+	expected := d.store.Take(ctx, id)
+	return expected != "" && expected == strings.TrimSpace(answer)
+}
+
+// WriteImage 输出验证码图片(PNG)
+func (d *DigitsCaptcha) WriteImage(ctx context.Context, w io.Writer, id string, width, height int) error {
+	digits, err := d.digits(ctx, id)
+	if err != nil {
+		return err
+	}
+	_, err = captcha.NewImage(id, digits, width, height).WriteTo(w)
+	return err
+}
+
+// WriteAudio 输出验证码语音(WAV)
+func (d *DigitsCaptcha) WriteAudio(ctx context.Context, w io.Writer, id string, lang string) error {
+	digits, err := d.digits(ctx, id)
+	if err != nil {
+		return err
+	}
+	_, err = captcha.NewAudio(id, digits, lang).WriteTo(w)
+	return err
+}
+
+// Reload 重新生成验证码答案(验证码ID不变)
+func (d *DigitsCaptcha) Reload(ctx context.Context, id string) error {
+	if d.store.Get(ctx, id) == "" {
+		return ErrCaptchaNotFound
+	}
+	return d.store.Set(ctx, id, digitsToString(captcha.RandomDigits(d.length)))
+}
+
+// digits 获取验证码数字
+func (d *DigitsCaptcha) digits(ctx context.Context, id string) ([]byte, error) {
+	answer := d.store.Get(ctx, id)
+	if answer == "" {
+		return nil, ErrCaptchaNotFound
+	}
+	digits := make([]byte, len(answer))
+	for i := range answer {
+		digits[i] = answer[i] - '0'
+	}
+	return digits, nil
+}
+
+// digitsToString 数字转换为答案字符串
+func digitsToString(digits []byte) string {
+	var buf bytes.Buffer
+	for _, n := range digits {
+		buf.WriteByte('0' + n)
+	}
+	return buf.String()
+}
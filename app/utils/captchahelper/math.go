@@ -0,0 +1,90 @@
+package captchahelper
+
+import (
+	"context"
+	"fmt"
+	"gin-fast/app/global/app"
+	"gin-fast/app/global/consts"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// MathCaptcha 算术验证码，答案为算式结果
+type MathCaptcha struct {
+	store      *Store
+	difficulty string
+	width      int
+	height     int
+}
+
+// NewMathCaptcha 创建算术验证码
+func NewMathCaptcha(store *Store, difficulty string, width, height int) *MathCaptcha {
+	return &MathCaptcha{store: store, difficulty: difficulty, width: width, height: height}
+}
+
+// Type 验证码类型
+func (m *MathCaptcha) Type() string {
+	return consts.CaptchaTypeMath
+}
+
+// Generate 生成验证码
+func (m *MathCaptcha) Generate(ctx context.Context) (*app.CaptchaData, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	expression, result := m.expression()
+	if err = m.store.Set(ctx, id, strconv.Itoa(result)); err != nil {
+		return nil, err
+	}
+	image, err := pngDataURI(drawText(expression+"=?", m.width, m.height))
+	if err != nil {
+		return nil, err
+	}
+	return &app.CaptchaData{
+		CaptchaID: id,
+		Type:      m.Type(),
+		Image:     image,
+		Width:     m.width,
+		Height:    m.height,
+		ExpiresAt: m.store.ExpiresAt(),
+	}, nil
+}
+
+// Verify 校验验证码答案
+func (m *MathCaptcha) Verify(ctx context.Context, id string, answer string) bool {
+	expected := m.store.Take(ctx, id)
+	return expected != "" && expected == strings.TrimSpace(answer)
+}
+
+// expression 按难度生成算式及结果
+// easy: 10以内加法；normal: 20以内加减法；hard: 两位数加减法及乘法口诀
+func (m *MathCaptcha) expression() (string, int) {
+	between := func(low, high int) int { return low + rand.IntN(high-low+1) }
+	switch m.difficulty {
+	case DifficultyEasy:
+		a, b := between(1, 9), between(1, 9)
+		return fmt.Sprintf("%d+%d", a, b), a + b
+	case DifficultyHard:
+		switch rand.IntN(3) {
+		case 0:
+			a, b := between(10, 50), between(10, 50)
+			return fmt.Sprintf("%d+%d", a, b), a + b
+		case 1:
+			a, b := between(10, 50), between(10, 50)
+			a, b = max(a, b), min(a, b)
+			return fmt.Sprintf("%d-%d", a, b), a - b
+		default:
+			a, b := between(2, 9), between(2, 9)
+			return fmt.Sprintf("%dx%d", a, b), a * b
+		}
+	default:
+		a, b := between(1, 20), between(1, 20)
+		if rand.IntN(2) == 0 {
+			return fmt.Sprintf("%d+%d", a, b), a + b
+		}
+		a, b = max(a, b), min(a, b)
+		return fmt.Sprintf("%d-%d", a, b), a - b
+	}
+}
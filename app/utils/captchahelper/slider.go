@@ -0,0 +1,137 @@
+package captchahelper
+
+import (
+	"context"
+	"gin-fast/app/global/app"
+	"gin-fast/app/global/consts"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// SliderCaptcha 滑块拼图验证码，答案为滑块拖动到缺口位置时的横坐标
+type SliderCaptcha struct {
+	store     *Store
+	width     int
+	height    int
+	pieceSize int
+	tolerance int
+}
+
+// NewSliderCaptcha 创建滑块拼图验证码，按难度确定允许的横坐标误差(像素)
+func NewSliderCaptcha(store *Store, difficulty string, width, height int) *SliderCaptcha {
+	tolerance := 5
+	switch difficulty {
+	case DifficultyEasy:
+		tolerance = 8
+	case DifficultyHard:
+		tolerance = 3
+	}
+	return &SliderCaptcha{
+		store:     store,
+		width:     width,
+		height:    height,
+		pieceSize: min(height*2/5, width/5),
+		tolerance: tolerance,
+	}
+}
+
+// Type 验证码类型
+func (s *SliderCaptcha) Type() string {
+	return consts.CaptchaTypeSlider
+}
+
+// Generate 生成验证码
+func (s *SliderCaptcha) Generate(ctx context.Context) (*app.CaptchaData, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	size := s.pieceSize
+	// 缺口不与滑块初始位置(最左侧)重叠
+	x := size + 10 + rand.IntN(max(s.width-2*size-20, 1))
+	y := 5 + rand.IntN(max(s.height-size-10, 1))
+	if err = s.store.Set(ctx, id, strconv.Itoa(x)); err != nil {
+		return nil, err
+	}
+
+	background := s.background()
+	rect := image.Rect(x, y, x+size, y+size)
+	piece := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(piece, piece.Bounds(), background, rect.Min, draw.Src)
+	drawBorder(piece, piece.Bounds(), color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	// 背景图缺口位置加深
+	draw.Draw(background, rect, &image.Uniform{color.RGBA{A: 128}}, image.Point{}, draw.Over)
+	drawBorder(background, rect, color.RGBA{R: 255, G: 255, B: 255, A: 160})
+
+	backgroundURI, err := pngDataURI(background)
+	if err != nil {
+		return nil, err
+	}
+	pieceURI, err := pngDataURI(piece)
+	if err != nil {
+		return nil, err
+	}
+	return &app.CaptchaData{
+		CaptchaID: id,
+		Type:      s.Type(),
+		Image:     backgroundURI,
+		Width:     s.width,
+		Height:    s.height,
+		Piece:     pieceURI,
+		PieceY:    y,
+		ExpiresAt: s.store.ExpiresAt(),
+	}, nil
+}
+
+// Verify 校验滑块横坐标，误差在允许范围内即通过
+func (s *SliderCaptcha) Verify(ctx context.Context, id string, answer string) bool {
+	expected, err := strconv.Atoi(s.store.Take(ctx, id))
+	if err != nil {
+		return false
+	}
+	x, err := strconv.ParseFloat(strings.TrimSpace(answer), 64)
+	if err != nil {
+		return false
+	}
+	return math.Abs(x-float64(expected)) <= float64(s.tolerance)
+}
+
+// background 生成随机渐变及色块的背景图
+func (s *SliderCaptcha) background() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, s.width, s.height))
+	from, to := randomColor(60, 200), randomColor(60, 200)
+	for x := 0; x < s.width; x++ {
+		c := color.RGBA{
+			R: uint8(int(from.R) + (int(to.R)-int(from.R))*x/s.width),
+			G: uint8(int(from.G) + (int(to.G)-int(from.G))*x/s.width),
+			B: uint8(int(from.B) + (int(to.B)-int(from.B))*x/s.width),
+			A: 255,
+		}
+		draw.Draw(img, image.Rect(x, 0, x+1, s.height), &image.Uniform{c}, image.Point{}, draw.Src)
+	}
+	for i := 0; i < 12; i++ {
+		w, h := 10+rand.IntN(s.width/4), 10+rand.IntN(s.height/3)
+		x, y := rand.IntN(s.width), rand.IntN(s.height)
+		c := randomColor(40, 230)
+		c.A = 160
+		draw.Draw(img, image.Rect(x, y, x+w, y+h), &image.Uniform{c}, image.Point{}, draw.Over)
+	}
+	return img
+}
+
+// drawBorder 绘制矩形边框
+func drawBorder(img *image.RGBA, rect image.Rectangle, c color.RGBA) {
+	for x := rect.Min.X; x < rect.Max.X; x++ {
+		img.Set(x, rect.Min.Y, c)
+		img.Set(x, rect.Max.Y-1, c)
+	}
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		img.Set(rect.Min.X, y, c)
+		img.Set(rect.Max.X-1, y, c)
+	}
+}
@@ -0,0 +1,77 @@
+package captchahelper
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"gin-fast/app/global/app"
+	"image"
+	"image/png"
+	"time"
+)
+
+// Store 验证码答案存储，基于缓存实现以支持多实例部署
+type Store struct {
+	cache  app.CacheInterf
+	expire time.Duration
+}
+
+// NewStore 创建验证码答案存储
+func NewStore(cache app.CacheInterf, expire time.Duration) *Store {
+	if expire <= 0 {
+		expire = 5 * time.Minute
+	}
+	return &Store{cache: cache, expire: expire}
+}
+
+// Set 保存验证码答案
+func (s *Store) Set(ctx context.Context, id string, answer string) error {
+	return s.cache.Set(ctx, s.key(id), answer, s.expire)
+}
+
+// Get 获取验证码答案(不删除)，不存在或已过期时返回空
+func (s *Store) Get(ctx context.Context, id string) string {
+	answer, err := s.cache.Get(ctx, s.key(id))
+	if err != nil {
+		return ""
+	}
+	return answer
+}
+
+// Take 获取并删除验证码答案，保证验证码单次有效
+func (s *Store) Take(ctx context.Context, id string) string {
+	answer := s.Get(ctx, id)
+	if answer != "" {
+		s.cache.Del(ctx, s.key(id))
+	}
+	return answer
+}
+
+// ExpiresAt 新生成验证码的过期时间
+func (s *Store) ExpiresAt() int64 {
+	return time.Now().Add(s.expire).Unix()
+}
+
+func (s *Store) key(id string) string {
+	return "captcha:" + id
+}
+
+// newID 生成验证码ID
+func newID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// pngDataURI 将图片编码为PNG data URI
+func pngDataURI(img image.Image) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -1,6 +1,7 @@
 package casbinhelper
 
 import (
+	"encoding/json"
 	"fmt"
 	"gin-fast/app/global/app"
 	"gin-fast/app/utils/common"
@@ -28,8 +29,14 @@ const (
 // CasbinService Casbin服务
 // 实现 app.CasbinInterf 接口
 type CasbinHelper struct {
-	enforcer *casbin.Enforcer
-	stopChan chan struct{} // 用于停止定期重载goroutine
+	enforcer *casbin.SyncedEnforcer // 并发安全的执行器，监听器应用策略变更与请求鉴权并发执行
+	stopChan chan struct{}          // 用于停止定期重载goroutine
+	watcher  *Watcher               // 多实例间同步策略变更的监听器
+
+	db         *gorm.DB                    // 保存策略条件的数据库
+	abac       bool                        // 模型的请求定义是否包含请求属性(启用ABAC条件)
+	conditions conditionStore              // 策略上的ABAC条件
+	resolver   app.CasbinAttributeResolver // 补充请求属性的函数
 }
 
 // 编译时检查是否实现了接口
@@ -43,6 +50,10 @@ func NewCasbinHelper() *CasbinHelper {
 // Close 关闭CasbinHelper，释放资源
 func (s *CasbinHelper) Close() {
 	s.StopAutoLoadPolicy()
+	if s.watcher != nil {
+		s.watcher.Close()
+		s.watcher = nil
+	}
 }
 
 // InitCasbin 初始化Casbin
@@ -74,22 +85,32 @@ func (s *CasbinHelper) InitCasbin(db *gorm.DB, config string) error {
 	}
 
 	// 创建Casbin执行器，使用数据库适配器
-	s.enforcer, err = casbin.NewEnforcer(m, adapter)
+	s.enforcer, err = casbin.NewSyncedEnforcer(m, adapter)
 	if err != nil {
 		return fmt.Errorf("failed to create enforcer: %v", err)
 	}
 
 	// 为角色关系(g)启用域模式匹配
 	s.enforcer.AddNamedDomainMatchingFunc("g", "KeyMatch2", util.KeyMatch2)
+	// 注册判定ABAC条件的匹配器函数
+	s.db = db
+	s.registerConditionFunction()
+	if s.abac {
+		if err = s.migrateConditions(); err != nil {
+			return err
+		}
+	}
 
 	// 加载策略
-	err = s.enforcer.LoadPolicy()
+	err = s.loadPolicy()
 	if err != nil {
 		return fmt.Errorf("failed to load policy: %v", err)
 	}
 
-	// 启动定期重载策略的goroutine
-	s.startAutoLoadPolicy()
+	// 缓存支持发布订阅时通过监听器同步其他实例的策略变更，否则定期重载策略
+	if !s.startWatcher() {
+		s.startAutoLoadPolicy()
+	}
 
 	return nil
 }
@@ -112,18 +133,68 @@ func (s *CasbinHelper) HandlerDomain(domain []string) []string {
 	return []string{"*"}
 }
 
-// Enforce 检查权限
+// registerConditionFunction 注册ABAC条件匹配器函数，模型请求定义包含第5个参数时启用条件
+func (s *CasbinHelper) registerConditionFunction() {
+	s.enforcer.AddFunction(ConditionFunction, s.abacMatch)
+	s.abac = usesConditions(s.enforcer.GetModel())
+}
+
+// Enforce 检查权限，未携带请求属性时有条件的策略不生效
 func (s *CasbinHelper) Enforce(sub, obj, act string, domain ...string) (bool, error) {
+	return s.EnforceWithAttributes(nil, sub, obj, act, domain...)
+}
+
+// EnforceWithAttributes 携带请求属性检查权限
+func (s *CasbinHelper) EnforceWithAttributes(attrs *app.CasbinAttributes, sub, obj, act string, domain ...string) (bool, error) {
 	if s.enforcer == nil {
 		return false, fmt.Errorf("casbin enforcer not initialized")
 	}
 	// 统一使用HandlerDomain处理域参数
 	domains := s.HandlerDomain(domain)
+	if s.abac {
+		return s.enforcer.Enforce(sub, obj, act, domains[0], attrs)
+	}
 	return s.enforcer.Enforce(sub, obj, act, domains[0])
 }
 
+// SetAttributeResolver 设置补充请求属性的函数
+func (s *CasbinHelper) SetAttributeResolver(resolver app.CasbinAttributeResolver) {
+	s.resolver = resolver
+}
+
+// attributes 获取请求属性，没有ABAC条件时返回nil
+func (s *CasbinHelper) attributes(c *gin.Context, claims *app.Claims) (*app.CasbinAttributes, error) {
+	if !s.abac || s.conditions.empty() {
+		return nil, nil
+	}
+	attrs := &app.CasbinAttributes{
+		Time:   time.Now(),
+		IP:     c.ClientIP(),
+		Params: make(map[string]string),
+	}
+	if claims != nil {
+		attrs.UserID = claims.UserID
+		attrs.TenantID = claims.TenantID
+	}
+	for key, values := range c.Request.URL.Query() {
+		if len(values) > 0 {
+			attrs.Params[key] = values[0]
+		}
+	}
+	// 路径参数优先于同名的查询参数
+	for _, param := range c.Params {
+		attrs.Params[param.Key] = param.Value
+	}
+	if s.resolver != nil {
+		if err := s.resolver(c, attrs); err != nil {
+			return nil, err
+		}
+	}
+	return attrs, nil
+}
+
 // GetEnforcer 获取Casbin执行器
-func (s *CasbinHelper) GetEnforcer() *casbin.Enforcer {
+func (s *CasbinHelper) GetEnforcer() *casbin.SyncedEnforcer {
 	return s.enforcer
 }
 
@@ -160,8 +231,15 @@ func (s *CasbinHelper) CasbinMiddleware() gin.HandlerFunc {
 		if claims != nil && claims.TenantID > 0 {
 			domain = s.PrefixDomain(claims.TenantID)
 		}
+		attrs, err := s.attributes(c, claims)
+		if err != nil {
+			app.ZapLog.Error("Resolve casbin attributes error", zap.Error(err))
+			// 500 服务器内部错误
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "权限检查时出现错误"})
+			c.Abort()
+			return
+		}
 		var ok bool
-		var err error
 
 		// 带租户的权限检查
 		app.ZapLog.Info("Permission check with tenant",
@@ -170,7 +248,7 @@ func (s *CasbinHelper) CasbinMiddleware() gin.HandlerFunc {
 			zap.String("path", path),
 			zap.String("method", method))
 		// domain为空时，代表全局权限
-		ok, err = s.Enforce(userSubject, path, method, domain)
+		ok, err = s.EnforceWithAttributes(attrs, userSubject, path, method, domain)
 
 		if err != nil {
 			app.ZapLog.Error("Permission check error", zap.Error(err))
@@ -391,6 +469,41 @@ func (s *CasbinHelper) RemoveAllPoliciesForRole(roleID uint, domain ...string) e
 	return nil
 }
 
+// GetPoliciesForRole 获取角色直接拥有的权限策略(不含继承)，返回 [obj, act] 列表
+func (s *CasbinHelper) GetPoliciesForRole(roleID uint, domain ...string) ([][]string, error) {
+	if s.enforcer == nil {
+		return nil, fmt.Errorf("casbin enforcer not initialized")
+	}
+	policies, err := s.enforcer.GetFilteredPolicy(0, s.PrefixRole(roleID), "", "", s.HandlerDomain(domain)[0])
+	if err != nil {
+		return nil, err
+	}
+	res := make([][]string, 0, len(policies))
+	for _, policy := range policies {
+		if len(policy) >= 3 {
+			res = append(res, []string{policy[1], policy[2]})
+		}
+	}
+	return res, nil
+}
+
+// RemoveDomain 删除域(租户)下的所有权限策略及角色关系
+func (s *CasbinHelper) RemoveDomain(domain string) error {
+	if s.enforcer == nil {
+		return fmt.Errorf("casbin enforcer not initialized")
+	}
+	if domain == "" || domain == "*" {
+		return fmt.Errorf("invalid domain %q", domain)
+	}
+	if _, err := s.enforcer.RemoveFilteredPolicy(3, domain); err != nil {
+		return fmt.Errorf("failed to remove policies for domain %s: %v", domain, err)
+	}
+	if _, err := s.enforcer.RemoveFilteredGroupingPolicy(2, domain); err != nil {
+		return fmt.Errorf("failed to remove role links for domain %s: %v", domain, err)
+	}
+	return s.removeDomainConditions(domain)
+}
+
 // GetRolesForUserByID 获取用户ID的所有角色（去除前缀，返回角色ID）
 func (s *CasbinHelper) GetRolesForUserByID(userID uint, domain ...string) ([]uint, error) {
 	if s.enforcer == nil {
@@ -458,6 +571,195 @@ func (s *CasbinHelper) GetPermissionsForUser(userID uint, domain ...string) ([][
 	return s.enforcer.GetPermissionsForUser(userSubject, s.HandlerDomain(domain)...)
 }
 
+// Explain 解释用户对资源的权限判定过程
+// 返回判定结果、决定结果的策略、所有匹配的策略及用户在该域下的角色链
+// 判定时不携带请求属性，有ABAC条件的策略不生效，其条件随匹配的策略一起返回
+func (s *CasbinHelper) Explain(userID uint, obj, act string, domain ...string) (*app.CasbinExplanation, error) {
+	if s.enforcer == nil {
+		return nil, fmt.Errorf("casbin enforcer not initialized")
+	}
+	// 与CasbinMiddleware保持一致，全局用户的域为空
+	var dom string
+	if len(domain) > 0 {
+		dom = domain[0]
+	}
+	userSubject := s.PrefixUser(userID)
+	rvals := []interface{}{userSubject, obj, act, dom}
+	if s.abac {
+		rvals = append(rvals, (*app.CasbinAttributes)(nil))
+	}
+	allowed, decisive, err := s.enforcer.EnforceEx(rvals...)
+	if err != nil {
+		return nil, err
+	}
+	roleChain, err := s.roleChain(userSubject, dom, map[string]bool{userSubject: true})
+	if err != nil {
+		return nil, err
+	}
+
+	// 收集用户及其角色链上的所有主体
+	subjects := map[string]bool{userSubject: true}
+	var collect func(nodes []*app.CasbinRoleNode)
+	collect = func(nodes []*app.CasbinRoleNode) {
+		for _, node := range nodes {
+			subjects[node.Subject] = true
+			collect(node.Parents)
+		}
+	}
+	collect(roleChain)
+
+	policies, err := s.enforcer.GetPolicy()
+	if err != nil {
+		return nil, err
+	}
+	// 按模型匹配器的规则筛选匹配的策略
+	matched := [][]string{}
+	conditions := []*app.CasbinCondition{}
+	for _, policy := range policies {
+		if len(policy) < 4 || !subjects[policy[0]] {
+			continue
+		}
+		if util.KeyMatch2(obj, policy[1]) && util.RegexMatch(act, policy[2]) && (dom == policy[3] || policy[3] == "*") {
+			matched = append(matched, policy)
+			conditions = append(conditions, s.conditions.get(conditionKey(policy[0], policy[1], policy[2], policy[3])))
+		}
+	}
+
+	return &app.CasbinExplanation{
+		Subject:         userSubject,
+		Object:          obj,
+		Action:          act,
+		Domain:          dom,
+		Allowed:         allowed,
+		DecisivePolicy:  decisive,
+		MatchedPolicies: matched,
+		Conditions:      conditions,
+		RoleChain:       roleChain,
+	}, nil
+}
+
+// roleChain 递归获取主体在域下的角色及其父角色，path 记录当前路径上的主体以避免循环继承
+func (s *CasbinHelper) roleChain(subject, domain string, path map[string]bool) ([]*app.CasbinRoleNode, error) {
+	roles, err := s.enforcer.GetRolesForUser(subject, domain)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*app.CasbinRoleNode, 0, len(roles))
+	for _, role := range roles {
+		node := &app.CasbinRoleNode{Subject: role, Parents: []*app.CasbinRoleNode{}}
+		if strings.HasPrefix(role, RolePrefix) {
+			if roleID, err := strconv.ParseUint(role[len(RolePrefix):], 10, 32); err == nil {
+				node.RoleID = uint(roleID)
+			}
+		}
+		if !path[role] {
+			path[role] = true
+			if node.Parents, err = s.roleChain(role, domain, path); err != nil {
+				return nil, err
+			}
+			delete(path, role)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// SetWatcher 设置策略监听器，本实例修改策略后通过发布订阅通知其他实例
+func (s *CasbinHelper) SetWatcher(pubsub app.PubSubInterf, channel string) error {
+	if s.enforcer == nil {
+		return fmt.Errorf("casbin enforcer not initialized")
+	}
+	watcher, err := NewWatcher(pubsub, channel)
+	if err != nil {
+		return err
+	}
+	if err = watcher.SetUpdateCallback(s.applyWatcherMessage); err != nil {
+		watcher.Close()
+		return err
+	}
+	if err = s.enforcer.SetWatcher(watcher); err != nil {
+		watcher.Close()
+		return err
+	}
+	s.watcher = watcher
+	return nil
+}
+
+// startWatcher 按配置启动策略监听器，缓存不支持发布订阅(内存缓存)时返回false
+func (s *CasbinHelper) startWatcher() bool {
+	if !app.ConfigYml.GetBool("casbin.watcher") {
+		return false
+	}
+	pubsub, ok := app.Cache.(app.PubSubInterf)
+	if !ok {
+		app.ZapLog.Info("Cache does not support pub/sub, fallback to auto reload policy")
+		return false
+	}
+	channel := app.ConfigYml.GetString("casbin.watcherchannel")
+	if channel == "" {
+		channel = "casbin_policy"
+	}
+	if err := s.SetWatcher(pubsub, channel); err != nil {
+		app.ZapLog.Error("Failed to start casbin watcher, fallback to auto reload policy", zap.Error(err))
+		return false
+	}
+	app.ZapLog.Info("Started casbin watcher", zap.String("channel", channel))
+	return true
+}
+
+// applyWatcherMessage 应用其他实例发布的策略变更，只更新内存中的策略，无法增量更新时重新加载全部策略
+func (s *CasbinHelper) applyWatcherMessage(message string) {
+	if s.enforcer == nil {
+		return
+	}
+	msg := &WatcherMessage{}
+	err := json.Unmarshal([]byte(message), msg)
+	if err == nil {
+		switch msg.Method {
+		case WatcherMethodAddPolicies, WatcherMethodRemovePolicies, WatcherMethodRemoveFilteredPolicy:
+			err = s.applyIncremental(msg)
+		default:
+			// LoadPolicy 内部加锁，不能在持有执行器锁时调用
+			err = s.loadPolicy()
+			msg.Method = WatcherMethodUpdate
+		}
+		if err == nil {
+			app.ZapLog.Debug("Applied casbin watcher message", zap.String("method", msg.Method))
+			return
+		}
+	}
+	app.ZapLog.Warn("Failed to apply casbin watcher message, reload policy", zap.Error(err))
+	if err = s.loadPolicy(); err != nil {
+		app.ZapLog.Error("Failed to reload policy", zap.Error(err))
+	}
+}
+
+// applyIncremental 增量更新内存中的策略及角色继承关系
+// 直接修改模型不经过执行器的锁，需持有写锁，避免与并发的鉴权请求产生数据竞争
+func (s *CasbinHelper) applyIncremental(msg *WatcherMessage) (err error) {
+	lock := s.enforcer.GetLock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	m := s.enforcer.GetModel()
+	var affected [][]string
+	switch msg.Method {
+	case WatcherMethodAddPolicies:
+		if affected, err = m.AddPoliciesWithAffected(msg.Sec, msg.Ptype, msg.Rules); err == nil && msg.Sec == "g" {
+			err = s.enforcer.BuildIncrementalRoleLinks(model.PolicyAdd, msg.Ptype, affected)
+		}
+	case WatcherMethodRemovePolicies:
+		if affected, err = m.RemovePoliciesWithAffected(msg.Sec, msg.Ptype, msg.Rules); err == nil && msg.Sec == "g" {
+			err = s.enforcer.BuildIncrementalRoleLinks(model.PolicyRemove, msg.Ptype, affected)
+		}
+	case WatcherMethodRemoveFilteredPolicy:
+		if _, affected, err = m.RemoveFilteredPolicy(msg.Sec, msg.Ptype, msg.FieldIndex, msg.FieldValues...); err == nil && msg.Sec == "g" {
+			err = s.enforcer.BuildIncrementalRoleLinks(model.PolicyRemove, msg.Ptype, affected)
+		}
+	}
+	return err
+}
+
 // startAutoLoadPolicy 启动定期重载策略的goroutine
 func (s *CasbinHelper) startAutoLoadPolicy() {
 	// 从配置文件读取自动重载间隔
@@ -481,7 +783,7 @@ func (s *CasbinHelper) startAutoLoadPolicy() {
 			select {
 			case <-ticker.C:
 				if s.enforcer != nil {
-					if err := s.enforcer.LoadPolicy(); err != nil {
+					if err := s.loadPolicy(); err != nil {
 						app.ZapLog.Error("Failed to auto reload policy", zap.Error(err))
 					} else {
 						app.ZapLog.Debug("Auto reload policy successfully")
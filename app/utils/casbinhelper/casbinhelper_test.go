@@ -37,7 +37,7 @@ func setupTestCasbin(t *testing.T) *CasbinHelper {
 	require.NoError(t, err, "Failed to create model")
 
 	// 创建内存适配器（使用默认适配器）
-	enforcer, err := casbin.NewEnforcer(m)
+	enforcer, err := casbin.NewSyncedEnforcer(m)
 	require.NoError(t, err, "Failed to create enforcer")
 
 	// 手动设置enforcer，跳过数据库初始化
@@ -391,6 +391,57 @@ func TestRemoveAllPoliciesForRole(t *testing.T) {
 	}
 }
 
+// TestRemoveDomain 测试删除域下的所有策略
+func TestRemoveDomain(t *testing.T) {
+	helper := setupTestCasbin(t)
+
+	require.NoError(t, helper.AddPoliciesForRole(1, [][]string{{"/api/users", "GET"}}, "domain_1"))
+	require.NoError(t, helper.AddPoliciesForRole(2, [][]string{{"/api/users", "GET"}}, "domain_2"))
+	require.NoError(t, helper.AddRoleInheritance(3, 1, "domain_1"))
+	require.NoError(t, helper.AddRolesForUserByID(1, []uint{3}, "domain_1"))
+	require.NoError(t, helper.AddRolesForUserByID(1, []uint{2}, "domain_2"))
+
+	require.NoError(t, helper.RemoveDomain("domain_1"))
+
+	allowed, err := helper.Enforce("user_1", "/api/users", "GET", "domain_1")
+	require.NoError(t, err)
+	assert.False(t, allowed, "Policies of removed domain should be deleted")
+	roles, err := helper.GetRolesForUserByID(1, "domain_1")
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+
+	// 其他域不受影响
+	allowed, err = helper.Enforce("user_1", "/api/users", "GET", "domain_2")
+	require.NoError(t, err)
+	assert.True(t, allowed, "Policies of other domains should be kept")
+
+	assert.Error(t, helper.RemoveDomain("*"))
+	assert.Error(t, helper.RemoveDomain(""))
+}
+
+// TestGetPoliciesForRole 测试获取角色的权限策略
+func TestGetPoliciesForRole(t *testing.T) {
+	helper := setupTestCasbin(t)
+
+	require.NoError(t, helper.AddPoliciesForRole(1, [][]string{{"/api/users", "GET"}, {"/api/users/add", "POST"}}, "domain_1"))
+	require.NoError(t, helper.AddPoliciesForRole(1, [][]string{{"/api/roles", "GET"}}))
+	require.NoError(t, helper.AddPoliciesForRole(2, [][]string{{"/api/menus", "GET"}}, "domain_1"))
+	require.NoError(t, helper.AddRoleInheritance(1, 2, "domain_1"))
+
+	// 只返回角色在指定域下直接拥有的策略
+	policies, err := helper.GetPoliciesForRole(1, "domain_1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, [][]string{{"/api/users", "GET"}, {"/api/users/add", "POST"}}, policies)
+
+	policies, err = helper.GetPoliciesForRole(1)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"/api/roles", "GET"}}, policies)
+
+	policies, err = helper.GetPoliciesForRole(3, "domain_1")
+	require.NoError(t, err)
+	assert.Empty(t, policies)
+}
+
 // TestGetRolesForUserByID 测试获取用户角色
 func TestGetRolesForUserByID(t *testing.T) {
 	helper := setupTestCasbin(t)
@@ -655,3 +706,51 @@ func TestRoleInheritanceWithDomain(t *testing.T) {
 	assert.NoError(t, err, "GetRolesForUserByID should not return error")
 	assert.Contains(t, userRoles, childRoleID, "User should have child role in specified domain")
 }
+
+// TestExplain 测试权限判定解释
+func TestExplain(t *testing.T) {
+	helper := setupTestCasbin(t)
+	domain := "domain_1"
+
+	require.NoError(t, helper.AddPolicyForRole(1, "/api/users/*", "GET", domain))
+	require.NoError(t, helper.AddPolicyForRole(2, "/api/users/list", "GET", domain))
+	require.NoError(t, helper.AddPolicyForRole(2, "/api/users/list", "GET", "domain_2"))
+	require.NoError(t, helper.AddRoleInheritance(2, 1, domain))
+	require.NoError(t, helper.AddRoleInheritance(1, 2, domain)) // 循环继承
+	require.NoError(t, helper.AddRolesForUserByID(100, []uint{2}, domain))
+
+	explanation, err := helper.Explain(100, "/api/users/list", "GET", domain)
+	require.NoError(t, err)
+	assert.True(t, explanation.Allowed)
+	assert.Equal(t, "user_100", explanation.Subject)
+	assert.Equal(t, domain, explanation.Domain)
+	assert.NotEmpty(t, explanation.DecisivePolicy)
+	assert.ElementsMatch(t, [][]string{
+		{"role_1", "/api/users/*", "GET", domain},
+		{"role_2", "/api/users/list", "GET", domain},
+	}, explanation.MatchedPolicies)
+
+	// 角色链: user_100 -> role_2 -> role_1 -> role_2(循环处终止)
+	require.Len(t, explanation.RoleChain, 1)
+	assert.Equal(t, uint(2), explanation.RoleChain[0].RoleID)
+	require.Len(t, explanation.RoleChain[0].Parents, 1)
+	parent := explanation.RoleChain[0].Parents[0]
+	assert.Equal(t, "role_1", parent.Subject)
+	require.Len(t, parent.Parents, 1)
+	assert.Equal(t, "role_2", parent.Parents[0].Subject)
+	assert.Empty(t, parent.Parents[0].Parents)
+
+	// 未授权的请求方法
+	explanation, err = helper.Explain(100, "/api/users/list", "POST", domain)
+	require.NoError(t, err)
+	assert.False(t, explanation.Allowed)
+	assert.Empty(t, explanation.DecisivePolicy)
+	assert.Empty(t, explanation.MatchedPolicies)
+}
+
+// TestExplain_NotInitialized 测试未初始化时解释权限
+func TestExplain_NotInitialized(t *testing.T) {
+	helper := NewCasbinHelper()
+	_, err := helper.Explain(1, "/api/users", "GET")
+	assert.Error(t, err)
+}
@@ -0,0 +1,398 @@
+package casbinhelper
+
+import (
+	"encoding/json"
+	"fmt"
+	"gin-fast/app/global/app"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"gorm.io/gorm"
+)
+
+// ConditionFunction 判定策略ABAC条件的匹配器函数名
+// 在模型中使用时请求定义需增加 env 参数，例如：
+//
+//	r = sub, obj, act, dom, env
+//	m = g(r.sub, p.sub, r.dom) && ... && abacMatch(r.env, p.sub, p.obj, p.act, p.dom)
+const ConditionFunction = "abacMatch"
+
+// conditionTableName 条件表名(不含前缀)，与策略表 casbin_rule 放在一起
+const conditionTableName = "casbin_condition"
+
+// casbinCondition 策略的ABAC条件记录，v0-v3 与 casbin_rule 中 p 策略的 sub, obj, act, dom 对应
+type casbinCondition struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	Ptype     string    `gorm:"size:100;uniqueIndex:idx_casbin_condition"`
+	V0        string    `gorm:"size:100;uniqueIndex:idx_casbin_condition"`
+	V1        string    `gorm:"size:100;uniqueIndex:idx_casbin_condition"`
+	V2        string    `gorm:"size:100;uniqueIndex:idx_casbin_condition"`
+	V3        string    `gorm:"size:100;uniqueIndex:idx_casbin_condition"`
+	Condition string    `gorm:"column:conditions;type:text"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// conditionStore 内存中的策略条件，以 sub|obj|act|dom 为键
+type conditionStore struct {
+	mu         sync.RWMutex
+	conditions map[string]*app.CasbinCondition
+}
+
+func conditionKey(sub, obj, act, dom string) string {
+	return sub + "|" + obj + "|" + act + "|" + dom
+}
+
+func (cs *conditionStore) get(key string) *app.CasbinCondition {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.conditions[key]
+}
+
+func (cs *conditionStore) empty() bool {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return len(cs.conditions) == 0
+}
+
+func (cs *conditionStore) replace(conditions map[string]*app.CasbinCondition) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.conditions = conditions
+}
+
+// list 获取主体在域下的所有条件，sub 为空时不限主体
+func (cs *conditionStore) list(sub, dom string) map[string]*app.CasbinCondition {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	res := make(map[string]*app.CasbinCondition)
+	for key, cond := range cs.conditions {
+		if (sub == "" || strings.HasPrefix(key, sub+"|")) && strings.HasSuffix(key, "|"+dom) {
+			res[key] = cond
+		}
+	}
+	return res
+}
+
+// replaceSubject 用新条件替换主体在域下的所有条件，sub 为空时替换域下所有主体的条件
+func (cs *conditionStore) replaceSubject(sub, dom string, conditions map[string]*app.CasbinCondition) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	res := make(map[string]*app.CasbinCondition, len(cs.conditions)+len(conditions))
+	for key, cond := range cs.conditions {
+		if (sub == "" || strings.HasPrefix(key, sub+"|")) && strings.HasSuffix(key, "|"+dom) {
+			continue
+		}
+		res[key] = cond
+	}
+	for key, cond := range conditions {
+		res[key] = cond
+	}
+	cs.conditions = res
+}
+
+// usesConditions 模型的请求定义是否包含请求属性参数(第5个参数)
+func usesConditions(m model.Model) bool {
+	r, ok := m["r"]["r"]
+	return ok && len(r.Tokens) > 4
+}
+
+// migrateConditions 与 gorm-adapter 创建策略表一致，自动创建条件表
+func (s *CasbinHelper) migrateConditions() error {
+	if err := s.conditionTable(s.db).AutoMigrate(&casbinCondition{}); err != nil {
+		return fmt.Errorf("failed to migrate condition table: %v", err)
+	}
+	return nil
+}
+
+// conditionTable 条件表
+func (s *CasbinHelper) conditionTable(db *gorm.DB) *gorm.DB {
+	return db.Table(app.ConfigYml.GetString("casbin.tableprefix") + conditionTableName)
+}
+
+// loadConditions 从数据库加载全部策略条件
+func (s *CasbinHelper) loadConditions() error {
+	if s.db == nil || !s.abac {
+		return nil
+	}
+	var rows []casbinCondition
+	if err := s.conditionTable(s.db).Where("ptype = ?", "p").Find(&rows).Error; err != nil {
+		return fmt.Errorf("failed to load policy conditions: %v", err)
+	}
+	conditions := make(map[string]*app.CasbinCondition, len(rows))
+	for _, row := range rows {
+		cond := &app.CasbinCondition{}
+		if err := json.Unmarshal([]byte(row.Condition), cond); err != nil {
+			return fmt.Errorf("invalid condition of policy %s, %s, %s, %s: %v", row.V0, row.V1, row.V2, row.V3, err)
+		}
+		conditions[conditionKey(row.V0, row.V1, row.V2, row.V3)] = cond
+	}
+	s.conditions.replace(conditions)
+	return nil
+}
+
+// loadPolicy 重新加载策略及策略条件
+func (s *CasbinHelper) loadPolicy() error {
+	if err := s.enforcer.LoadPolicy(); err != nil {
+		return err
+	}
+	return s.loadConditions()
+}
+
+// GetConditionsForRole 获取角色权限策略上的ABAC条件
+func (s *CasbinHelper) GetConditionsForRole(roleID uint, domain ...string) ([]app.CasbinPolicyCondition, error) {
+	res := []app.CasbinPolicyCondition{}
+	for key, cond := range s.conditions.list(s.PrefixRole(roleID), s.HandlerDomain(domain)[0]) {
+		parts := strings.Split(key, "|")
+		if len(parts) != 4 {
+			continue
+		}
+		res = append(res, app.CasbinPolicyCondition{Obj: parts[1], Act: parts[2], Condition: cond})
+	}
+	slices.SortFunc(res, func(a, b app.CasbinPolicyCondition) int {
+		if n := strings.Compare(a.Obj, b.Obj); n != 0 {
+			return n
+		}
+		return strings.Compare(a.Act, b.Act)
+	})
+	return res, nil
+}
+
+// SetConditionsForRole 覆盖角色权限策略上的ABAC条件
+// 条件与策略分开保存，角色策略因菜单变更重新生成后，相同资源和动作的策略仍使用原有条件
+func (s *CasbinHelper) SetConditionsForRole(roleID uint, conditions []app.CasbinPolicyCondition, domain ...string) error {
+	if s.enforcer == nil {
+		return fmt.Errorf("casbin enforcer not initialized")
+	}
+	return s.setConditions(map[uint][]app.CasbinPolicyCondition{roleID: conditions}, s.HandlerDomain(domain)[0])
+}
+
+// setConditions 在同一事务中覆盖多个角色在域下的ABAC条件
+func (s *CasbinHelper) setConditions(roles map[uint][]app.CasbinPolicyCondition, dom string) error {
+	if !s.abac {
+		for _, conditions := range roles {
+			if slices.ContainsFunc(conditions, func(item app.CasbinPolicyCondition) bool { return !item.Condition.IsEmpty() }) {
+				return fmt.Errorf("casbin model does not support conditions, add env to request_definition and %s to matchers", ConditionFunction)
+			}
+		}
+		return nil
+	}
+	var rows []casbinCondition
+	subjects := make(map[string]map[string]*app.CasbinCondition, len(roles))
+	for roleID, conditions := range roles {
+		sub := s.PrefixRole(roleID)
+		memory := make(map[string]*app.CasbinCondition, len(conditions))
+		for _, item := range conditions {
+			if item.Condition.IsEmpty() {
+				continue
+			}
+			if err := ValidateCondition(item.Condition); err != nil {
+				return fmt.Errorf("invalid condition of %s %s: %v", item.Act, item.Obj, err)
+			}
+			data, err := json.Marshal(item.Condition)
+			if err != nil {
+				return err
+			}
+			key := conditionKey(sub, item.Obj, item.Act, dom)
+			if _, ok := memory[key]; ok {
+				continue
+			}
+			memory[key] = item.Condition
+			rows = append(rows, casbinCondition{Ptype: "p", V0: sub, V1: item.Obj, V2: item.Act, V3: dom, Condition: string(data)})
+		}
+		subjects[sub] = memory
+	}
+
+	if s.db != nil {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			for sub := range subjects {
+				if err := s.conditionTable(tx).Where("ptype = ? AND v0 = ? AND v3 = ?", "p", sub, dom).Delete(&casbinCondition{}).Error; err != nil {
+					return err
+				}
+			}
+			if len(rows) == 0 {
+				return nil
+			}
+			return s.conditionTable(tx).CreateInBatches(&rows, 100).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save conditions: %v", err)
+		}
+	}
+	// 同一角色在不同域下的条件互不影响
+	for sub, memory := range subjects {
+		s.conditions.replaceSubject(sub, dom, memory)
+	}
+
+	// 条件不在策略中，通知其他实例重新加载
+	if s.watcher != nil {
+		if err := s.watcher.Update(); err != nil {
+			return fmt.Errorf("failed to notify condition change: %v", err)
+		}
+	}
+	return nil
+}
+
+// removeDomainConditions 删除域下的所有策略条件
+func (s *CasbinHelper) removeDomainConditions(domain string) error {
+	if s.db != nil && s.abac {
+		if err := s.conditionTable(s.db).Where("ptype = ? AND v3 = ?", "p", domain).Delete(&casbinCondition{}).Error; err != nil {
+			return fmt.Errorf("failed to remove conditions for domain %s: %v", domain, err)
+		}
+	}
+	s.conditions.replaceSubject("", domain, nil)
+	return nil
+}
+
+// abacMatch 匹配器函数，参数为请求属性及策略的 sub, obj, act, dom
+// 策略没有条件时直接通过；有条件但未提供请求属性时不通过
+func (s *CasbinHelper) abacMatch(args ...interface{}) (interface{}, error) {
+	if len(args) != 5 {
+		return false, fmt.Errorf("%s expects 5 arguments, got %d", ConditionFunction, len(args))
+	}
+	keys := make([]string, 4)
+	for i, arg := range args[1:] {
+		str, ok := arg.(string)
+		if !ok {
+			return false, fmt.Errorf("%s argument %d must be a string", ConditionFunction, i+2)
+		}
+		keys[i] = str
+	}
+	cond := s.conditions.get(conditionKey(keys[0], keys[1], keys[2], keys[3]))
+	if cond == nil {
+		return true, nil
+	}
+	attrs, _ := args[0].(*app.CasbinAttributes)
+	return MatchCondition(cond, attrs), nil
+}
+
+// MatchCondition 判定请求属性是否满足条件
+func MatchCondition(cond *app.CasbinCondition, attrs *app.CasbinAttributes) bool {
+	if cond.IsEmpty() {
+		return true
+	}
+	if attrs == nil {
+		return false
+	}
+	if len(cond.Times) > 0 && !slices.ContainsFunc(cond.Times, func(w app.CasbinTimeWindow) bool {
+		return matchTimeWindow(w, attrs.Time)
+	}) {
+		return false
+	}
+	if len(cond.IPs) > 0 && !matchIP(cond.IPs, attrs.IP) {
+		return false
+	}
+	if len(cond.Plans) > 0 && !slices.Contains(cond.Plans, attrs.Plan) {
+		return false
+	}
+	for _, rule := range cond.Params {
+		value, ok := attrs.Params[rule.Name]
+		if !ok || value != expandParamValue(rule.Value, attrs) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateCondition 校验条件格式
+func ValidateCondition(cond *app.CasbinCondition) error {
+	if cond == nil {
+		return nil
+	}
+	for _, w := range cond.Times {
+		if _, err := parseClock(w.Start); err != nil {
+			return err
+		}
+		if _, err := parseClock(w.End); err != nil {
+			return err
+		}
+		for _, day := range w.Weekdays {
+			if day < 0 || day > 6 {
+				return fmt.Errorf("invalid weekday %d", day)
+			}
+		}
+	}
+	for _, ip := range cond.IPs {
+		if _, _, err := net.ParseCIDR(ip); err != nil && net.ParseIP(ip) == nil {
+			return fmt.Errorf("invalid ip or cidr %q", ip)
+		}
+	}
+	for _, rule := range cond.Params {
+		if rule.Name == "" {
+			return fmt.Errorf("param name is required")
+		}
+	}
+	return nil
+}
+
+// matchTimeWindow 判断时间是否在时间段内
+func matchTimeWindow(w app.CasbinTimeWindow, t time.Time) bool {
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	weekday := int(t.Weekday())
+	if start <= end {
+		return minute >= start && minute < end && matchWeekday(w.Weekdays, weekday)
+	}
+	// 跨越零点时，零点之后的部分属于前一天的时间段
+	if minute >= start {
+		return matchWeekday(w.Weekdays, weekday)
+	}
+	return minute < end && matchWeekday(w.Weekdays, (weekday+6)%7)
+}
+
+func matchWeekday(weekdays []int, weekday int) bool {
+	return len(weekdays) == 0 || slices.Contains(weekdays, weekday)
+}
+
+// parseClock 解析 HH:MM 为当天的分钟数，24:00 表示一天结束
+func parseClock(clock string) (int, error) {
+	hour, minute, ok := strings.Cut(clock, ":")
+	h, err1 := strconv.Atoi(hour)
+	m, err2 := strconv.Atoi(minute)
+	if !ok || err1 != nil || err2 != nil || h < 0 || m < 0 || m > 59 || h > 24 || h == 24 && m > 0 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", clock)
+	}
+	return h*60 + m, nil
+}
+
+// matchIP 判断IP是否属于列表中的IP或CIDR
+func matchIP(allowed []string, ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, item := range allowed {
+		if _, network, err := net.ParseCIDR(item); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+		} else if other := net.ParseIP(item); other != nil && other.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandParamValue 替换期望值中引用的用户属性
+func expandParamValue(value string, attrs *app.CasbinAttributes) string {
+	if !strings.Contains(value, "{") {
+		return value
+	}
+	return strings.NewReplacer(
+		"{userId}", strconv.FormatUint(uint64(attrs.UserID), 10),
+		"{deptId}", strconv.FormatUint(uint64(attrs.DeptID), 10),
+		"{tenantId}", strconv.FormatUint(uint64(attrs.TenantID), 10),
+	).Replace(value)
+}
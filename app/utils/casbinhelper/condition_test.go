@@ -0,0 +1,197 @@
+package casbinhelper
+
+import (
+	"gin-fast/app/global/app"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 测试用的带ABAC条件的Casbin模型配置
+const testConditionModelConfig = `
+[request_definition]
+r = sub, obj, act, dom, env
+
+[policy_definition]
+p = sub, obj, act, dom
+
+[role_definition]
+g = _, _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub, r.dom) && keyMatch2(r.obj, p.obj) && regexMatch(r.act, p.act) && (r.dom == p.dom || p.dom == "*") && abacMatch(r.env, p.sub, p.obj, p.act, p.dom)
+`
+
+// setupTestConditionCasbin 创建启用ABAC条件的CasbinHelper实例（使用内存适配器）
+func setupTestConditionCasbin(t *testing.T) *CasbinHelper {
+	helper := NewCasbinHelper()
+	m, err := model.NewModelFromString(testConditionModelConfig)
+	require.NoError(t, err, "Failed to create model")
+	helper.enforcer, err = casbin.NewSyncedEnforcer(m)
+	require.NoError(t, err, "Failed to create enforcer")
+	helper.registerConditionFunction()
+	return helper
+}
+
+// TestMatchCondition 测试条件判定
+func TestMatchCondition(t *testing.T) {
+	// 2025-01-06 是周一
+	monday := time.Date(2025, 1, 6, 10, 30, 0, 0, time.Local)
+	attrs := &app.CasbinAttributes{
+		Time:     monday,
+		IP:       "10.1.2.3",
+		UserID:   7,
+		DeptID:   3,
+		TenantID: 1,
+		Plan:     "pro",
+		Params:   map[string]string{"deptId": "3", "status": "1"},
+	}
+
+	assert.True(t, MatchCondition(nil, nil), "Empty condition should always match")
+	assert.True(t, MatchCondition(&app.CasbinCondition{}, attrs))
+	assert.False(t, MatchCondition(&app.CasbinCondition{Plans: []string{"pro"}}, nil), "Condition without attributes should not match")
+
+	workTime := []app.CasbinTimeWindow{{Start: "09:00", End: "18:00", Weekdays: []int{1, 2, 3, 4, 5}}}
+	assert.True(t, MatchCondition(&app.CasbinCondition{Times: workTime}, attrs))
+	assert.False(t, MatchCondition(&app.CasbinCondition{Times: []app.CasbinTimeWindow{{Start: "09:00", End: "10:30"}}}, attrs), "End time is exclusive")
+	assert.False(t, MatchCondition(&app.CasbinCondition{Times: []app.CasbinTimeWindow{{Start: "09:00", End: "18:00", Weekdays: []int{0, 6}}}}, attrs))
+
+	assert.True(t, MatchCondition(&app.CasbinCondition{IPs: []string{"192.168.0.1", "10.0.0.0/8"}}, attrs))
+	assert.False(t, MatchCondition(&app.CasbinCondition{IPs: []string{"10.1.2.4", "172.16.0.0/12"}}, attrs))
+
+	assert.True(t, MatchCondition(&app.CasbinCondition{Plans: []string{"pro", "enterprise"}}, attrs))
+	assert.False(t, MatchCondition(&app.CasbinCondition{Plans: []string{"enterprise"}}, attrs))
+
+	assert.True(t, MatchCondition(&app.CasbinCondition{Params: []app.CasbinParamRule{{Name: "deptId", Value: "{deptId}"}, {Name: "status", Value: "1"}}}, attrs))
+	assert.False(t, MatchCondition(&app.CasbinCondition{Params: []app.CasbinParamRule{{Name: "deptId", Value: "{userId}"}}}, attrs))
+	assert.False(t, MatchCondition(&app.CasbinCondition{Params: []app.CasbinParamRule{{Name: "userId", Value: "{userId}"}}}, attrs), "Missing param should not match")
+
+	// 各项条件需全部满足
+	assert.False(t, MatchCondition(&app.CasbinCondition{Times: workTime, Plans: []string{"enterprise"}}, attrs))
+}
+
+// TestMatchTimeWindow_CrossMidnight 测试跨越零点的时间段
+func TestMatchTimeWindow_CrossMidnight(t *testing.T) {
+	// 周五 22:00 - 次日 06:00
+	window := app.CasbinTimeWindow{Start: "22:00", End: "06:00", Weekdays: []int{5}}
+	assert.True(t, matchTimeWindow(window, time.Date(2025, 1, 10, 23, 0, 0, 0, time.Local)), "Friday night should match")
+	assert.True(t, matchTimeWindow(window, time.Date(2025, 1, 11, 5, 59, 0, 0, time.Local)), "Saturday early morning belongs to Friday")
+	assert.False(t, matchTimeWindow(window, time.Date(2025, 1, 11, 6, 0, 0, 0, time.Local)))
+	assert.False(t, matchTimeWindow(window, time.Date(2025, 1, 10, 5, 0, 0, 0, time.Local)), "Friday early morning belongs to Thursday")
+
+	assert.True(t, matchTimeWindow(app.CasbinTimeWindow{Start: "00:00", End: "24:00"}, time.Date(2025, 1, 10, 23, 59, 0, 0, time.Local)))
+}
+
+// TestValidateCondition 测试条件格式校验
+func TestValidateCondition(t *testing.T) {
+	assert.NoError(t, ValidateCondition(nil))
+	assert.NoError(t, ValidateCondition(&app.CasbinCondition{
+		Times:  []app.CasbinTimeWindow{{Start: "09:00", End: "24:00", Weekdays: []int{0, 6}}},
+		IPs:    []string{"10.0.0.1", "10.0.0.0/8", "::1"},
+		Params: []app.CasbinParamRule{{Name: "deptId", Value: "{deptId}"}},
+	}))
+	assert.Error(t, ValidateCondition(&app.CasbinCondition{Times: []app.CasbinTimeWindow{{Start: "9", End: "18:00"}}}))
+	assert.Error(t, ValidateCondition(&app.CasbinCondition{Times: []app.CasbinTimeWindow{{Start: "09:00", End: "24:30"}}}))
+	assert.Error(t, ValidateCondition(&app.CasbinCondition{Times: []app.CasbinTimeWindow{{Start: "09:00", End: "18:00", Weekdays: []int{7}}}}))
+	assert.Error(t, ValidateCondition(&app.CasbinCondition{IPs: []string{"10.0.0.0/33"}}))
+	assert.Error(t, ValidateCondition(&app.CasbinCondition{Params: []app.CasbinParamRule{{Value: "1"}}}))
+}
+
+// TestEnforceWithAttributes 测试带ABAC条件的权限检查
+func TestEnforceWithAttributes(t *testing.T) {
+	helper := setupTestConditionCasbin(t)
+	domain := "domain_1"
+	require.NoError(t, helper.AddPoliciesForRole(1, [][]string{{"/api/users/list", "GET"}, {"/api/users/:id", "DELETE"}}, domain))
+	require.NoError(t, helper.AddRolesForUserByID(100, []uint{1}, domain))
+	require.NoError(t, helper.SetConditionsForRole(1, []app.CasbinPolicyCondition{
+		{Obj: "/api/users/:id", Act: "DELETE", Condition: &app.CasbinCondition{IPs: []string{"10.0.0.0/8"}, Plans: []string{"pro"}}},
+		{Obj: "/api/users/list", Act: "GET", Condition: &app.CasbinCondition{}},
+	}, domain))
+
+	user := helper.PrefixUser(100)
+	internal := &app.CasbinAttributes{IP: "10.1.2.3", Plan: "pro"}
+	external := &app.CasbinAttributes{IP: "8.8.8.8", Plan: "pro"}
+
+	// 没有条件的策略不受请求属性影响
+	allowed, err := helper.EnforceWithAttributes(external, user, "/api/users/list", "GET", domain)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, err = helper.Enforce(user, "/api/users/list", "GET", domain)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, err = helper.EnforceWithAttributes(internal, user, "/api/users/5", "DELETE", domain)
+	require.NoError(t, err)
+	assert.True(t, allowed, "Request satisfying the condition should be allowed")
+	allowed, err = helper.EnforceWithAttributes(external, user, "/api/users/5", "DELETE", domain)
+	require.NoError(t, err)
+	assert.False(t, allowed, "Request from other network should be denied")
+	allowed, err = helper.Enforce(user, "/api/users/5", "DELETE", domain)
+	require.NoError(t, err)
+	assert.False(t, allowed, "Request without attributes should be denied")
+
+	// 条件只属于指定域
+	conditions, err := helper.GetConditionsForRole(1, domain)
+	require.NoError(t, err)
+	require.Len(t, conditions, 1)
+	assert.Equal(t, "/api/users/:id", conditions[0].Obj)
+	assert.Equal(t, "DELETE", conditions[0].Act)
+	conditions, err = helper.GetConditionsForRole(1)
+	require.NoError(t, err)
+	assert.Empty(t, conditions)
+
+	// 策略重新生成后仍使用原有条件
+	require.NoError(t, helper.RemoveAllPoliciesForRole(1, domain))
+	require.NoError(t, helper.AddPoliciesForRole(1, [][]string{{"/api/users/:id", "DELETE"}}, domain))
+	allowed, err = helper.EnforceWithAttributes(external, user, "/api/users/5", "DELETE", domain)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	explanation, err := helper.Explain(100, "/api/users/5", "DELETE", domain)
+	require.NoError(t, err)
+	assert.False(t, explanation.Allowed)
+	require.Len(t, explanation.Conditions, 1)
+	assert.Equal(t, []string{"pro"}, explanation.Conditions[0].Plans)
+
+	// 清除条件后策略无条件生效
+	require.NoError(t, helper.SetConditionsForRole(1, nil, domain))
+	allowed, err = helper.EnforceWithAttributes(external, user, "/api/users/5", "DELETE", domain)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	require.NoError(t, helper.SetConditionsForRole(1, []app.CasbinPolicyCondition{
+		{Obj: "/api/users/:id", Act: "DELETE", Condition: &app.CasbinCondition{IPs: []string{"10.0.0.0/8"}}},
+	}, domain))
+	require.NoError(t, helper.RemoveDomain(domain))
+	conditions, err = helper.GetConditionsForRole(1, domain)
+	require.NoError(t, err)
+	assert.Empty(t, conditions, "Conditions should be removed with domain")
+}
+
+// TestSetConditionsForRole_Unsupported 测试模型未启用ABAC条件时设置条件
+func TestSetConditionsForRole_Unsupported(t *testing.T) {
+	helper := setupTestCasbin(t)
+	helper.registerConditionFunction()
+	assert.False(t, helper.abac)
+
+	// 不设置条件时不报错
+	assert.NoError(t, helper.SetConditionsForRole(1, []app.CasbinPolicyCondition{{Obj: "/api/users", Act: "GET"}}))
+	err := helper.SetConditionsForRole(1, []app.CasbinPolicyCondition{
+		{Obj: "/api/users", Act: "GET", Condition: &app.CasbinCondition{Plans: []string{"pro"}}},
+	})
+	assert.Error(t, err)
+
+	// 未启用时Enforce仍按原有模型检查
+	require.NoError(t, helper.AddPolicyForRole(1, "/api/users", "GET"))
+	require.NoError(t, helper.AddRolesForUserByID(100, []uint{1}))
+	allowed, err := helper.EnforceWithAttributes(&app.CasbinAttributes{}, helper.PrefixUser(100), "/api/users", "GET")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+}
@@ -0,0 +1,136 @@
+package casbinhelper
+
+import (
+	"fmt"
+	"gin-fast/app/global/app"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// roleChangeStep 批量应用角色变更的一个步骤及其撤销操作
+type roleChangeStep struct {
+	name  string
+	apply func() (bool, error)
+	undo  func() (bool, error)
+}
+
+// ApplyRoleChanges 批量应用多个角色的权限策略、继承关系及ABAC条件变更
+// 先计算与当前策略的差异，再以批量操作依次删除、添加策略及继承关系；任一步骤失败时撤销已完成的步骤并恢复原有条件
+func (s *CasbinHelper) ApplyRoleChanges(changes []app.CasbinRoleChange, domain ...string) error {
+	if s.enforcer == nil {
+		return fmt.Errorf("casbin enforcer not initialized")
+	}
+	dom := s.HandlerDomain(domain)[0]
+	var removePolicies, addPolicies, removeLinks, addLinks [][]string
+	conditions := make(map[uint][]app.CasbinPolicyCondition)
+	oldConditions := make(map[uint][]app.CasbinPolicyCondition)
+	for _, change := range changes {
+		sub := s.PrefixRole(change.RoleID)
+		if change.Policies != nil {
+			current, err := s.enforcer.GetFilteredPolicy(0, sub, "", "", dom)
+			if err != nil {
+				return err
+			}
+			rules := make([][]string, 0, len(change.Policies))
+			for _, policy := range change.Policies {
+				if len(policy) < 2 {
+					return fmt.Errorf("invalid policy format: policy must contain at least obj and act, got %v", policy)
+				}
+				rules = append(rules, []string{sub, policy[0], policy[1], dom})
+			}
+			removed, added := diffRules(current, rules)
+			removePolicies = append(removePolicies, removed...)
+			addPolicies = append(addPolicies, added...)
+		}
+		if change.ParentChanged {
+			current, err := s.enforcer.GetFilteredGroupingPolicy(0, sub, "", dom)
+			if err != nil {
+				return err
+			}
+			var links [][]string
+			if change.ParentID > 0 && change.ParentID != change.RoleID {
+				links = append(links, []string{sub, s.PrefixRole(change.ParentID), dom})
+			}
+			removed, added := diffRules(current, links)
+			removeLinks = append(removeLinks, removed...)
+			addLinks = append(addLinks, added...)
+		}
+		if change.Conditions != nil {
+			old, err := s.GetConditionsForRole(change.RoleID, dom)
+			if err != nil {
+				return err
+			}
+			oldConditions[change.RoleID] = old
+			conditions[change.RoleID] = change.Conditions
+		}
+	}
+
+	// 条件先于策略更新，避免新策略在条件生效前放行
+	if len(conditions) > 0 {
+		if err := s.setConditions(conditions, dom); err != nil {
+			return err
+		}
+	}
+	steps := []roleChangeStep{
+		{"remove policies", s.batch(removePolicies, s.enforcer.RemovePolicies), s.batch(removePolicies, s.enforcer.AddPolicies)},
+		{"add policies", s.batch(addPolicies, s.enforcer.AddPolicies), s.batch(addPolicies, s.enforcer.RemovePolicies)},
+		{"remove role links", s.batch(removeLinks, s.enforcer.RemoveGroupingPolicies), s.batch(removeLinks, s.enforcer.AddGroupingPolicies)},
+		{"add role links", s.batch(addLinks, s.enforcer.AddGroupingPolicies), s.batch(addLinks, s.enforcer.RemoveGroupingPolicies)},
+	}
+	for i, step := range steps {
+		ok, err := step.apply()
+		if err == nil && !ok {
+			err = fmt.Errorf("policies changed concurrently")
+		}
+		if err == nil {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			if _, undoErr := steps[j].undo(); undoErr != nil {
+				app.ZapLog.Error("Failed to undo casbin role changes", zap.String("step", steps[j].name), zap.Error(undoErr))
+			}
+		}
+		if len(oldConditions) > 0 {
+			if undoErr := s.setConditions(oldConditions, dom); undoErr != nil {
+				app.ZapLog.Error("Failed to restore casbin conditions", zap.Error(undoErr))
+			}
+		}
+		return fmt.Errorf("failed to %s: %v", step.name, err)
+	}
+	return nil
+}
+
+// batch 包装批量操作，没有规则时跳过
+func (s *CasbinHelper) batch(rules [][]string, fn func([][]string) (bool, error)) func() (bool, error) {
+	return func() (bool, error) {
+		if len(rules) == 0 {
+			return true, nil
+		}
+		return fn(rules)
+	}
+}
+
+// diffRules 比较当前规则与目标规则，返回需要删除及需要添加的规则
+func diffRules(current, target [][]string) (removed, added [][]string) {
+	keys := make(map[string]bool, len(target))
+	for _, rule := range target {
+		keys[strings.Join(rule, ",")] = true
+	}
+	existing := make(map[string]bool, len(current))
+	for _, rule := range current {
+		key := strings.Join(rule, ",")
+		existing[key] = true
+		if !keys[key] {
+			removed = append(removed, rule)
+		}
+	}
+	for _, rule := range target {
+		key := strings.Join(rule, ",")
+		if !existing[key] {
+			existing[key] = true
+			added = append(added, rule)
+		}
+	}
+	return removed, added
+}
@@ -0,0 +1,93 @@
+package casbinhelper
+
+import (
+	"errors"
+	"gin-fast/app/global/app"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// failingAdapter 第一次保存角色继承关系时失败的适配器，用于测试变更的恢复
+type failingAdapter struct {
+	failed bool
+}
+
+func (*failingAdapter) LoadPolicy(model.Model) error                              { return nil }
+func (*failingAdapter) SavePolicy(model.Model) error                              { return nil }
+func (*failingAdapter) AddPolicy(string, string, []string) error                  { return nil }
+func (*failingAdapter) RemovePolicy(string, string, []string) error               { return nil }
+func (*failingAdapter) RemoveFilteredPolicy(string, string, int, ...string) error { return nil }
+func (*failingAdapter) RemovePolicies(string, string, [][]string) error           { return nil }
+func (a *failingAdapter) AddPolicies(sec string, ptype string, rules [][]string) error {
+	if sec == "g" && !a.failed {
+		a.failed = true
+		return errors.New("storage unavailable")
+	}
+	return nil
+}
+
+// TestApplyRoleChanges 测试批量应用角色的权限策略、继承关系及条件
+func TestApplyRoleChanges(t *testing.T) {
+	helper := setupTestConditionCasbin(t)
+	domain := "domain_1"
+	require.NoError(t, helper.AddPoliciesForRole(1, [][]string{{"/api/a", "GET"}, {"/api/b", "GET"}}, domain))
+	require.NoError(t, helper.AddRoleInheritance(1, 3, domain))
+
+	cond := &app.CasbinCondition{Plans: []string{"pro"}}
+	err := helper.ApplyRoleChanges([]app.CasbinRoleChange{
+		{RoleID: 1, Policies: [][]string{{"/api/b", "GET"}, {"/api/c", "POST"}}, ParentChanged: true, ParentID: 2,
+			Conditions: []app.CasbinPolicyCondition{{Obj: "/api/c", Act: "POST", Condition: cond}}},
+		{RoleID: 2, Policies: [][]string{{"/api/x", "GET"}}},
+		{RoleID: 4, ParentChanged: true, ParentID: 4},
+	}, domain)
+	require.NoError(t, err)
+
+	policies, err := helper.GetPoliciesForRole(1, domain)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, [][]string{{"/api/b", "GET"}, {"/api/c", "POST"}}, policies)
+	policies, err = helper.GetPoliciesForRole(2, domain)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"/api/x", "GET"}}, policies)
+
+	links, err := helper.enforcer.GetFilteredGroupingPolicy(0, helper.PrefixRole(1), "", domain)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"role_1", "role_2", domain}}, links)
+	links, err = helper.enforcer.GetFilteredGroupingPolicy(0, helper.PrefixRole(4), "", domain)
+	require.NoError(t, err)
+	assert.Empty(t, links, "Role should not inherit itself")
+
+	conditions, err := helper.GetConditionsForRole(1, domain)
+	require.NoError(t, err)
+	assert.Equal(t, []app.CasbinPolicyCondition{{Obj: "/api/c", Act: "POST", Condition: cond}}, conditions)
+}
+
+// TestApplyRoleChangesRestore 测试任一步骤失败时恢复原有策略、继承关系及条件
+func TestApplyRoleChangesRestore(t *testing.T) {
+	app.ZapLog = zap.NewNop()
+	helper := setupTestConditionCasbin(t)
+	domain := "domain_1"
+	require.NoError(t, helper.AddPoliciesForRole(1, [][]string{{"/api/a", "GET"}, {"/api/b", "GET"}}, domain))
+	require.NoError(t, helper.AddRoleInheritance(1, 3, domain))
+	oldCond := []app.CasbinPolicyCondition{{Obj: "/api/a", Act: "GET", Condition: &app.CasbinCondition{Plans: []string{"basic"}}}}
+	require.NoError(t, helper.SetConditionsForRole(1, oldCond, domain))
+	helper.enforcer.SetAdapter(&failingAdapter{})
+
+	err := helper.ApplyRoleChanges([]app.CasbinRoleChange{
+		{RoleID: 1, Policies: [][]string{{"/api/c", "POST"}}, ParentChanged: true, ParentID: 2, Conditions: []app.CasbinPolicyCondition{}},
+	}, domain)
+	require.Error(t, err)
+
+	policies, err := helper.GetPoliciesForRole(1, domain)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, [][]string{{"/api/a", "GET"}, {"/api/b", "GET"}}, policies)
+	links, err := helper.enforcer.GetFilteredGroupingPolicy(0, helper.PrefixRole(1), "", domain)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"role_1", "role_3", domain}}, links)
+	conditions, err := helper.GetConditionsForRole(1, domain)
+	require.NoError(t, err)
+	assert.Equal(t, oldCond, conditions)
+}
@@ -0,0 +1,159 @@
+package casbinhelper
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"gin-fast/app/global/app"
+	"sync"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"go.uber.org/zap"
+)
+
+// 策略变更消息类型
+const (
+	WatcherMethodUpdate               = "Update"               // 重新加载全部策略
+	WatcherMethodAddPolicies          = "AddPolicies"          // 增加策略
+	WatcherMethodRemovePolicies       = "RemovePolicies"       // 删除策略
+	WatcherMethodRemoveFilteredPolicy = "RemoveFilteredPolicy" // 按字段删除策略
+)
+
+// WatcherMessage 策略变更消息
+type WatcherMessage struct {
+	ID          string     `json:"id"`                    // 发布消息的实例ID，实例忽略自己发布的消息
+	Method      string     `json:"method"`                // 消息类型
+	Sec         string     `json:"sec,omitempty"`         // 策略段(p/g)
+	Ptype       string     `json:"ptype,omitempty"`       // 策略类型
+	Rules       [][]string `json:"rules,omitempty"`       // 增加或删除的策略
+	FieldIndex  int        `json:"fieldIndex,omitempty"`  // 按字段删除时的起始字段
+	FieldValues []string   `json:"fieldValues,omitempty"` // 按字段删除时的字段值
+}
+
+// Watcher 基于发布订阅的Casbin策略监听器
+// 本实例修改策略后向频道发布变更，其他实例收到后增量更新内存中的策略
+type Watcher struct {
+	pubsub      app.PubSubInterf
+	channel     string
+	id          string
+	unsubscribe func() error
+
+	mu       sync.RWMutex
+	callback func(string)
+}
+
+// 编译时检查是否实现了接口
+var _ persist.WatcherEx = (*Watcher)(nil)
+
+// NewWatcher 创建策略监听器并订阅频道
+func NewWatcher(pubsub app.PubSubInterf, channel string) (*Watcher, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	w := &Watcher{pubsub: pubsub, channel: channel, id: hex.EncodeToString(buf)}
+	unsubscribe, err := pubsub.Subscribe(channel, w.receive, w.resubscribe)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe channel %s: %v", channel, err)
+	}
+	w.unsubscribe = unsubscribe
+	return w, nil
+}
+
+// SetUpdateCallback 设置收到其他实例策略变更时的回调，参数为消息内容
+func (w *Watcher) SetUpdateCallback(callback func(string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callback = callback
+	return nil
+}
+
+// Update 通知其他实例重新加载全部策略
+func (w *Watcher) Update() error {
+	return w.publish(&WatcherMessage{Method: WatcherMethodUpdate})
+}
+
+// Close 取消订阅
+func (w *Watcher) Close() {
+	if w.unsubscribe != nil {
+		w.unsubscribe()
+	}
+}
+
+// UpdateForAddPolicy 通知其他实例增加策略
+func (w *Watcher) UpdateForAddPolicy(sec, ptype string, params ...string) error {
+	return w.UpdateForAddPolicies(sec, ptype, params)
+}
+
+// UpdateForRemovePolicy 通知其他实例删除策略
+func (w *Watcher) UpdateForRemovePolicy(sec, ptype string, params ...string) error {
+	return w.UpdateForRemovePolicies(sec, ptype, params)
+}
+
+// UpdateForRemoveFilteredPolicy 通知其他实例按字段删除策略
+func (w *Watcher) UpdateForRemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return w.publish(&WatcherMessage{
+		Method:      WatcherMethodRemoveFilteredPolicy,
+		Sec:         sec,
+		Ptype:       ptype,
+		FieldIndex:  fieldIndex,
+		FieldValues: fieldValues,
+	})
+}
+
+// UpdateForSavePolicy 通知其他实例重新加载全部策略
+func (w *Watcher) UpdateForSavePolicy(model model.Model) error {
+	return w.Update()
+}
+
+// UpdateForAddPolicies 通知其他实例批量增加策略
+func (w *Watcher) UpdateForAddPolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(&WatcherMessage{Method: WatcherMethodAddPolicies, Sec: sec, Ptype: ptype, Rules: rules})
+}
+
+// UpdateForRemovePolicies 通知其他实例批量删除策略
+func (w *Watcher) UpdateForRemovePolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(&WatcherMessage{Method: WatcherMethodRemovePolicies, Sec: sec, Ptype: ptype, Rules: rules})
+}
+
+// publish 发布策略变更消息
+func (w *Watcher) publish(msg *WatcherMessage) error {
+	msg.ID = w.id
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return w.pubsub.Publish(context.Background(), w.channel, string(data))
+}
+
+// receive 处理收到的消息，忽略本实例发布的消息
+func (w *Watcher) receive(message string) {
+	msg := &WatcherMessage{}
+	if err := json.Unmarshal([]byte(message), msg); err != nil {
+		app.ZapLog.Warn("Invalid casbin watcher message", zap.String("message", message), zap.Error(err))
+		return
+	}
+	if msg.ID == w.id {
+		return
+	}
+	w.mu.RLock()
+	callback := w.callback
+	w.mu.RUnlock()
+	if callback != nil {
+		callback(message)
+	}
+}
+
+// resubscribe 重新订阅后期间的消息可能已丢失，重新加载全部策略
+func (w *Watcher) resubscribe() {
+	w.mu.RLock()
+	callback := w.callback
+	w.mu.RUnlock()
+	if callback != nil {
+		data, _ := json.Marshal(&WatcherMessage{Method: WatcherMethodUpdate})
+		callback(string(data))
+	}
+}
@@ -0,0 +1,164 @@
+package casbinhelper
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"gin-fast/app/global/app"
+
+	stringadapter "github.com/casbin/casbin/v2/persist/string-adapter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakePubSub 进程内的发布订阅，同步投递消息给所有订阅者
+type fakePubSub struct {
+	mu           sync.Mutex
+	subscribers  map[string][]func(string)
+	resubscribes []func()
+}
+
+func newFakePubSub() *fakePubSub {
+	return &fakePubSub{subscribers: make(map[string][]func(string))}
+}
+
+func (p *fakePubSub) Publish(ctx context.Context, channel string, message string) error {
+	p.mu.Lock()
+	handlers := append([]func(string){}, p.subscribers[channel]...)
+	p.mu.Unlock()
+	for _, handler := range handlers {
+		handler(message)
+	}
+	return nil
+}
+
+func (p *fakePubSub) Subscribe(channel string, onMessage func(string), onResubscribe func()) (func() error, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.subscribers[channel] = append(p.subscribers[channel], onMessage)
+	p.resubscribes = append(p.resubscribes, onResubscribe)
+	return func() error { return nil }, nil
+}
+
+// setupWatchedCasbin 创建两个通过同一频道同步策略的实例
+func setupWatchedCasbin(t *testing.T) (*CasbinHelper, *CasbinHelper, *fakePubSub) {
+	app.ZapLog = zap.NewNop()
+	pubsub := newFakePubSub()
+	a := setupTestCasbin(t)
+	b := setupTestCasbin(t)
+	require.NoError(t, a.SetWatcher(pubsub, "casbin_policy"))
+	require.NoError(t, b.SetWatcher(pubsub, "casbin_policy"))
+	t.Cleanup(func() {
+		a.Close()
+		b.Close()
+	})
+	return a, b, pubsub
+}
+
+func TestWatcherPropagatesPolicies(t *testing.T) {
+	a, b, _ := setupWatchedCasbin(t)
+	domain := "domain_1"
+
+	require.NoError(t, a.AddPoliciesForRole(1, [][]string{{"/api/users/list", "GET"}, {"/api/users/add", "POST"}}, domain))
+	require.NoError(t, a.AddRoleInheritance(2, 1, domain))
+	require.NoError(t, a.AddRolesForUserByID(100, []uint{2}, domain))
+
+	allowed, err := b.Enforce(b.PrefixUser(100), "/api/users/list", "GET", domain)
+	require.NoError(t, err)
+	assert.True(t, allowed, "peer should apply added policies and role links")
+
+	require.NoError(t, a.RemovePolicyForRole(1, "/api/users/list", "GET", domain))
+	allowed, err = b.Enforce(b.PrefixUser(100), "/api/users/list", "GET", domain)
+	require.NoError(t, err)
+	assert.False(t, allowed, "peer should apply removed policy")
+
+	allowed, err = b.Enforce(b.PrefixUser(100), "/api/users/add", "POST", domain)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	// 按字段删除用户的所有角色
+	require.NoError(t, a.DeleteRolesForUserByID(100, nil, domain))
+	allowed, err = b.Enforce(b.PrefixUser(100), "/api/users/add", "POST", domain)
+	require.NoError(t, err)
+	assert.False(t, allowed, "peer should apply filtered removal of role links")
+
+	// 发布者忽略自己的消息，策略不会重复
+	policies, err := a.enforcer.GetPolicy()
+	require.NoError(t, err)
+	assert.Len(t, policies, 1)
+	peerPolicies, err := b.enforcer.GetPolicy()
+	require.NoError(t, err)
+	assert.Equal(t, policies, peerPolicies)
+}
+
+// TestWatcherConcurrentEnforce 应用监听器消息与鉴权并发执行，需配合 -race 运行
+func TestWatcherConcurrentEnforce(t *testing.T) {
+	a, b, _ := setupWatchedCasbin(t)
+	domain := "domain_1"
+	// 全量更新消息从适配器重新加载策略
+	b.enforcer.SetAdapter(stringadapter.NewAdapter("p, role_1, /api/users/list, GET, *"))
+	require.NoError(t, a.AddRolesForUserByID(100, []uint{1}, domain))
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				_, err := b.Enforce(b.PrefixUser(100), "/api/users/list", "GET", domain)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		require.NoError(t, a.AddPoliciesForRole(1, [][]string{{"/api/users/list", "GET"}}, domain))
+		require.NoError(t, a.AddRoleInheritance(2, 1, domain))
+		require.NoError(t, a.RemovePolicyForRole(1, "/api/users/list", "GET", domain))
+		require.NoError(t, a.DeleteRolesForUserByID(200, nil, domain))
+		if i%10 == 0 {
+			b.applyWatcherMessage(`{"id":"other","method":"Update"}`)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestWatcherReloadPolicy(t *testing.T) {
+	a, b, pubsub := setupWatchedCasbin(t)
+	// 模拟数据库中的策略
+	a.enforcer.SetAdapter(stringadapter.NewAdapter("p, role_1, /api/users/list, GET, *"))
+	b.enforcer.SetAdapter(stringadapter.NewAdapter("p, role_1, /api/users/list, GET, *"))
+	require.NoError(t, b.enforcer.LoadPolicy())
+	hasPolicy := func() bool {
+		ok, err := b.enforcer.HasPolicy("role_1", "/api/users/list", "GET", "*")
+		require.NoError(t, err)
+		return ok
+	}
+
+	// 无法解析的消息被忽略
+	require.NoError(t, pubsub.Publish(context.Background(), "casbin_policy", "not json"))
+	assert.True(t, hasPolicy())
+
+	// 无法增量应用的消息及全量更新消息重新加载全部策略
+	for _, message := range []string{`{"id":"other","method":"AddPolicies","sec":"x","ptype":"x"}`, `{"id":"other","method":"Update"}`} {
+		b.enforcer.ClearPolicy()
+		require.NoError(t, pubsub.Publish(context.Background(), "casbin_policy", message))
+		assert.True(t, hasPolicy(), message)
+	}
+
+	// 重新订阅后重新加载全部策略
+	b.enforcer.ClearPolicy()
+	for _, resubscribe := range pubsub.resubscribes {
+		resubscribe()
+	}
+	assert.True(t, hasPolicy())
+}
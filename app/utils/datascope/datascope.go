@@ -1,24 +1,34 @@
 package datascope
 
 import (
+	"context"
 	"gin-fast/app/global/app"
 	"gin-fast/app/models"
 	"gin-fast/app/utils/common"
+	"gin-fast/app/utils/tenanthelper"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-// 获取用户角色列表
-func getUserRoles(userID uint) ([]*models.SysRole, error) {
-	var user models.User
-	err := app.DB().Preload("Roles").Where("id = ?", userID).First(&user).Error
-	if err != nil {
-		return nil, err
-	}
-	return user.Roles, nil
+// 获取用户在租户下当前生效的角色，用户关联了多个租户时只取该租户的角色
+func getUserRoles(c context.Context, userID, tenantID uint) ([]*models.SysRole, error) {
+	db := app.DB().WithContext(tenanthelper.SkipTenant(c))
+	subQuery := db.Model(&models.SysUserRole{}).Select("role_id").
+		Where("user_id = ?", userID).Scopes(models.ActiveUserRoleScope(time.Now()))
+	var roles []*models.SysRole
+	err := db.Where("id IN (?) AND tenant_id = ?", subQuery, tenantID).Find(&roles).Error
+	return roles, err
+}
+
+// 获取租户的所有部门
+func getTenantDepartments(c context.Context, tenantID uint) (models.SysDepartmentList, error) {
+	var departments models.SysDepartmentList
+	err := app.DB().WithContext(tenanthelper.SkipTenant(c)).Where("tenant_id = ?", tenantID).Find(&departments).Error
+	return departments, err
 }
 
 // 获取部门及其所有子部门ID
@@ -88,23 +98,23 @@ func stringToUintSlice(s string) ([]uint, error) {
 }
 
 // 获取用户所属部门ID
-func getUserDepartmentID(userID uint) (uint, error) {
+func getUserDepartmentID(c context.Context, userID uint) (uint, error) {
 	var user models.User
-	err := app.DB().Select("dept_id").Where("id = ?", userID).First(&user).Error
+	err := app.DB().WithContext(tenanthelper.SkipTenant(c)).Select("dept_id").Where("id = ?", userID).First(&user).Error
 	if err != nil {
 		return 0, err
 	}
 	return user.DeptID, nil
 }
 
-// 根据部门ID获取用户ID列表
-func getUserIDsByDepartmentIDs(deptIDs []uint) ([]uint, error) {
+// 根据部门ID获取用户ID列表，最多返回 limit 个；部门已限定在当前租户内
+func getUserIDsByDepartmentIDs(c context.Context, deptIDs []uint, limit int) ([]uint, error) {
 	if len(deptIDs) == 0 {
 		return []uint{}, nil
 	}
 
 	var users []models.User
-	err := app.DB().Select("id").Where("dept_id IN ?", deptIDs).Find(&users).Error
+	err := app.DB().WithContext(tenanthelper.SkipTenant(c)).Select("id").Where("dept_id IN ?", deptIDs).Limit(limit).Find(&users).Error
 	if err != nil {
 		return nil, err
 	}
@@ -117,6 +127,8 @@ func getUserIDsByDepartmentIDs(deptIDs []uint) ([]uint, error) {
 }
 
 // 数据权限(默认可以查看自己创建的数据)
+// 按查询所在表的数据权限规则过滤，见 Rule
+// 用户的数据权限范围按用户及租户缓存，角色、部门或用户所属部门变更时失效
 func GetDataScope(c *gin.Context) func(db *gorm.DB) *gorm.DB {
 	// 定义数据权限函数
 	return func(db *gorm.DB) *gorm.DB {
@@ -138,104 +150,16 @@ func GetDataScope(c *gin.Context) func(db *gorm.DB) *gorm.DB {
 			}
 		}
 
-		// 获取用户角色
-		roles, err := getUserRoles(userID)
-		if err != nil || len(roles) == 0 {
-			// 如果没有角色或查询失败，默认只能查看自己的数据
-			return db.Where("created_by = ?", userID)
-		}
-
-		// 检查是否有全表权限的角色
-		hasFullPermission := false
-		for _, role := range roles {
-			if role.DataScope == 1 {
-				hasFullPermission = true
-				break
-			}
-		}
-
-		if hasFullPermission {
-			// 有全表权限，不添加任何限制
+		rule, err := ruleFor(db)
+		if err != nil {
+			_ = db.AddError(err)
 			return db
 		}
-
-		// 收集所有需要查询的部门ID
-		allowedDeptIDs := make(map[uint]bool)
-
-		// 获取用户所属部门ID（只查询一次）
-		userDeptID, _ := getUserDepartmentID(userID)
-
-		// 构建部门树
-		var allDepartments models.SysDepartmentList
-		err = app.DB().Find(&allDepartments).Error
+		scope, err := Resolve(c, userID, claims.TenantID)
 		if err != nil {
 			// 查询失败，默认只能查看自己的数据
-			return db.Where("created_by = ?", userID)
-		}
-		// 构建部门树
-		departmentTree := allDepartments.BuildTree()
-
-		// 收集所有需要处理的部门ID
-		var allDeptIDs []uint
-		for _, role := range roles {
-			switch role.DataScope {
-			case 2: // 查询checked_depts字段定义的部门所属用户创建的数据
-				if role.CheckedDepts != "" {
-					deptIDs, err := stringToUintSlice(role.CheckedDepts)
-					if err == nil && len(deptIDs) > 0 {
-						allDeptIDs = append(allDeptIDs, deptIDs...)
-					}
-				}
-
-			case 3: // 查询自身所属部门的所属用户创建的数据
-				if userDeptID != 0 {
-					allDeptIDs = append(allDeptIDs, userDeptID)
-				}
-
-			case 4: // 查询自身所属部门及该部门下所有子级部门的所属用户创建的数据
-				if userDeptID != 0 {
-					deptIDs, err := getDepartmentAndChildrenIDs(departmentTree, userDeptID)
-					if err == nil && len(deptIDs) > 0 {
-						allDeptIDs = append(allDeptIDs, deptIDs...)
-					}
-				}
-			}
-		}
-
-		// 去重部门ID
-		for _, deptID := range allDeptIDs {
-			allowedDeptIDs[deptID] = true
-		}
-
-		// 将部门ID转换为切片
-		var deptIDSlice []uint
-		for deptID := range allowedDeptIDs {
-			deptIDSlice = append(deptIDSlice, deptID)
+			scope = &Scope{UserID: userID}
 		}
-
-		// 批量查询所有相关部门的用户ID
-		var allowedUserIDs []uint
-		if len(deptIDSlice) > 0 {
-			userIDs, err := getUserIDsByDepartmentIDs(deptIDSlice)
-			if err == nil {
-				allowedUserIDs = append(allowedUserIDs, userIDs...)
-			}
-		}
-
-		// 添加当前用户ID（默认可以查看自己的数据）
-		allowedUserIDs = append(allowedUserIDs, userID)
-
-		// 去重用户ID
-		userIDMap := make(map[uint]bool)
-		var userIDSlice []uint
-		for _, uid := range allowedUserIDs {
-			if !userIDMap[uid] {
-				userIDMap[uid] = true
-				userIDSlice = append(userIDSlice, uid)
-			}
-		}
-
-		// 添加数据权限过滤条件
-		return db.Where("created_by IN ?", userIDSlice)
+		return scope.Apply(db, rule)
 	}
 }
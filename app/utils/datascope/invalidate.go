@@ -0,0 +1,169 @@
+package datascope
+
+import (
+	"context"
+	"fmt"
+	"gin-fast/app/global/app"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// skipInvalidateKey 更新的列不影响数据权限时跳过清除缓存
+	skipInvalidateKey = "datascope:skip_invalidate"
+	// deferContextKey 请求上下文中记录事务内的变更，请求结束后再次清除缓存
+	deferContextKey = "datascope:defer_invalidate"
+	// txInvalidateDelay 请求之外(如后台任务)的事务无法获知提交时间，延迟该时间后再次清除缓存
+	txInvalidateDelay = 5 * time.Second
+)
+
+// invalidateColumns 变更后需要重新计算数据权限的表及影响数据权限的列
+// 新增及删除记录时总是失效；更新时只有更新了这些列(列为空表示任意列)才失效
+var invalidateColumns = map[string][]string{
+	"sys_users":      {"dept_id", "tenant_id"},
+	"sys_role":       {"data_scope", "checked_depts", "tenant_id"},
+	"sys_user_role":  nil,
+	"sys_department": {"parent_id", "tenant_id"},
+}
+
+// deferred 请求中是否有事务内的变更需要在提交后清除缓存
+type deferred struct {
+	pending atomic.Bool
+}
+
+// Invalidate 使所有用户的数据权限缓存失效
+// 缓存键中包含版本号，更新版本号后旧的缓存不再使用并自然过期
+func Invalidate(c context.Context) {
+	if app.Cache == nil {
+		return
+	}
+	version := strconv.FormatInt(time.Now().UnixNano(), 10)
+	if err := app.Cache.Set(c, versionKey, version, versionExpire); err != nil {
+		app.ZapLog.Error("清除数据权限缓存失败", zap.Error(err))
+	}
+}
+
+// DeferInvalidate 开启请求内的延迟清除，与 FlushInvalidate 配合，在请求结束(事务已提交)后清除事务中变更的数据权限缓存
+func DeferInvalidate(c *gin.Context) {
+	c.Set(deferContextKey, &deferred{})
+}
+
+// FlushInvalidate 请求中的事务变更了影响数据权限的数据时再次清除缓存，在请求结束后调用
+func FlushInvalidate(c *gin.Context) {
+	if d, ok := c.Value(deferContextKey).(*deferred); ok && d.pending.Load() {
+		Invalidate(context.Background())
+	}
+}
+
+// InvalidateHook gorm回调(新增、更新及删除之后)，角色、部门、用户所属部门或用户角色变更后使数据权限缓存失效
+// 事务提交前其他请求仍读取到旧数据，可能以新版本号缓存旧的数据权限，因此事务中的变更在提交后需再次清除
+func InvalidateHook(gormDB *gorm.DB) {
+	stmt := gormDB.Statement
+	if gormDB.Error != nil || stmt.RowsAffected == 0 {
+		return
+	}
+	if _, ok := invalidateColumns[stmt.Table]; !ok {
+		return
+	}
+	if skip, ok := gormDB.InstanceGet(skipInvalidateKey); ok && skip.(bool) {
+		return
+	}
+	Invalidate(stmt.Context)
+
+	if _, inTx := stmt.ConnPool.(gorm.TxCommitter); !inTx {
+		return
+	}
+	if d, ok := stmt.Context.Value(deferContextKey).(*deferred); ok {
+		d.pending.Store(true)
+		return
+	}
+	time.AfterFunc(txInvalidateDelay, func() {
+		Invalidate(context.Background())
+	})
+}
+
+// PrepareUpdateHook gorm回调(执行更新之前)，更新的列不影响数据权限时跳过 InvalidateHook
+// 如登录、修改个人资料等只更新其他列的操作不清除缓存；按主键保存整条记录时比较更新前后的值
+func PrepareUpdateHook(gormDB *gorm.DB) {
+	stmt := gormDB.Statement
+	columns, ok := invalidateColumns[stmt.Table]
+	if gormDB.Error != nil || !ok || len(columns) == 0 {
+		return
+	}
+	values, known := updatingValues(stmt)
+	if !known {
+		return
+	}
+	updating := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if _, ok := values[column]; ok {
+			updating = append(updating, column)
+		}
+	}
+	gormDB.InstanceSet(skipInvalidateKey, len(updating) == 0 || !changed(gormDB, values, updating))
+}
+
+// updatingValues 更新语句将要更新的列及新值，无法确定时 known 为false
+func updatingValues(stmt *gorm.Statement) (values map[string]interface{}, known bool) {
+	if stmt.Schema == nil {
+		return nil, false
+	}
+	values = map[string]interface{}{}
+	if dest, ok := stmt.Dest.(map[string]interface{}); ok {
+		for key, value := range dest {
+			if field := stmt.Schema.LookUpField(key); field != nil {
+				key = field.DBName
+			}
+			values[key] = value
+		}
+		return values, true
+	}
+
+	// 结构体：Save 更新所有列，Updates 更新选择的列或非零值的列
+	destValue := reflect.Indirect(reflect.ValueOf(stmt.Dest))
+	if destValue.Kind() != reflect.Struct || destValue.Type() != stmt.Schema.ModelType {
+		return nil, false
+	}
+	selectColumns, restricted := stmt.SelectAndOmitColumns(false, true)
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		value, zero := field.ValueOf(stmt.Context, destValue)
+		if selected, ok := selectColumns[field.DBName]; (ok && selected) || (!ok && !restricted && !zero) {
+			values[field.DBName] = value
+		}
+	}
+	return values, true
+}
+
+// changed 按主键查询更新前的值，比较列是否变化；无法按主键确定更新的记录时按已变化处理
+func changed(gormDB *gorm.DB, values map[string]interface{}, columns []string) bool {
+	stmt := gormDB.Statement
+	field := stmt.Schema.PrioritizedPrimaryField
+	if field == nil || !stmt.ReflectValue.IsValid() || stmt.ReflectValue.Kind() != reflect.Struct {
+		return true
+	}
+	id, zero := field.ValueOf(stmt.Context, stmt.ReflectValue)
+	if zero {
+		return true
+	}
+	old := map[string]interface{}{}
+	err := gormDB.Session(&gorm.Session{NewDB: true}).Table(stmt.Table).Select(columns).
+		Where(field.DBName+" = ?", id).Take(&old).Error
+	if err != nil {
+		return true
+	}
+	for _, column := range columns {
+		if fmt.Sprint(old[column]) != fmt.Sprint(values[column]) {
+			return true
+		}
+	}
+	return false
+}
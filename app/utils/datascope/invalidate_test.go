@@ -0,0 +1,150 @@
+package datascope
+
+import (
+	"context"
+	"gin-fast/app/global/app"
+	"gin-fast/app/models"
+	"gin-fast/app/utils/cachehelper"
+	"gin-fast/app/utils/ymlconfig"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormLog "gorm.io/gorm/logger"
+)
+
+// 测试配置，使用 app.DB() 获取测试数据库
+const testScopeConfig = `
+gormv2:
+  usedbtype: "mysql"
+datascope:
+  maxinlist: 100
+`
+
+func setupTestScopeDB(t *testing.T) *gorm.DB {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yml"), []byte(testScopeConfig), 0644))
+	app.ConfigYml = ymlconfig.CreateYamlFactory(dir)
+	app.Cache = cachehelper.NewMemoryHelper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormLog.Default.LogMode(gormLog.Silent)})
+	require.NoError(t, err)
+	_ = db.Callback().Update().Before("gorm:update").Register("DataScopePrepareUpdateHook", PrepareUpdateHook)
+	_ = db.Callback().Create().After("gorm:after_create").Register("DataScopeInvalidateHook", InvalidateHook)
+	_ = db.Callback().Update().After("gorm:after_update").Register("DataScopeInvalidateHook", InvalidateHook)
+	_ = db.Callback().Delete().After("gorm:after_delete").Register("DataScopeInvalidateHook", InvalidateHook)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.SysRole{}, &models.SysUserRole{}, &models.SysDepartment{}))
+	app.GormDbMysql = db
+	return db
+}
+
+func version() string {
+	v, _ := app.Cache.Get(context.Background(), versionKey)
+	return v
+}
+
+// TestInvalidateHook 测试只有影响数据权限的变更才清除缓存
+func TestInvalidateHook(t *testing.T) {
+	db := setupTestScopeDB(t)
+
+	user := &models.User{Username: "u1", DeptID: 1}
+	before := version()
+	require.NoError(t, db.Create(user).Error)
+	assert.NotEqual(t, before, version(), "Creating user should invalidate")
+
+	// 只更新其他列
+	before = version()
+	user.NickName = "nick"
+	require.NoError(t, db.Save(user).Error)
+	require.NoError(t, db.Model(user).Update("avatar", "a.png").Error)
+	require.NoError(t, db.Model(user).Updates(&models.User{Description: "desc"}).Error)
+	assert.Equal(t, before, version(), "Updating other columns should not invalidate")
+
+	// 更新所属部门
+	require.NoError(t, db.Model(user).Update("dept_id", 2).Error)
+	assert.NotEqual(t, before, version())
+	before = version()
+	user.DeptID = 3
+	require.NoError(t, db.Save(user).Error)
+	assert.NotEqual(t, before, version())
+
+	// 无法按主键确定记录时按已变化处理
+	before = version()
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", user.ID).Update("dept_id", 3).Error)
+	assert.NotEqual(t, before, version())
+
+	role := &models.SysRole{Name: "r1", DataScope: models.DataScopeSelf}
+	require.NoError(t, db.Create(role).Error)
+	before = version()
+	require.NoError(t, db.Model(role).Update("sort", 2).Error)
+	assert.Equal(t, before, version())
+	require.NoError(t, db.Model(role).Update("data_scope", models.DataScopeAll).Error)
+	assert.NotEqual(t, before, version())
+}
+
+// TestInvalidateAfterCommit 测试请求中事务内的变更在请求结束后再次清除缓存
+func TestInvalidateAfterCommit(t *testing.T) {
+	db := setupTestScopeDB(t)
+	user := &models.User{Username: "u1", DeptID: 1}
+	require.NoError(t, db.Create(user).Error)
+
+	c := &gin.Context{}
+	DeferInvalidate(c)
+	require.NoError(t, db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		return tx.Model(user).Update("dept_id", 2).Error
+	}))
+	inTx := version()
+	FlushInvalidate(c)
+	assert.NotEqual(t, inTx, version(), "Should invalidate again after commit")
+
+	// 没有事务内的变更时不清除
+	c = &gin.Context{}
+	DeferInvalidate(c)
+	require.NoError(t, db.WithContext(c).Model(user).Update("dept_id", 3).Error)
+	before := version()
+	FlushInvalidate(c)
+	assert.Equal(t, before, version())
+}
+
+// TestComputeTenant 测试数据权限只按当前租户的角色及部门计算
+func TestComputeTenant(t *testing.T) {
+	db := setupTestScopeDB(t)
+	ctx := context.Background()
+
+	require.NoError(t, db.Create(&[]models.SysDepartment{
+		{BaseModel: models.BaseModel{ID: 100}, Name: "d1", TenantID: 1},
+		{BaseModel: models.BaseModel{ID: 200}, Name: "d2", TenantID: 2},
+	}).Error)
+	require.NoError(t, db.Create(&[]models.User{
+		{BaseModel: models.BaseModel{ID: 10}, Username: "u10", DeptID: 100, TenantID: 1},
+		{BaseModel: models.BaseModel{ID: 11}, Username: "u11", DeptID: 200, TenantID: 2},
+	}).Error)
+	require.NoError(t, db.Create(&[]models.SysRole{
+		{BaseModel: models.BaseModel{ID: 1}, Name: "all", DataScope: models.DataScopeAll, TenantID: 1},
+		{BaseModel: models.BaseModel{ID: 2}, Name: "dept", DataScope: models.DataScopeDept, TenantID: 2},
+		{BaseModel: models.BaseModel{ID: 3}, Name: "custom", DataScope: models.DataScopeCustom, CheckedDepts: "100,200", TenantID: 2},
+	}).Error)
+	require.NoError(t, db.Create(&[]models.SysUserRole{{UserID: 10, RoleID: 1}, {UserID: 10, RoleID: 2}}).Error)
+
+	scope, err := compute(ctx, 10, 1)
+	require.NoError(t, err)
+	assert.True(t, scope.All)
+
+	// 租户1的全部数据权限不影响租户2，所属部门不属于租户2时按本人处理
+	scope, err = compute(ctx, 10, 2)
+	require.NoError(t, err)
+	assert.False(t, scope.All)
+	assert.Empty(t, scope.DeptIDs)
+
+	// 自定义部门中其他租户的部门不计入
+	require.NoError(t, db.Create(&models.SysUserRole{UserID: 10, RoleID: 3}).Error)
+	scope, err = compute(ctx, 10, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{200}, scope.DeptIDs)
+	assert.Equal(t, []uint{11}, scope.UserIDs)
+}
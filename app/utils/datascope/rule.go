@@ -0,0 +1,124 @@
+package datascope
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin-fast/app/global/app"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// DefaultUserColumn 未声明规则时按数据的创建人过滤
+	DefaultUserColumn = "created_by"
+	// TagName 模型字段上声明数据权限列的标签，如 `datascope:"user"`
+	TagName = "datascope"
+)
+
+// Rule 数据权限规则，声明数据按哪些列归属到用户及部门
+// 规则可按表在配置 datascope.rules.{表名} 中声明，也可在模型字段上使用 datascope 标签声明：
+//   - user: 数据所属用户列，替代默认的 created_by
+//   - dept: 数据所属部门列，部门范围直接按该列过滤，不再按所属用户的部门过滤
+//   - self: 同 user，并且该表的数据只能由本人查看，不受角色数据权限影响
+//
+// 同时声明用户列和部门列时，满足任一条件的数据均可查看
+type Rule struct {
+	UserColumn string `json:"userColumn"` // 数据所属用户列，默认 created_by
+	DeptColumn string `json:"deptColumn"` // 数据所属部门列，为空时按所属用户的部门过滤
+	SelfOnly   bool   `json:"selfOnly"`   // 只能查看本人的数据
+}
+
+// HasRule 表是否在配置中声明了数据权限规则
+func HasRule(table string) bool {
+	rule, err := configRule(table)
+	return err == nil && rule != nil
+}
+
+// ruleFor 获取查询所在表的数据权限规则，优先使用配置，其次使用模型字段标签，均未声明时按 created_by 过滤
+func ruleFor(db *gorm.DB) (*Rule, error) {
+	stmt := db.Statement
+	// 数据权限在查询回调解析模型之前执行，需要先解析模型获取表名及字段
+	if stmt.Schema == nil {
+		model := stmt.Model
+		if model == nil {
+			model = stmt.Dest
+		}
+		if model != nil {
+			if err := stmt.Parse(model); err != nil && stmt.Table == "" {
+				return nil, err
+			}
+		}
+	}
+	if stmt.Table == "" {
+		return nil, errors.New("数据权限无法确定查询的表，请使用 Model 或 Table 指定")
+	}
+
+	rule, err := configRule(stmt.Table)
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil {
+		rule = tagRule(stmt.Schema)
+	}
+	if rule.UserColumn == "" {
+		rule.UserColumn = DefaultUserColumn
+	}
+	if err = rule.check(stmt.Table, stmt.Schema); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// configRule 读取配置中声明的规则，未声明时返回nil
+func configRule(table string) (*Rule, error) {
+	config := app.ConfigYml.Get("datascope.rules." + strings.ToLower(table))
+	if config == nil {
+		return nil, nil
+	}
+	// 配置为嵌套的map，经JSON转换为规则结构(字段名不区分大小写)
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	rule := &Rule{}
+	if err = json.Unmarshal(data, rule); err != nil {
+		return nil, fmt.Errorf("表 %s 的数据权限规则配置错误: %v", table, err)
+	}
+	return rule, nil
+}
+
+// tagRule 根据模型字段的 datascope 标签生成规则
+func tagRule(s *schema.Schema) *Rule {
+	rule := &Rule{}
+	if s == nil {
+		return rule
+	}
+	for _, field := range s.Fields {
+		switch strings.TrimSpace(field.Tag.Get(TagName)) {
+		case "user":
+			rule.UserColumn = field.DBName
+		case "self":
+			rule.UserColumn = field.DBName
+			rule.SelfOnly = true
+		case "dept":
+			rule.DeptColumn = field.DBName
+		}
+	}
+	return rule
+}
+
+// check 检查规则中的列是否存在于模型中，无法获取模型时不检查
+func (r *Rule) check(table string, s *schema.Schema) error {
+	if s == nil {
+		return nil
+	}
+	for _, column := range []string{r.UserColumn, r.DeptColumn} {
+		if column != "" && s.LookUpField(column) == nil {
+			return fmt.Errorf("表 %s 没有数据权限列 %s", table, column)
+		}
+	}
+	return nil
+}
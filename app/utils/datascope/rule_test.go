@@ -0,0 +1,124 @@
+package datascope
+
+import (
+	"gin-fast/app/global/app"
+	"gin-fast/app/utils/ymlconfig"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/utils/tests"
+)
+
+// 测试配置，按表声明数据权限规则
+const testRuleConfig = `
+datascope:
+  rules:
+    test_orders:
+      usercolumn: "owner_id"
+      deptcolumn: "dept_id"
+    test_notes:
+      selfonly: true
+    test_invalids:
+      usercolumn: "owner_id"
+`
+
+// testDocument 未声明规则，按 created_by 过滤
+type testDocument struct {
+	ID        uint
+	CreatedBy uint
+}
+
+// testTask 使用字段标签声明规则
+type testTask struct {
+	ID         uint
+	AssigneeID uint `datascope:"user"`
+	DeptID     uint `datascope:"dept"`
+}
+
+// testDiary 使用字段标签声明只能查看本人的数据
+type testDiary struct {
+	ID     uint
+	UserID uint `datascope:"self"`
+}
+
+type testOrder struct {
+	ID      uint
+	OwnerID uint
+	DeptID  uint
+}
+
+type testNote struct {
+	ID        uint
+	CreatedBy uint
+}
+
+type testInvalid struct {
+	ID        uint
+	CreatedBy uint
+}
+
+func setupTestRule(t *testing.T) *gorm.DB {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yml"), []byte(testRuleConfig), 0644))
+	app.ConfigYml = ymlconfig.CreateYamlFactory(dir)
+
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true})
+	require.NoError(t, err)
+	return db
+}
+
+// toSQL 生成按数据权限过滤的查询语句
+func toSQL(t *testing.T, db *gorm.DB, scope *Scope, dest interface{}) string {
+	return db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return tx.Scopes(func(db *gorm.DB) *gorm.DB {
+			rule, err := ruleFor(db)
+			require.NoError(t, err)
+			return scope.Apply(db, rule)
+		}).Find(dest)
+	})
+}
+
+// TestRuleFor 测试规则的声明方式及优先级
+func TestRuleFor(t *testing.T) {
+	db := setupTestRule(t)
+	scope := &Scope{UserID: 7, DeptIDs: []uint{2, 3}, UserIDs: []uint{7, 8}}
+
+	assert.Contains(t, toSQL(t, db, scope, &[]testDocument{}), "WHERE `created_by` IN (7,8)")
+	assert.Contains(t, toSQL(t, db, scope, &[]testTask{}), "WHERE (`assignee_id` = 7 OR `dept_id` IN (2,3))")
+	assert.Contains(t, toSQL(t, db, scope, &[]testOrder{}), "WHERE (`owner_id` = 7 OR `dept_id` IN (2,3))")
+	assert.Contains(t, toSQL(t, db, scope, &[]testNote{}), "WHERE `created_by` = 7")
+	assert.Contains(t, toSQL(t, db, scope, &[]testDiary{}), "WHERE `user_id` = 7")
+
+	// 规则中的列不存在时报错
+	_, err := ruleFor(db.Model(&testInvalid{}))
+	assert.Error(t, err)
+
+	assert.True(t, HasRule("test_orders"))
+	assert.False(t, HasRule("test_documents"))
+}
+
+// TestScopeApply 测试数据权限范围转换为查询条件
+func TestScopeApply(t *testing.T) {
+	db := setupTestRule(t)
+
+	all := &Scope{All: true, UserID: 7}
+	assert.NotContains(t, toSQL(t, db, all, &[]testDocument{}), "WHERE")
+	assert.NotContains(t, toSQL(t, db, all, &[]testOrder{}), "WHERE")
+	assert.Contains(t, toSQL(t, db, all, &[]testNote{}), "WHERE `created_by` = 7", "Self only rule should ignore role scope")
+
+	self := &Scope{UserID: 7}
+	assert.Contains(t, toSQL(t, db, self, &[]testDocument{}), "WHERE `created_by` = 7")
+	assert.Contains(t, toSQL(t, db, self, &[]testOrder{}), "WHERE `owner_id` = 7")
+
+	// 当前用户不在部门用户中时仍可查看本人的数据
+	dept := &Scope{UserID: 7, DeptIDs: []uint{2}, UserIDs: []uint{8}}
+	assert.Contains(t, toSQL(t, db, dept, &[]testDocument{}), "WHERE `created_by` IN (8,7)")
+
+	subquery := &Scope{UserID: 7, DeptIDs: []uint{2}, Subquery: true}
+	assert.Contains(t, toSQL(t, db, subquery, &[]testDocument{}), "WHERE (`created_by` = 7 OR `created_by` IN (SELECT `id` FROM `sys_users` WHERE dept_id IN (2)")
+	assert.Contains(t, toSQL(t, db, subquery, &[]testOrder{}), "WHERE (`owner_id` = 7 OR `dept_id` IN (2))", "Dept column should not use subquery")
+}
@@ -0,0 +1,190 @@
+package datascope
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gin-fast/app/global/app"
+	"gin-fast/app/models"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	cacheKeyPrefix = "datascope:"
+	versionKey     = cacheKeyPrefix + "version"
+	// versionExpire 版本号的缓存时间，需远大于数据权限的缓存时间，避免过期后重新使用旧版本号
+	versionExpire = 30 * 24 * time.Hour
+)
+
+// Scope 用户的数据权限范围
+type Scope struct {
+	All      bool   `json:"all,omitempty"`      // 可查看全部数据
+	UserID   uint   `json:"userId"`             // 当前用户，始终可查看自己创建的数据
+	DeptIDs  []uint `json:"deptIds,omitempty"`  // 可查看这些部门的用户创建的数据
+	UserIDs  []uint `json:"userIds,omitempty"`  // 上述部门的用户ID
+	Subquery bool   `json:"subquery,omitempty"` // 部门用户过多时使用子查询过滤，不保存用户ID
+}
+
+// Apply 按数据权限规则将数据权限范围转换为查询条件
+// 始终可查看本人的数据；部门范围在声明了部门列时按该列过滤，否则按数据所属用户的部门过滤
+func (s *Scope) Apply(db *gorm.DB, rule *Rule) *gorm.DB {
+	userColumn := clause.Column{Name: rule.UserColumn}
+	if rule.SelfOnly {
+		return db.Where("? = ?", userColumn, s.UserID)
+	}
+	if s.All {
+		return db
+	}
+	if len(s.DeptIDs) == 0 {
+		return db.Where("? = ?", userColumn, s.UserID)
+	}
+	if rule.DeptColumn != "" {
+		return db.Where("(? = ? OR ? IN ?)", userColumn, s.UserID, clause.Column{Name: rule.DeptColumn}, s.DeptIDs)
+	}
+	if s.Subquery {
+		users := db.Session(&gorm.Session{NewDB: true}).Model(&models.User{}).Select("id").Where("dept_id IN ?", s.DeptIDs)
+		return db.Where("(? = ? OR ? IN (?))", userColumn, s.UserID, userColumn, users)
+	}
+	userIDs := s.UserIDs
+	if !slices.Contains(userIDs, s.UserID) {
+		userIDs = append(slices.Clone(userIDs), s.UserID)
+	}
+	return db.Where("? IN ?", userColumn, userIDs)
+}
+
+// Resolve 获取用户在租户下的数据权限范围，优先使用缓存
+func Resolve(c context.Context, userID, tenantID uint) (*Scope, error) {
+	expire := app.ConfigYml.GetInt("datascope.cacheexpire")
+	if expire <= 0 {
+		return compute(c, userID, tenantID)
+	}
+	key := cacheKey(c, userID, tenantID)
+	if data, err := app.Cache.Get(c, key); err == nil && data != "" {
+		scope := &Scope{}
+		if json.Unmarshal([]byte(data), scope) == nil {
+			return scope, nil
+		}
+	}
+	scope, err := compute(c, userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(scope); err == nil {
+		app.Cache.Set(c, key, string(data), time.Duration(expire)*time.Second)
+	}
+	return scope, nil
+}
+
+// cacheKey 数据权限缓存键：datascope:{版本号}:{租户ID}:{用户ID}
+func cacheKey(c context.Context, userID, tenantID uint) string {
+	version, err := app.Cache.Get(c, versionKey)
+	if err != nil || version == "" {
+		version = "0"
+	}
+	return fmt.Sprintf("%s%s:%d:%d", cacheKeyPrefix, version, tenantID, userID)
+}
+
+// compute 根据用户在租户下当前生效的角色计算数据权限范围
+// 角色、部门及部门用户均限定在该租户内，用户关联了多个租户时各租户的数据权限互不影响
+func compute(c context.Context, userID, tenantID uint) (*Scope, error) {
+	scope := &Scope{UserID: userID}
+
+	// 获取用户在该租户下的角色
+	roles, err := getUserRoles(c, userID, tenantID)
+	if err != nil || len(roles) == 0 {
+		// 如果没有角色或查询失败，默认只能查看自己的数据
+		return scope, nil
+	}
+
+	// 检查是否有全表权限的角色
+	if slices.ContainsFunc(roles, func(role *models.SysRole) bool { return role.DataScope == models.DataScopeAll }) {
+		scope.All = true
+		return scope, nil
+	}
+
+	// 只有按部门授权的角色时才查询部门
+	if !slices.ContainsFunc(roles, func(role *models.SysRole) bool {
+		return role.DataScope == models.DataScopeCustom || role.DataScope == models.DataScopeDept || role.DataScope == models.DataScopeDeptAndChildren
+	}) {
+		return scope, nil
+	}
+	departments, err := getTenantDepartments(c, tenantID)
+	if err != nil {
+		// 查询失败，默认只能查看自己的数据
+		return scope, nil
+	}
+	tenantDeptIDs := make(map[uint]bool, len(departments))
+	for _, dept := range departments {
+		tenantDeptIDs[dept.ID] = true
+	}
+
+	// 获取用户所属部门ID（只查询一次），不属于该租户的部门不计入
+	userDeptID, _ := getUserDepartmentID(c, userID)
+	if !tenantDeptIDs[userDeptID] {
+		userDeptID = 0
+	}
+
+	// 收集所有需要处理的部门ID
+	var allDeptIDs []uint
+	var departmentTree models.SysDepartmentList
+	for _, role := range roles {
+		switch role.DataScope {
+		case models.DataScopeCustom: // 查询checked_depts字段定义的部门所属用户创建的数据
+			if role.CheckedDepts != "" {
+				deptIDs, err := stringToUintSlice(role.CheckedDepts)
+				if err == nil && len(deptIDs) > 0 {
+					allDeptIDs = append(allDeptIDs, deptIDs...)
+				}
+			}
+
+		case models.DataScopeDept: // 查询自身所属部门的所属用户创建的数据
+			if userDeptID != 0 {
+				allDeptIDs = append(allDeptIDs, userDeptID)
+			}
+
+		case models.DataScopeDeptAndChildren: // 查询自身所属部门及该部门下所有子级部门的所属用户创建的数据
+			if userDeptID == 0 {
+				continue
+			}
+			// 只在需要子级部门时构建部门树
+			if departmentTree == nil {
+				departmentTree = departments.BuildTree()
+			}
+			deptIDs, err := getDepartmentAndChildrenIDs(departmentTree, userDeptID)
+			if err == nil && len(deptIDs) > 0 {
+				allDeptIDs = append(allDeptIDs, deptIDs...)
+			}
+
+		case models.DataScopeSelf: // 只能查看本人的数据，不增加部门范围
+		}
+	}
+
+	// 去重部门ID，并排除其他租户的部门
+	allDeptIDs = slices.DeleteFunc(allDeptIDs, func(id uint) bool { return !tenantDeptIDs[id] })
+	slices.Sort(allDeptIDs)
+	scope.DeptIDs = slices.Compact(allDeptIDs)
+	if len(scope.DeptIDs) == 0 {
+		return scope, nil
+	}
+
+	// 部门用户超过限制时使用子查询，避免过长的 IN 条件
+	maxInList := app.ConfigYml.GetInt("datascope.maxinlist")
+	if maxInList <= 0 {
+		scope.Subquery = true
+		return scope, nil
+	}
+	userIDs, err := getUserIDsByDepartmentIDs(c, scope.DeptIDs, maxInList+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(userIDs) > maxInList {
+		scope.Subquery = true
+		return scope, nil
+	}
+	scope.UserIDs = userIDs
+	return scope, nil
+}
@@ -0,0 +1,275 @@
+package fieldpermhelper
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Action 字段权限，数值越大限制越严格
+type Action int8
+
+const (
+	ActionReadonly Action = 1 // 只读：可查看，更新时忽略
+	ActionMask     Action = 2 // 脱敏：返回脱敏后的值，更新时忽略
+	ActionHide     Action = 3 // 隐藏：不返回该字段，更新时忽略
+)
+
+// Valid 是否为有效的字段权限
+func (a Action) Valid() bool {
+	return a >= ActionReadonly && a <= ActionHide
+}
+
+// Rule 角色的字段权限规则
+type Rule struct {
+	RoleID uint
+	Model  string // 模型(表名)
+	Field  string // 字段(JSON字段名)
+	Action Action
+}
+
+// Rules 用户生效的字段权限：模型 -> 字段 -> 权限
+type Rules map[string]map[string]Action
+
+// Merge 合并用户所有角色的字段权限
+// 多个角色时取限制最宽松的权限：只要有一个角色未限制该字段，该字段即不受限制
+func Merge(roleIDs []uint, rules []Rule) Rules {
+	roles := make(map[uint]bool, len(roleIDs))
+	for _, roleID := range roleIDs {
+		roles[roleID] = true
+	}
+	type key struct{ model, field string }
+	actions := make(map[key]map[uint]Action)
+	for _, rule := range rules {
+		if !roles[rule.RoleID] || !rule.Action.Valid() {
+			continue
+		}
+		k := key{rule.Model, rule.Field}
+		if actions[k] == nil {
+			actions[k] = make(map[uint]Action)
+		}
+		actions[k][rule.RoleID] = rule.Action
+	}
+
+	merged := Rules{}
+	for k, roleActions := range actions {
+		if len(roleActions) < len(roles) {
+			continue
+		}
+		var action Action
+		for _, a := range roleActions {
+			if action == 0 || a < action {
+				action = a
+			}
+		}
+		if merged[k.model] == nil {
+			merged[k.model] = make(map[string]Action)
+		}
+		merged[k.model][k.field] = action
+	}
+	return merged
+}
+
+// Restricted 获取模型中受限制(不可修改)的字段，按字段名排序
+func (r Rules) Restricted(model string) []string {
+	fields := make([]string, 0, len(r[model]))
+	for field := range r[model] {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// Apply 按字段权限处理响应数据，返回可直接序列化的数据
+// 包含受限模型的结构体按json标签转换为map后隐藏或脱敏字段，其余数据原样返回
+// 模型通过 TableName() 方法识别，实现了 json.Marshaler 的类型不做处理
+func (r Rules) Apply(data interface{}) interface{} {
+	if len(r) == 0 || data == nil {
+		return data
+	}
+	w := &walker{rules: r, affects: make(map[reflect.Type]bool)}
+	v := reflect.ValueOf(data)
+	if !w.affected(v.Type()) {
+		return data
+	}
+	return w.apply(v)
+}
+
+// MaskString 字符串脱敏
+// 邮箱保留首字符及域名(a***@example.com)，7位及以上保留前3位和后4位(138****0000)，其余全部替换
+func MaskString(s string) string {
+	if s == "" {
+		return s
+	}
+	if i := strings.LastIndex(s, "@"); i > 0 {
+		_, size := utf8.DecodeRuneInString(s)
+		return s[:size] + "***" + s[i:]
+	}
+	runes := []rune(s)
+	if len(runes) >= 7 {
+		return string(runes[:3]) + "****" + string(runes[len(runes)-4:])
+	}
+	return "***"
+}
+
+var (
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	tablerType    = reflect.TypeOf((*interface{ TableName() string })(nil)).Elem()
+)
+
+type walker struct {
+	rules   Rules
+	affects map[reflect.Type]bool
+}
+
+// affected 类型中是否可能包含受限模型
+func (w *walker) affected(t reflect.Type) bool {
+	if res, ok := w.affects[t]; ok {
+		return res
+	}
+	// 先标记为否，避免递归类型死循环
+	w.affects[t] = false
+	res := false
+	switch {
+	case isMarshaler(t):
+	case t.Kind() == reflect.Interface:
+		res = true
+	case t.Kind() == reflect.Ptr, t.Kind() == reflect.Slice, t.Kind() == reflect.Array:
+		res = w.affected(t.Elem())
+	case t.Kind() == reflect.Map:
+		res = t.Key().Kind() == reflect.String && w.affected(t.Elem())
+	case t.Kind() == reflect.Struct:
+		if len(w.rules[tableName(t)]) > 0 {
+			res = true
+			break
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if w.affected(t.Field(i).Type) {
+				res = true
+				break
+			}
+		}
+	}
+	w.affects[t] = res
+	return res
+}
+
+func (w *walker) apply(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if !w.affected(v.Type()) {
+		return v.Interface()
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return v.Interface()
+		}
+		return w.apply(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return v.Interface()
+		}
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = w.apply(v.Index(i))
+		}
+		return list
+	case reflect.Map:
+		if v.IsNil() {
+			return v.Interface()
+		}
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = w.apply(iter.Value())
+		}
+		return m
+	case reflect.Struct:
+		return w.structMap(v, w.rules[tableName(v.Type())])
+	}
+	return v.Interface()
+}
+
+// structMap 按json标签将结构体转换为map，嵌入的结构体字段展开(外层字段优先)
+func (w *walker) structMap(v reflect.Value, rules map[string]Action) map[string]interface{} {
+	t := v.Type()
+	m := make(map[string]interface{}, t.NumField())
+	var embedded []map[string]interface{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		fv := v.Field(i)
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if ft.Kind() == reflect.Struct && !isMarshaler(ft) {
+				embedded = append(embedded, w.structMap(fv, rules))
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && fv.IsZero() && fv.Kind() != reflect.Struct {
+			continue
+		}
+		switch rules[name] {
+		case ActionHide:
+			continue
+		case ActionMask:
+			m[name] = mask(fv)
+		default:
+			m[name] = w.apply(fv)
+		}
+	}
+	for _, fields := range embedded {
+		for name, value := range fields {
+			if _, ok := m[name]; !ok {
+				m[name] = value
+			}
+		}
+	}
+	return m
+}
+
+// mask 字段脱敏，非字符串类型按字符串格式化后脱敏
+func mask(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.String {
+		return MaskString(v.String())
+	}
+	return MaskString(fmt.Sprint(v.Interface()))
+}
+
+func isMarshaler(t reflect.Type) bool {
+	return t.Implements(marshalerType) || (t.Kind() != reflect.Ptr && reflect.PointerTo(t).Implements(marshalerType))
+}
+
+func tableName(t reflect.Type) string {
+	if reflect.PointerTo(t).Implements(tablerType) {
+		return reflect.New(t).Interface().(interface{ TableName() string }).TableName()
+	}
+	return ""
+}
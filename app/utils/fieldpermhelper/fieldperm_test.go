@@ -0,0 +1,118 @@
+package fieldpermhelper
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testBase struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type testUser struct {
+	testBase
+	Username string      `json:"userName"`
+	Phone    string      `json:"phone"`
+	Email    string      `json:"email"`
+	Password string      `json:"-"`
+	Remark   string      `json:"remark,omitempty"`
+	Dept     *testDept   `json:"dept"`
+	Roles    []*testRole `json:"roles"`
+}
+
+func (testUser) TableName() string { return "sys_users" }
+
+type testDept struct {
+	Name  string `json:"name"`
+	Phone string `json:"phone"`
+}
+
+func (testDept) TableName() string { return "sys_department" }
+
+type testRole struct {
+	Name string `json:"name"`
+}
+
+func TestMerge(t *testing.T) {
+	rules := []Rule{
+		{RoleID: 1, Model: "sys_users", Field: "phone", Action: ActionMask},
+		{RoleID: 2, Model: "sys_users", Field: "phone", Action: ActionHide},
+		{RoleID: 1, Model: "sys_users", Field: "email", Action: ActionHide},
+		{RoleID: 3, Model: "sys_users", Field: "remark", Action: ActionHide},
+		{RoleID: 1, Model: "sys_users", Field: "status", Action: 9},
+	}
+
+	// 单个角色直接使用该角色的规则
+	assert.Equal(t, Rules{"sys_users": {"phone": ActionMask, "email": ActionHide}}, Merge([]uint{1}, rules))
+	// 多个角色取最宽松的规则，未限制的角色放开该字段
+	assert.Equal(t, Rules{"sys_users": {"phone": ActionMask}}, Merge([]uint{1, 2}, rules))
+	assert.Empty(t, Merge([]uint{1, 4}, rules))
+	assert.Empty(t, Merge(nil, rules))
+}
+
+func TestRestricted(t *testing.T) {
+	rules := Rules{"sys_users": {"phone": ActionMask, "email": ActionHide, "status": ActionReadonly}}
+	assert.Equal(t, []string{"email", "phone", "status"}, rules.Restricted("sys_users"))
+	assert.Empty(t, rules.Restricted("sys_role"))
+}
+
+func TestMaskString(t *testing.T) {
+	assert.Equal(t, "138****0000", MaskString("13800000000"))
+	assert.Equal(t, "a***@example.com", MaskString("alice@example.com"))
+	assert.Equal(t, "张***@example.com", MaskString("张三@example.com"))
+	assert.Equal(t, "***", MaskString("123456"))
+	assert.Equal(t, "", MaskString(""))
+}
+
+func TestApply(t *testing.T) {
+	user := &testUser{
+		testBase: testBase{ID: 1, CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+		Username: "alice",
+		Phone:    "13800000000",
+		Email:    "alice@example.com",
+		Password: "secret",
+		Dept:     &testDept{Name: "研发部", Phone: "01012345678"},
+		Roles:    []*testRole{{Name: "admin"}},
+	}
+	rules := Rules{
+		"sys_users":      {"phone": ActionMask, "email": ActionHide, "userName": ActionReadonly},
+		"sys_department": {"phone": ActionHide},
+	}
+
+	data := rules.Apply(map[string]interface{}{"list": []*testUser{user}, "total": 1})
+	out, err := json.Marshal(data)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"list": [{
+			"id": 1,
+			"createdAt": "2025-01-02T03:04:05Z",
+			"userName": "alice",
+			"phone": "138****0000",
+			"dept": {"name": "研发部"},
+			"roles": [{"name": "admin"}]
+		}],
+		"total": 1
+	}`, string(out))
+
+	// 原数据不受影响
+	assert.Equal(t, "13800000000", user.Phone)
+}
+
+func TestApplyUnaffected(t *testing.T) {
+	rules := Rules{"sys_users": {"phone": ActionHide}}
+	role := &testRole{Name: "admin"}
+	// 不包含受限模型的数据原样返回
+	assert.Same(t, role, rules.Apply(role))
+	assert.Nil(t, rules.Apply(nil))
+
+	user := &testUser{Phone: "13800000000"}
+	assert.Same(t, user, Rules{}.Apply(user))
+
+	var users []*testUser
+	assert.Nil(t, rules.Apply(users))
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"gin-fast/app/global/app"
@@ -16,6 +17,9 @@ import (
 	"go.uber.org/zap"
 )
 
+// currentEngine 最近创建的Gin引擎，用于读取已注册的路由表
+var currentEngine *gin.Engine
+
 func GetEngine() *gin.Engine {
 	var engine *gin.Engine
 	if !app.ConfigYml.GetBool("server.appdebug") {
@@ -42,6 +46,7 @@ func GetEngine() *gin.Engine {
 		**/
 		pprof.Register(engine)
 	}
+	currentEngine = engine
 	return engine
 
 }
@@ -129,6 +134,27 @@ func GetPluginRouteFuncs() []PluginRouteFunc {
 	return pluginRouteFuncs
 }
 
+// Routes 获取已注册的路由表(系统路由及插件路由)
+func Routes() gin.RoutesInfo {
+	if currentEngine == nil {
+		return nil
+	}
+	return currentEngine.Routes()
+}
+
+// HandlerName 获取路由处理函数的简短名称
+// 如 gin-fast/app/controllers.(*SysRoleController).List-fm 返回 SysRoleController.List
+func HandlerName(handler string) string {
+	name := strings.TrimSuffix(handler, "-fm")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.NewReplacer("(*", "", ")", "").Replace(name)
+}
+
 // StartServer 配置并启动HTTP服务器
 func StartServer(engine *gin.Engine) error {
 	// 配置HTTP服务器超时设置
@@ -0,0 +1,19 @@
+package ginhelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlerName(t *testing.T) {
+	cases := map[string]string{
+		"gin-fast/app/controllers.(*SysRoleController).List-fm":               "SysRoleController.List",
+		"gin-fast/plugins/example/controllers.(*ExampleController).Create-fm": "ExampleController.Create",
+		"gin-fast/app/routes.InitRoutes.func1":                                "InitRoutes.func1",
+		"main.handler":                                                        "handler",
+	}
+	for handler, want := range cases {
+		assert.Equal(t, want, HandlerName(handler), handler)
+	}
+}
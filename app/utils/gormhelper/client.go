@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"gin-fast/app/global/app"
 	"gin-fast/app/global/myerrors"
+	"gin-fast/app/utils/datascope"
 	"strings"
 	"time"
 
@@ -87,6 +88,15 @@ func GetSqlDriver(sqlType string, readDbIsOpen int, dbConf ...ConfigParams) (*go
 	_ = gormDb.Callback().Create().Before("gorm:before_create").Register("CreateBeforeHook", CreateBeforeHook)
 	// 为了完美支持gorm的一系列回调函数
 	_ = gormDb.Callback().Update().Before("gorm:before_update").Register("UpdateBeforeHook", UpdateBeforeHook)
+	// 字段权限：更新时忽略当前用户无权修改的字段
+	_ = gormDb.Callback().Update().Before("gorm:before_update").Register("FieldPermissionHook", FieldPermissionHook)
+	// 租户数据隔离：查询、更新及删除时自动添加当前租户条件
+	registerTenantIsolation(gormDb)
+	// 数据权限：角色、部门、用户所属部门或用户角色变更后清除数据权限缓存
+	_ = gormDb.Callback().Update().Before("gorm:update").Register("DataScopePrepareUpdateHook", datascope.PrepareUpdateHook)
+	_ = gormDb.Callback().Create().After("gorm:after_create").Register("DataScopeInvalidateHook", datascope.InvalidateHook)
+	_ = gormDb.Callback().Update().After("gorm:after_update").Register("DataScopeInvalidateHook", datascope.InvalidateHook)
+	_ = gormDb.Callback().Delete().After("gorm:after_delete").Register("DataScopeInvalidateHook", datascope.InvalidateHook)
 
 	// 为主连接设置连接池(43行返回的数据库驱动指针)
 	if rawDb, err := gormDb.DB(); err != nil {
@@ -145,6 +145,38 @@ func UpdateBeforeHook(gormDB *gorm.DB) {
 	}
 }
 
+// FieldPermissionHook 更新时忽略当前用户无权修改的字段(字段权限为只读、脱敏、隐藏)
+func FieldPermissionHook(gormDB *gorm.DB) {
+	if app.FieldPermission == nil || gormDB.Statement.Schema == nil {
+		return
+	}
+	fields, err := app.FieldPermission.RestrictedFields(gormDB.Statement.Context, gormDB.Statement.Schema.Table)
+	if err != nil {
+		// 无法确定字段权限时拒绝更新
+		gormDB.AddError(err)
+		return
+	}
+	if len(fields) == 0 {
+		return
+	}
+	restricted := make(map[string]bool, len(fields))
+	for _, name := range fields {
+		restricted[name] = true
+	}
+	for _, field := range gormDB.Statement.Schema.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		if restricted[name] {
+			gormDB.Statement.Omits = append(gormDB.Statement.Omits, field.DBName)
+		}
+	}
+}
+
 // structHasSpecialField  检查结构体是否有特定字段
 func structHasSpecialField(fieldName string, anyStructPtr interface{}) (bool, string) {
 	var tmp reflect.Type
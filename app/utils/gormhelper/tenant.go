@@ -0,0 +1,88 @@
+package gormhelper
+
+import (
+	"gin-fast/app/utils/tenanthelper"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// 租户数据隔离：查询、更新及删除有 TenantID 字段的模型时自动添加当前租户条件
+// 当前租户从语句的上下文中获取，没有登录信息(如后台任务)或全局租户(租户ID为0)时不隔离
+// 需要跨租户访问数据时使用 tenanthelper.WithoutTenant() 或 tenanthelper.SkipTenant(ctx)
+// 原生SQL(Raw、Exec)、子查询及 Joins 关联的表不会添加租户条件
+func registerTenantIsolation(gormDb *gorm.DB) {
+	_ = gormDb.Callback().Query().Before("gorm:query").Register("TenantQueryHook", TenantQueryHook)
+	_ = gormDb.Callback().Row().Before("gorm:row").Register("TenantQueryHook", TenantQueryHook)
+	_ = gormDb.Callback().Update().Before("gorm:update").Register("TenantWriteHook", TenantWriteHook)
+	_ = gormDb.Callback().Delete().Before("gorm:delete").Register("TenantWriteHook", TenantWriteHook)
+}
+
+// TenantQueryHook 查询时添加当前租户条件
+func TenantQueryHook(gormDB *gorm.DB) {
+	if expr := tenantCondition(gormDB); expr != nil {
+		addTenantCondition(gormDB.Statement, expr)
+	}
+}
+
+// TenantWriteHook 更新及删除时添加当前租户条件
+// 没有任何条件的更新及删除由gorm拒绝执行(ErrMissingWhereClause)，此时不添加租户条件，避免变为更新或删除租户下的全部数据
+func TenantWriteHook(gormDB *gorm.DB) {
+	expr := tenantCondition(gormDB)
+	if expr == nil {
+		return
+	}
+	stmt := gormDB.Statement
+	if _, ok := stmt.Clauses["WHERE"]; !ok && !gormDB.AllowGlobalUpdate && !hasPrimaryValues(stmt) {
+		return
+	}
+	addTenantCondition(stmt, expr)
+}
+
+// tenantCondition 获取语句的租户条件，不需要隔离时返回nil
+func tenantCondition(gormDB *gorm.DB) clause.Expression {
+	stmt := gormDB.Statement
+	if gormDB.Error != nil || stmt.Schema == nil {
+		return nil
+	}
+	field := stmt.Schema.LookUpField("TenantID")
+	if field == nil || field.DBName == "" {
+		return nil
+	}
+	tenantID := getTenantIDFromContext(stmt.Context)
+	if tenantID == 0 {
+		return nil
+	}
+	if conditioner, ok := reflect.New(stmt.Schema.ModelType).Interface().(tenanthelper.Conditioner); ok {
+		return conditioner.TenantCondition(tenantID, getCurrentUserIDFromContext(stmt.Context))
+	}
+	return clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenantID}
+}
+
+// addTenantCondition 添加租户条件，已有条件中包含 Or 时整体加括号，避免 a OR b AND tenant_id = ? 绕过租户条件
+func addTenantCondition(stmt *gorm.Statement, expr clause.Expression) {
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+			for _, e := range where.Exprs {
+				if _, isOr := e.(clause.OrConditions); isOr {
+					where.Exprs = []clause.Expression{clause.And(where.Exprs...)}
+					c.Expression = where
+					stmt.Clauses["WHERE"] = c
+					break
+				}
+			}
+		}
+	}
+	stmt.AddClause(clause.Where{Exprs: []clause.Expression{expr}})
+}
+
+// hasPrimaryValues 更新或删除的模型是否设置了主键(gorm会按主键添加条件)
+func hasPrimaryValues(stmt *gorm.Statement) bool {
+	if !stmt.ReflectValue.IsValid() || len(stmt.Schema.PrimaryFields) == 0 {
+		return false
+	}
+	_, values := schema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields)
+	return len(values) > 0
+}
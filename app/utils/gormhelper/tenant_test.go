@@ -0,0 +1,213 @@
+package gormhelper
+
+import (
+	"context"
+	"gin-fast/app/global/app"
+	"gin-fast/app/global/consts"
+	"gin-fast/app/models"
+	"gin-fast/app/utils/tenanthelper"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormLog "gorm.io/gorm/logger"
+)
+
+// testCustomer 有租户ID的测试模型
+type testCustomer struct {
+	ID       uint
+	Name     string
+	TenantID uint
+	Orders   []testOrder `gorm:"foreignKey:CustomerID"`
+}
+
+// testOrder 有租户ID的关联模型
+type testOrder struct {
+	ID         uint
+	CustomerID uint
+	TenantID   uint
+}
+
+// testRegion 没有租户ID的测试模型，不进行隔离
+type testRegion struct {
+	ID   uint
+	Name string
+}
+
+func setupTestTenantDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormLog.Default.LogMode(gormLog.Silent)})
+	require.NoError(t, err)
+	registerTenantIsolation(db)
+	require.NoError(t, db.AutoMigrate(&testCustomer{}, &testOrder{}, &testRegion{}, &models.User{}, &models.SysUserTenant{}))
+
+	require.NoError(t, db.Create(&[]testCustomer{
+		{ID: 1, Name: "alice", TenantID: 1},
+		{ID: 2, Name: "bob", TenantID: 1},
+		{ID: 3, Name: "alice", TenantID: 2},
+		{ID: 4, Name: "carol", TenantID: 2},
+	}).Error)
+	require.NoError(t, db.Create(&[]testOrder{
+		{ID: 1, CustomerID: 1, TenantID: 1},
+		{ID: 2, CustomerID: 1, TenantID: 2},
+	}).Error)
+	require.NoError(t, db.Create(&[]testRegion{{ID: 1, Name: "north"}, {ID: 2, Name: "south"}}).Error)
+	return db
+}
+
+// tenantContext 模拟租户下登录用户的请求上下文
+func tenantContext(tenantID, userID uint) *gin.Context {
+	c := &gin.Context{}
+	c.Set(consts.BindContextKeyName, &app.Claims{ClaimsUser: app.ClaimsUser{UserID: userID, TenantID: tenantID}})
+	return c
+}
+
+// TestTenantIsolation_Query 测试租户下无法读取其他租户的数据
+func TestTenantIsolation_Query(t *testing.T) {
+	db := setupTestTenantDB(t)
+	c := tenantContext(1, 100)
+
+	var customers []testCustomer
+	require.NoError(t, db.WithContext(c).Find(&customers).Error)
+	assert.Len(t, customers, 2)
+	for _, customer := range customers {
+		assert.Equal(t, uint(1), customer.TenantID)
+	}
+
+	// 按ID读取其他租户的数据
+	var customer testCustomer
+	err := db.WithContext(c).First(&customer, 3).Error
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	var count int64
+	require.NoError(t, db.WithContext(c).Model(&testCustomer{}).Where("name = ?", "alice").Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	var names []string
+	require.NoError(t, db.WithContext(c).Model(&testCustomer{}).Order("id").Pluck("name", &names).Error)
+	assert.Equal(t, []string{"alice", "bob"}, names)
+
+	// Or 条件不能绕过租户条件
+	customers = nil
+	require.NoError(t, db.WithContext(c).Where("name = ?", "bob").Or("name = ?", "carol").Find(&customers).Error)
+	require.Len(t, customers, 1)
+	assert.Equal(t, "bob", customers[0].Name)
+
+	// 预加载的关联数据同样隔离
+	customer = testCustomer{}
+	require.NoError(t, db.WithContext(c).Preload("Orders").First(&customer, 1).Error)
+	require.Len(t, customer.Orders, 1)
+	assert.Equal(t, uint(1), customer.Orders[0].TenantID)
+
+	// 关联查询时租户条件限定表名，只对主表添加
+	customers = nil
+	require.NoError(t, db.WithContext(c).Joins("JOIN test_orders ON test_orders.customer_id = test_customers.id").Find(&customers).Error)
+	require.NotEmpty(t, customers)
+	for _, customer := range customers {
+		assert.Equal(t, uint(1), customer.TenantID)
+	}
+
+	var row struct{ Total int }
+	require.NoError(t, db.WithContext(c).Model(&testCustomer{}).Select("COUNT(*) AS total").Row().Scan(&row.Total))
+	assert.Equal(t, 2, row.Total)
+
+	// 没有租户ID字段的模型不受影响
+	var regions []testRegion
+	require.NoError(t, db.WithContext(c).Find(&regions).Error)
+	assert.Len(t, regions, 2)
+}
+
+// TestTenantIsolation_Write 测试租户下无法修改及删除其他租户的数据
+func TestTenantIsolation_Write(t *testing.T) {
+	db := setupTestTenantDB(t)
+	c := tenantContext(1, 100)
+
+	result := db.WithContext(c).Model(&testCustomer{ID: 3}).Update("name", "mallory")
+	require.NoError(t, result.Error)
+	assert.Equal(t, int64(0), result.RowsAffected)
+
+	result = db.WithContext(c).Model(&testCustomer{}).Where("name = ?", "alice").Update("name", "alice2")
+	require.NoError(t, result.Error)
+	assert.Equal(t, int64(1), result.RowsAffected)
+
+	result = db.WithContext(c).Delete(&testCustomer{ID: 4})
+	require.NoError(t, result.Error)
+	assert.Equal(t, int64(0), result.RowsAffected)
+
+	// 没有条件的更新及删除仍由gorm拒绝执行
+	err := db.WithContext(c).Delete(&testCustomer{}).Error
+	assert.ErrorIs(t, err, gorm.ErrMissingWhereClause)
+	err = db.WithContext(c).Model(&testCustomer{}).Update("name", "x").Error
+	assert.ErrorIs(t, err, gorm.ErrMissingWhereClause)
+
+	var names []string
+	require.NoError(t, db.Model(&testCustomer{}).Order("id").Pluck("name", &names).Error)
+	assert.Equal(t, []string{"alice2", "bob", "alice", "carol"}, names)
+
+	// 允许全局更新时只更新本租户的数据
+	result = db.WithContext(c).Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&testCustomer{}).Update("name", "x")
+	require.NoError(t, result.Error)
+	assert.Equal(t, int64(2), result.RowsAffected)
+}
+
+// TestTenantIsolation_Skip 测试不进行租户隔离的场景
+func TestTenantIsolation_Skip(t *testing.T) {
+	db := setupTestTenantDB(t)
+	c := tenantContext(1, 100)
+
+	var customers []testCustomer
+	require.NoError(t, db.WithContext(c).Scopes(tenanthelper.WithoutTenant()).Find(&customers).Error)
+	assert.Len(t, customers, 4)
+
+	var customer testCustomer
+	require.NoError(t, db.WithContext(c).Scopes(tenanthelper.WithoutTenant()).Preload("Orders").First(&customer, 1).Error)
+	assert.Len(t, customer.Orders, 2, "Preload should also skip tenant isolation")
+
+	customers = nil
+	require.NoError(t, db.WithContext(tenanthelper.SkipTenant(c)).Find(&customers).Error)
+	assert.Len(t, customers, 4)
+
+	// 没有登录信息(后台任务)及全局租户不隔离
+	customers = nil
+	require.NoError(t, db.WithContext(context.Background()).Find(&customers).Error)
+	assert.Len(t, customers, 4)
+	customers = nil
+	require.NoError(t, db.WithContext(tenantContext(0, 1)).Find(&customers).Error)
+	assert.Len(t, customers, 4)
+
+	// 后续查询不受 WithoutTenant 影响
+	customers = nil
+	require.NoError(t, db.WithContext(c).Find(&customers).Error)
+	assert.Len(t, customers, 2)
+}
+
+// TestTenantIsolation_Conditioner 测试模型自定义的租户隔离条件
+func TestTenantIsolation_Conditioner(t *testing.T) {
+	db := setupTestTenantDB(t)
+	require.NoError(t, db.Create(&[]models.User{
+		{BaseModel: models.BaseModel{ID: 1}, Username: "u1", TenantID: 1},
+		{BaseModel: models.BaseModel{ID: 2}, Username: "u2", TenantID: 2},
+		{BaseModel: models.BaseModel{ID: 3}, Username: "u3", TenantID: 2},
+		{BaseModel: models.BaseModel{ID: 4}, Username: "u4", TenantID: 3},
+	}).Error)
+	require.NoError(t, db.Create(&[]models.SysUserTenant{
+		{UserID: 2, TenantID: 1},
+		{UserID: 3, TenantID: 2, IsDefault: true},
+		{UserID: 3, TenantID: 3},
+	}).Error)
+
+	// 租户1下可见本租户用户、关联了租户1的用户2及当前用户本人(用户3)，不可见用户4
+	var ids []uint
+	require.NoError(t, db.WithContext(tenantContext(1, 3)).Model(&models.User{}).Order("id").Pluck("id", &ids).Error)
+	assert.Equal(t, []uint{1, 2, 3}, ids)
+
+	// 当前用户可以获取自己关联的所有租户
+	var userTenants []models.SysUserTenant
+	require.NoError(t, db.WithContext(tenantContext(3, 3)).Where("user_id = ?", 3).Find(&userTenants).Error)
+	assert.Len(t, userTenants, 2)
+	userTenants = nil
+	require.NoError(t, db.WithContext(tenantContext(1, 3)).Find(&userTenants).Error)
+	assert.Len(t, userTenants, 3, "Tenant 1 association and own associations")
+}
@@ -1,14 +1,21 @@
 package gormhelper
 
 import (
+	"context"
 	"gin-fast/app/global/app"
 	"gin-fast/app/global/consts"
+	"gin-fast/app/utils/tenanthelper"
 
 	"github.com/gin-gonic/gin"
 )
 
 // getTenantIDFromContext 从上下文中获取租户ID
 func getTenantIDFromContext(ctx interface{}) uint {
+	// 不进行租户隔离的上下文按没有租户处理
+	if c, ok := ctx.(context.Context); ok && tenanthelper.IsSkipped(c) {
+		return 0
+	}
+
 	// 检查context是否为gin.Context类型
 	if gc, ok := ctx.(*gin.Context); ok {
 		// 从gin.Context中获取Claims
@@ -0,0 +1,208 @@
+package ldaphelper
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Config 目录服务(LDAP / Active Directory)配置
+type Config struct {
+	URL                string        // 服务地址，如 ldap://ldap.example.com:389 或 ldaps://ad.example.com:636
+	StartTLS           bool          // ldap:// 连接是否升级为TLS
+	InsecureSkipVerify bool          // 是否跳过证书校验(仅用于测试环境)
+	BindDN             string        // 查询用户使用的服务账号DN，为空时匿名查询
+	BindPassword       string        // 服务账号密码
+	BaseDN             string        // 用户查询基础DN
+	UserFilter         string        // 用户查询过滤器，%s 替换为转义后的用户名，默认 (uid=%s)
+	EmailAttr          string        // 邮箱属性，默认 mail
+	NameAttr           string        // 显示名称属性，默认 displayName
+	GroupAttr          string        // 用户所属组属性，默认 memberOf
+	Timeout            time.Duration // 连接及请求超时，默认10秒
+}
+
+// Entry 认证通过的目录用户
+type Entry struct {
+	DN     string
+	Email  string
+	Name   string
+	Groups []string // 用户所属组DN
+}
+
+// ErrInvalidCredentials 用户不存在或密码错误(不区分两者，避免泄露目录中的用户)
+var ErrInvalidCredentials = errors.New("用户名或密码错误")
+
+// DialFunc 建立目录服务连接
+type DialFunc func(config Config) (ldap.Client, error)
+
+// Client 目录服务客户端，每次认证使用独立连接
+type Client struct {
+	config Config
+	dial   DialFunc
+}
+
+// NewClient 创建目录服务客户端，dial 为空时使用默认连接方式
+func NewClient(config Config, dial DialFunc) *Client {
+	if config.UserFilter == "" {
+		config.UserFilter = "(uid=%s)"
+	}
+	if config.EmailAttr == "" {
+		config.EmailAttr = "mail"
+	}
+	if config.NameAttr == "" {
+		config.NameAttr = "displayName"
+	}
+	if config.GroupAttr == "" {
+		config.GroupAttr = "memberOf"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if dial == nil {
+		dial = Dial
+	}
+	return &Client{config: config, dial: dial}
+}
+
+// Dial 按配置建立连接，需要时升级为TLS
+func Dial(config Config) (ldap.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+	conn, err := ldap.DialURL(config.URL,
+		ldap.DialWithTLSConfig(tlsConfig),
+		ldap.DialWithDialer(&net.Dialer{Timeout: config.Timeout}))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(config.Timeout)
+	if config.StartTLS && !strings.HasPrefix(strings.ToLower(config.URL), "ldaps://") {
+		if err = conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// Authenticate 使用服务账号查询用户，再以用户DN及密码绑定校验密码
+func (c *Client) Authenticate(username, password string) (*Entry, error) {
+	// 空密码会被服务端视为匿名绑定而直接成功，必须拒绝
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := c.dial(c.config)
+	if err != nil {
+		return nil, fmt.Errorf("连接目录服务失败: %w", err)
+	}
+	defer conn.Close()
+
+	if err = c.serviceBind(conn); err != nil {
+		return nil, err
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		c.config.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(c.config.Timeout/time.Second), false,
+		UserFilter(c.config.UserFilter, username),
+		[]string{"dn", c.config.EmailAttr, c.config.NameAttr, c.config.GroupAttr},
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("查询目录用户失败: %w", err)
+	}
+	if len(result.Entries) == 0 {
+		return nil, ErrInvalidCredentials
+	}
+	if len(result.Entries) > 1 {
+		return nil, errors.New("目录中匹配到多个用户，请检查用户查询过滤器")
+	}
+
+	entry := result.Entries[0]
+	if err = conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("目录用户认证失败: %w", err)
+	}
+
+	return &Entry{
+		DN:     entry.DN,
+		Email:  entry.GetAttributeValue(c.config.EmailAttr),
+		Name:   entry.GetAttributeValue(c.config.NameAttr),
+		Groups: entry.GetAttributeValues(c.config.GroupAttr),
+	}, nil
+}
+
+// TestConnection 测试连接：服务账号绑定并读取基础DN
+func (c *Client) TestConnection() error {
+	conn, err := c.dial(c.config)
+	if err != nil {
+		return fmt.Errorf("连接目录服务失败: %w", err)
+	}
+	defer conn.Close()
+
+	if err = c.serviceBind(conn); err != nil {
+		return err
+	}
+	_, err = conn.Search(ldap.NewSearchRequest(
+		c.config.BaseDN,
+		ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, int(c.config.Timeout/time.Second), false,
+		"(objectClass=*)", []string{"dn"}, nil,
+	))
+	if err != nil {
+		return fmt.Errorf("读取基础DN失败: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) serviceBind(conn ldap.Client) error {
+	var err error
+	if c.config.BindDN == "" {
+		err = conn.UnauthenticatedBind("")
+	} else {
+		err = conn.Bind(c.config.BindDN, c.config.BindPassword)
+	}
+	if err != nil {
+		return fmt.Errorf("目录服务账号绑定失败: %w", err)
+	}
+	return nil
+}
+
+// UserFilter 生成用户查询过滤器，用户名按 RFC 4515 转义
+func UserFilter(filter, username string) string {
+	return strings.ReplaceAll(filter, "%s", ldap.EscapeFilter(username))
+}
+
+// GroupMatch 判断用户所属组中是否包含指定组
+// group 可以是完整DN(不区分大小写)，也可以是组的CN
+func GroupMatch(groups []string, group string) bool {
+	group = strings.TrimSpace(group)
+	if group == "" {
+		return false
+	}
+	for _, groupDN := range groups {
+		if strings.EqualFold(groupDN, group) || strings.EqualFold(GroupCN(groupDN), group) {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupCN 获取组DN中第一个RDN的CN值，无法解析时返回空字符串
+func GroupCN(groupDN string) string {
+	dn, err := ldap.ParseDN(groupDN)
+	if err != nil || len(dn.RDNs) == 0 {
+		return ""
+	}
+	for _, attr := range dn.RDNs[0].Attributes {
+		if strings.EqualFold(attr.Type, "cn") {
+			return attr.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package ldaphelper
+
+import (
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockConn 模拟的目录服务连接，仅实现认证用到的方法
+type mockConn struct {
+	ldap.Client
+	serviceDN  string
+	servicePwd string
+	users      map[string]string // 用户DN => 密码
+	entries    []*ldap.Entry
+
+	lastFilter string
+	closed     bool
+}
+
+func (m *mockConn) Bind(username, password string) error {
+	if username == m.serviceDN && password == m.servicePwd {
+		return nil
+	}
+	if pwd, ok := m.users[username]; ok && pwd == password {
+		return nil
+	}
+	return ldap.NewError(ldap.LDAPResultInvalidCredentials, nil)
+}
+
+func (m *mockConn) UnauthenticatedBind(username string) error {
+	return nil
+}
+
+func (m *mockConn) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	m.lastFilter = req.Filter
+	return &ldap.SearchResult{Entries: m.entries}, nil
+}
+
+func (m *mockConn) Close() error {
+	m.closed = true
+	return nil
+}
+
+func newMockConn() *mockConn {
+	return &mockConn{
+		serviceDN:  "cn=svc,dc=example,dc=com",
+		servicePwd: "svc-secret",
+		users:      map[string]string{"uid=alice,ou=people,dc=example,dc=com": "alice-pwd"},
+		entries: []*ldap.Entry{ldap.NewEntry("uid=alice,ou=people,dc=example,dc=com", map[string][]string{
+			"mail":        {"alice@example.com"},
+			"displayName": {"Alice"},
+			"memberOf":    {"cn=Developers,ou=groups,dc=example,dc=com", "cn=Admins,ou=groups,dc=example,dc=com"},
+		})},
+	}
+}
+
+func newTestClient(conn *mockConn) *Client {
+	return NewClient(Config{
+		URL:          "ldap://mock",
+		BindDN:       conn.serviceDN,
+		BindPassword: conn.servicePwd,
+		BaseDN:       "dc=example,dc=com",
+	}, func(Config) (ldap.Client, error) {
+		return conn, nil
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	conn := newMockConn()
+	client := newTestClient(conn)
+
+	entry, err := client.Authenticate("alice", "alice-pwd")
+	require.NoError(t, err)
+	assert.Equal(t, "uid=alice,ou=people,dc=example,dc=com", entry.DN)
+	assert.Equal(t, "alice@example.com", entry.Email)
+	assert.Equal(t, "Alice", entry.Name)
+	assert.Len(t, entry.Groups, 2)
+	assert.Equal(t, "(uid=alice)", conn.lastFilter)
+	assert.True(t, conn.closed)
+}
+
+func TestAuthenticateInvalidCredentials(t *testing.T) {
+	conn := newMockConn()
+	client := newTestClient(conn)
+
+	_, err := client.Authenticate("alice", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	// 空密码不能发送到服务端(会被视为匿名绑定)
+	_, err = client.Authenticate("alice", "")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	conn.entries = nil
+	_, err = client.Authenticate("bob", "bob-pwd")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestAuthenticateMultipleEntries(t *testing.T) {
+	conn := newMockConn()
+	conn.entries = append(conn.entries, ldap.NewEntry("uid=alice,ou=others,dc=example,dc=com", nil))
+	client := newTestClient(conn)
+
+	_, err := client.Authenticate("alice", "alice-pwd")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestServiceBindFailure(t *testing.T) {
+	conn := newMockConn()
+	conn.servicePwd = "rotated"
+	client := NewClient(Config{
+		BindDN:       conn.serviceDN,
+		BindPassword: "svc-secret",
+		BaseDN:       "dc=example,dc=com",
+	}, func(Config) (ldap.Client, error) {
+		return conn, nil
+	})
+
+	_, err := client.Authenticate("alice", "alice-pwd")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidCredentials)
+	assert.Error(t, client.TestConnection())
+}
+
+func TestUserFilter(t *testing.T) {
+	assert.Equal(t, "(sAMAccountName=alice)", UserFilter("(sAMAccountName=%s)", "alice"))
+	// 特殊字符必须转义，防止过滤器注入
+	assert.Equal(t, `(uid=\2a\29\28uid=\2a)`, UserFilter("(uid=%s)", "*)(uid=*"))
+	assert.Equal(t, "(|(uid=bob)(mail=bob))", UserFilter("(|(uid=%s)(mail=%s))", "bob"))
+}
+
+func TestGroupMatch(t *testing.T) {
+	groups := []string{"CN=Developers,OU=Groups,DC=example,DC=com", "cn=Ops\\, Team,ou=groups,dc=example,dc=com"}
+
+	assert.True(t, GroupMatch(groups, "developers"))
+	assert.True(t, GroupMatch(groups, "cn=developers,ou=groups,dc=example,dc=com"))
+	assert.True(t, GroupMatch(groups, "Ops, Team"))
+	assert.False(t, GroupMatch(groups, "Admins"))
+	assert.False(t, GroupMatch(groups, ""))
+	assert.Equal(t, "", GroupCN("not a dn"))
+}
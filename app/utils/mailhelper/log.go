@@ -0,0 +1,32 @@
+package mailhelper
+
+import (
+	"context"
+	"errors"
+	"gin-fast/app/global/app"
+
+	"go.uber.org/zap"
+)
+
+// LogMailer 仅将邮件内容写入日志，不实际发送，用于开发调试
+type LogMailer struct {
+	logger *zap.Logger
+}
+
+// NewLogMailer 创建仅记录日志的邮件发送服务
+func NewLogMailer(logger *zap.Logger) *LogMailer {
+	return &LogMailer{logger: logger}
+}
+
+// Send 记录邮件内容
+func (m *LogMailer) Send(ctx context.Context, msg *app.MailMessage) error {
+	if len(msg.To) == 0 {
+		return errors.New("收件人不能为空")
+	}
+	m.logger.Info("邮件(未实际发送)",
+		zap.Strings("to", msg.To),
+		zap.String("subject", msg.Subject),
+		zap.String("body", msg.Body),
+	)
+	return nil
+}
@@ -0,0 +1,40 @@
+package mailhelper
+
+import (
+	"errors"
+	"gin-fast/app/global/app"
+	"gin-fast/app/global/consts"
+	"time"
+)
+
+// GetMailType 获取邮件发送类型
+func GetMailType() string {
+	return app.ConfigYml.GetString("mail.type")
+}
+
+// GetSmtpConfig 获取SMTP服务配置
+func GetSmtpConfig() SmtpConfig {
+	return SmtpConfig{
+		Host:               app.ConfigYml.GetString("mail.smtp.host"),
+		Port:               app.ConfigYml.GetInt("mail.smtp.port"),
+		Username:           app.ConfigYml.GetString("mail.smtp.username"),
+		Password:           app.ConfigYml.GetString("mail.smtp.password"),
+		From:               app.ConfigYml.GetString("mail.smtp.from"),
+		FromName:           app.ConfigYml.GetString("mail.smtp.fromname"),
+		Encryption:         app.ConfigYml.GetString("mail.smtp.encryption"),
+		InsecureSkipVerify: app.ConfigYml.GetBool("mail.smtp.insecureskipverify"),
+		Timeout:            time.Duration(app.ConfigYml.GetInt("mail.smtp.timeout")) * time.Second,
+	}
+}
+
+// CreateMailer 创建邮件发送服务，未配置时仅记录日志
+func CreateMailer() (app.Mailer, error) {
+	switch GetMailType() {
+	case consts.MailTypeSmtp:
+		return NewSmtpMailer(GetSmtpConfig()), nil
+	case consts.MailTypeLog, "":
+		return NewLogMailer(app.ZapLog), nil
+	default:
+		return nil, errors.New("不支持的邮件发送类型")
+	}
+}
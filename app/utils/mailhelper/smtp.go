@@ -0,0 +1,178 @@
+package mailhelper
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"gin-fast/app/global/app"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 连接加密方式
+const (
+	EncryptionNone     = "none"     // 不加密
+	EncryptionStartTLS = "starttls" // 明文连接后升级为TLS(通常为587端口)
+	EncryptionSSL      = "ssl"      // 直接使用TLS连接(通常为465端口)
+)
+
+// SmtpConfig SMTP服务配置
+type SmtpConfig struct {
+	Host               string        // 服务器地址
+	Port               int           // 服务器端口
+	Username           string        // 认证用户名，为空表示不认证
+	Password           string        // 认证密码
+	From               string        // 发件人地址
+	FromName           string        // 发件人名称
+	Encryption         string        // 加密方式 none / starttls / ssl
+	InsecureSkipVerify bool          // 是否跳过服务器证书校验
+	Timeout            time.Duration // 连接超时
+}
+
+// SmtpMailer 通过SMTP服务发送邮件
+type SmtpMailer struct {
+	config SmtpConfig
+}
+
+// NewSmtpMailer 创建SMTP邮件发送服务
+func NewSmtpMailer(config SmtpConfig) *SmtpMailer {
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.Encryption == "" {
+		config.Encryption = EncryptionStartTLS
+	}
+	return &SmtpMailer{config: config}
+}
+
+// Send 发送邮件
+func (m *SmtpMailer) Send(ctx context.Context, msg *app.MailMessage) error {
+	if len(msg.To) == 0 {
+		return errors.New("收件人不能为空")
+	}
+	from, err := mail.ParseAddress(m.config.From)
+	if err != nil {
+		return fmt.Errorf("发件人地址格式错误: %w", err)
+	}
+	from.Name = m.config.FromName
+	to := make([]string, 0, len(msg.To))
+	for _, addr := range msg.To {
+		parsed, err := mail.ParseAddress(addr)
+		if err != nil {
+			return fmt.Errorf("收件人地址格式错误: %s", addr)
+		}
+		to = append(to, parsed.Address)
+	}
+	data, err := BuildMessage(from, to, msg)
+	if err != nil {
+		return err
+	}
+
+	client, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if m.config.Encryption == EncryptionStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("邮件服务器不支持STARTTLS")
+		}
+		if err = client.StartTLS(m.tlsConfig()); err != nil {
+			return fmt.Errorf("邮件服务器TLS握手失败: %w", err)
+		}
+	}
+	if m.config.Username != "" {
+		auth := smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+		if err = client.Auth(auth); err != nil {
+			return fmt.Errorf("邮件服务器认证失败: %w", err)
+		}
+	}
+	if err = client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err = client.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(data); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// dial 连接邮件服务器
+func (m *SmtpMailer) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	dialer := &net.Dialer{Timeout: m.config.Timeout}
+	var conn net.Conn
+	var err error
+	if m.config.Encryption == EncryptionSSL {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: m.tlsConfig()}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("连接邮件服务器失败: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(m.config.Timeout))
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("连接邮件服务器失败: %w", err)
+	}
+	return client, nil
+}
+
+func (m *SmtpMailer) tlsConfig() *tls.Config {
+	return &tls.Config{
+		ServerName:         m.config.Host,
+		InsecureSkipVerify: m.config.InsecureSkipVerify,
+	}
+}
+
+// BuildMessage 构建邮件报文(RFC 5322)，主题及正文统一使用UTF-8编码
+func BuildMessage(from *mail.Address, to []string, msg *app.MailMessage) ([]byte, error) {
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, errors.New("邮件主题不能包含换行符")
+	}
+	contentType := "text/plain"
+	if msg.HTML {
+		contentType = "text/html"
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("From: " + from.String() + "\r\n")
+	buf.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	buf.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", msg.Subject) + "\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: " + contentType + "; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+	buf.WriteString("\r\n")
+
+	// 正文按76字符折行
+	body := base64.StdEncoding.EncodeToString([]byte(msg.Body))
+	for len(body) > 76 {
+		buf.WriteString(body[:76] + "\r\n")
+		body = body[76:]
+	}
+	buf.WriteString(body + "\r\n")
+	return buf.Bytes(), nil
+}
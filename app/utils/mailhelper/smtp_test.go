@@ -0,0 +1,178 @@
+package mailhelper
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"mime"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"gin-fast/app/global/app"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSmtpServer 本地模拟的SMTP服务，记录收到的信封及报文
+type fakeSmtpServer struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu   sync.Mutex
+	auth string
+	from string
+	rcpt []string
+	data string
+}
+
+func newFakeSmtpServer(t *testing.T) *fakeSmtpServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeSmtpServer{listener: l}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(func() {
+		l.Close()
+		s.wg.Wait()
+	})
+	return s
+}
+
+func (s *fakeSmtpServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSmtpServer) serve() {
+	defer s.wg.Done()
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		s.mu.Lock()
+		switch cmd {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			s.auth = line
+			reply("235 ok")
+		case "MAIL":
+			s.from = line
+			reply("250 ok")
+		case "RCPT":
+			s.rcpt = append(s.rcpt, line)
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				dl, err := r.ReadString('\n')
+				if err != nil || dl == ".\r\n" {
+					break
+				}
+				data.WriteString(dl)
+			}
+			s.data = data.String()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			s.mu.Unlock()
+			return
+		default:
+			reply("502 unsupported")
+		}
+		s.mu.Unlock()
+	}
+}
+
+func TestSmtpMailerSend(t *testing.T) {
+	server := newFakeSmtpServer(t)
+	mailer := NewSmtpMailer(SmtpConfig{
+		Host:       "127.0.0.1",
+		Port:       server.port(),
+		Username:   "robot",
+		Password:   "secret",
+		From:       "noreply@example.com",
+		FromName:   "GinFast",
+		Encryption: EncryptionNone,
+	})
+
+	err := mailer.Send(context.Background(), &app.MailMessage{
+		To:      []string{"alice@example.com", "Bob <bob@example.com>"},
+		Subject: "重置密码",
+		Body:    "点击链接重置密码",
+	})
+	require.NoError(t, err)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.True(t, strings.HasPrefix(server.auth, "AUTH PLAIN "))
+	assert.True(t, strings.HasPrefix(server.from, "MAIL FROM:<noreply@example.com>"))
+	assert.Equal(t, []string{"RCPT TO:<alice@example.com>", "RCPT TO:<bob@example.com>"}, server.rcpt)
+	assert.Contains(t, server.data, "To: alice@example.com, bob@example.com\r\n")
+	assert.Contains(t, server.data, "Content-Type: text/plain; charset=UTF-8\r\n")
+
+	parts := strings.SplitN(server.data, "\r\n\r\n", 2)
+	require.Len(t, parts, 2)
+	body, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(strings.TrimSpace(parts[1]), "\r\n", ""))
+	require.NoError(t, err)
+	assert.Equal(t, "点击链接重置密码", string(body))
+}
+
+func TestSmtpMailerRequiresStartTLS(t *testing.T) {
+	server := newFakeSmtpServer(t)
+	mailer := NewSmtpMailer(SmtpConfig{
+		Host: "127.0.0.1",
+		Port: server.port(),
+		From: "noreply@example.com",
+	})
+	err := mailer.Send(context.Background(), &app.MailMessage{To: []string{"alice@example.com"}, Subject: "hi"})
+	assert.Error(t, err)
+}
+
+func TestSmtpMailerInvalidAddress(t *testing.T) {
+	mailer := NewSmtpMailer(SmtpConfig{Host: "127.0.0.1", Port: 25, From: "noreply@example.com"})
+	err := mailer.Send(context.Background(), &app.MailMessage{To: []string{"alice@example.com\r\nBcc: eve@example.com"}})
+	assert.Error(t, err)
+	err = mailer.Send(context.Background(), &app.MailMessage{})
+	assert.Error(t, err)
+}
+
+func TestBuildMessage(t *testing.T) {
+	from := &mail.Address{Name: "系统", Address: "noreply@example.com"}
+	_, err := BuildMessage(from, []string{"alice@example.com"}, &app.MailMessage{Subject: "a\r\nBcc: eve@example.com"})
+	assert.Error(t, err)
+
+	data, err := BuildMessage(from, []string{"alice@example.com"}, &app.MailMessage{
+		Subject: "重置密码",
+		Body:    strings.Repeat("长", 100),
+		HTML:    true,
+	})
+	require.NoError(t, err)
+	msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "重置密码", subject)
+	assert.Equal(t, "text/html; charset=UTF-8", msg.Header.Get("Content-Type"))
+	for _, line := range strings.Split(strings.TrimSpace(string(data[strings.Index(string(data), "\r\n\r\n")+4:])), "\r\n") {
+		assert.LessOrEqual(t, len(line), 76, "body line length "+strconv.Itoa(len(line)))
+	}
+}
@@ -0,0 +1,199 @@
+package oidchelper
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockIdP 本地模拟的OIDC提供方
+type mockIdP struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+
+	mu    sync.Mutex
+	codes map[string]mockAuthCode // 授权码 => 授权请求
+}
+
+type mockAuthCode struct {
+	challenge string
+	nonce     string
+	subject   string
+	email     string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	idp := &mockIdP{key: key, clientID: "ginfast", codes: make(map[string]mockAuthCode)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"userinfo_endpoint":      idp.server.URL + "/userinfo",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "mock-key",
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		idp.mu.Lock()
+		auth, ok := idp.codes[r.PostForm.Get("code")]
+		delete(idp.codes, r.PostForm.Get("code"))
+		idp.mu.Unlock()
+		if !ok || CodeChallenge(r.PostForm.Get("code_verifier")) != auth.challenge || r.PostForm.Get("client_id") != idp.clientID {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "mock-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   300,
+			"id_token":     idp.signIDToken(t, auth.subject, auth.email, auth.nonce, idp.clientID),
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer mock-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"sub": "user-1", "email": "alice@example.com"})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// authorize 模拟用户在提供方完成登录，返回授权码
+func (idp *mockIdP) authorize(t *testing.T, authURL, subject, email string) (code, state string) {
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	q := u.Query()
+	require.Equal(t, "S256", q.Get("code_challenge_method"))
+	code = "code-" + subject
+	idp.mu.Lock()
+	idp.codes[code] = mockAuthCode{challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), subject: subject, email: email}
+	idp.mu.Unlock()
+	return code, q.Get("state")
+}
+
+func (idp *mockIdP) signIDToken(t *testing.T, subject, email, nonce, audience string) string {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, &IDTokenClaims{
+		Nonce:         nonce,
+		Email:         email,
+		EmailVerified: true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    idp.server.URL,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		},
+	})
+	token.Header["kid"] = "mock-key"
+	signed, err := token.SignedString(idp.key)
+	require.NoError(t, err)
+	return signed
+}
+
+// TestProvider_AuthorizationCodeFlow 授权码 + PKCE 完整流程
+func TestProvider_AuthorizationCodeFlow(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := NewProvider(Config{
+		Issuer:      idp.server.URL,
+		ClientID:    "ginfast",
+		RedirectURL: "http://localhost/callback",
+	}, nil)
+	ctx := context.Background()
+
+	state, err := RandomString(16)
+	require.NoError(t, err)
+	nonce, err := RandomString(16)
+	require.NoError(t, err)
+	verifier, err := RandomString(32)
+	require.NoError(t, err)
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	require.NoError(t, err)
+	code, returnedState := idp.authorize(t, authURL, "user-1", "alice@example.com")
+	assert.Equal(t, state, returnedState)
+
+	token, err := provider.Exchange(ctx, code, verifier)
+	require.NoError(t, err)
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, nonce)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.Subject)
+	assert.Equal(t, "alice@example.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+
+	info, err := provider.UserInfo(ctx, token.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", info["email"])
+}
+
+// TestProvider_RejectsInvalidRequests 校验失败场景
+func TestProvider_RejectsInvalidRequests(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := NewProvider(Config{Issuer: idp.server.URL, ClientID: "ginfast", RedirectURL: "http://localhost/callback"}, nil)
+	ctx := context.Background()
+
+	// PKCE校验码不匹配
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", "right-verifier")
+	require.NoError(t, err)
+	code, _ := idp.authorize(t, authURL, "user-1", "alice@example.com")
+	_, err = provider.Exchange(ctx, code, "wrong-verifier")
+	assert.Error(t, err)
+
+	// nonce不匹配
+	_, err = provider.VerifyIDToken(ctx, idp.signIDToken(t, "user-1", "", "other-nonce", "ginfast"), "nonce")
+	assert.Error(t, err)
+
+	// 受众不匹配
+	_, err = provider.VerifyIDToken(ctx, idp.signIDToken(t, "user-1", "", "nonce", "other-client"), "nonce")
+	assert.Error(t, err)
+
+	// 签名被篡改
+	raw := idp.signIDToken(t, "user-1", "", "nonce", "ginfast")
+	_, err = provider.VerifyIDToken(ctx, raw[:len(raw)-4]+"AAAA", "nonce")
+	assert.Error(t, err)
+
+	// 签发者不一致
+	wrongIssuer := NewProvider(Config{Issuer: idp.server.URL + "/other", ClientID: "ginfast"}, nil)
+	_, err = wrongIssuer.Discover(ctx)
+	assert.Error(t, err)
+}
+
+// TestCodeChallenge S256挑战码为校验码SHA256摘要的base64url编码(无填充)
+func TestCodeChallenge(t *testing.T) {
+	assert.Equal(t, "1shF6s_Z6ZTVScYNGUZRs8TkV26g2tFJ0Lf_SDIsgWg", CodeChallenge("dBjftJeZ4CVP-mJ92K7ftAtwVcmGDHfjZ4IoKHXhoxQ"))
+}
@@ -0,0 +1,131 @@
+package passwordhelper
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultSpecialChars 默认特殊字符集合
+const DefaultSpecialChars = "!@#$%^&*()-_=+[]{};:'\",.<>/?\\|`~"
+
+// Policy 密码策略
+type Policy struct {
+	MinLength        int      `json:"minLength"`        // 最小长度，0表示不限制
+	RequireUpper     bool     `json:"requireUpper"`     // 是否必须包含大写字母
+	RequireLower     bool     `json:"requireLower"`     // 是否必须包含小写字母
+	RequireDigit     bool     `json:"requireDigit"`     // 是否必须包含数字
+	RequireSpecial   bool     `json:"requireSpecial"`   // 是否必须包含特殊字符
+	SpecialChars     string   `json:"specialChars"`     // 特殊字符集合，为空时使用默认集合
+	ForbiddenWords   []string `json:"forbiddenWords"`   // 禁止包含的词(不区分大小写)
+	DisallowUsername bool     `json:"disallowUsername"` // 是否禁止包含用户名
+	HistoryCount     int      `json:"historyCount"`     // 禁止重复使用最近N次的密码，0表示不限制
+	MaxAgeDays       int      `json:"maxAgeDays"`       // 密码最长使用天数，过期后登录需修改密码，0表示永不过期
+}
+
+// Validate 校验密码是否满足策略的字符规则(不含历史密码及有效期)
+// username 为空时不校验是否包含用户名
+func (p *Policy) Validate(password, username string) error {
+	if p.MinLength > 0 && utf8.RuneCountInString(password) < p.MinLength {
+		return fmt.Errorf("密码长度不能少于%d位", p.MinLength)
+	}
+
+	specialChars := p.specialChars()
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+		if strings.ContainsRune(specialChars, r) {
+			hasSpecial = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		return errors.New("密码必须包含大写字母")
+	}
+	if p.RequireLower && !hasLower {
+		return errors.New("密码必须包含小写字母")
+	}
+	if p.RequireDigit && !hasDigit {
+		return errors.New("密码必须包含数字")
+	}
+	if p.RequireSpecial && !hasSpecial {
+		return errors.New("密码必须包含特殊字符 " + specialChars)
+	}
+
+	lower := strings.ToLower(password)
+	if p.DisallowUsername && username != "" && strings.Contains(lower, strings.ToLower(username)) {
+		return errors.New("密码不能包含用户名")
+	}
+	for _, word := range p.ForbiddenWords {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word != "" && strings.Contains(lower, word) {
+			return fmt.Errorf("密码不能包含 %s", word)
+		}
+	}
+	return nil
+}
+
+// Generate 生成满足策略的随机密码，长度为16位与最小长度中的较大值，始终包含大小写字母、数字及特殊字符
+func (p *Policy) Generate(username string) (string, error) {
+	const (
+		upper  = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+		lower  = "abcdefghijkmnopqrstuvwxyz"
+		digits = "23456789"
+	)
+	length := max(p.MinLength, 16)
+	for i := 0; i < 10; i++ {
+		sets := []string{upper, lower, digits, p.specialChars()}
+		for len(sets) < length {
+			sets = append(sets, upper+lower+digits)
+		}
+		password := make([]rune, len(sets))
+		for j, set := range sets {
+			r, err := randomRune(set)
+			if err != nil {
+				return "", err
+			}
+			password[j] = r
+		}
+		// 打乱顺序，避免各类字符位置固定
+		for j := len(password) - 1; j > 0; j-- {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(j+1)))
+			if err != nil {
+				return "", err
+			}
+			k := int(n.Int64())
+			password[j], password[k] = password[k], password[j]
+		}
+		if p.Validate(string(password), username) == nil {
+			return string(password), nil
+		}
+	}
+	return "", errors.New("无法生成满足密码策略的密码")
+}
+
+// randomRune 从字符集合中随机选取一个字符
+func randomRune(set string) (rune, error) {
+	runes := []rune(set)
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(runes))))
+	if err != nil {
+		return 0, err
+	}
+	return runes[n.Int64()], nil
+}
+
+// specialChars 获取特殊字符集合
+func (p *Policy) specialChars() string {
+	if p.SpecialChars == "" {
+		return DefaultSpecialChars
+	}
+	return p.SpecialChars
+}
@@ -0,0 +1,89 @@
+package passwordhelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	policy := &Policy{
+		MinLength:        8,
+		RequireUpper:     true,
+		RequireLower:     true,
+		RequireDigit:     true,
+		RequireSpecial:   true,
+		ForbiddenWords:   []string{"password", " Company "},
+		DisallowUsername: true,
+	}
+
+	cases := []struct {
+		name     string
+		password string
+		wantErr  string
+	}{
+		{"太短", "Ab1!", "密码长度不能少于8位"},
+		{"缺少大写字母", "abcdef1!", "密码必须包含大写字母"},
+		{"缺少小写字母", "ABCDEF1!", "密码必须包含小写字母"},
+		{"缺少数字", "Abcdefg!", "密码必须包含数字"},
+		{"缺少特殊字符", "Abcdefg1", "密码必须包含特殊字符 " + DefaultSpecialChars},
+		{"包含用户名", "xAlice1!yz", "密码不能包含用户名"},
+		{"包含禁用词", "MyPassword1!", "密码不能包含 password"},
+		{"禁用词去除空格", "Company2025!x", "密码不能包含 company"},
+		{"满足策略", "Tr0ub4dor&3", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Validate(tc.password, "alice")
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicyValidateDefaults(t *testing.T) {
+	policy := &Policy{}
+	assert.NoError(t, policy.Validate("a", "a"))
+
+	// 长度按字符计算
+	policy = &Policy{MinLength: 4}
+	assert.NoError(t, policy.Validate("密码密码", ""))
+	assert.Error(t, policy.Validate("密码", ""))
+
+	// 自定义特殊字符集合
+	policy = &Policy{RequireSpecial: true, SpecialChars: "!@#$%"}
+	assert.NoError(t, policy.Validate("abc%", ""))
+	assert.EqualError(t, policy.Validate("abc^", ""), "密码必须包含特殊字符 !@#$%")
+
+	// 未提供用户名时不校验
+	policy = &Policy{DisallowUsername: true}
+	assert.NoError(t, policy.Validate("alice123", ""))
+}
+
+func TestPolicyGenerate(t *testing.T) {
+	policy := &Policy{
+		MinLength:        20,
+		RequireUpper:     true,
+		RequireLower:     true,
+		RequireDigit:     true,
+		RequireSpecial:   true,
+		SpecialChars:     "#%",
+		DisallowUsername: true,
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		password, err := policy.Generate("alice")
+		assert.NoError(t, err)
+		assert.Len(t, password, 20)
+		assert.NoError(t, policy.Validate(password, "alice"))
+		seen[password] = true
+	}
+	assert.Len(t, seen, 20, "generated passwords should be random")
+
+	password, err := (&Policy{}).Generate("")
+	assert.NoError(t, err)
+	assert.Len(t, password, 16)
+}
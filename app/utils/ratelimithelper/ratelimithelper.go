@@ -0,0 +1,148 @@
+package ratelimithelper
+
+import (
+	"context"
+	"gin-fast/app/global/app"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 限流维度
+const (
+	DimensionIP     = "ip"
+	DimensionUser   = "user"
+	DimensionTenant = "tenant"
+)
+
+// Rule 限流规则，各维度为每个时间窗口内允许的请求数，0表示该维度不限制
+type Rule struct {
+	Name    string
+	Path    string   // 请求路径，以 * 结尾表示前缀匹配，为空匹配所有路径
+	Methods []string // 请求方法，为空匹配所有方法
+	Window  time.Duration
+	IP      int
+	User    int
+	Tenant  int
+}
+
+// Limit 获取规则在指定维度的限制次数
+func (r *Rule) Limit(dimension string) int {
+	switch dimension {
+	case DimensionIP:
+		return r.IP
+	case DimensionUser:
+		return r.User
+	case DimensionTenant:
+		return r.Tenant
+	}
+	return 0
+}
+
+// Match 检查请求是否匹配规则，匹配时返回匹配的路径长度(用于选择最具体的规则)
+func (r *Rule) Match(method, path string) (int, bool) {
+	if len(r.Methods) > 0 {
+		matched := false
+		for _, m := range r.Methods {
+			if strings.EqualFold(m, method) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return 0, false
+		}
+	}
+	if prefix, ok := strings.CutSuffix(r.Path, "*"); ok {
+		return len(prefix), strings.HasPrefix(path, prefix)
+	}
+	if r.Path == "" {
+		return 0, true
+	}
+	return len(r.Path) + 1, r.Path == path
+}
+
+// MatchRule 选择匹配请求的最具体规则(完整路径优先于前缀，较长前缀优先)，没有匹配时返回nil
+// 路径长度相同时选择排在前面的规则，规则需先经 SortRules 排序
+func MatchRule(rules []*Rule, method, path string) *Rule {
+	var matched *Rule
+	best := -1
+	for _, rule := range rules {
+		if n, ok := rule.Match(method, path); ok && n > best {
+			matched, best = rule, n
+		}
+	}
+	return matched
+}
+
+// SortRules 排序规则，使匹配结果与配置顺序无关：路径长的在前，路径相同时限定请求方法的在前，其余按名称排序
+func SortRules(rules []*Rule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		a, b := rules[i], rules[j]
+		if len(a.Path) != len(b.Path) {
+			return len(a.Path) > len(b.Path)
+		}
+		if (len(a.Methods) > 0) != (len(b.Methods) > 0) {
+			return len(a.Methods) > 0
+		}
+		return a.Name < b.Name
+	})
+}
+
+// Limiter 基于缓存的滑动窗口限流器，使用redis缓存时多实例共享计数
+// 按当前窗口计数及上一窗口计数的加权和近似滑动窗口，被拒绝的请求同样计数
+type Limiter struct {
+	cache app.CacheInterf
+	now   func() time.Time
+}
+
+// NewLimiter 创建限流器
+func NewLimiter(cache app.CacheInterf) *Limiter {
+	return &Limiter{cache: cache, now: time.Now}
+}
+
+// Allow 记录一次请求并检查是否超出限制，超出时返回需等待的时间
+func (l *Limiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	if limit <= 0 || window <= 0 {
+		return true, 0, nil
+	}
+	now := l.now()
+	windowStart := now.Truncate(window)
+	index := windowStart.UnixNano() / int64(window)
+
+	current, err := l.cache.Incr(ctx, l.key(key, index), 2*window)
+	if err != nil {
+		return true, 0, err
+	}
+	var previous int64
+	if value, err := l.cache.Get(ctx, l.key(key, index-1)); err == nil && value != "" {
+		previous, _ = strconv.ParseInt(value, 10, 64)
+	}
+
+	elapsed := now.Sub(windowStart)
+	weight := 1 - float64(elapsed)/float64(window)
+	if float64(previous)*weight+float64(current) <= float64(limit) {
+		return true, 0, nil
+	}
+
+	// 等待上一窗口的计数衰减到限制以内，当前窗口已超出限制时等待到下一窗口
+	retryAfter := window - elapsed
+	if previous > 0 && current <= int64(limit) {
+		wait := time.Duration(float64(previous+current-int64(limit))/float64(previous)*float64(window)) - elapsed
+		if wait > 0 && wait < retryAfter {
+			retryAfter = wait
+		}
+	}
+	return false, retryAfter, nil
+}
+
+// RetryAfterSeconds 将等待时间转换为 Retry-After 响应头的秒数(向上取整，至少1秒)
+func RetryAfterSeconds(d time.Duration) int {
+	return max(int(math.Ceil(d.Seconds())), 1)
+}
+
+func (l *Limiter) key(key string, index int64) string {
+	return "rate_limit:" + key + ":" + strconv.FormatInt(index, 10)
+}
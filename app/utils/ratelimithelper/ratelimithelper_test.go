@@ -0,0 +1,90 @@
+package ratelimithelper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gin-fast/app/utils/cachehelper"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchRule(t *testing.T) {
+	rules := []*Rule{
+		{Name: "api", Path: "/api/*"},
+		{Name: "captcha", Path: "/api/captcha/*"},
+		{Name: "login", Path: "/api/login", Methods: []string{"POST"}},
+		{Name: "loginPrefix", Path: "/api/login*"},
+	}
+	assert.Equal(t, "login", MatchRule(rules, "POST", "/api/login").Name)
+	assert.Equal(t, "loginPrefix", MatchRule(rules, "GET", "/api/login").Name)
+	assert.Equal(t, "loginPrefix", MatchRule(rules, "POST", "/api/login/mfa").Name)
+	assert.Equal(t, "captcha", MatchRule(rules, "GET", "/api/captcha/id").Name)
+	assert.Equal(t, "api", MatchRule(rules, "GET", "/api/users/list").Name)
+	assert.Nil(t, MatchRule(rules, "GET", "/swagger/index.html"))
+}
+
+func TestSortRules(t *testing.T) {
+	// 路径相同的规则按配置顺序的不同排列均得到相同的匹配结果
+	for _, names := range [][]string{{"a", "b", "c"}, {"c", "b", "a"}, {"b", "c", "a"}} {
+		all := map[string]*Rule{
+			"a": {Name: "a", Path: "/api/*"},
+			"b": {Name: "b", Path: "/api/*"},
+			"c": {Name: "c", Path: "/api/*", Methods: []string{"POST"}},
+		}
+		rules := make([]*Rule, 0, len(names))
+		for _, name := range names {
+			rules = append(rules, all[name])
+		}
+		SortRules(rules)
+		assert.Equal(t, "a", MatchRule(rules, "GET", "/api/users/list").Name, names)
+		assert.Equal(t, "c", MatchRule(rules, "POST", "/api/users/add").Name, names)
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	cache := cachehelper.NewMemoryHelper()
+	defer cache.Close()
+	ctx := context.Background()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(cache)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		allowed, _, err := limiter.Allow(ctx, "ip:1.2.3.4", 3, time.Minute)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+	allowed, retryAfter, err := limiter.Allow(ctx, "ip:1.2.3.4", 3, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Minute, retryAfter)
+
+	// 其他键不受影响
+	allowed, _, _ = limiter.Allow(ctx, "ip:5.6.7.8", 3, time.Minute)
+	assert.True(t, allowed)
+
+	// 下一窗口开始时上一窗口(4次)仍按权重计入
+	now = now.Add(time.Minute)
+	allowed, retryAfter, _ = limiter.Allow(ctx, "ip:1.2.3.4", 3, time.Minute)
+	assert.False(t, allowed)
+	assert.Equal(t, 30*time.Second, retryAfter)
+
+	// 过半个窗口后上一窗口权重为0.5: 4*0.5+2 = 4 > 3
+	now = now.Add(30 * time.Second)
+	allowed, _, _ = limiter.Allow(ctx, "ip:1.2.3.4", 3, time.Minute)
+	assert.False(t, allowed)
+
+	// 不限制
+	allowed, _, _ = limiter.Allow(ctx, "ip:1.2.3.4", 0, time.Minute)
+	assert.True(t, allowed)
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	assert.Equal(t, 1, RetryAfterSeconds(0))
+	assert.Equal(t, 2, RetryAfterSeconds(1500*time.Millisecond))
+	assert.Equal(t, 60, RetryAfterSeconds(time.Minute))
+}
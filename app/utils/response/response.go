@@ -71,6 +71,16 @@ func (r *DefaultResponseHandler) Success(c *gin.Context, data ...interface{}) {
 			code = codeValue
 		}
 	}
+	// 按当前用户的字段权限隐藏或脱敏字段
+	if app.FieldPermission != nil && dataValue != nil {
+		filtered, err := app.FieldPermission.Filter(c, dataValue)
+		if err != nil {
+			// 无法确定字段权限时不返回数据，避免泄露受限字段
+			r.ErrorSystem(c, "字段权限加载失败", nil)
+			return
+		}
+		dataValue = filtered
+	}
 	r.ReturnJson(c, http.StatusOK, code, msg, dataValue)
 }
 
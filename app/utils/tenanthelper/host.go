@@ -0,0 +1,29 @@
+package tenanthelper
+
+import (
+	"net"
+	"strings"
+)
+
+// NormalizeHost 规范化请求Host：去除端口及末尾的点，并转换为小写
+func NormalizeHost(host string) string {
+	host = strings.TrimSpace(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimPrefix(strings.TrimSuffix(host, "]"), "[")
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// SplitSubdomain 拆分最左侧的子域名及上级域名，如 acme.example.com 返回 acme 和 example.com
+// IP地址或不含上级域名时返回空
+func SplitSubdomain(host string) (sub, parent string) {
+	if net.ParseIP(host) != nil {
+		return "", ""
+	}
+	sub, parent, ok := strings.Cut(host, ".")
+	if !ok || sub == "" || parent == "" {
+		return "", ""
+	}
+	return sub, parent
+}
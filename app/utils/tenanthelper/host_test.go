@@ -0,0 +1,36 @@
+package tenanthelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeHost(t *testing.T) {
+	cases := map[string]string{
+		"Acme.Example.com":      "acme.example.com",
+		"acme.example.com:8080": "acme.example.com",
+		"acme.example.com.":     "acme.example.com",
+		"127.0.0.1:8080":        "127.0.0.1",
+		"[::1]:8080":            "::1",
+		"[::1]":                 "::1",
+		"":                      "",
+	}
+	for host, want := range cases {
+		assert.Equal(t, want, NormalizeHost(host), host)
+	}
+}
+
+func TestSplitSubdomain(t *testing.T) {
+	sub, parent := SplitSubdomain("acme.example.com")
+	assert.Equal(t, "acme", sub)
+	assert.Equal(t, "example.com", parent)
+
+	sub, parent = SplitSubdomain("localhost")
+	assert.Empty(t, sub)
+	assert.Empty(t, parent)
+
+	sub, parent = SplitSubdomain("192.168.1.10")
+	assert.Empty(t, sub)
+	assert.Empty(t, parent)
+}
@@ -0,0 +1,41 @@
+package tenanthelper
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type skipTenantKey struct{}
+
+// Conditioner 模型自定义租户隔离条件，未实现时按 tenant_id = 当前租户 过滤
+// 列名需使用 clause.CurrentTable 限定表名，避免关联查询时列名不明确
+type Conditioner interface {
+	TenantCondition(tenantID, userID uint) clause.Expression
+}
+
+// WithoutTenant 本次查询(包括预加载的关联数据)不进行租户隔离，用于平台管理等需要跨租户访问数据的场景
+func WithoutTenant() func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db.Statement.Context = SkipTenant(db.Statement.Context)
+		return db
+	}
+}
+
+// SkipTenant 返回不进行租户隔离的上下文，使用该上下文的数据库操作(包括事务)均不添加租户条件，创建时也不自动设置租户ID
+func SkipTenant(c context.Context) context.Context {
+	if c == nil {
+		c = context.Background()
+	}
+	return context.WithValue(c, skipTenantKey{}, true)
+}
+
+// IsSkipped 上下文是否不进行租户隔离
+func IsSkipped(c context.Context) bool {
+	if c == nil {
+		return false
+	}
+	skip, _ := c.Value(skipTenantKey{}).(bool)
+	return skip
+}
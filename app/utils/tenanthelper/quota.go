@@ -0,0 +1,59 @@
+package tenanthelper
+
+import (
+	"fmt"
+	"time"
+)
+
+// Quota 租户资源配额及当前用量
+type Quota struct {
+	Limit     int64 `json:"limit"`     // 配额，0表示不限制
+	Used      int64 `json:"used"`      // 当前用量
+	Remaining int64 `json:"remaining"` // 剩余可用量，不限制时为-1
+}
+
+// NewQuota 创建配额用量，limit 小于等于0表示不限制
+func NewQuota(limit, used int64) *Quota {
+	if limit < 0 {
+		limit = 0
+	}
+	q := &Quota{Limit: limit, Used: used, Remaining: -1}
+	if limit > 0 {
+		q.Remaining = limit - used
+		if q.Remaining < 0 {
+			q.Remaining = 0
+		}
+	}
+	return q
+}
+
+// Unlimited 是否不限制
+func (q *Quota) Unlimited() bool {
+	return q.Limit <= 0
+}
+
+// Allow 在当前用量上再使用 n 是否不超出配额
+func (q *Quota) Allow(n int64) bool {
+	return q.Unlimited() || n <= 0 || q.Used+n <= q.Limit
+}
+
+// DayKey 按日统计用量的日期标识，如 20060102
+func DayKey(t time.Time) string {
+	return t.Format("20060102")
+}
+
+// FormatBytes 将字节数转换为便于阅读的大小，如 1.5MB
+func FormatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	value := float64(size)
+	units := []string{"KB", "MB", "GB", "TB", "PB"}
+	i := -1
+	for value >= unit && i < len(units)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.2f%s", value, units[i])
+}
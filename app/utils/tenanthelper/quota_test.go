@@ -0,0 +1,45 @@
+package tenanthelper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuota(t *testing.T) {
+	q := NewQuota(10, 8)
+	assert.False(t, q.Unlimited())
+	assert.Equal(t, int64(2), q.Remaining)
+	assert.True(t, q.Allow(2))
+	assert.False(t, q.Allow(3))
+	assert.True(t, q.Allow(0))
+
+	// 配额下调后用量可能超出配额
+	q = NewQuota(5, 8)
+	assert.Equal(t, int64(0), q.Remaining)
+	assert.False(t, q.Allow(1))
+
+	q = NewQuota(0, 100)
+	assert.True(t, q.Unlimited())
+	assert.Equal(t, int64(-1), q.Remaining)
+	assert.True(t, q.Allow(1<<40))
+}
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[int64]string{
+		0:                "0B",
+		1023:             "1023B",
+		1024:             "1.00KB",
+		1536:             "1.50KB",
+		10 * 1024 * 1024: "10.00MB",
+		5 << 40:          "5.00TB",
+	}
+	for size, want := range cases {
+		assert.Equal(t, want, FormatBytes(size), size)
+	}
+}
+
+func TestDayKey(t *testing.T) {
+	assert.Equal(t, "20261016", DayKey(time.Date(2026, 10, 16, 23, 59, 0, 0, time.Local)))
+}
@@ -8,6 +8,7 @@ import (
 )
 
 // TenantScope 租户数据隔离作用域
+// 租户下的查询已由gorm回调自动添加租户条件，全局租户(租户ID为0)需使用该作用域只查询全局数据
 func TenantScope(c *gin.Context) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		// 获取租户ID
@@ -0,0 +1,257 @@
+package tokenhelper
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"gin-fast/app/global/app"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey 非对称签名密钥
+type SigningKey struct {
+	Kid        string            // 密钥标识，写入token头部的kid
+	Method     jwt.SigningMethod // 签名算法
+	PrivateKey crypto.PrivateKey // 私钥，为nil时仅用于验证
+	PublicKey  crypto.PublicKey  // 公钥
+	ActiveAt   time.Time         // 开始用于签名的时间
+}
+
+// KeySet 签名密钥集合，按生效时间轮换
+// 生效时间最晚且已生效的密钥用于签名；被替换的密钥在最长token有效期内继续用于验证，之后自动退役
+type KeySet struct {
+	keys             []*SigningKey // 按ActiveAt升序
+	maxTokenLifetime time.Duration // token最长有效期(取access token与refresh token的较大值)
+	now              func() time.Time
+}
+
+// NewKeySet 创建签名密钥集合，所有密钥必须与指定的签名算法匹配
+func NewKeySet(method string, keys []*SigningKey, maxTokenLifetime time.Duration) (*KeySet, error) {
+	signingMethod := jwt.GetSigningMethod(method)
+	if signingMethod == nil || !isAsymmetricMethod(signingMethod) {
+		return nil, fmt.Errorf("unsupported signing method: %s", method)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("at least one signing key is required")
+	}
+
+	kids := make(map[string]bool, len(keys))
+	sorted := make([]*SigningKey, len(keys))
+	for i, key := range keys {
+		if key.Kid == "" {
+			return nil, errors.New("signing key kid is required")
+		}
+		if kids[key.Kid] {
+			return nil, fmt.Errorf("duplicate signing key kid: %s", key.Kid)
+		}
+		kids[key.Kid] = true
+		keyMethod, err := methodForKey(key.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", key.Kid, err)
+		}
+		if keyMethod.Alg() != signingMethod.Alg() {
+			return nil, fmt.Errorf("signing key %s is %s, expected %s", key.Kid, keyMethod.Alg(), signingMethod.Alg())
+		}
+		key.Method = keyMethod
+		sorted[i] = key
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ActiveAt.Before(sorted[j].ActiveAt)
+	})
+
+	return &KeySet{
+		keys:             sorted,
+		maxTokenLifetime: maxTokenLifetime,
+		now:              time.Now,
+	}, nil
+}
+
+// SigningKey 获取当前用于签名的密钥
+func (k *KeySet) SigningKey() (*SigningKey, error) {
+	now := k.now()
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		key := k.keys[i]
+		if key.PrivateKey != nil && !key.ActiveAt.After(now) {
+			return key, nil
+		}
+	}
+	return nil, errors.New("no active signing key")
+}
+
+// VerifyKey 根据kid获取用于验证的密钥，已退役的密钥不再用于验证
+func (k *KeySet) VerifyKey(kid string) (*SigningKey, error) {
+	now := k.now()
+	for i, key := range k.keys {
+		if key.Kid != kid {
+			continue
+		}
+		if retiredAt, ok := k.retiredAt(i); ok && now.After(retiredAt) {
+			return nil, fmt.Errorf("signing key %s has been retired", kid)
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key: %s", kid)
+}
+
+// retiredAt 计算密钥退役时间：下一个密钥生效后再经过token最长有效期
+func (k *KeySet) retiredAt(index int) (time.Time, bool) {
+	if index+1 >= len(k.keys) {
+		return time.Time{}, false
+	}
+	return k.keys[index+1].ActiveAt.Add(k.maxTokenLifetime), true
+}
+
+// JWKS 获取公开的公钥集合，包含未退役的密钥及尚未生效的密钥(便于验证方提前缓存)
+func (k *KeySet) JWKS() *app.JSONWebKeySet {
+	now := k.now()
+	set := &app.JSONWebKeySet{Keys: make([]app.JSONWebKey, 0, len(k.keys))}
+	for i, key := range k.keys {
+		if retiredAt, ok := k.retiredAt(i); ok && now.After(retiredAt) {
+			continue
+		}
+		jwk, err := publicJWK(key)
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// LoadPEMKey 从PEM文件加载签名密钥，privateKeyPath为空时从publicKeyPath加载仅用于验证的公钥
+func LoadPEMKey(kid, privateKeyPath, publicKeyPath string, activeAt time.Time) (*SigningKey, error) {
+	key := &SigningKey{Kid: kid, ActiveAt: activeAt}
+	if privateKeyPath != "" {
+		block, err := readPEM(privateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		privateKey, err := parsePrivateKey(block)
+		if err != nil {
+			return nil, fmt.Errorf("parse private key %s: %w", privateKeyPath, err)
+		}
+		signer, ok := privateKey.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+		}
+		key.PrivateKey = privateKey
+		key.PublicKey = signer.Public()
+	} else if publicKeyPath != "" {
+		block, err := readPEM(publicKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse public key %s: %w", publicKeyPath, err)
+		}
+		key.PublicKey = publicKey
+	} else {
+		return nil, fmt.Errorf("signing key %s requires a private or public key file", kid)
+	}
+	if _, err := methodForKey(key.PublicKey); err != nil {
+		return nil, fmt.Errorf("signing key %s: %w", kid, err)
+	}
+	return key, nil
+}
+
+// readPEM 读取PEM文件的第一个数据块
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	return block, nil
+}
+
+// parsePrivateKey 解析PKCS#8、PKCS#1(RSA)及SEC1(EC)格式的私钥
+func parsePrivateKey(block *pem.Block) (crypto.PrivateKey, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+}
+
+// methodForKey 根据公钥类型确定签名算法
+func methodForKey(publicKey crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch pub := publicKey.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, errors.New("ES256 requires a P-256 key")
+		}
+		return jwt.SigningMethodES256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+}
+
+// isAsymmetricMethod 是否为支持的非对称签名算法
+func isAsymmetricMethod(method jwt.SigningMethod) bool {
+	switch method.Alg() {
+	case jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg(), jwt.SigningMethodEdDSA.Alg():
+		return true
+	}
+	return false
+}
+
+// publicJWK 将公钥转换为JWK格式(RFC 7517)
+func publicJWK(key *SigningKey) (app.JSONWebKey, error) {
+	jwk := app.JSONWebKey{
+		Kid: key.Kid,
+		Alg: key.Method.Alg(),
+		Use: "sig",
+	}
+	switch pub := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64URL(pub.N.Bytes())
+		jwk.E = base64URL(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		ecdhKey, err := pub.ECDH()
+		if err != nil {
+			return jwk, err
+		}
+		// 非压缩格式：0x04 || X || Y
+		raw := ecdhKey.Bytes()
+		size := (len(raw) - 1) / 2
+		jwk.Kty = "EC"
+		jwk.Crv = "P-256"
+		jwk.X = base64URL(raw[1 : 1+size])
+		jwk.Y = base64URL(raw[1+size:])
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64URL(pub)
+	default:
+		return jwk, fmt.Errorf("unsupported public key type %T", key.PublicKey)
+	}
+	return jwk, nil
+}
+
+func base64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
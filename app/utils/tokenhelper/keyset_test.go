@@ -0,0 +1,208 @@
+package tokenhelper
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"gin-fast/app/global/app"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePrivateKeyPEM 将私钥以PKCS#8格式写入临时文件
+func writePrivateKeyPEM(t *testing.T, dir, name string, key crypto.PrivateKey) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	return path
+}
+
+// writePublicKeyPEM 将公钥以PKIX格式写入临时文件
+func writePublicKeyPEM(t *testing.T, dir, name string, key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644))
+	return path
+}
+
+func newKeySetTestService(keySet *KeySet) *TokenService {
+	return &TokenService{
+		Ctx:            context.Background(),
+		RedisHelper:    NewMockCacheInterf(),
+		TokenExpire:    3600,
+		RefreshExpire:  86400,
+		CacheKeyPrefix: "test:",
+		KeySet:         keySet,
+	}
+}
+
+// TestKeySet_Algorithms 测试各非对称算法的签名、验证及JWK输出
+func TestKeySet_Algorithms(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	cases := []struct {
+		method string
+		key    crypto.PrivateKey
+		kty    string
+	}{
+		{"RS256", rsaKey, "RSA"},
+		{"ES256", ecKey, "EC"},
+		{"EdDSA", edKey, "OKP"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.method, func(t *testing.T) {
+			path := writePrivateKeyPEM(t, dir, tc.method+".pem", tc.key)
+			key, err := LoadPEMKey("k1", path, "", time.Time{})
+			require.NoError(t, err)
+			keySet, err := NewKeySet(tc.method, []*SigningKey{key}, 24*time.Hour)
+			require.NoError(t, err)
+
+			tokenService := newKeySetTestService(keySet)
+			token, err := tokenService.GenerateToken(&app.ClaimsUser{UserID: 1, Username: "admin"})
+			require.NoError(t, err)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &app.Claims{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.method, parsed.Method.Alg())
+			assert.Equal(t, "k1", parsed.Header["kid"])
+
+			claims, err := tokenService.ParseToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, uint(1), claims.UserID)
+
+			jwks := tokenService.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, tc.kty, jwks.Keys[0].Kty)
+			assert.Equal(t, tc.method, jwks.Keys[0].Alg)
+			assert.Equal(t, "sig", jwks.Keys[0].Use)
+		})
+	}
+
+	// 密钥类型与签名算法不匹配
+	path := writePrivateKeyPEM(t, dir, "mismatch.pem", ecKey)
+	key, err := LoadPEMKey("k1", path, "", time.Time{})
+	require.NoError(t, err)
+	_, err = NewKeySet("RS256", []*SigningKey{key}, time.Hour)
+	assert.Error(t, err)
+
+	// HS256 不是非对称算法
+	_, err = NewKeySet("HS256", []*SigningKey{key}, time.Hour)
+	assert.Error(t, err)
+}
+
+// TestKeySet_Rotation 测试密钥轮换：新密钥生效后用于签名，旧密钥在token最长有效期内继续验证
+func TestKeySet_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	_, oldPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, newPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	base := time.Now().Add(-48 * time.Hour)
+	oldKey, err := LoadPEMKey("old", writePrivateKeyPEM(t, dir, "old.pem", oldPriv), "", base)
+	require.NoError(t, err)
+	newKey, err := LoadPEMKey("new", writePrivateKeyPEM(t, dir, "new.pem", newPriv), "", base.Add(time.Hour))
+	require.NoError(t, err)
+
+	lifetime := 24 * time.Hour
+	keySet, err := NewKeySet("EdDSA", []*SigningKey{newKey, oldKey}, lifetime)
+	require.NoError(t, err)
+	tokenService := newKeySetTestService(keySet)
+
+	// 新密钥生效前使用旧密钥签名
+	keySet.now = func() time.Time { return base.Add(30 * time.Minute) }
+	signing, err := keySet.SigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, "old", signing.Kid)
+	oldToken, err := tokenService.signClaims(jwt.RegisteredClaims{Subject: "1"})
+	require.NoError(t, err)
+	assert.Len(t, keySet.JWKS().Keys, 2, "尚未生效的新密钥应提前公开")
+
+	// 新密钥生效后使用新密钥签名，旧密钥仍可验证
+	keySet.now = func() time.Time { return base.Add(2 * time.Hour) }
+	signing, err = keySet.SigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, "new", signing.Kid)
+	_, err = jwt.Parse(oldToken, tokenService.keyFunc)
+	assert.NoError(t, err)
+
+	// 超过token最长有效期后旧密钥退役
+	keySet.now = func() time.Time { return base.Add(time.Hour + lifetime + time.Minute) }
+	_, err = keySet.VerifyKey("old")
+	assert.Error(t, err)
+	jwks := keySet.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "new", jwks.Keys[0].Kid)
+}
+
+// TestKeySet_PublicKeyOnly 仅公钥的密钥只用于验证，不用于签名
+func TestKeySet_PublicKeyOnly(t *testing.T) {
+	dir := t.TempDir()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	verifyOnly, err := LoadPEMKey("legacy", "", writePublicKeyPEM(t, dir, "legacy.pub", pub), time.Time{})
+	require.NoError(t, err)
+	keySet, err := NewKeySet("EdDSA", []*SigningKey{verifyOnly}, time.Hour)
+	require.NoError(t, err)
+
+	_, err = keySet.SigningKey()
+	assert.Error(t, err)
+
+	// 外部使用对应私钥签发的token可以验证
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.RegisteredClaims{Subject: "1"})
+	token.Header["kid"] = "legacy"
+	signed, err := token.SignedString(priv)
+	require.NoError(t, err)
+	tokenService := newKeySetTestService(keySet)
+	_, err = jwt.Parse(signed, tokenService.keyFunc)
+	assert.NoError(t, err)
+}
+
+// TestKeyFunc_RejectsAlgorithmConfusion 使用公钥作为HMAC密钥伪造的token应被拒绝
+func TestKeyFunc_RejectsAlgorithmConfusion(t *testing.T) {
+	dir := t.TempDir()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key, err := LoadPEMKey("k1", writePrivateKeyPEM(t, dir, "k1.pem", priv), "", time.Time{})
+	require.NoError(t, err)
+	keySet, err := NewKeySet("EdDSA", []*SigningKey{key}, time.Hour)
+	require.NoError(t, err)
+	tokenService := newKeySetTestService(keySet)
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &app.Claims{ClaimsUser: app.ClaimsUser{UserID: 1}})
+	forged.Header["kid"] = "k1"
+	forgedString, err := forged.SignedString([]byte(pub))
+	require.NoError(t, err)
+	_, err = tokenService.ParseToken(forgedString)
+	assert.Error(t, err)
+
+	// HMAC模式下拒绝非HMAC算法的token
+	hmacService := newKeySetTestService(nil)
+	hmacService.JWTSecret = "test_secret"
+	asymmetric, err := tokenService.GenerateToken(&app.ClaimsUser{UserID: 1})
+	require.NoError(t, err)
+	_, err = hmacService.ParseToken(asymmetric)
+	assert.Error(t, err)
+}
@@ -3,9 +3,15 @@ package tokenhelper
 import (
 	"context"
 	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"gin-fast/app/global/app"
+	"slices"
+	"sort"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -20,31 +26,80 @@ type TokenService struct {
 	RefreshExpire  time.Duration
 	CacheKeyPrefix string
 	IsCache        bool
+	MaxSessions    int     // 每个用户最大同时在线会话数，0表示不限制
+	KeySet         *KeySet // 非对称签名密钥集合，为nil时使用JWTSecret进行HS256签名
 }
 
+// errSessionNotFound 会话不存在或已过期
+var errSessionNotFound = errors.New("refresh token session not found")
+
 /**
 	token管理（非缓存模式）
 **/
 // GenerateToken 生成JWT令牌
 func (s *TokenService) GenerateToken(user *app.ClaimsUser) (string, error) {
+	return s.generateToken(user, s.TokenExpire*time.Second)
+}
+
+// generateToken 生成指定有效期的JWT令牌
+func (s *TokenService) generateToken(user *app.ClaimsUser, expire time.Duration) (string, error) {
 	claims := &app.Claims{
 		ClaimsUser: *user,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.TokenExpire * time.Second)), // 过期时间
-			IssuedAt:  jwt.NewNumericDate(time.Now()),                                  // 签发时间
-			NotBefore: jwt.NewNumericDate(time.Now()),                                  // 生效时间
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expire)), // 过期时间
+			IssuedAt:  jwt.NewNumericDate(time.Now()),             // 签发时间
+			NotBefore: jwt.NewNumericDate(time.Now()),             // 生效时间
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.JWTSecret))
+	return s.signClaims(claims)
+}
+
+// signClaims 签名token：配置了密钥集合时使用当前签名密钥并写入kid，否则使用HS256
+func (s *TokenService) signClaims(claims jwt.Claims) (string, error) {
+	if s.KeySet == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(s.JWTSecret))
+	}
+	key, err := s.KeySet.SigningKey()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.Kid
+	return token.SignedString(key.PrivateKey)
+}
+
+// keyFunc 获取验证token签名的密钥，并校验签名算法与密钥匹配
+func (s *TokenService) keyFunc(token *jwt.Token) (interface{}, error) {
+	if s.KeySet == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.JWTSecret), nil
+	}
+	kid, _ := token.Header["kid"].(string)
+	key, err := s.KeySet.VerifyKey(kid)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.PublicKey, nil
+}
+
+// JWKS 获取用于验证token的公钥集合
+func (s *TokenService) JWKS() *app.JSONWebKeySet {
+	if s.KeySet == nil {
+		return &app.JSONWebKeySet{Keys: []app.JSONWebKey{}}
+	}
+	return s.KeySet.JWKS()
 }
 
 // ParseToken 解析JWT令牌
 func (s *TokenService) ParseToken(tokenString string) (*app.Claims, error) {
 	claims := &app.Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.JWTSecret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.keyFunc)
 
 	if err != nil {
 		return nil, err
@@ -65,7 +120,12 @@ func (s *TokenService) ValidateToken(tokenString string) (*app.Claims, error) {
 **/
 // GenerateTokenWithCache 生成JWT令牌并存储到缓存
 func (s *TokenService) GenerateTokenWithCache(user *app.ClaimsUser) (string, error) {
-	tokenString, err := s.GenerateToken(user)
+	return s.GenerateTokenWithExpire(user, s.TokenExpire*time.Second)
+}
+
+// GenerateTokenWithExpire 生成指定有效期的JWT令牌并存储到缓存(不创建会话，无法刷新)
+func (s *TokenService) GenerateTokenWithExpire(user *app.ClaimsUser, expire time.Duration) (string, error) {
+	tokenString, err := s.generateToken(user, expire)
 	if err != nil {
 		return "", err
 	}
@@ -75,7 +135,7 @@ func (s *TokenService) GenerateTokenWithCache(user *app.ClaimsUser) (string, err
 		tokenInfo := &app.TokenInfo{
 			UserID:    user.UserID,
 			Token:     tokenString,
-			ExpiresAt: time.Now().Add(s.TokenExpire * time.Second),
+			ExpiresAt: time.Now().Add(expire),
 			CreatedAt: time.Now(),
 		}
 		err = s.storeTokenWithCache(tokenInfo)
@@ -107,6 +167,13 @@ func (s *TokenService) ValidateTokenWithCache(tokenString string) (*app.Claims,
 		if err != nil || exists == 0 {
 			return nil, errors.New("token not found")
 		}
+		// 会话被撤销后，该会话签发的token立即失效
+		if claims.SessionID != "" {
+			exists, err = s.RedisHelper.Exists(s.Ctx, s.getRefreshTokenKey(claims.UserID, claims.SessionID))
+			if err != nil || exists == 0 {
+				return nil, errors.New("session has been revoked")
+			}
+		}
 	}
 	return claims, nil
 }
@@ -134,52 +201,242 @@ func (s *TokenService) getTokenKeyWithCache(userID uint, tokenString string) str
 }
 
 /*****************************************refreshToken管理****************************************************/
+// sessionRecord 缓存中存储的会话记录，只保存refresh token摘要
+type sessionRecord struct {
+	app.SessionInfo
+	TokenHash string `json:"tokenHash"`
+}
+
 // GenerateRefreshToken 生成Refresh Token
 func (s *TokenService) GenerateRefreshToken(userID uint) (string, error) {
-	expirationTime := time.Now().Add(s.RefreshExpire * time.Second)
-	claims := &app.RefreshTokenClaims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-		},
+	return s.GenerateRefreshTokenWithSession(userID, nil)
+}
+
+// GenerateRefreshTokenWithSession 生成Refresh Token并创建登录会话
+func (s *TokenService) GenerateRefreshTokenWithSession(userID uint, session *app.SessionInfo) (string, error) {
+	sessionID, err := newSessionID()
+	if err != nil {
+		return "", err
 	}
+	now := time.Now()
+	record := &sessionRecord{}
+	if session != nil {
+		record.SessionInfo = *session
+	}
+	record.SessionID = sessionID
+	record.UserID = userID
+	record.CreatedAt = now
+	record.LastUsedAt = now
+	record.ExpiresAt = now.Add(s.RefreshExpire * time.Second)
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.JWTSecret))
+	tokenString, err := s.signRefreshToken(&record.SessionInfo, now)
 	if err != nil {
 		return "", err
 	}
+	record.TokenHash = hashToken(tokenString)
+
+	if err = s.setSession(record); err != nil {
+		return "", err
+	}
+	if s.MaxSessions <= 0 {
+		if err = s.addSessionIndex(userID, sessionID); err != nil {
+			return "", err
+		}
+		return tokenString, nil
+	}
 
-	// 存储refresh token到Redis
-	refreshTokenInfo := &app.RefreshTokenInfo{
-		UserID:    userID,
-		Token:     tokenString,
-		ExpiresAt: expirationTime,
-		CreatedAt: time.Now(),
+	// 超出最大会话数时，淘汰最久未使用的会话
+	records, err := s.loadSessions(userID)
+	if err != nil {
+		return "", err
+	}
+	lastUsed := make(map[string]time.Time, len(records))
+	for _, r := range records {
+		lastUsed[r.SessionID] = r.LastUsedAt
+	}
+	var evicted []string
+	err = s.updateSessionIndex(userID, func(ids []string) []string {
+		// 读取会话记录之后由其他实例加入索引的会话视为最近使用
+		alive := make([]string, 0, len(ids)+1)
+		for _, id := range ids {
+			if id != sessionID {
+				alive = append(alive, id)
+			}
+		}
+		usedAt := func(id string) time.Time {
+			if t, ok := lastUsed[id]; ok {
+				return t
+			}
+			return now
+		}
+		sort.SliceStable(alive, func(i, j int) bool {
+			return usedAt(alive[i]).Before(usedAt(alive[j]))
+		})
+		n := max(len(alive)-s.MaxSessions+1, 0)
+		evicted = slices.Clone(alive[:n])
+		return append(alive[n:], sessionID)
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(evicted) > 0 {
+		keys := make([]string, len(evicted))
+		for i, id := range evicted {
+			keys[i] = s.getRefreshTokenKey(userID, id)
+		}
+		if err = s.RedisHelper.Del(s.Ctx, keys...); err != nil {
+			return "", err
+		}
 	}
+	return tokenString, nil
+}
 
-	err = s.storeRefreshToken(refreshTokenInfo)
+// signRefreshToken 按会话信息签发Refresh Token，token中携带会话ID及租户
+// 每次签发使用随机的jti，同一秒内轮换得到的token也互不相同
+func (s *TokenService) signRefreshToken(session *app.SessionInfo, issuedAt time.Time) (string, error) {
+	tokenID, err := newSessionID()
 	if err != nil {
 		return "", err
 	}
+	claims := &app.RefreshTokenClaims{
+		UserID:     session.UserID,
+		SessionID:  session.SessionID,
+		TenantID:   session.TenantID,
+		TenantCode: session.TenantCode,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(session.ExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			NotBefore: jwt.NewNumericDate(issuedAt),
+		},
+	}
+	return s.signClaims(claims)
+}
 
-	return tokenString, nil
+// setSession 存储会话记录，有效期与会话一致
+func (s *TokenService) setSession(record *sessionRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	key := s.getRefreshTokenKey(record.UserID, record.SessionID)
+	return s.RedisHelper.Set(s.Ctx, key, string(data), time.Until(record.ExpiresAt))
 }
 
-// StoreRefreshToken 存储Refresh Token到Redis
-func (s *TokenService) storeRefreshToken(info *app.RefreshTokenInfo) error {
-	key := s.getRefreshTokenKey(info.UserID)
-	return s.RedisHelper.Set(s.Ctx, key, info.Token, time.Until(info.ExpiresAt))
+// updateSession 原子地修改会话记录，多个实例同时轮换同一会话时只有一个能基于原记录修改成功
+func (s *TokenService) updateSession(userID uint, sessionID string, expiration time.Duration, fn func(record *sessionRecord) error) error {
+	return s.RedisHelper.Update(s.Ctx, s.getRefreshTokenKey(userID, sessionID), expiration, func(value string) (string, error) {
+		if value == "" {
+			return "", errSessionNotFound
+		}
+		record := &sessionRecord{}
+		if err := json.Unmarshal([]byte(value), record); err != nil {
+			return "", err
+		}
+		if err := fn(record); err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(record)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	})
+}
+
+// getSession 获取会话记录，不存在时返回nil
+func (s *TokenService) getSession(userID uint, sessionID string) (*sessionRecord, error) {
+	// 内存缓存未命中返回空字符串，redis未命中返回redis.Nil错误，统一视为不存在
+	data, err := s.RedisHelper.Get(s.Ctx, s.getRefreshTokenKey(userID, sessionID))
+	if err != nil || data == "" {
+		return nil, nil
+	}
+	record := &sessionRecord{}
+	if err = json.Unmarshal([]byte(data), record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// loadSessions 获取用户所有有效会话记录，并清理索引中已过期的会话
+func (s *TokenService) loadSessions(userID uint) ([]*sessionRecord, error) {
+	ids := s.getSessionIndex(userID)
+	records := make([]*sessionRecord, 0, len(ids))
+	var expired []string
+	for _, id := range ids {
+		record, err := s.getSession(userID, id)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			expired = append(expired, id)
+			continue
+		}
+		records = append(records, record)
+	}
+	if len(expired) > 0 {
+		if err := s.removeSessionIndex(userID, expired...); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// getSessionIndex 获取用户会话ID索引
+func (s *TokenService) getSessionIndex(userID uint) []string {
+	var ids []string
+	data, err := s.RedisHelper.Get(s.Ctx, s.getSessionIndexKey(userID))
+	if err != nil || data == "" {
+		return ids
+	}
+	_ = json.Unmarshal([]byte(data), &ids)
+	return ids
+}
+
+// updateSessionIndex 原子地修改用户会话ID索引，索引有效期与refresh token一致，修改后为空时删除索引
+// 多个实例共享缓存时，并发的登录、撤销不会覆盖彼此对索引的修改
+func (s *TokenService) updateSessionIndex(userID uint, fn func(ids []string) []string) error {
+	key := s.getSessionIndexKey(userID)
+	return s.RedisHelper.Update(s.Ctx, key, s.RefreshExpire*time.Second, func(value string) (string, error) {
+		var ids []string
+		if value != "" {
+			_ = json.Unmarshal([]byte(value), &ids)
+		}
+		ids = fn(ids)
+		if len(ids) == 0 {
+			return "", nil
+		}
+		data, err := json.Marshal(ids)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	})
+}
+
+// addSessionIndex 将会话ID加入用户会话索引
+func (s *TokenService) addSessionIndex(userID uint, sessionID string) error {
+	return s.updateSessionIndex(userID, func(ids []string) []string {
+		if slices.Contains(ids, sessionID) {
+			return ids
+		}
+		return append(ids, sessionID)
+	})
+}
+
+// removeSessionIndex 从用户会话索引中移除会话ID
+func (s *TokenService) removeSessionIndex(userID uint, sessionIDs ...string) error {
+	return s.updateSessionIndex(userID, func(ids []string) []string {
+		return slices.DeleteFunc(ids, func(id string) bool {
+			return slices.Contains(sessionIDs, id)
+		})
+	})
 }
 
 // ParseRefreshToken 解析Refresh Token
 func (s *TokenService) ParseRefreshToken(tokenString string) (*app.RefreshTokenClaims, error) {
 	claims := &app.RefreshTokenClaims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.JWTSecret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.keyFunc)
 	if err != nil {
 		return nil, err
 	}
@@ -196,22 +453,58 @@ func (s *TokenService) ValidateRefreshToken(tokenString string) (*app.RefreshTok
 		return nil, err
 	}
 
-	// 检查Redis中是否存在该token
-	key := s.getRefreshTokenKey(claims.UserID)
-	storedToken, err := s.RedisHelper.Get(s.Ctx, key)
-	if err != nil {
+	// 检查缓存中是否存在该会话且token一致
+	record, err := s.getSession(claims.UserID, claims.SessionID)
+	if err != nil || record == nil {
 		return nil, errors.New("refresh Token not found")
 	}
-	if storedToken == tokenString {
+	if record.TokenHash == hashToken(tokenString) {
 		return claims, nil
 	}
 	return nil, errors.New("invalid refresh token")
 }
 
-// RevokeRefreshToken 撤销Refresh Token
+// RevokeRefreshToken 撤销用户所有会话的Refresh Token
 func (s *TokenService) RevokeRefreshToken(userID uint) error {
-	key := s.getRefreshTokenKey(userID)
-	return s.RedisHelper.Del(s.Ctx, key)
+	// 先原子地取出并清空索引，之后新建的会话不受影响
+	var ids []string
+	err := s.updateSessionIndex(userID, func(current []string) []string {
+		ids = current
+		return nil
+	})
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.getRefreshTokenKey(userID, id)
+	}
+	return s.RedisHelper.Del(s.Ctx, keys...)
+}
+
+// RevokeSession 撤销指定会话
+func (s *TokenService) RevokeSession(userID uint, sessionID string) error {
+	if err := s.RedisHelper.Del(s.Ctx, s.getRefreshTokenKey(userID, sessionID)); err != nil {
+		return err
+	}
+	return s.removeSessionIndex(userID, sessionID)
+}
+
+// ListSessions 获取用户所有有效会话，按最近使用时间倒序
+func (s *TokenService) ListSessions(userID uint) ([]*app.SessionInfo, error) {
+	records, err := s.loadSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]*app.SessionInfo, len(records))
+	for i, record := range records {
+		info := record.SessionInfo
+		sessions[i] = &info
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+	return sessions, nil
 }
 
 // RefreshAccessTokenWithCache 使用Refresh Token刷新Access Token 并记录到缓存中
@@ -229,7 +522,7 @@ func (s *TokenService) RefreshAccessTokenWithCache(refreshTokenString string, us
 	return newAccessToken, nil
 }
 
-// RotateRefreshToken 轮换Refresh Token（撤销旧的，生成新的，保持相同的剩余过期时间）
+// RotateRefreshToken 轮换Refresh Token（撤销旧的，生成新的，保持相同的会话及剩余过期时间）
 func (s *TokenService) RotateRefreshToken(oldRefreshToken string) (string, error) {
 	// 1. 验证旧的refresh token
 	claims, err := s.ValidateRefreshToken(oldRefreshToken)
@@ -244,45 +537,84 @@ func (s *TokenService) RotateRefreshToken(oldRefreshToken string) (string, error
 		return "", errors.New("refresh token has expired")
 	}
 
-	// 3. 撤销旧的refresh token
-	if err := s.RevokeRefreshToken(claims.UserID); err != nil {
-		return "", fmt.Errorf("failed to revoke old refresh token: %w", err)
-	}
-
-	// 4. 生成新的refresh token，使用剩余的有效时间
-	expirationTime := now.Add(remainingDuration)
-	newClaims := &app.RefreshTokenClaims{
-		UserID: claims.UserID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(now),
-			NotBefore: jwt.NewNumericDate(now),
-		},
+	// 3. 在同一会话下生成新的refresh token，使用剩余的有效时间
+	// 4. 原子地覆盖会话中的token摘要，旧token随即失效；并发轮换同一token时只有一个成功
+	oldHash := hashToken(oldRefreshToken)
+	var newTokenString string
+	err = s.updateSession(claims.UserID, claims.SessionID, remainingDuration, func(record *sessionRecord) error {
+		if record.TokenHash != oldHash {
+			return errors.New("invalid old refresh token: refresh token already rotated")
+		}
+		record.LastUsedAt = now
+		record.ExpiresAt = now.Add(remainingDuration)
+		tokenString, err := s.signRefreshToken(&record.SessionInfo, now)
+		if err != nil {
+			return fmt.Errorf("failed to sign new refresh token: %w", err)
+		}
+		record.TokenHash = hashToken(tokenString)
+		newTokenString = tokenString
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
+	return newTokenString, nil
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, newClaims)
-	newTokenString, err := token.SignedString([]byte(s.JWTSecret))
+// SwitchSessionTenant 切换会话所属租户，重新签发携带新租户的Refresh Token（保持剩余过期时间）
+func (s *TokenService) SwitchSessionTenant(userID uint, sessionID string, tenantID uint, tenantCode string) (string, error) {
+	record, err := s.getSession(userID, sessionID)
 	if err != nil {
-		return "", fmt.Errorf("failed to sign new refresh token: %w", err)
+		return "", err
 	}
-
-	// 5. 存储新的refresh token到Redis
-	newRefreshTokenInfo := &app.RefreshTokenInfo{
-		UserID:    claims.UserID,
-		Token:     newTokenString,
-		ExpiresAt: expirationTime,
-		CreatedAt: now,
+	if record == nil {
+		return "", errSessionNotFound
 	}
-
-	if err := s.storeRefreshToken(newRefreshTokenInfo); err != nil {
-		return "", fmt.Errorf("failed to store new refresh token: %w", err)
+	now := time.Now()
+	if !record.ExpiresAt.After(now) {
+		return "", errors.New("refresh token has expired")
 	}
 
+	var newTokenString string
+	err = s.updateSession(userID, sessionID, record.ExpiresAt.Sub(now), func(record *sessionRecord) error {
+		record.TenantID = tenantID
+		record.TenantCode = tenantCode
+		record.LastUsedAt = now
+		tokenString, err := s.signRefreshToken(&record.SessionInfo, now)
+		if err != nil {
+			return fmt.Errorf("failed to sign new refresh token: %w", err)
+		}
+		record.TokenHash = hashToken(tokenString)
+		newTokenString = tokenString
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
 	return newTokenString, nil
 }
 
-// getRefreshTokenKey 获取Redis中存储Refresh Token的key
-func (s *TokenService) getRefreshTokenKey(userID uint) string {
-	return fmt.Sprintf("%srefresh_token:%d", s.CacheKeyPrefix, userID)
+// getRefreshTokenKey 获取缓存中存储会话Refresh Token的key
+func (s *TokenService) getRefreshTokenKey(userID uint, sessionID string) string {
+	return fmt.Sprintf("%srefresh_token:%d:%s", s.CacheKeyPrefix, userID, sessionID)
+}
+
+// getSessionIndexKey 获取缓存中存储用户会话ID索引的key
+func (s *TokenService) getSessionIndexKey(userID uint) string {
+	return fmt.Sprintf("%srefresh_session:%d", s.CacheKeyPrefix, userID)
+}
+
+// newSessionID 生成随机会话ID
+func newSessionID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
 
+// hashToken 计算token摘要，缓存中不保存refresh token明文
+func hashToken(tokenString string) string {
+	sum := sha256.Sum256([]byte(tokenString))
+	return hex.EncodeToString(sum[:])
 }
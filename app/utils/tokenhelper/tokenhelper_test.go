@@ -3,6 +3,9 @@ package tokenhelper
 import (
 	"context"
 	"gin-fast/app/global/app"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -13,6 +16,7 @@ import (
 // MockCacheInterf 模拟缓存接口
 type MockCacheInterf struct {
 	mock.Mock
+	mu      sync.Mutex
 	storage map[string]string // 简单的内存存储模拟Redis
 }
 
@@ -23,11 +27,15 @@ func NewMockCacheInterf() *MockCacheInterf {
 }
 
 func (m *MockCacheInterf) Set(ctx context.Context, key string, value string, expiration time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.storage[key] = value
 	return nil
 }
 
 func (m *MockCacheInterf) Get(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if value, exists := m.storage[key]; exists {
 		return value, nil
 	}
@@ -35,6 +43,8 @@ func (m *MockCacheInterf) Get(ctx context.Context, key string) (string, error) {
 }
 
 func (m *MockCacheInterf) Del(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	for _, key := range keys {
 		delete(m.storage, key)
 	}
@@ -42,6 +52,8 @@ func (m *MockCacheInterf) Del(ctx context.Context, keys ...string) error {
 }
 
 func (m *MockCacheInterf) Exists(ctx context.Context, keys ...string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	count := int64(0)
 	for _, key := range keys {
 		if _, exists := m.storage[key]; exists {
@@ -55,6 +67,30 @@ func (m *MockCacheInterf) Expire(ctx context.Context, key string, expiration tim
 	return nil
 }
 
+func (m *MockCacheInterf) Incr(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, _ := strconv.ParseInt(m.storage[key], 10, 64)
+	n++
+	m.storage[key] = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+func (m *MockCacheInterf) Update(ctx context.Context, key string, expiration time.Duration, fn func(value string) (string, error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, err := fn(m.storage[key])
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		delete(m.storage, key)
+	} else {
+		m.storage[key] = value
+	}
+	return nil
+}
+
 func (m *MockCacheInterf) Close() error {
 	return nil
 }
@@ -105,11 +141,14 @@ func TestRotateRefreshToken(t *testing.T) {
 	assert.True(t, timeDiff < 2*time.Second && timeDiff > -2*time.Second,
 		"新token的过期时间应该与原token相近，时间差: %v", timeDiff)
 
-	// 验证原token已从缓存中移除
-	originalKey := tokenService.getRefreshTokenKey(userID)
-	storedToken, err := mockCache.Get(context.Background(), originalKey)
-	assert.NoError(t, err)
-	assert.Equal(t, newRefreshToken, storedToken, "缓存中应该存储新的refresh token")
+	// 轮换后保持同一会话
+	assert.Equal(t, originalClaims.SessionID, newClaims.SessionID)
+
+	// 验证原token已失效，新token有效
+	_, err = tokenService.ValidateRefreshToken(originalRefreshToken)
+	assert.Error(t, err, "原refresh token应该失效")
+	_, err = tokenService.ValidateRefreshToken(newRefreshToken)
+	assert.NoError(t, err, "缓存中应该存储新的refresh token")
 }
 
 func TestRotateRefreshToken_ExpiredToken(t *testing.T) {
@@ -154,3 +193,222 @@ func TestRotateRefreshToken_InvalidToken(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid")
 }
+
+func newSessionTestService(maxSessions int) *TokenService {
+	return &TokenService{
+		Ctx:            context.Background(),
+		RedisHelper:    NewMockCacheInterf(),
+		JWTSecret:      "test_secret",
+		TokenExpire:    3600,
+		RefreshExpire:  86400,
+		CacheKeyPrefix: "test:",
+		MaxSessions:    maxSessions,
+	}
+}
+
+// TestSessions_MultiDevice 多设备登录互不影响
+func TestSessions_MultiDevice(t *testing.T) {
+	tokenService := newSessionTestService(0)
+	userID := uint(1)
+
+	pcToken, err := tokenService.GenerateRefreshTokenWithSession(userID, &app.SessionInfo{DeviceName: "PC", IP: "10.0.0.1", UserAgent: "Mozilla/5.0"})
+	assert.NoError(t, err)
+	phoneToken, err := tokenService.GenerateRefreshTokenWithSession(userID, &app.SessionInfo{DeviceName: "Phone", IP: "10.0.0.2"})
+	assert.NoError(t, err)
+
+	// 第二台设备登录后，第一台设备的refresh token仍然有效
+	_, err = tokenService.ValidateRefreshToken(pcToken)
+	assert.NoError(t, err)
+	_, err = tokenService.ValidateRefreshToken(phoneToken)
+	assert.NoError(t, err)
+
+	sessions, err := tokenService.ListSessions(userID)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	devices := []string{sessions[0].DeviceName, sessions[1].DeviceName}
+	assert.ElementsMatch(t, []string{"PC", "Phone"}, devices)
+
+	// 撤销单个会话
+	pcClaims, err := tokenService.ParseRefreshToken(pcToken)
+	assert.NoError(t, err)
+	assert.NoError(t, tokenService.RevokeSession(userID, pcClaims.SessionID))
+	_, err = tokenService.ValidateRefreshToken(pcToken)
+	assert.Error(t, err)
+	_, err = tokenService.ValidateRefreshToken(phoneToken)
+	assert.NoError(t, err)
+
+	sessions, err = tokenService.ListSessions(userID)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, "Phone", sessions[0].DeviceName)
+
+	// 退出所有设备
+	assert.NoError(t, tokenService.RevokeRefreshToken(userID))
+	_, err = tokenService.ValidateRefreshToken(phoneToken)
+	assert.Error(t, err)
+	sessions, err = tokenService.ListSessions(userID)
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+// TestSessions_MaxSessions 超出最大会话数时淘汰最久未使用的会话
+func TestSessions_MaxSessions(t *testing.T) {
+	tokenService := newSessionTestService(2)
+	userID := uint(1)
+
+	first, err := tokenService.GenerateRefreshTokenWithSession(userID, &app.SessionInfo{DeviceName: "first"})
+	assert.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	second, err := tokenService.GenerateRefreshTokenWithSession(userID, &app.SessionInfo{DeviceName: "second"})
+	assert.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	third, err := tokenService.GenerateRefreshTokenWithSession(userID, &app.SessionInfo{DeviceName: "third"})
+	assert.NoError(t, err)
+
+	_, err = tokenService.ValidateRefreshToken(first)
+	assert.Error(t, err, "最早的会话应该被淘汰")
+	_, err = tokenService.ValidateRefreshToken(second)
+	assert.NoError(t, err)
+	_, err = tokenService.ValidateRefreshToken(third)
+	assert.NoError(t, err)
+
+	sessions, err := tokenService.ListSessions(userID)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	assert.Equal(t, "third", sessions[0].DeviceName)
+}
+
+// TestSessions_SharedCache 多个实例共享缓存并发登录、轮换时会话索引不丢失，同一token只能轮换一次
+func TestSessions_SharedCache(t *testing.T) {
+	cache := NewMockCacheInterf()
+	newService := func() *TokenService {
+		return &TokenService{
+			Ctx:            context.Background(),
+			RedisHelper:    cache,
+			JWTSecret:      "test_secret",
+			TokenExpire:    3600,
+			RefreshExpire:  86400,
+			CacheKeyPrefix: "test:",
+			MaxSessions:    100,
+		}
+	}
+	instances := []*TokenService{newService(), newService()}
+	userID := uint(1)
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 40)
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			token, err := instances[i%2].GenerateRefreshTokenWithSession(userID, nil)
+			assert.NoError(t, err)
+			tokens[i] = token
+		}(i)
+	}
+	wg.Wait()
+
+	sessions, err := instances[0].ListSessions(userID)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, len(tokens))
+
+	var succeeded atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := instances[i%2].RotateRefreshToken(tokens[0]); err == nil {
+				succeeded.Add(1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), succeeded.Load())
+
+	assert.NoError(t, instances[1].RevokeRefreshToken(userID))
+	sessions, err = instances[0].ListSessions(userID)
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+// TestValidateTokenWithCache_RevokedSession 缓存模式下会话撤销后access token立即失效
+func TestValidateTokenWithCache_RevokedSession(t *testing.T) {
+	tokenService := newSessionTestService(0)
+	tokenService.IsCache = true
+	userID := uint(1)
+
+	refreshToken, err := tokenService.GenerateRefreshTokenWithSession(userID, nil)
+	assert.NoError(t, err)
+	refreshClaims, err := tokenService.ParseRefreshToken(refreshToken)
+	assert.NoError(t, err)
+
+	accessToken, err := tokenService.GenerateTokenWithCache(&app.ClaimsUser{UserID: userID, Username: "admin", SessionID: refreshClaims.SessionID})
+	assert.NoError(t, err)
+	_, err = tokenService.ValidateTokenWithCache(accessToken)
+	assert.NoError(t, err)
+
+	assert.NoError(t, tokenService.RevokeSession(userID, refreshClaims.SessionID))
+	_, err = tokenService.ValidateTokenWithCache(accessToken)
+	assert.Error(t, err)
+}
+
+// TestSwitchSessionTenant 切换租户后refresh token携带新租户，轮换时保持租户
+func TestSwitchSessionTenant(t *testing.T) {
+	tokenService := newSessionTestService(0)
+	userID := uint(1)
+
+	refreshToken, err := tokenService.GenerateRefreshTokenWithSession(userID, &app.SessionInfo{TenantID: 1, TenantCode: "default"})
+	assert.NoError(t, err)
+	claims, err := tokenService.ParseRefreshToken(refreshToken)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), claims.TenantID)
+	assert.Equal(t, "default", claims.TenantCode)
+
+	switched, err := tokenService.SwitchSessionTenant(userID, claims.SessionID, 2, "other")
+	assert.NoError(t, err)
+	_, err = tokenService.ValidateRefreshToken(refreshToken)
+	assert.Error(t, err, "切换租户后原refresh token应该失效")
+
+	switchedClaims, err := tokenService.ValidateRefreshToken(switched)
+	assert.NoError(t, err)
+	assert.Equal(t, claims.SessionID, switchedClaims.SessionID)
+	assert.Equal(t, uint(2), switchedClaims.TenantID)
+	assert.Equal(t, "other", switchedClaims.TenantCode)
+
+	rotated, err := tokenService.RotateRefreshToken(switched)
+	assert.NoError(t, err)
+	rotatedClaims, err := tokenService.ParseRefreshToken(rotated)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), rotatedClaims.TenantID)
+	assert.Equal(t, "other", rotatedClaims.TenantCode)
+
+	_, err = tokenService.SwitchSessionTenant(userID, "not-exists", 2, "other")
+	assert.Error(t, err)
+}
+
+func TestGenerateTokenWithExpire(t *testing.T) {
+	tokenService := newSessionTestService(0)
+	tokenService.IsCache = true
+
+	token, err := tokenService.GenerateTokenWithExpire(&app.ClaimsUser{
+		UserID:           2,
+		Username:         "alice",
+		TenantID:         1,
+		ImpersonatorID:   1,
+		ImpersonatorName: "admin",
+	}, 5*time.Minute)
+	assert.NoError(t, err)
+
+	claims, err := tokenService.ValidateTokenWithCache(token)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), claims.UserID)
+	assert.Equal(t, uint(1), claims.ImpersonatorID)
+	assert.Equal(t, "admin", claims.ImpersonatorName)
+	assert.Empty(t, claims.SessionID)
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), claims.ExpiresAt.Time, 2*time.Second)
+
+	// 撤销后立即失效
+	assert.NoError(t, tokenService.RevokeTokenWithCache(token))
+	_, err = tokenService.ValidateTokenWithCache(token)
+	assert.Error(t, err)
+}
@@ -0,0 +1,144 @@
+package totphelper
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RFC 6238 默认参数
+const (
+	DefaultPeriod = 30 // 时间步长(秒)
+	DefaultDigits = 6  // 验证码位数
+	DefaultSkew   = 1  // 允许前后偏移的时间步数
+	SecretSize    = 20 // 密钥字节数(160位，与HMAC-SHA1输出长度一致)
+)
+
+var b32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret 生成Base32编码的随机密钥
+func GenerateSecret() (string, error) {
+	buf := make([]byte, SecretSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return b32NoPadding.EncodeToString(buf), nil
+}
+
+// decodeSecret 解码Base32密钥，兼容小写、空格及填充符
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	secret = strings.TrimRight(secret, "=")
+	key, err := b32NoPadding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid totp secret: %w", err)
+	}
+	return key, nil
+}
+
+// hotp 按RFC 4226计算指定计数器的一次性密码
+func hotp(key []byte, counter uint64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(math.Pow10(digits))
+	return fmt.Sprintf("%0*d", digits, code%mod)
+}
+
+// TimeStep 获取指定时间所在的时间步
+func TimeStep(t time.Time) int64 {
+	return t.Unix() / DefaultPeriod
+}
+
+// GenerateCode 生成指定时间的验证码
+func GenerateCode(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, uint64(TimeStep(t)), DefaultDigits), nil
+}
+
+// ValidateCode 校验验证码，允许前后 DefaultSkew 个时间步的时钟偏差
+// 返回匹配的时间步，调用方可据此拒绝重放(同一时间步的验证码只能使用一次)
+func ValidateCode(secret, code string, t time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != DefaultDigits {
+		return 0, false
+	}
+	if _, err := strconv.Atoi(code); err != nil {
+		return 0, false
+	}
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+	current := TimeStep(t)
+	for i := -DefaultSkew; i <= DefaultSkew; i++ {
+		step := current + int64(i)
+		if step < 0 {
+			continue
+		}
+		if hmac.Equal([]byte(hotp(key, uint64(step), DefaultDigits)), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// ProvisioningURI 生成otpauth://协议地址，前端可直接渲染为二维码供验证器App扫描
+func ProvisioningURI(secret, issuer, account string) string {
+	label := url.PathEscape(account)
+	if issuer != "" {
+		label = url.PathEscape(issuer) + ":" + label
+	}
+	params := url.Values{}
+	params.Set("secret", secret)
+	if issuer != "" {
+		params.Set("issuer", issuer)
+	}
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", strconv.Itoa(DefaultDigits))
+	params.Set("period", strconv.Itoa(DefaultPeriod))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// GenerateRecoveryCodes 生成一组恢复码，格式为 xxxxx-xxxxx
+func GenerateRecoveryCodes(count int) ([]string, error) {
+	if count <= 0 {
+		return nil, errors.New("recovery code count must be positive")
+	}
+	codes := make([]string, count)
+	for i := range codes {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		s := hex.EncodeToString(buf)
+		codes[i] = s[:5] + "-" + s[5:]
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode 对恢复码做摘要，数据库中只保存摘要
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
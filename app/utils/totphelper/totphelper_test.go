@@ -0,0 +1,95 @@
+package totphelper
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 6238 附录B中SHA1算法使用的密钥 "12345678901234567890"
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+// TestGenerateCode_RFC6238 使用RFC 6238测试向量验证(取8位结果的后6位)
+func TestGenerateCode_RFC6238(t *testing.T) {
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tc := range cases {
+		code, err := GenerateCode(rfcSecret, time.Unix(tc.unix, 0))
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, code, "unix=%d", tc.unix)
+	}
+}
+
+// TestValidateCode 测试验证码校验及时钟偏移
+func TestValidateCode(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	code, err := GenerateCode(secret, now)
+	require.NoError(t, err)
+
+	step, ok := ValidateCode(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, TimeStep(now), step)
+
+	// 上一个时间步的验证码在偏移范围内仍然有效
+	prev, err := GenerateCode(secret, now.Add(-DefaultPeriod*time.Second))
+	require.NoError(t, err)
+	_, ok = ValidateCode(secret, prev, now)
+	assert.True(t, ok)
+
+	// 超出偏移范围的验证码无效
+	old, err := GenerateCode(secret, now.Add(-3*DefaultPeriod*time.Second))
+	require.NoError(t, err)
+	_, ok = ValidateCode(secret, old, now)
+	assert.False(t, ok)
+
+	// 格式错误
+	_, ok = ValidateCode(secret, "abc", now)
+	assert.False(t, ok)
+	_, ok = ValidateCode("!!!", code, now)
+	assert.False(t, ok)
+}
+
+// TestProvisioningURI 测试otpauth地址格式
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("JBSWY3DPEHPK3PXP", "GinFast", "admin")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/GinFast:admin?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=GinFast")
+	assert.Contains(t, uri, "digits=6")
+}
+
+// TestRecoveryCodes 测试恢复码生成及摘要
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(8)
+	require.NoError(t, err)
+	assert.Len(t, codes, 8)
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		assert.Len(t, code, 11)
+		assert.False(t, seen[code], "恢复码不应重复")
+		seen[code] = true
+	}
+
+	// 摘要忽略大小写及分隔符
+	assert.Equal(t, HashRecoveryCode(codes[0]), HashRecoveryCode(strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))))
+
+	_, err = GenerateRecoveryCodes(0)
+	assert.Error(t, err)
+}
@@ -7,8 +7,10 @@ import (
 	"gin-fast/app/global/myerrors"
 	"gin-fast/app/service"
 	"gin-fast/app/utils/cachehelper"
+	"gin-fast/app/utils/captchahelper"
 	"gin-fast/app/utils/casbinhelper"
 	"gin-fast/app/utils/gormhelper"
+	"gin-fast/app/utils/mailhelper"
 	"gin-fast/app/utils/response"
 	"gin-fast/app/utils/tokenhelper"
 	"gin-fast/app/utils/uploadhelper"
@@ -30,21 +32,24 @@ func init() {
 	app.ConfigYml = ymlconfig.CreateYamlFactory(app.BasePath + "/config")
 	app.ConfigYml.ConfigFileChangeListen(func() {
 		//配置文件发生变化
+		service.NewRateLimitService().Reload()
 	})
 	// 日志
 	app.ZapLog = createZapFactory(service.ZapLogHandler)
 	// 初始化数据库
 	initDB()
 
+	// 初始化缓存管理(casbin策略监听器依赖缓存的发布订阅)
+	app.Cache = newCache()
+
 	// 初始化casbin
 	app.CasbinV2 = casbinhelper.NewCasbinHelper()
 	err := app.CasbinV2.InitCasbin(app.DB(), app.ConfigYml.GetString("casbin.modelconfig"))
 	if err != nil {
 		log.Fatal("CasbinV2.InitCasbin err :" + err.Error())
 	}
-
-	// 初始化缓存管理
-	app.Cache = newCache()
+	// 权限策略ABAC条件使用的用户部门及租户套餐
+	app.CasbinV2.SetAttributeResolver(service.NewPermissionService().ResolveAttributes)
 
 	// 初始化token管理
 	app.TokenService = newTokenService(app.Cache)
@@ -52,6 +57,15 @@ func init() {
 	// 初始化文件上传服务
 	app.UploadService = newUploadService()
 
+	// 初始化邮件发送服务
+	app.MailService = newMailService()
+
+	// 初始化验证码服务
+	app.CaptchaService = newCaptchaService()
+
+	// 初始化字段权限(响应脱敏及更新时忽略无权修改的字段)
+	app.FieldPermission = service.NewFieldPermissionService()
+
 	// 初始化Response
 	app.Response = response.NewResponseHandler()
 }
@@ -187,6 +201,21 @@ func newTokenService(cache app.CacheInterf) app.TokenServiceInterface {
 	tokenExpire := app.ConfigYml.GetDuration("token.jwttokenexpire")
 	refreshExpire := app.ConfigYml.GetDuration("token.jwttokenrefreshexpire")
 
+	// 非对称签名密钥
+	var keySet *tokenhelper.KeySet
+	signMethod := app.ConfigYml.GetString("token.signmethod")
+	if signMethod != "" && signMethod != "HS256" {
+		maxLifetime := tokenExpire
+		if refreshExpire > maxLifetime {
+			maxLifetime = refreshExpire
+		}
+		var err error
+		keySet, err = tokenhelper.NewKeySet(signMethod, loadSigningKeys(), maxLifetime*time.Second)
+		if err != nil {
+			log.Fatal("初始化token签名密钥失败: " + err.Error())
+		}
+	}
+
 	return &tokenhelper.TokenService{
 		RedisHelper:    cache,
 		JWTSecret:      app.ConfigYml.GetString("token.jwttokensignkey"),
@@ -195,7 +224,46 @@ func newTokenService(cache app.CacheInterf) app.TokenServiceInterface {
 		RefreshExpire:  refreshExpire,
 		CacheKeyPrefix: app.ConfigYml.GetString("token.cachekeyprefix"),
 		IsCache:        app.ConfigYml.GetBool("token.iscache"),
+		MaxSessions:    app.ConfigYml.GetInt("token.maxsessions"),
+		KeySet:         keySet,
+	}
+}
+
+// loadSigningKeys 从配置 token.signkeys 加载PEM格式的签名密钥
+func loadSigningKeys() []*tokenhelper.SigningKey {
+	items, _ := app.ConfigYml.Get("token.signkeys").([]interface{})
+	keys := make([]*tokenhelper.SigningKey, 0, len(items))
+	for _, item := range items {
+		conf, ok := item.(map[string]interface{})
+		if !ok {
+			log.Fatal("token.signkeys 配置格式错误")
+		}
+		kid, _ := conf["kid"].(string)
+		privateKey, _ := conf["privatekey"].(string)
+		publicKey, _ := conf["publickey"].(string)
+		activeAtStr, _ := conf["activeat"].(string)
+
+		var activeAt time.Time
+		if activeAtStr != "" {
+			var err error
+			activeAt, err = time.ParseInLocation(time.DateTime, activeAtStr, time.Local)
+			if err != nil {
+				log.Fatal("签名密钥 " + kid + " 的生效时间格式错误: " + err.Error())
+			}
+		}
+		if privateKey != "" {
+			privateKey = app.BasePath + privateKey
+		}
+		if publicKey != "" {
+			publicKey = app.BasePath + publicKey
+		}
+		key, err := tokenhelper.LoadPEMKey(kid, privateKey, publicKey, activeAt)
+		if err != nil {
+			log.Fatal("加载签名密钥失败: " + err.Error())
+		}
+		keys = append(keys, key)
 	}
+	return keys
 }
 
 // newUploadService 初始化文件上传服务
@@ -206,3 +274,21 @@ func newUploadService() app.FileUploadService {
 	}
 	return uploadService
 }
+
+// newMailService 初始化邮件发送服务
+func newMailService() app.Mailer {
+	mailer, err := mailhelper.CreateMailer()
+	if err != nil {
+		log.Fatal("初始化邮件发送服务失败: " + err.Error())
+	}
+	return mailer
+}
+
+// newCaptchaService 初始化验证码服务
+func newCaptchaService() app.Captcha {
+	captcha, err := captchahelper.CreateCaptcha()
+	if err != nil {
+		log.Fatal("初始化验证码服务失败: " + err.Error())
+	}
+	return captcha
+}
@@ -4,7 +4,7 @@ import (
 	"gin-fast/plugins/{{.DirName}}/models"
 	"github.com/gin-gonic/gin"
     "gorm.io/gorm"
-{{- if or .HasCreatedBy .HasTenantID}}
+{{- if or .HasDataScope .HasTenantID}}
 	"gin-fast/app/utils/datascope"
 {{- end}}
 {{- if .HasTenantID}}
@@ -89,7 +89,7 @@ func (s *{{.StructName}}Service) List(c *gin.Context, req models.{{.StructName}}
 	// 获取总数
 	{{.StructNameLower}}List := models.New{{.StructName}}List()
 	scopes := []func(*gorm.DB) *gorm.DB{req.Handle()}
-{{- if .HasCreatedBy}}
+{{- if .HasDataScope}}
 	scopes = append(scopes, datascope.GetDataScope(c))
 {{- end}}
 {{- if .HasTenantID}}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"gin-fast/app/routes"
+	"gin-fast/app/service"
 	"gin-fast/app/utils/ginhelper"
 	_ "gin-fast/bootstrap"
 
@@ -30,6 +31,10 @@ func main() {
 	routes.InitRoutes(engine)
 	// 初始化插件路由
 	ginhelper.InitPluginRoutes(engine)
+	// 按路由表同步API表
+	service.NewSysApiService().SyncOnStartup(engine.Routes())
+	// 定期处理限时角色授权的生效及回收
+	service.NewRoleGrantService().StartSweeper()
 	// 启动服务器
 	_ = ginhelper.StartServer(engine)
 
@@ -524,6 +524,24 @@ INSERT INTO `sys_casbin_rule` VALUES ('2967', 'p', 'role_4', '/api/users/logout'
 INSERT INTO `sys_casbin_rule` VALUES ('2968', 'p', 'role_4', '/api/users/profile', 'GET', '*', '', '');
 INSERT INTO `sys_casbin_rule` VALUES ('2965', 'p', 'role_4', '/api/users/uploadAvatar', 'POST', '*', '', '');
 
+-- ----------------------------
+-- Table structure for sys_casbin_condition
+-- ----------------------------
+DROP TABLE IF EXISTS `sys_casbin_condition`;
+CREATE TABLE `sys_casbin_condition` (
+  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `ptype` varchar(100) COLLATE utf8_unicode_ci DEFAULT NULL COMMENT '策略类型',
+  `v0` varchar(100) COLLATE utf8_unicode_ci DEFAULT NULL COMMENT '策略主体(角色)',
+  `v1` varchar(100) COLLATE utf8_unicode_ci DEFAULT NULL COMMENT '策略资源(路径)',
+  `v2` varchar(100) COLLATE utf8_unicode_ci DEFAULT NULL COMMENT '策略动作(请求方法)',
+  `v3` varchar(100) COLLATE utf8_unicode_ci DEFAULT NULL COMMENT '策略域',
+  `conditions` text COLLATE utf8_unicode_ci COMMENT 'ABAC条件(JSON)',
+  `created_at` datetime DEFAULT NULL,
+  `updated_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`) USING BTREE,
+  UNIQUE KEY `idx_casbin_condition` (`ptype`,`v0`,`v1`,`v2`,`v3`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci ROW_FORMAT=DYNAMIC COMMENT='权限策略ABAC条件表';
+
 -- ----------------------------
 -- Table structure for sys_department
 -- ----------------------------
@@ -936,6 +954,8 @@ CREATE TABLE `sys_operation_logs` (
   `error_msg` text COMMENT '错误信息',
   `location` varchar(100) DEFAULT NULL COMMENT '操作地点',
   `tenant_id` int(11) unsigned DEFAULT '0' COMMENT '租户ID字段',
+  `impersonator_id` int(11) unsigned DEFAULT '0' COMMENT '模拟登录操作人ID',
+  `impersonator_name` varchar(50) DEFAULT NULL COMMENT '模拟登录操作人用户名',
   PRIMARY KEY (`id`) USING BTREE,
   KEY `idx_sys_operation_logs_deleted_at` (`deleted_at`) USING BTREE,
   KEY `idx_user_id` (`user_id`) USING BTREE
@@ -972,6 +992,20 @@ CREATE TABLE `sys_role` (
 INSERT INTO `sys_role` VALUES ('1', '系统管理员', '0', '1', '最高权限管理员角色', '0', '2025-09-01 17:32:12', '2025-09-30 15:53:24', null, '1', '1', '', '0');
 INSERT INTO `sys_role` VALUES ('2', '演示', '0', '1', '', '0', '2025-10-14 15:12:09', '2025-10-17 15:34:47', null, '1', '0', '', '0');
 
+-- ----------------------------
+-- Table structure for sys_role_field
+-- ----------------------------
+DROP TABLE IF EXISTS `sys_role_field`;
+CREATE TABLE `sys_role_field` (
+  `role_id` int(11) unsigned NOT NULL COMMENT '角色ID',
+  `model` varchar(100) NOT NULL COMMENT '模型(表名)',
+  `field` varchar(100) NOT NULL COMMENT '字段(JSON字段名)',
+  `action` tinyint(4) NOT NULL DEFAULT '1' COMMENT '权限 1只读 2脱敏 3隐藏',
+  `created_at` datetime DEFAULT NULL,
+  `updated_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`role_id`,`model`,`field`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci ROW_FORMAT=DYNAMIC COMMENT='角色字段权限表';
+
 -- ----------------------------
 -- Table structure for sys_role_menu
 -- ----------------------------
@@ -1128,6 +1162,53 @@ INSERT INTO `sys_role_menu` VALUES ('2', '140338');
 INSERT INTO `sys_role_menu` VALUES ('2', '140339');
 INSERT INTO `sys_role_menu` VALUES ('2', '140340');
 
+-- ----------------------------
+-- Table structure for sys_tenant_ldap
+-- ----------------------------
+DROP TABLE IF EXISTS `sys_tenant_ldap`;
+CREATE TABLE `sys_tenant_ldap` (
+  `tenant_id` int(11) unsigned NOT NULL DEFAULT '0' COMMENT '租户ID',
+  `enabled` tinyint(1) DEFAULT '0' COMMENT '是否启用 0停用 1启用',
+  `url` varchar(255) NOT NULL DEFAULT '' COMMENT '服务地址(ldap://或ldaps://)',
+  `start_tls` tinyint(1) DEFAULT '0' COMMENT '是否使用StartTLS',
+  `insecure_skip_verify` tinyint(1) DEFAULT '0' COMMENT '是否跳过证书校验',
+  `bind_dn` varchar(255) DEFAULT '' COMMENT '服务账号DN',
+  `bind_password` varchar(255) DEFAULT '' COMMENT '服务账号密码',
+  `base_dn` varchar(255) NOT NULL DEFAULT '' COMMENT '用户查询基础DN',
+  `user_filter` varchar(255) DEFAULT '' COMMENT '用户查询过滤器(%s为用户名)',
+  `email_attr` varchar(64) DEFAULT '' COMMENT '邮箱属性',
+  `name_attr` varchar(64) DEFAULT '' COMMENT '显示名称属性',
+  `group_attr` varchar(64) DEFAULT '' COMMENT '所属组属性',
+  `group_roles` text COMMENT '用户组与角色映射(JSON)',
+  `require_group` tinyint(1) DEFAULT '0' COMMENT '是否仅允许映射用户组中的用户登录',
+  `link_existing` tinyint(1) DEFAULT '0' COMMENT '是否按用户名绑定租户内已有用户',
+  `created_at` datetime DEFAULT NULL,
+  `updated_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`tenant_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci ROW_FORMAT=DYNAMIC COMMENT='租户目录服务登录配置表';
+
+-- ----------------------------
+-- Table structure for sys_tenant_password_policy
+-- ----------------------------
+DROP TABLE IF EXISTS `sys_tenant_password_policy`;
+CREATE TABLE `sys_tenant_password_policy` (
+  `tenant_id` int(11) unsigned NOT NULL COMMENT '租户ID',
+  `enabled` tinyint(1) DEFAULT '0' COMMENT '是否启用 0停用 1启用',
+  `min_length` int(11) DEFAULT '0' COMMENT '最小长度',
+  `require_upper` tinyint(1) DEFAULT '0' COMMENT '是否必须包含大写字母',
+  `require_lower` tinyint(1) DEFAULT '0' COMMENT '是否必须包含小写字母',
+  `require_digit` tinyint(1) DEFAULT '0' COMMENT '是否必须包含数字',
+  `require_special` tinyint(1) DEFAULT '0' COMMENT '是否必须包含特殊字符',
+  `special_chars` varchar(100) DEFAULT '' COMMENT '特殊字符集合',
+  `forbidden_words` text COMMENT '禁止包含的词(JSON数组)',
+  `disallow_username` tinyint(1) DEFAULT '0' COMMENT '是否禁止包含用户名',
+  `history_count` int(11) DEFAULT '0' COMMENT '禁止重复使用最近N次的密码',
+  `max_age_days` int(11) DEFAULT '0' COMMENT '密码最长使用天数',
+  `created_at` datetime DEFAULT NULL,
+  `updated_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`tenant_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci ROW_FORMAT=DYNAMIC COMMENT='租户密码策略表';
+
 -- ----------------------------
 -- Table structure for sys_tenants
 -- ----------------------------
@@ -1144,6 +1225,11 @@ CREATE TABLE `sys_tenants` (
   `status` tinyint(4) NOT NULL DEFAULT '1' COMMENT '状态 0停用 1启用',
   `domain` varchar(255) DEFAULT NULL COMMENT '租户域名',
   `platform_domain` varchar(255) DEFAULT NULL COMMENT '主域名',
+  `plan` varchar(50) NOT NULL DEFAULT '' COMMENT '租户套餐',
+  `max_users` int(11) NOT NULL DEFAULT '0' COMMENT '最大用户数(0不限制)',
+  `max_roles` int(11) NOT NULL DEFAULT '0' COMMENT '最大角色数(0不限制)',
+  `max_storage` bigint(20) NOT NULL DEFAULT '0' COMMENT '最大存储空间(字节，0不限制)',
+  `max_api_calls` int(11) NOT NULL DEFAULT '0' COMMENT '每日最大API调用次数(0不限制)',
   PRIMARY KEY (`id`) USING BTREE,
   UNIQUE KEY `code` (`code`) USING BTREE,
   UNIQUE KEY `domain` (`domain`) USING BTREE,
@@ -1153,7 +1239,7 @@ CREATE TABLE `sys_tenants` (
 -- ----------------------------
 -- Records of sys_tenants
 -- ----------------------------
-INSERT INTO `sys_tenants` VALUES ('1', '2025-11-03 11:16:45', '2025-11-03 11:16:45', null, '1', '测试租户1', 'dom1', '', '1', '', null);
+INSERT INTO `sys_tenants` VALUES ('1', '2025-11-03 11:16:45', '2025-11-03 11:16:45', null, '1', '测试租户1', 'dom1', '', '1', '', null, '', '0', '0', '0', '0');
 
 -- ----------------------------
 -- Table structure for sys_users
@@ -1176,6 +1262,7 @@ CREATE TABLE `sys_users` (
   `deleted_at` datetime DEFAULT NULL,
   `created_by` int(11) unsigned DEFAULT '0' COMMENT '创建人',
   `tenant_id` int(11) unsigned DEFAULT '0' COMMENT '租户ID字段',
+  `password_changed_at` datetime DEFAULT NULL COMMENT '密码修改时间',
   PRIMARY KEY (`id`) USING BTREE,
   UNIQUE KEY `username` (`username`) USING BTREE
 ) ENGINE=InnoDB AUTO_INCREMENT=5 DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci ROW_FORMAT=DYNAMIC;
@@ -1183,8 +1270,80 @@ CREATE TABLE `sys_users` (
 -- ----------------------------
 -- Records of sys_users
 -- ----------------------------
-INSERT INTO `sys_users` VALUES ('1', 'admin', '$2a$10$0aS9FxWlOz/PXiqzsBr7huy.Dqdwucyb795qiWcA6fsn0Lu.GLA.C', 'admin@example.com', '1', '1', '18800000006', '1', '超级管理员', '/public/uploads/2025-11-04/20251104_0945787a-8536-45fc-ba75-e94c8daaec06.jpeg', '超级管理员', '2025-08-18 14:55:05', '2025-11-17 17:38:01', null, '0', '0');
-INSERT INTO `sys_users` VALUES ('4', 'demo', '$2a$10$yxq80jnZCRPn/hhQYUffheRnDopYjiq1AKGdgrg1oatLha7tc/.Qe', '', '1', '1', '', '1', '演示账号', '', '演示账号', '2025-10-17 15:38:37', '2025-10-31 16:32:34', null, '1', '0');
+INSERT INTO `sys_users` VALUES ('1', 'admin', '$2a$10$0aS9FxWlOz/PXiqzsBr7huy.Dqdwucyb795qiWcA6fsn0Lu.GLA.C', 'admin@example.com', '1', '1', '18800000006', '1', '超级管理员', '/public/uploads/2025-11-04/20251104_0945787a-8536-45fc-ba75-e94c8daaec06.jpeg', '超级管理员', '2025-08-18 14:55:05', '2025-11-17 17:38:01', null, '0', '0', null);
+INSERT INTO `sys_users` VALUES ('4', 'demo', '$2a$10$yxq80jnZCRPn/hhQYUffheRnDopYjiq1AKGdgrg1oatLha7tc/.Qe', '', '1', '1', '', '1', '演示账号', '', '演示账号', '2025-10-17 15:38:37', '2025-10-31 16:32:34', null, '1', '0', null);
+
+-- ----------------------------
+-- Table structure for sys_user_api_key
+-- ----------------------------
+DROP TABLE IF EXISTS `sys_user_api_key`;
+CREATE TABLE `sys_user_api_key` (
+  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) unsigned NOT NULL DEFAULT '0' COMMENT '用户ID',
+  `tenant_id` int(11) unsigned DEFAULT '0' COMMENT '租户ID',
+  `tenant_code` varchar(100) DEFAULT '' COMMENT '租户编码',
+  `name` varchar(100) NOT NULL DEFAULT '' COMMENT '密钥名称',
+  `prefix` varchar(20) NOT NULL DEFAULT '' COMMENT '密钥前缀(用于识别)',
+  `key_hash` varchar(64) NOT NULL DEFAULT '' COMMENT '密钥摘要(SHA-256)',
+  `scopes` text COMMENT '授权范围(JSON数组)',
+  `allowed_ips` text COMMENT 'IP白名单(JSON数组)',
+  `status` tinyint(4) DEFAULT '1' COMMENT '状态 0停用 1启用',
+  `expires_at` datetime DEFAULT NULL COMMENT '过期时间，为空表示永不过期',
+  `last_used_at` datetime DEFAULT NULL COMMENT '最近使用时间',
+  `last_used_ip` varchar(64) DEFAULT '' COMMENT '最近使用IP',
+  `created_at` datetime DEFAULT NULL,
+  `updated_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`) USING BTREE,
+  UNIQUE KEY `idx_sys_user_api_key_key_hash` (`key_hash`) USING BTREE,
+  KEY `idx_sys_user_api_key_user_id` (`user_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci ROW_FORMAT=DYNAMIC COMMENT='用户API密钥表';
+
+-- ----------------------------
+-- Table structure for sys_user_password_history
+-- ----------------------------
+DROP TABLE IF EXISTS `sys_user_password_history`;
+CREATE TABLE `sys_user_password_history` (
+  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) unsigned NOT NULL DEFAULT '0' COMMENT '用户ID',
+  `password` varchar(255) NOT NULL DEFAULT '' COMMENT '密码摘要',
+  `created_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`) USING BTREE,
+  KEY `idx_sys_user_password_history_user_id` (`user_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci ROW_FORMAT=DYNAMIC COMMENT='用户历史密码表';
+
+-- ----------------------------
+-- Table structure for sys_user_identity
+-- ----------------------------
+DROP TABLE IF EXISTS `sys_user_identity`;
+CREATE TABLE `sys_user_identity` (
+  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) unsigned NOT NULL DEFAULT '0' COMMENT '用户ID',
+  `provider` varchar(64) NOT NULL DEFAULT '' COMMENT '身份提供方标识',
+  `subject` varchar(255) NOT NULL DEFAULT '' COMMENT '提供方用户唯一标识(sub)',
+  `email` varchar(100) DEFAULT '' COMMENT '提供方返回的邮箱',
+  `last_login_at` datetime DEFAULT NULL COMMENT '最近登录时间',
+  `created_at` datetime DEFAULT NULL,
+  `updated_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`) USING BTREE,
+  UNIQUE KEY `idx_provider_subject` (`provider`,`subject`) USING BTREE,
+  KEY `idx_sys_user_identity_user_id` (`user_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci ROW_FORMAT=DYNAMIC COMMENT='用户第三方身份绑定表';
+
+-- ----------------------------
+-- Table structure for sys_user_mfa
+-- ----------------------------
+DROP TABLE IF EXISTS `sys_user_mfa`;
+CREATE TABLE `sys_user_mfa` (
+  `user_id` int(11) unsigned NOT NULL DEFAULT '0' COMMENT '用户ID',
+  `secret` varchar(64) NOT NULL DEFAULT '' COMMENT 'TOTP密钥(Base32)',
+  `enabled` tinyint(1) DEFAULT '0' COMMENT '是否已启用 0未启用 1已启用',
+  `recovery_codes` text COMMENT '恢复码摘要(JSON数组)',
+  `last_used_step` bigint(20) DEFAULT '0' COMMENT '最近一次使用的时间步(防重放)',
+  `confirmed_at` datetime DEFAULT NULL COMMENT '绑定确认时间',
+  `created_at` datetime DEFAULT NULL,
+  `updated_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`user_id`) USING BTREE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci ROW_FORMAT=DYNAMIC COMMENT='用户二次验证表';
 
 -- ----------------------------
 -- Table structure for sys_user_role
@@ -1193,14 +1352,17 @@ DROP TABLE IF EXISTS `sys_user_role`;
 CREATE TABLE `sys_user_role` (
   `user_id` int(11) unsigned NOT NULL DEFAULT '0' COMMENT '用户ID',
   `role_id` int(11) unsigned NOT NULL DEFAULT '0' COMMENT '角色ID',
-  PRIMARY KEY (`user_id`,`role_id`) USING BTREE
+  `valid_from` datetime DEFAULT NULL COMMENT '生效时间',
+  `valid_until` datetime DEFAULT NULL COMMENT '失效时间',
+  PRIMARY KEY (`user_id`,`role_id`) USING BTREE,
+  KEY `idx_valid_until` (`valid_until`) USING BTREE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci ROW_FORMAT=DYNAMIC;
 
 -- ----------------------------
 -- Records of sys_user_role
 -- ----------------------------
-INSERT INTO `sys_user_role` VALUES ('1', '1');
-INSERT INTO `sys_user_role` VALUES ('4', '2');
+INSERT INTO `sys_user_role` VALUES ('1', '1', NULL, NULL);
+INSERT INTO `sys_user_role` VALUES ('4', '2', NULL, NULL);
 
 -- ----------------------------
 -- Table structure for sys_user_tenant
@@ -554,6 +554,30 @@ SELECT setval('sys_casbin_rule_id_seq', 7025, false);
 CREATE UNIQUE INDEX idx_casbin_rule ON sys_casbin_rule (ptype, v0, v1, v2, v3, v4, v5);
 CREATE UNIQUE INDEX idx_sys_casbin_rule ON sys_casbin_rule (ptype, v0, v1, v2, v3, v4, v5);
 
+-- 表: sys_casbin_condition
+DROP TABLE IF EXISTS sys_casbin_condition;
+CREATE TABLE sys_casbin_condition (
+    id SERIAL PRIMARY KEY,
+    ptype VARCHAR(100),
+    v0 VARCHAR(100),
+    v1 VARCHAR(100),
+    v2 VARCHAR(100),
+    v3 VARCHAR(100),
+    conditions TEXT,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+);
+
+COMMENT ON TABLE sys_casbin_condition IS '权限策略ABAC条件表';
+COMMENT ON COLUMN sys_casbin_condition.ptype IS '策略类型';
+COMMENT ON COLUMN sys_casbin_condition.v0 IS '策略主体(角色)';
+COMMENT ON COLUMN sys_casbin_condition.v1 IS '策略资源(路径)';
+COMMENT ON COLUMN sys_casbin_condition.v2 IS '策略动作(请求方法)';
+COMMENT ON COLUMN sys_casbin_condition.v3 IS '策略域';
+COMMENT ON COLUMN sys_casbin_condition.conditions IS 'ABAC条件(JSON)';
+
+CREATE UNIQUE INDEX idx_casbin_condition ON sys_casbin_condition (ptype, v0, v1, v2, v3);
+
 -- 表: sys_department
 DROP TABLE IF EXISTS sys_department;
 CREATE TABLE sys_department (
@@ -1038,7 +1062,9 @@ CREATE TABLE sys_operation_logs (
     duration BIGINT,
     error_msg TEXT,
     location VARCHAR(100),
-    tenant_id INTEGER DEFAULT 0
+    tenant_id INTEGER DEFAULT 0,
+    impersonator_id INTEGER DEFAULT 0,
+    impersonator_name VARCHAR(50)
 );
 
 COMMENT ON TABLE sys_operation_logs IS '系统操作日志表';
@@ -1061,6 +1087,8 @@ COMMENT ON COLUMN sys_operation_logs.duration IS '操作耗时(毫秒)';
 COMMENT ON COLUMN sys_operation_logs.error_msg IS '错误信息';
 COMMENT ON COLUMN sys_operation_logs.location IS '操作地点';
 COMMENT ON COLUMN sys_operation_logs.tenant_id IS '租户ID字段';
+COMMENT ON COLUMN sys_operation_logs.impersonator_id IS '模拟登录操作人ID';
+COMMENT ON COLUMN sys_operation_logs.impersonator_name IS '模拟登录操作人用户名';
 
 CREATE INDEX idx_sys_operation_logs_deleted_at ON sys_operation_logs (deleted_at);
 CREATE INDEX idx_user_id ON sys_operation_logs (user_id);
@@ -1104,6 +1132,24 @@ INSERT INTO sys_role (id, name, sort, status, description, parent_id, created_at
 
 SELECT setval('sys_role_id_seq', 3, false);
 
+-- 表: sys_role_field
+DROP TABLE IF EXISTS sys_role_field;
+CREATE TABLE sys_role_field (
+    role_id INTEGER NOT NULL,
+    model VARCHAR(100) NOT NULL,
+    field VARCHAR(100) NOT NULL,
+    action SMALLINT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    PRIMARY KEY (role_id, model, field)
+);
+
+COMMENT ON TABLE sys_role_field IS '角色字段权限表';
+COMMENT ON COLUMN sys_role_field.role_id IS '角色ID';
+COMMENT ON COLUMN sys_role_field.model IS '模型(表名)';
+COMMENT ON COLUMN sys_role_field.field IS '字段(JSON字段名)';
+COMMENT ON COLUMN sys_role_field.action IS '权限 1只读 2脱敏 3隐藏';
+
 -- 表: sys_role_menu
 DROP TABLE IF EXISTS sys_role_menu;
 CREATE TABLE sys_role_menu (
@@ -1256,6 +1302,78 @@ INSERT INTO sys_role_menu (role_id, menu_id) VALUES
 (2, 140339),
 (2, 140340);
 
+-- 表: sys_tenant_ldap
+DROP TABLE IF EXISTS sys_tenant_ldap;
+CREATE TABLE sys_tenant_ldap (
+    tenant_id INTEGER NOT NULL DEFAULT 0 PRIMARY KEY,
+    enabled BOOLEAN DEFAULT FALSE,
+    url VARCHAR(255) NOT NULL DEFAULT '',
+    start_tls BOOLEAN DEFAULT FALSE,
+    insecure_skip_verify BOOLEAN DEFAULT FALSE,
+    bind_dn VARCHAR(255) DEFAULT '',
+    bind_password VARCHAR(255) DEFAULT '',
+    base_dn VARCHAR(255) NOT NULL DEFAULT '',
+    user_filter VARCHAR(255) DEFAULT '',
+    email_attr VARCHAR(64) DEFAULT '',
+    name_attr VARCHAR(64) DEFAULT '',
+    group_attr VARCHAR(64) DEFAULT '',
+    group_roles TEXT,
+    require_group BOOLEAN DEFAULT FALSE,
+    link_existing BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+);
+
+COMMENT ON TABLE sys_tenant_ldap IS '租户目录服务登录配置表';
+COMMENT ON COLUMN sys_tenant_ldap.tenant_id IS '租户ID';
+COMMENT ON COLUMN sys_tenant_ldap.enabled IS '是否启用';
+COMMENT ON COLUMN sys_tenant_ldap.url IS '服务地址(ldap://或ldaps://)';
+COMMENT ON COLUMN sys_tenant_ldap.start_tls IS '是否使用StartTLS';
+COMMENT ON COLUMN sys_tenant_ldap.insecure_skip_verify IS '是否跳过证书校验';
+COMMENT ON COLUMN sys_tenant_ldap.bind_dn IS '服务账号DN';
+COMMENT ON COLUMN sys_tenant_ldap.bind_password IS '服务账号密码';
+COMMENT ON COLUMN sys_tenant_ldap.base_dn IS '用户查询基础DN';
+COMMENT ON COLUMN sys_tenant_ldap.user_filter IS '用户查询过滤器(%s为用户名)';
+COMMENT ON COLUMN sys_tenant_ldap.email_attr IS '邮箱属性';
+COMMENT ON COLUMN sys_tenant_ldap.name_attr IS '显示名称属性';
+COMMENT ON COLUMN sys_tenant_ldap.group_attr IS '所属组属性';
+COMMENT ON COLUMN sys_tenant_ldap.group_roles IS '用户组与角色映射(JSON)';
+COMMENT ON COLUMN sys_tenant_ldap.require_group IS '是否仅允许映射用户组中的用户登录';
+COMMENT ON COLUMN sys_tenant_ldap.link_existing IS '是否按用户名绑定租户内已有用户';
+
+-- 表: sys_tenant_password_policy
+DROP TABLE IF EXISTS sys_tenant_password_policy;
+CREATE TABLE sys_tenant_password_policy (
+    tenant_id INTEGER PRIMARY KEY,
+    enabled BOOLEAN DEFAULT FALSE,
+    min_length INTEGER DEFAULT 0,
+    require_upper BOOLEAN DEFAULT FALSE,
+    require_lower BOOLEAN DEFAULT FALSE,
+    require_digit BOOLEAN DEFAULT FALSE,
+    require_special BOOLEAN DEFAULT FALSE,
+    special_chars VARCHAR(100) DEFAULT '',
+    forbidden_words TEXT,
+    disallow_username BOOLEAN DEFAULT FALSE,
+    history_count INTEGER DEFAULT 0,
+    max_age_days INTEGER DEFAULT 0,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+);
+
+COMMENT ON TABLE sys_tenant_password_policy IS '租户密码策略表';
+COMMENT ON COLUMN sys_tenant_password_policy.tenant_id IS '租户ID';
+COMMENT ON COLUMN sys_tenant_password_policy.enabled IS '是否启用 0停用 1启用';
+COMMENT ON COLUMN sys_tenant_password_policy.min_length IS '最小长度';
+COMMENT ON COLUMN sys_tenant_password_policy.require_upper IS '是否必须包含大写字母';
+COMMENT ON COLUMN sys_tenant_password_policy.require_lower IS '是否必须包含小写字母';
+COMMENT ON COLUMN sys_tenant_password_policy.require_digit IS '是否必须包含数字';
+COMMENT ON COLUMN sys_tenant_password_policy.require_special IS '是否必须包含特殊字符';
+COMMENT ON COLUMN sys_tenant_password_policy.special_chars IS '特殊字符集合';
+COMMENT ON COLUMN sys_tenant_password_policy.forbidden_words IS '禁止包含的词(JSON数组)';
+COMMENT ON COLUMN sys_tenant_password_policy.disallow_username IS '是否禁止包含用户名';
+COMMENT ON COLUMN sys_tenant_password_policy.history_count IS '禁止重复使用最近N次的密码';
+COMMENT ON COLUMN sys_tenant_password_policy.max_age_days IS '密码最长使用天数';
+
 -- 表: sys_tenants
 DROP TABLE IF EXISTS sys_tenants;
 CREATE TABLE sys_tenants (
@@ -1269,7 +1387,12 @@ CREATE TABLE sys_tenants (
     description VARCHAR(500),
     status SMALLINT NOT NULL DEFAULT 1,
     domain VARCHAR(255),
-    platform_domain VARCHAR(255)
+    platform_domain VARCHAR(255),
+    plan VARCHAR(50) NOT NULL DEFAULT '',
+    max_users INTEGER NOT NULL DEFAULT 0,
+    max_roles INTEGER NOT NULL DEFAULT 0,
+    max_storage BIGINT NOT NULL DEFAULT 0,
+    max_api_calls INTEGER NOT NULL DEFAULT 0
 );
 
 COMMENT ON TABLE sys_tenants IS '租户表';
@@ -1284,13 +1407,18 @@ COMMENT ON COLUMN sys_tenants.description IS '租户描述';
 COMMENT ON COLUMN sys_tenants.status IS '状态 0停用 1启用';
 COMMENT ON COLUMN sys_tenants.domain IS '租户域名';
 COMMENT ON COLUMN sys_tenants.platform_domain IS '主域名';
+COMMENT ON COLUMN sys_tenants.plan IS '租户套餐';
+COMMENT ON COLUMN sys_tenants.max_users IS '最大用户数(0不限制)';
+COMMENT ON COLUMN sys_tenants.max_roles IS '最大角色数(0不限制)';
+COMMENT ON COLUMN sys_tenants.max_storage IS '最大存储空间(字节，0不限制)';
+COMMENT ON COLUMN sys_tenants.max_api_calls IS '每日最大API调用次数(0不限制)';
 
 CREATE UNIQUE INDEX sys_tenants_code_idx ON sys_tenants (code);
 CREATE UNIQUE INDEX sys_tenants_domain_idx ON sys_tenants (domain);
 CREATE INDEX idx_sys_tenants_deleted_at ON sys_tenants (deleted_at);
 
-INSERT INTO sys_tenants (id, created_at, updated_at, deleted_at, created_by, name, code, description, status, domain, platform_domain) VALUES
-(1, '2025-11-03 11:16:45', '2025-11-03 11:16:45', NULL, 1, '测试租户1', 'dom1', '', 1, '', NULL);
+INSERT INTO sys_tenants (id, created_at, updated_at, deleted_at, created_by, name, code, description, status, domain, platform_domain, plan, max_users, max_roles, max_storage, max_api_calls) VALUES
+(1, '2025-11-03 11:16:45', '2025-11-03 11:16:45', NULL, 1, '测试租户1', 'dom1', '', 1, '', NULL, '', 0, 0, 0, 0);
 
 SELECT setval('sys_tenants_id_seq', 2, false);
 
@@ -1312,7 +1440,8 @@ CREATE TABLE sys_users (
     updated_at TIMESTAMP,
     deleted_at TIMESTAMP,
     created_by INTEGER DEFAULT 0,
-    tenant_id INTEGER DEFAULT 0
+    tenant_id INTEGER DEFAULT 0,
+    password_changed_at TIMESTAMP
 );
 
 COMMENT ON TABLE sys_users IS '用户表';
@@ -1332,6 +1461,7 @@ COMMENT ON COLUMN sys_users.updated_at IS '更新时间';
 COMMENT ON COLUMN sys_users.deleted_at IS '删除时间';
 COMMENT ON COLUMN sys_users.created_by IS '创建人';
 COMMENT ON COLUMN sys_users.tenant_id IS '租户ID字段';
+COMMENT ON COLUMN sys_users.password_changed_at IS '密码修改时间';
 
 CREATE UNIQUE INDEX sys_users_username_idx ON sys_users (username);
 
@@ -1341,17 +1471,119 @@ INSERT INTO sys_users (id, username, password, email, status, dept_id, phone, se
 
 SELECT setval('sys_users_id_seq', 5, false);
 
+-- 表: sys_user_api_key
+DROP TABLE IF EXISTS sys_user_api_key;
+CREATE TABLE sys_user_api_key (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    tenant_id INTEGER DEFAULT 0,
+    tenant_code VARCHAR(100) DEFAULT '',
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    prefix VARCHAR(20) NOT NULL DEFAULT '',
+    key_hash VARCHAR(64) NOT NULL DEFAULT '',
+    scopes TEXT,
+    allowed_ips TEXT,
+    status SMALLINT DEFAULT 1,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    last_used_ip VARCHAR(64) DEFAULT '',
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_sys_user_api_key_key_hash ON sys_user_api_key (key_hash);
+CREATE INDEX idx_sys_user_api_key_user_id ON sys_user_api_key (user_id);
+
+COMMENT ON TABLE sys_user_api_key IS '用户API密钥表';
+COMMENT ON COLUMN sys_user_api_key.user_id IS '用户ID';
+COMMENT ON COLUMN sys_user_api_key.tenant_id IS '租户ID';
+COMMENT ON COLUMN sys_user_api_key.tenant_code IS '租户编码';
+COMMENT ON COLUMN sys_user_api_key.name IS '密钥名称';
+COMMENT ON COLUMN sys_user_api_key.prefix IS '密钥前缀(用于识别)';
+COMMENT ON COLUMN sys_user_api_key.key_hash IS '密钥摘要(SHA-256)';
+COMMENT ON COLUMN sys_user_api_key.scopes IS '授权范围(JSON数组)';
+COMMENT ON COLUMN sys_user_api_key.allowed_ips IS 'IP白名单(JSON数组)';
+COMMENT ON COLUMN sys_user_api_key.status IS '状态 0停用 1启用';
+COMMENT ON COLUMN sys_user_api_key.expires_at IS '过期时间，为空表示永不过期';
+COMMENT ON COLUMN sys_user_api_key.last_used_at IS '最近使用时间';
+COMMENT ON COLUMN sys_user_api_key.last_used_ip IS '最近使用IP';
+
+-- 表: sys_user_password_history
+DROP TABLE IF EXISTS sys_user_password_history;
+CREATE TABLE sys_user_password_history (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    password VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP
+);
+
+CREATE INDEX idx_sys_user_password_history_user_id ON sys_user_password_history (user_id);
+
+COMMENT ON TABLE sys_user_password_history IS '用户历史密码表';
+COMMENT ON COLUMN sys_user_password_history.user_id IS '用户ID';
+COMMENT ON COLUMN sys_user_password_history.password IS '密码摘要';
+
+-- 表: sys_user_identity
+DROP TABLE IF EXISTS sys_user_identity;
+CREATE TABLE sys_user_identity (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    provider VARCHAR(64) NOT NULL DEFAULT '',
+    subject VARCHAR(255) NOT NULL DEFAULT '',
+    email VARCHAR(100) DEFAULT '',
+    last_login_at TIMESTAMP,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_provider_subject ON sys_user_identity (provider, subject);
+CREATE INDEX idx_sys_user_identity_user_id ON sys_user_identity (user_id);
+
+COMMENT ON TABLE sys_user_identity IS '用户第三方身份绑定表';
+COMMENT ON COLUMN sys_user_identity.user_id IS '用户ID';
+COMMENT ON COLUMN sys_user_identity.provider IS '身份提供方标识';
+COMMENT ON COLUMN sys_user_identity.subject IS '提供方用户唯一标识(sub)';
+COMMENT ON COLUMN sys_user_identity.email IS '提供方返回的邮箱';
+COMMENT ON COLUMN sys_user_identity.last_login_at IS '最近登录时间';
+
+-- 表: sys_user_mfa
+DROP TABLE IF EXISTS sys_user_mfa;
+CREATE TABLE sys_user_mfa (
+    user_id INTEGER NOT NULL DEFAULT 0 PRIMARY KEY,
+    secret VARCHAR(64) NOT NULL DEFAULT '',
+    enabled BOOLEAN DEFAULT FALSE,
+    recovery_codes TEXT,
+    last_used_step BIGINT DEFAULT 0,
+    confirmed_at TIMESTAMP,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+);
+
+COMMENT ON TABLE sys_user_mfa IS '用户二次验证表';
+COMMENT ON COLUMN sys_user_mfa.user_id IS '用户ID';
+COMMENT ON COLUMN sys_user_mfa.secret IS 'TOTP密钥(Base32)';
+COMMENT ON COLUMN sys_user_mfa.enabled IS '是否已启用';
+COMMENT ON COLUMN sys_user_mfa.recovery_codes IS '恢复码摘要(JSON数组)';
+COMMENT ON COLUMN sys_user_mfa.last_used_step IS '最近一次使用的时间步(防重放)';
+COMMENT ON COLUMN sys_user_mfa.confirmed_at IS '绑定确认时间';
+
 -- 表: sys_user_role
 DROP TABLE IF EXISTS sys_user_role;
 CREATE TABLE sys_user_role (
     user_id INTEGER NOT NULL DEFAULT 0,
     role_id INTEGER NOT NULL DEFAULT 0,
+    valid_from TIMESTAMP,
+    valid_until TIMESTAMP,
     PRIMARY KEY (user_id, role_id)
 );
 
+CREATE INDEX idx_sys_user_role_valid_until ON sys_user_role (valid_until);
+
 COMMENT ON TABLE sys_user_role IS '用户角色关联表';
 COMMENT ON COLUMN sys_user_role.user_id IS '用户ID';
 COMMENT ON COLUMN sys_user_role.role_id IS '角色ID';
+COMMENT ON COLUMN sys_user_role.valid_from IS '生效时间';
+COMMENT ON COLUMN sys_user_role.valid_until IS '失效时间';
 
 INSERT INTO sys_user_role (user_id, role_id) VALUES
 (1, 1),
@@ -3851,7 +3851,12 @@ CREATE TABLE [dbo].[sys_tenants] (
 [description] nvarchar(500) NULL ,
 [status] tinyint NOT NULL DEFAULT ((1)) ,
 [domain] nvarchar(255) NULL ,
-[platform_domain] nvarchar(255) NULL 
+[platform_domain] nvarchar(255) NULL ,
+[plan] nvarchar(50) NOT NULL DEFAULT N'' ,
+[max_users] int NOT NULL DEFAULT ((0)) ,
+[max_roles] int NOT NULL DEFAULT ((0)) ,
+[max_storage] bigint NOT NULL DEFAULT ((0)) ,
+[max_api_calls] int NOT NULL DEFAULT ((0)) 
 )
 
 
@@ -3970,7 +3975,9 @@ DROP TABLE [dbo].[sys_user_role]
 GO
 CREATE TABLE [dbo].[sys_user_role] (
 [user_id] int NOT NULL DEFAULT ((0)) ,
-[role_id] int NOT NULL DEFAULT ((0)) 
+[role_id] int NOT NULL DEFAULT ((0)) ,
+[valid_from] datetime NULL ,
+[valid_until] datetime NULL 
 )
 
 